@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
+)
+
+// auditMiddleware 把调用者放入请求上下文, 之后修改规则等操作记录为该调用者所为.
+// 修改数据的请求 (非 GET, HEAD 和 OPTIONS) 在审计日志中记录一次调用
+func auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(audit.WithActor(r.Context(), principalFrom(r).auditActor()))
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		r, pattern := withRouteRecorder(r)
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(wrapped, r)
+
+		audit.Record(r.Context(), audit.ActionAPIRequest, r.Method+" "+r.URL.Path, map[string]any{
+			"route":  routePattern(*pattern),
+			"status": wrapped.statusCode,
+		})
+	})
+}
+
+// auditActor 返回审计日志中记录的调用者
+func (p *Principal) auditActor() audit.Actor {
+	return audit.Actor{UserID: p.UserID, Source: audit.SourceAPI, KeyID: p.KeyID}
+}
+
+func auditLogResponse(l *database.AuditLog) AuditLogResponse {
+	var details json.RawMessage
+	if l.Details != "" {
+		details = json.RawMessage(l.Details)
+	}
+	return AuditLogResponse{
+		ID:      l.ID,
+		Time:    l.CreatedAt,
+		ActorID: l.ActorID,
+		Source:  l.Source,
+		KeyID:   l.KeyID,
+		Action:  l.Action,
+		Target:  l.Target,
+		Details: details,
+	}
+}
+
+// ListAuditLogsHandler 列出审计记录, 需要 admin 权限.
+// 支持 ?actor=, ?action=, ?source=, ?since=, ?before= 和 ?limit=
+func (h *Handlers) ListAuditLogsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := database.AuditLogFilter{
+		Action: query.Get("action"),
+		Source: query.Get("source"),
+		Limit:  100,
+	}
+	if v := query.Get("actor"); v != "" {
+		actor, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid_request", "invalid actor: "+v)
+			return
+		}
+		filter.ActorID = &actor
+	}
+	if v := query.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid_request", "since must be an RFC 3339 time")
+			return
+		}
+		filter.Since = since
+	}
+	if v := query.Get("before"); v != "" {
+		before, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid_request", "invalid before: "+v)
+			return
+		}
+		filter.BeforeID = uint(before)
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 1000 {
+			WriteError(w, http.StatusBadRequest, "invalid_request", "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+	logs, err := database.ListAuditLogs(r.Context(), filter)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	resp := AuditLogsResponse{Logs: make([]AuditLogResponse, 0, len(logs)), Total: len(logs)}
+	for i := range logs {
+		resp.Logs = append(resp.Logs, auditLogResponse(&logs[i]))
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
@@ -1,19 +1,105 @@
 package api
 
 import (
+	"context"
 	"crypto/subtle"
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/common/utils/streamutil"
 	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/apikey"
+	"gorm.io/gorm"
 )
 
-// AuthMiddleware 返回认证中间件
+// Principal 是通过认证的调用者
+type Principal struct {
+	// UserID 为密钥所属的 telegram user id, 使用配置中的 token 时为 0
+	UserID int64
+	KeyID  uint
+	Scopes []apikey.Scope
+}
+
+// rootPrincipal 对应配置中的 token, 拥有全部权限
+var rootPrincipal = &Principal{Scopes: []apikey.Scope{apikey.ScopeAdmin}}
+
+func (p *Principal) IsRoot() bool {
+	return p.UserID == 0
+}
+
+func (p *Principal) Has(scope apikey.Scope) bool {
+	return apikey.HasScope(p.Scopes, scope)
+}
+
+// CanUseStorage 检查调用者能否使用存储, 与用户在 bot 中的存储权限一致
+func (p *Principal) CanUseStorage(name string) bool {
+	return p.IsRoot() || config.C().HasStorage(p.UserID, name)
+}
+
+// CanManageUser 检查调用者能否读写用户的设置, 除 admin 外只能访问自己
+func (p *Principal) CanManageUser(userID int64) bool {
+	return p.Has(apikey.ScopeAdmin) || p.UserID == userID
+}
+
+// CanSendToChat 检查调用者能否让 bot 向聊天发送文件, 除 admin 外只能发送到自己的聊天
+func (p *Principal) CanSendToChat(chatID int64) bool {
+	return p.Has(apikey.ScopeAdmin) || p.UserID == chatID
+}
+
+// CanAccessTask 检查调用者能否查看和取消任务, 除 admin 外只能访问自己创建的任务
+func (p *Principal) CanAccessTask(task *TaskProgressInfo) bool {
+	if p.Has(apikey.ScopeAdmin) {
+		return true
+	}
+	return task.UserID == p.UserID && p.CanUseStorage(task.Storage)
+}
+
+type principalKey struct{}
+
+func withPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// principalFrom 返回请求的调用者. 未经过认证中间件的请求 (未配置 token) 视为 root
+func principalFrom(r *http.Request) *Principal {
+	if p, ok := r.Context().Value(principalKey{}).(*Principal); ok {
+		return p
+	}
+	return rootPrincipal
+}
+
+// requireScope 包装处理器, 调用者缺少 scope 时返回 403
+func requireScope(scope apikey.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !principalFrom(r).Has(scope) {
+			WriteError(w, http.StatusForbidden, "forbidden", "missing scope: "+string(scope))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// 最后使用时间的更新间隔, 避免每个请求都写数据库
+const keyTouchInterval = time.Minute
+
+// AuthMiddleware 返回认证中间件.
+// 请求可以使用配置中的 token, 或用户通过 /apikey 创建的密钥
 func AuthMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			cfg := config.C().API
 
+			// 签名的流式链接由 StreamHandler 自行校验, WebDAV 使用 Basic 认证, OpenAPI 文档公开
+			if (strings.HasPrefix(r.URL.Path, streamutil.PathPrefix) && r.URL.Query().Get("sig") != "") || isWebDAVPath(r.URL.Path) || r.URL.Path == OpenAPIPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			// 从请求头获取 token
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
@@ -31,12 +117,62 @@ func AuthMiddleware() func(http.Handler) http.Handler {
 			token := parts[1]
 
 			// 验证 token
-			if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) != 1 {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) == 1 {
+				next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), rootPrincipal)))
+				return
+			}
+			principal, err := principalFromKey(r.Context(), token)
+			if err != nil {
+				if !errors.Is(err, errInvalidKey) {
+					log.FromContext(r.Context()).Errorf("Failed to check API key: %s", err)
+				}
 				WriteError(w, http.StatusUnauthorized, "unauthorized", "invalid token")
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
 		})
 	}
 }
+
+var errInvalidKey = errors.New("invalid api key")
+
+// principalFromKey 根据用户创建的密钥认证, 过期的密钥和已从配置中移除的用户的密钥无效.
+// 只有配置中的管理员的密钥拥有 admin 权限
+func principalFromKey(ctx context.Context, token string) (*Principal, error) {
+	if !strings.HasPrefix(token, apikey.Prefix) {
+		return nil, errInvalidKey
+	}
+	key, err := database.GetAPIKeyByHash(ctx, apikey.Hash(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, errInvalidKey
+	}
+	if !slices.Contains(config.C().GetUsersID(), key.UserID) {
+		return nil, errInvalidKey
+	}
+	scopes, err := apikey.ParseScopes(key.Scopes)
+	if err != nil {
+		return nil, errInvalidKey
+	}
+	// 用户不再是管理员时密钥失去 admin 权限
+	if !config.C().IsAdmin(key.UserID) {
+		scopes = slices.DeleteFunc(scopes, func(s apikey.Scope) bool { return s == apikey.ScopeAdmin })
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= keyTouchInterval {
+		if err := database.TouchAPIKey(ctx, key.ID, now); err != nil {
+			log.FromContext(ctx).Warnf("Failed to update last use of API key %d: %s", key.ID, err)
+		}
+	}
+	return &Principal{
+		UserID: key.UserID,
+		KeyID:  key.ID,
+		Scopes: scopes,
+	}, nil
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+)
+
+const (
+	// 每个客户端缓存的事件数, 客户端跟不上时丢弃新的进度事件
+	taskEventBuffer = 64
+	// 保持连接的心跳间隔, 避免代理关闭空闲的连接
+	taskEventPingInterval = 15 * time.Second
+)
+
+// taskEventStream 是一个客户端的订阅, 将任务事件转换为带速度和剩余时间的 TaskEvent.
+// 结束事件单独缓存, 总会送达
+type taskEventStream struct {
+	task   *TaskProgressInfo
+	events chan TaskEvent
+	done   chan TaskEvent
+
+	mu        sync.Mutex
+	lastFiles int
+}
+
+var _ taskevent.Sink = (*taskEventStream)(nil)
+
+func newTaskEventStream(task *TaskProgressInfo) *taskEventStream {
+	_, _, _, _, downloadedFiles, _, _, _ := task.snapshot()
+	return &taskEventStream{
+		task:      task,
+		events:    make(chan TaskEvent, taskEventBuffer),
+		done:      make(chan TaskEvent, 1),
+		lastFiles: downloadedFiles,
+	}
+}
+
+// Emit implements taskevent.Sink. It is called after the task state is updated.
+func (s *taskEventStream) Emit(e taskevent.Event) {
+	switch e.Phase {
+	case taskevent.PhaseDone:
+		ev := s.task.event(TaskEventDone)
+		if e.Err != nil {
+			ev.Event = TaskEventError
+			ev.Error = e.Err.Error()
+			if errors.Is(e.Err, context.Canceled) {
+				ev.Status = TaskStatusCancelled
+			}
+		}
+		select {
+		case s.done <- ev:
+		default:
+		}
+		return
+	case taskevent.PhaseStart:
+		s.send(s.task.event(TaskEventStart))
+	case taskevent.PhaseProgress:
+		ev := s.task.event(TaskEventProgress)
+		s.mu.Lock()
+		if ev.Progress != nil && ev.Progress.DownloadedFiles > s.lastFiles {
+			ev.Event = TaskEventFile
+			s.lastFiles = ev.Progress.DownloadedFiles
+		}
+		s.mu.Unlock()
+		s.send(ev)
+	}
+}
+
+func (s *taskEventStream) send(ev TaskEvent) {
+	select {
+	case s.events <- ev:
+	default:
+	}
+}
+
+// event 返回任务当前状态的事件
+func (t *TaskProgressInfo) event(name string) TaskEvent {
+	status, total, downloaded, totalFiles, downloadedFiles, startedAt, errMsg, _ := t.snapshot()
+	return TaskEvent{
+		Event:    name,
+		TaskID:   t.TaskID,
+		Status:   status,
+		Progress: buildTaskProgress(total, downloaded, totalFiles, downloadedFiles, startedAt),
+		Error:    errMsg,
+		Time:     time.Now(),
+	}
+}
+
+func isTerminalStatus(status TaskStatus) bool {
+	return status == TaskStatusCompleted || status == TaskStatusFailed || status == TaskStatusCancelled
+}
+
+// taskFromPath 获取路径中的任务, 调用者无权访问任务时视为不存在
+func taskFromPath(w http.ResponseWriter, r *http.Request) (*TaskProgressInfo, bool) {
+	taskID := r.PathValue("id")
+	task, ok := GetTask(taskID)
+	if !ok || !principalFrom(r).CanAccessTask(task) {
+		WriteError(w, http.StatusNotFound, "task_not_found", "task not found: "+taskID)
+		return nil, false
+	}
+	return task, true
+}
+
+// streamTaskEvents 先发送任务的当前状态, 然后推送事件直到任务结束或客户端断开
+func streamTaskEvents(ctx context.Context, task *TaskProgressInfo, send func(TaskEvent) error, ping func() error) {
+	stream := newTaskEventStream(task)
+	remove := task.AddSink(stream)
+	defer remove()
+
+	current := task.event(TaskEventStatus)
+	if err := send(current); err != nil || isTerminalStatus(current.Status) {
+		return
+	}
+	ticker := time.NewTicker(taskEventPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-stream.events:
+			if err := send(ev); err != nil {
+				return
+			}
+		case ev := <-stream.done:
+			// 先送出结束前的进度事件
+			for len(stream.events) > 0 {
+				if err := send(<-stream.events); err != nil {
+					return
+				}
+			}
+			send(ev)
+			return
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// TaskEventsHandler 通过 Server-Sent Events 推送任务事件
+func (h *Handlers) TaskEventsHandler(w http.ResponseWriter, r *http.Request) {
+	task, ok := taskFromPath(w, r)
+	if !ok {
+		return
+	}
+	rc := http.NewResponseController(w)
+	// 事件流会持续到任务结束, 远超服务器的 WriteTimeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.FromContext(r.Context()).Debugf("Failed to clear write deadline: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(ev TaskEvent) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Event, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	ping := func() error {
+		if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	streamTaskEvents(r.Context(), task, send, ping)
+}
+
+// TaskEventsWebSocketHandler 通过 WebSocket 推送任务事件, 每个事件为一条 JSON 消息
+func (h *Handlers) TaskEventsWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	task, ok := taskFromPath(w, r)
+	if !ok {
+		return
+	}
+	// 接管后的连接保留服务器设置的超时, 需要在此之前清除
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		log.FromContext(r.Context()).Debugf("Failed to clear read deadline: %v", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.FromContext(r.Context()).Debugf("Failed to clear write deadline: %v", err)
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		log.FromContext(r.Context()).Debugf("Failed to accept websocket: %v", err)
+		return
+	}
+	defer conn.CloseNow()
+
+	// 客户端只接收事件, 读取仅用于处理控制帧和关闭
+	ctx := conn.CloseRead(r.Context())
+	send := func(ev TaskEvent) error {
+		writeCtx, cancel := context.WithTimeout(ctx, taskEventPingInterval)
+		defer cancel()
+		return wsjson.Write(writeCtx, conn, ev)
+	}
+	ping := func() error {
+		pingCtx, cancel := context.WithTimeout(ctx, taskEventPingInterval)
+		defer cancel()
+		return conn.Ping(pingCtx)
+	}
+	streamTaskEvents(ctx, task, send, ping)
+	conn.Close(websocket.StatusNormalClosure, "")
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/krau/SaveAny-Bot/core/tasks/ytdlp"
 	"github.com/krau/SaveAny-Bot/parsers/parsers"
 	"github.com/krau/SaveAny-Bot/pkg/aria2"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
 	"github.com/krau/SaveAny-Bot/pkg/parser"
 	"github.com/krau/SaveAny-Bot/pkg/taskevent"
@@ -29,6 +30,8 @@ import (
 // TaskFactory 任务工厂
 type TaskFactory struct {
 	ctx context.Context
+	// userID 为创建任务的用户, 记录为任务的所有者
+	userID int64
 }
 
 // NewTaskFactory 创建任务工厂
@@ -36,6 +39,11 @@ func NewTaskFactory(ctx context.Context) *TaskFactory {
 	return &TaskFactory{ctx: ctx}
 }
 
+// forActor 返回一个工厂, 其创建的任务在审计日志中记录为 actor 所为
+func (f *TaskFactory) forActor(actor audit.Actor) *TaskFactory {
+	return &TaskFactory{ctx: audit.WithActor(f.ctx, actor), userID: actor.UserID}
+}
+
 // CreateTask 创建任务
 func (f *TaskFactory) CreateTask(req *CreateTaskRequest) (*CreateTaskResponse, error) {
 	// 验证存储
@@ -67,14 +75,29 @@ func (f *TaskFactory) CreateTask(req *CreateTaskRequest) (*CreateTaskResponse, e
 	}
 }
 
-func (f *TaskFactory) registerAndEnqueueTask(task core.Executable, taskType tasktype.TaskType, storageName, path, webhook string) error {
+func (f *TaskFactory) registerAndEnqueueTask(task core.Executable, taskType tasktype.TaskType, storageName, path string, hook *webhookTarget) error {
 	taskID := task.TaskID()
-	info := RegisterTask(taskID, string(taskType), storageName, path, task.Title(), webhook)
+	webhookURL := ""
+	if hook != nil {
+		webhookURL = hook.URL
+	}
+	info := RegisterTask(taskID, string(taskType), storageName, path, task.Title(), webhookURL, f.userID)
 
 	// Inject the progress sink into the context so the task's Emit calls update
-	// the API store (and fire the webhook on terminal states) without the task
-	// knowing about the API.
-	taskCtx := taskevent.WithSink(f.ctx, info)
+	// the API store (and fire the webhook) without the task knowing about the API.
+	meta := webhookTask{
+		TaskID:  taskID,
+		Type:    string(taskType),
+		Title:   task.Title(),
+		Storage: storageName,
+		Path:    path,
+		UserID:  f.userID,
+	}
+	sinks := []taskevent.Sink{info}
+	if hook != nil {
+		sinks = append(sinks, newWebhookSink(f.ctx, meta, []webhookTarget{*hook}))
+	}
+	taskCtx := withWebhookTask(taskevent.WithSink(f.ctx, sinks...), meta)
 
 	err := core.AddTask(taskCtx, task)
 	if err != nil {
@@ -98,7 +121,7 @@ func (f *TaskFactory) createDirectLinksTask(taskID string, createdAt time.Time,
 
 	task := directlinks.NewTask(taskID, f.ctx, params.URLs, stor, req.Path, nil)
 
-	err := f.registerAndEnqueueTask(task, tasktype.TaskTypeDirectlinks, req.Storage, req.Path, req.Webhook)
+	err := f.registerAndEnqueueTask(task, tasktype.TaskTypeDirectlinks, req.Storage, req.Path, req.webhook())
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +147,7 @@ func (f *TaskFactory) createYTDLPTask(taskID string, createdAt time.Time, req *C
 
 	task := ytdlp.NewTask(taskID, f.ctx, params.URLs, params.Flags, stor, req.Path, nil)
 
-	err := f.registerAndEnqueueTask(task, tasktype.TaskTypeYtdlp, req.Storage, req.Path, req.Webhook)
+	err := f.registerAndEnqueueTask(task, tasktype.TaskTypeYtdlp, req.Storage, req.Path, req.webhook())
 	if err != nil {
 		return nil, err
 	}
@@ -167,7 +190,7 @@ func (f *TaskFactory) createAria2Task(taskID string, createdAt time.Time, req *C
 
 	task := aria2dl.NewTask(taskID, f.ctx, gid, params.URLs, aria2Client, stor, req.Path, nil)
 
-	err = f.registerAndEnqueueTask(task, tasktype.TaskTypeAria2, req.Storage, req.Path, req.Webhook)
+	err = f.registerAndEnqueueTask(task, tasktype.TaskTypeAria2, req.Storage, req.Path, req.webhook())
 	if err != nil {
 		return nil, err
 	}
@@ -212,7 +235,7 @@ func (f *TaskFactory) createParsedTask(taskID string, createdAt time.Time, req *
 
 	task := parsed.NewTask(taskID, f.ctx, stor, req.Path, item, nil)
 
-	err = f.registerAndEnqueueTask(task, tasktype.TaskTypeParseditem, req.Storage, req.Path, req.Webhook)
+	err = f.registerAndEnqueueTask(task, tasktype.TaskTypeParseditem, req.Storage, req.Path, req.webhook())
 	if err != nil {
 		return nil, err
 	}
@@ -269,7 +292,7 @@ func (f *TaskFactory) createTGFilesTask(taskID string, createdAt time.Time, req
 		task = batchtfile.NewBatchTGFileTask(taskID, f.ctx, elems, nil, true)
 	}
 
-	err = f.registerAndEnqueueTask(task, tasktype.TaskTypeTgfiles, req.Storage, req.Path, req.Webhook)
+	err = f.registerAndEnqueueTask(task, tasktype.TaskTypeTgfiles, req.Storage, req.Path, req.webhook())
 	if err != nil {
 		return nil, err
 	}
@@ -306,7 +329,7 @@ func (f *TaskFactory) createTPHPicsTask(taskID string, createdAt time.Time, req
 	client := telegraph.NewClient()
 	task := tphtask.NewTask(taskID, f.ctx, phPath, pics, stor, req.Path, client, nil)
 
-	err = f.registerAndEnqueueTask(task, tasktype.TaskTypeTphpics, req.Storage, req.Path, req.Webhook)
+	err = f.registerAndEnqueueTask(task, tasktype.TaskTypeTphpics, req.Storage, req.Path, req.webhook())
 	if err != nil {
 		return nil, err
 	}
@@ -368,7 +391,7 @@ func (f *TaskFactory) createTransferTask(taskID string, createdAt time.Time, req
 
 	task := transfer.NewTransferTask(taskID, f.ctx, elems, nil, true)
 
-	err = f.registerAndEnqueueTask(task, tasktype.TaskTypeTransfer, params.TargetStorage, params.TargetPath, req.Webhook)
+	err = f.registerAndEnqueueTask(task, tasktype.TaskTypeTransfer, params.TargetStorage, params.TargetPath, req.webhook())
 	if err != nil {
 		return nil, err
 	}
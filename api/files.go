@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
+	"github.com/krau/SaveAny-Bot/storage"
+)
+
+// storageFromPath 获取路径中的存储, 调用者无权使用该存储时视为不存在
+func storageFromPath(w http.ResponseWriter, r *http.Request) (storage.Storage, bool) {
+	name := r.PathValue("name")
+	stor, ok := storage.GetStorage(name)
+	if !ok || !principalFrom(r).CanUseStorage(name) {
+		WriteError(w, http.StatusNotFound, "storage_not_found", "storage not found: "+name)
+		return nil, false
+	}
+	return stor, true
+}
+
+// cleanStoragePath 规范化请求中的路径, 结果以 / 开头且不会指向存储之外
+func cleanStoragePath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// writeStorageError 写入存储操作失败的响应
+func writeStorageError(w http.ResponseWriter, err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		WriteError(w, http.StatusNotFound, "file_not_found", err.Error())
+		return
+	}
+	WriteError(w, http.StatusInternalServerError, "storage_error", err.Error())
+}
+
+func writeUnsupported(w http.ResponseWriter, stor storage.Storage, op string) {
+	WriteError(w, http.StatusNotImplemented, "unsupported_operation",
+		"storage "+stor.Name()+" ("+string(stor.Type())+") does not support "+op)
+}
+
+// ListFilesHandler 列出存储中的目录内容, 通过 ?path= 指定目录, 默认为根目录
+func (h *Handlers) ListFilesHandler(w http.ResponseWriter, r *http.Request) {
+	stor, ok := storageFromPath(w, r)
+	if !ok {
+		return
+	}
+	listable, ok := stor.(storage.StorageListable)
+	if !ok {
+		writeUnsupported(w, stor, "listing files")
+		return
+	}
+	dir := cleanStoragePath(r.URL.Query().Get("path"))
+	files, err := listable.ListFiles(r.Context(), dir)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	entries := make([]FileEntry, 0, len(files))
+	for _, f := range files {
+		entries = append(entries, FileEntry{
+			Name:    f.Name,
+			Path:    path.Join(dir, f.Name),
+			Size:    f.Size,
+			IsDir:   f.IsDir,
+			ModTime: f.ModTime,
+		})
+	}
+	slices.SortFunc(entries, func(a, b FileEntry) int {
+		if a.IsDir != b.IsDir {
+			if a.IsDir {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	WriteJSON(w, http.StatusOK, FilesResponse{
+		Storage: stor.Name(),
+		Path:    dir,
+		Files:   entries,
+		Total:   len(entries),
+	})
+}
+
+// DownloadFileHandler 下载存储中的文件, 通过 ?path= 指定文件.
+// 存储返回可 Seek 的内容时支持 Range 请求, 否则总是返回完整的文件
+func (h *Handlers) DownloadFileHandler(w http.ResponseWriter, r *http.Request) {
+	stor, ok := storageFromPath(w, r)
+	if !ok {
+		return
+	}
+	readable, ok := stor.(storage.StorageReadable)
+	if !ok {
+		writeUnsupported(w, stor, "reading files")
+		return
+	}
+	filePath := cleanStoragePath(r.URL.Query().Get("path"))
+	if filePath == "/" {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "path is required")
+		return
+	}
+	rc, size, err := readable.OpenFile(r.Context(), filePath)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	// 大文件下载可能远超服务器的 WriteTimeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.FromContext(r.Context()).Debugf("Failed to clear write deadline: %v", err)
+	}
+
+	name := path.Base(filePath)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		// ServeContent 处理 Range 和条件请求
+		http.ServeContent(w, r, name, time.Time{}, rs)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Accept-Ranges", "none")
+	if size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, rc); err != nil {
+		log.FromContext(r.Context()).Debugf("Failed to send file %s: %v", filePath, err)
+	}
+}
+
+// DeleteFileHandler 删除存储中的文件或目录, 通过 ?path= 指定, 不允许删除根目录
+func (h *Handlers) DeleteFileHandler(w http.ResponseWriter, r *http.Request) {
+	stor, ok := storageFromPath(w, r)
+	if !ok {
+		return
+	}
+	deletable, ok := stor.(storage.StorageDeletable)
+	if !ok {
+		writeUnsupported(w, stor, "deleting files")
+		return
+	}
+	filePath := cleanStoragePath(r.URL.Query().Get("path"))
+	if filePath == "/" {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "path is required")
+		return
+	}
+	if err := deletable.Delete(r.Context(), filePath); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	log.FromContext(r.Context()).Infof("Deleted %s from storage %s via API", filePath, stor.Name())
+	audit.Record(r.Context(), audit.ActionStorageDelete, stor.Name()+":"+filePath, nil)
+	WriteJSON(w, http.StatusOK, MessageResponse{Message: "file deleted"})
+}
+
+// MoveFileHandler 在同一存储内移动或重命名文件
+func (h *Handlers) MoveFileHandler(w http.ResponseWriter, r *http.Request) {
+	stor, ok := storageFromPath(w, r)
+	if !ok {
+		return
+	}
+	movable, ok := stor.(storage.StorageMovable)
+	if !ok {
+		writeUnsupported(w, stor, "moving files")
+		return
+	}
+	var req MoveFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "failed to decode request body: "+err.Error())
+		return
+	}
+	src, dst := cleanStoragePath(req.Path), cleanStoragePath(req.Destination)
+	if src == "/" || dst == "/" {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "path and destination are required")
+		return
+	}
+	if src == dst {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "path and destination are the same")
+		return
+	}
+	if err := movable.Move(r.Context(), src, dst); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	log.FromContext(r.Context()).Infof("Moved %s to %s in storage %s via API", src, dst, stor.Name())
+	audit.Record(r.Context(), audit.ActionStorageMove, stor.Name()+":"+src, map[string]any{"destination": dst})
+	WriteJSON(w, http.StatusOK, MessageResponse{Message: "file moved"})
+}
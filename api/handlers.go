@@ -2,12 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/krau/SaveAny-Bot/core"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
 	"github.com/krau/SaveAny-Bot/storage"
 )
 
@@ -33,26 +36,98 @@ func (h *Handlers) CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, http.StatusBadRequest, "invalid_request", "failed to decode request body: "+err.Error())
 		return
 	}
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		req.IdempotencyKey = key
+	}
+
+	resp, replayed, apiErr := h.createTask(principalFrom(r), &req)
+	if apiErr != nil {
+		WriteError(w, apiErr.StatusCode, apiErr.ErrorCode, apiErr.Message)
+		return
+	}
+	tracing.SetAttributes(r.Context(), tracing.TaskIDKey.String(resp.TaskID))
+	if replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	}
+	WriteJSON(w, http.StatusCreated, resp)
+}
 
+// createTask 校验并创建任务, 供单个和批量创建共用.
+// 请求带有幂等键且已成功创建过时返回原来的任务, replayed 为 true
+func (h *Handlers) createTask(principal *Principal, req *CreateTaskRequest) (resp *CreateTaskResponse, replayed bool, apiErr *APIError) {
 	// 验证请求
 	if req.Type == "" {
-		WriteError(w, http.StatusBadRequest, "invalid_request", "task type is required")
-		return
+		return nil, false, &APIError{http.StatusBadRequest, "invalid_request", "task type is required"}
 	}
 
 	if req.Storage == "" {
-		WriteError(w, http.StatusBadRequest, "invalid_request", "storage is required")
-		return
+		return nil, false, &APIError{http.StatusBadRequest, "invalid_request", "storage is required"}
 	}
 
-	// 创建任务
-	resp, err := h.factory.CreateTask(&req)
-	if err != nil {
-		WriteError(w, http.StatusBadRequest, "task_creation_failed", err.Error())
+	if err := validateWebhookEvents(req.WebhookEvents); err != nil {
+		return nil, false, &APIError{http.StatusBadRequest, "invalid_request", err.Error()}
+	}
+
+	for _, name := range requestStorages(req) {
+		if !principal.CanUseStorage(name) {
+			return nil, false, &APIError{http.StatusForbidden, "forbidden", "no access to storage: " + name}
+		}
+	}
+
+	create := func() (*CreateTaskResponse, *APIError) {
+		resp, err := h.factory.forActor(principal.auditActor()).CreateTask(req)
+		if err != nil {
+			return nil, &APIError{http.StatusBadRequest, "task_creation_failed", err.Error()}
+		}
+		return resp, nil
+	}
+	if req.IdempotencyKey == "" {
+		resp, apiErr := create()
+		return resp, false, apiErr
+	}
+	return idempotencyKeys.do(principal, req, create)
+}
+
+// BatchCreateTasksHandler 批量创建任务, 每个任务单独校验和创建, 结果与请求的顺序一致.
+// 请求头中的 Idempotency-Key 作为未设置幂等键的任务的前缀, 重试整个批量请求不会重复创建
+func (h *Handlers) BatchCreateTasksHandler(w http.ResponseWriter, r *http.Request) {
+	var req BatchCreateTaskRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize)).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "failed to decode request body: "+err.Error())
+		return
+	}
+	if len(req.Tasks) == 0 {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "tasks is required")
+		return
+	}
+	if len(req.Tasks) > maxBatchTasks {
+		WriteError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("at most %d tasks per batch", maxBatchTasks))
 		return
 	}
 
-	WriteJSON(w, http.StatusCreated, resp)
+	principal := principalFrom(r)
+	batchKey := r.Header.Get(IdempotencyKeyHeader)
+	resp := BatchCreateTaskResponse{Results: make([]BatchTaskResult, 0, len(req.Tasks))}
+	for i := range req.Tasks {
+		task := &req.Tasks[i]
+		if task.IdempotencyKey == "" && batchKey != "" {
+			task.IdempotencyKey = batchKey + ":" + strconv.Itoa(i)
+		}
+		result := BatchTaskResult{Index: i}
+		created, replayed, apiErr := h.createTask(principal, task)
+		if apiErr != nil {
+			result.Status = apiErr.StatusCode
+			result.Error = &ErrorResponse{Error: apiErr.ErrorCode, Message: apiErr.Message}
+			resp.Failed++
+		} else {
+			result.Status = http.StatusCreated
+			result.Task = created
+			result.Replayed = replayed
+			resp.Succeeded++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	WriteJSON(w, http.StatusOK, resp)
 }
 
 // ListTasksHandler 列出任务处理器
@@ -65,7 +140,11 @@ func (h *Handlers) ListTasksHandler(w http.ResponseWriter, r *http.Request) {
 	tasks := GetAllTasks()
 	response := make([]TaskInfoResponse, 0, len(tasks))
 
+	principal := principalFrom(r)
 	for _, task := range tasks {
+		if !principal.CanAccessTask(task) {
+			continue
+		}
 		info := convertTaskProgressToResponse(task)
 		response = append(response, info)
 	}
@@ -90,7 +169,7 @@ func (h *Handlers) GetTaskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	task, ok := GetTask(taskID)
-	if !ok {
+	if !ok || !principalFrom(r).CanAccessTask(task) {
 		WriteError(w, http.StatusNotFound, "task_not_found", "task not found: "+taskID)
 		return
 	}
@@ -113,7 +192,7 @@ func (h *Handlers) CancelTaskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	task, ok := GetTask(taskID)
-	if !ok {
+	if !ok || !principalFrom(r).CanAccessTask(task) {
 		WriteError(w, http.StatusNotFound, "task_not_found", "task not found: "+taskID)
 		return
 	}
@@ -125,7 +204,7 @@ func (h *Handlers) CancelTaskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	task.UpdateStatus(TaskStatusCancelled)
-	WriteJSON(w, http.StatusOK, map[string]string{"message": "task cancelled successfully"})
+	WriteJSON(w, http.StatusOK, MessageResponse{Message: "task cancelled successfully"})
 }
 
 // ListStoragesHandler 列出存储处理器
@@ -137,7 +216,11 @@ func (h *Handlers) ListStoragesHandler(w http.ResponseWriter, r *http.Request) {
 
 	all := storage.AllStorages()
 	storages := make([]StorageInfo, 0, len(all))
+	principal := principalFrom(r)
 	for name, stor := range all {
+		if !principal.CanUseStorage(name) {
+			continue
+		}
 		storages = append(storages, StorageInfo{
 			Name: name,
 			Type: string(stor.Type()),
@@ -154,26 +237,30 @@ func (h *Handlers) GetTaskTypesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	types := []tasktype.TaskType{
-		tasktype.TaskTypeDirectlinks,
-		tasktype.TaskTypeYtdlp,
-		tasktype.TaskTypeAria2,
-		tasktype.TaskTypeParseditem,
-		tasktype.TaskTypeTgfiles,
-		tasktype.TaskTypeTphpics,
-		tasktype.TaskTypeTransfer,
+	types := make([]tasktype.TaskType, 0, len(taskParamTypes))
+	for _, t := range taskParamTypes {
+		types = append(types, t.Type)
 	}
 
-	WriteJSON(w, http.StatusOK, map[string]any{
-		"types": types,
-	})
+	WriteJSON(w, http.StatusOK, TaskTypesResponse{Types: types})
 }
 
 // HealthCheckHandler 健康检查处理器
 func (h *Handlers) HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	WriteJSON(w, http.StatusOK, map[string]string{
-		"status": "ok",
-	})
+	WriteJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// requestStorages 返回任务请求使用的所有存储, transfer 任务还包括源存储和目标存储
+func requestStorages(req *CreateTaskRequest) []string {
+	names := []string{req.Storage}
+	if req.Type == tasktype.TaskTypeTransfer {
+		var params TransferParams
+		// 参数错误由 CreateTask 返回
+		if err := json.Unmarshal(req.Params, &params); err == nil {
+			names = append(names, params.SourceStorage, params.TargetStorage)
+		}
+	}
+	return names
 }
 
 // extractTaskIDFromPath 从路径中提取任务 ID
@@ -186,49 +273,59 @@ func extractTaskIDFromPath(path string) string {
 	return parts[3]
 }
 
-// convertTaskProgressToResponse renders a task's current state, computing
-// percent and speed from the snapshot taken under the task's mutex.
+// convertTaskProgressToResponse renders a task's current state from the
+// snapshot taken under the task's mutex.
 func convertTaskProgressToResponse(task *TaskProgressInfo) TaskInfoResponse {
 	status, total, downloaded, totalFiles, downloadedFiles, startedAt, errMsg, updatedAt := task.snapshot()
 
-	resp := TaskInfoResponse{
+	return TaskInfoResponse{
 		TaskID:    task.TaskID,
 		Type:      tasktype.TaskType(task.Type),
 		Status:    status,
 		Title:     task.Title,
+		Progress:  buildTaskProgress(total, downloaded, totalFiles, downloadedFiles, startedAt),
 		Storage:   task.Storage,
 		Path:      task.Path,
 		Error:     errMsg,
 		CreatedAt: task.CreatedAt,
 		UpdatedAt: updatedAt,
 	}
+}
 
-	var percent float64
-	var speedMBPS float64
+// buildTaskProgress computes percent, average speed and ETA from the task
+// counters. It returns nil when the task has not reported any totals yet.
+func buildTaskProgress(total, downloaded int64, totalFiles, downloadedFiles int, startedAt time.Time) *TaskProgress {
+	if total <= 0 && totalFiles <= 0 {
+		return nil
+	}
+	progress := &TaskProgress{
+		TotalBytes:      total,
+		DownloadedBytes: downloaded,
+		TotalFiles:      totalFiles,
+		DownloadedFiles: downloadedFiles,
+	}
 	if total > 0 {
-		percent = float64(downloaded) * 100 / float64(total)
-	} else if totalFiles > 0 {
-		percent = float64(downloadedFiles) * 100 / float64(totalFiles)
-	}
-	if !startedAt.IsZero() {
-		elapsed := time.Since(startedAt).Seconds()
-		if elapsed > 0 {
-			speedMBPS = float64(downloaded) / elapsed / (1024 * 1024)
-		}
+		progress.Percent = float64(downloaded) * 100 / float64(total)
+	} else {
+		progress.Percent = float64(downloadedFiles) * 100 / float64(totalFiles)
 	}
-
-	if total > 0 || totalFiles > 0 {
-		resp.Progress = &TaskProgress{
-			TotalBytes:      total,
-			DownloadedBytes: downloaded,
-			TotalFiles:      totalFiles,
-			DownloadedFiles: downloadedFiles,
-			Percent:         percent,
-			SpeedMBPS:       speedMBPS,
-		}
+	if startedAt.IsZero() {
+		return progress
 	}
-
-	return resp
+	elapsed := time.Since(startedAt).Seconds()
+	if elapsed <= 0 {
+		return progress
+	}
+	speed := float64(downloaded) / elapsed
+	progress.SpeedMBPS = speed / (1024 * 1024)
+	// 有字节数时按字节估算, 否则按文件数估算
+	switch {
+	case total > 0 && speed > 0:
+		progress.ETASeconds = float64(max(total-downloaded, 0)) / speed
+	case total <= 0 && downloadedFiles > 0:
+		progress.ETASeconds = float64(max(totalFiles-downloadedFiles, 0)) * elapsed / float64(downloadedFiles)
+	}
+	return progress
 }
 
 // NotFoundHandler 404 处理器
@@ -5,15 +5,34 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	storcfg "github.com/krau/SaveAny-Bot/config/storage"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/apiclient"
+	"github.com/krau/SaveAny-Bot/pkg/apikey"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
 	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+	"github.com/krau/SaveAny-Bot/storage"
+	"github.com/krau/SaveAny-Bot/storage/local"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
 )
 
 // setupTestServer creates a test server with handlers
@@ -179,7 +198,7 @@ func TestGetTaskHandler(t *testing.T) {
 
 	// Register a test task
 	testTaskID := "test-get-task"
-	RegisterTask(testTaskID, "directlinks", "local", "downloads", "Test", "")
+	RegisterTask(testTaskID, "directlinks", "local", "downloads", "Test", "", 0)
 	defer DeleteTask(testTaskID)
 
 	tests := []struct {
@@ -237,6 +256,16 @@ func TestGetTaskHandler(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("Task of another user", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+testTaskID, nil)
+		req = req.WithContext(withPrincipal(req.Context(), &Principal{UserID: 42, Scopes: []apikey.Scope{apikey.ScopeTasksRead}}))
+		rr := httptest.NewRecorder()
+		handlers.GetTaskHandler(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
 }
 
 // TestCancelTaskHandler tests the cancel task endpoint
@@ -245,7 +274,7 @@ func TestCancelTaskHandler(t *testing.T) {
 
 	// Register a test task
 	testTaskID := "test-cancel-task"
-	RegisterTask(testTaskID, "directlinks", "local", "downloads", "Test", "")
+	RegisterTask(testTaskID, "directlinks", "local", "downloads", "Test", "", 0)
 	defer DeleteTask(testTaskID)
 
 	tests := []struct {
@@ -364,7 +393,7 @@ func TestConcurrentProgressStore(t *testing.T) {
 		go func(id int) {
 			defer wg.Done()
 			taskID := fmt.Sprintf("concurrent-test-%d", id)
-			RegisterTask(taskID, "directlinks", "local", "downloads", "Test", "")
+			RegisterTask(taskID, "directlinks", "local", "downloads", "Test", "", 0)
 		}(i)
 	}
 
@@ -404,7 +433,7 @@ func TestConcurrentProgressStore(t *testing.T) {
 
 // TestProgressTrackerConcurrentUpdates tests concurrent progress updates
 func TestProgressTrackerConcurrentUpdates(t *testing.T) {
-	info := RegisterTask("concurrent-progress", "directlinks", "local", "downloads", "Test", "")
+	info := RegisterTask("concurrent-progress", "directlinks", "local", "downloads", "Test", "", 0)
 	info.Emit(taskevent.Event{TaskID: "concurrent-progress", Phase: taskevent.PhaseStart, TotalBytes: 10000})
 
 	var wg sync.WaitGroup
@@ -438,6 +467,106 @@ func TestProgressTrackerConcurrentUpdates(t *testing.T) {
 	}
 }
 
+// TestTaskEventsHandler tests that the SSE endpoint streams the current state
+// followed by every task event until the task is done
+func TestTaskEventsHandler(t *testing.T) {
+	handlers, _ := setupTestServer(t)
+	info := RegisterTask("events-task", "directlinks", "local", "downloads", "Test", "", 0)
+	defer DeleteTask("events-task")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/tasks/{id}/events", handlers.TaskEventsHandler)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/tasks/missing/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown task, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/v1/tasks/events-task/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected event stream, got %q", ct)
+	}
+
+	events := make(chan TaskEvent)
+	go func() {
+		defer close(events)
+		buf := make([]byte, 0, 4096)
+		chunk := make([]byte, 1024)
+		for {
+			n, err := resp.Body.Read(chunk)
+			buf = append(buf, chunk[:n]...)
+			for {
+				end := bytes.Index(buf, []byte("\n\n"))
+				if end < 0 {
+					break
+				}
+				for line := range strings.SplitSeq(string(buf[:end]), "\n") {
+					if data, ok := strings.CutPrefix(line, "data: "); ok {
+						var ev TaskEvent
+						if err := json.Unmarshal([]byte(data), &ev); err == nil {
+							events <- ev
+						}
+					}
+				}
+				buf = buf[end+2:]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	next := func() TaskEvent {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatal("event stream closed early")
+			}
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+		return TaskEvent{}
+	}
+
+	if ev := next(); ev.Event != TaskEventStatus || ev.Status != TaskStatusQueued {
+		t.Errorf("expected queued status event first, got %+v", ev)
+	}
+	info.Emit(taskevent.Event{TaskID: "events-task", Phase: taskevent.PhaseStart, TotalFiles: 2})
+	if ev := next(); ev.Event != TaskEventStart || ev.Status != TaskStatusRunning {
+		t.Errorf("expected start event, got %+v", ev)
+	}
+	info.Emit(taskevent.Event{TaskID: "events-task", Phase: taskevent.PhaseProgress, TotalFiles: 2, DownloadedFiles: 1})
+	ev := next()
+	if ev.Event != TaskEventFile || ev.Progress == nil || ev.Progress.DownloadedFiles != 1 || ev.Progress.Percent != 50 {
+		t.Errorf("expected file event at 50%%, got %+v", ev)
+	}
+	if ev.Progress != nil && ev.Progress.ETASeconds <= 0 {
+		t.Errorf("expected an ETA, got %+v", ev.Progress)
+	}
+	info.Emit(taskevent.Event{TaskID: "events-task", Phase: taskevent.PhaseDone, Err: fmt.Errorf("boom")})
+	if ev := next(); ev.Event != TaskEventError || ev.Status != TaskStatusFailed || ev.Error != "boom" {
+		t.Errorf("expected error event, got %+v", ev)
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected the stream to end after the task is done")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("stream not closed after the task is done")
+	}
+}
+
 // TestTaskFactoryValidation tests TaskFactory parameter validation
 func TestTaskFactoryValidation(t *testing.T) {
 	factory := NewTaskFactory(context.Background())
@@ -533,7 +662,7 @@ func TestEdgeCases(t *testing.T) {
 		{
 			name: "Progress tracker with empty webhook",
 			fn: func(t *testing.T) {
-				info := RegisterTask("test-empty-webhook", "type", "storage", "path", "title", "")
+				info := RegisterTask("test-empty-webhook", "type", "storage", "path", "title", "", 0)
 				if info.Webhook != "" {
 					t.Error("expected empty webhook")
 				}
@@ -654,7 +783,7 @@ func TestMethodNotAllowedHandler(t *testing.T) {
 
 // TestTaskProgressInfoTimeUpdate tests that timestamps are updated correctly
 func TestTaskProgressInfoTimeUpdate(t *testing.T) {
-	info := RegisterTask("time-test", "directlinks", "local", "downloads", "Test", "")
+	info := RegisterTask("time-test", "directlinks", "local", "downloads", "Test", "", 0)
 	defer DeleteTask("time-test")
 
 	originalTime := info.UpdatedAt
@@ -693,3 +822,481 @@ func TestWebhookPayloadWithNilCompletedAt(t *testing.T) {
 		t.Error("expected completed_at to be omitted when nil")
 	}
 }
+
+// TestWebhookSignature tests the HMAC signature of webhook requests
+func TestWebhookSignature(t *testing.T) {
+	got := signWebhook("secret", 1700000000, []byte(`{"event":"completed"}`))
+	want := "sha256=676f90e8af78f8238c3e041e70bfaf5b49dd6cc1159c66134662420b525bdc11"
+	if got != want {
+		t.Errorf("expected signature %s, got %s", want, got)
+	}
+}
+
+// TestDeliverySecret tests that task webhook secrets are looked up from memory, not the delivery log
+func TestDeliverySecret(t *testing.T) {
+	if _, ok := deliverySecret(&database.WebhookDelivery{TaskID: "t1", URL: "http://example.com"}); !ok {
+		t.Error("expected unsigned deliveries to need no secret")
+	}
+	d := &database.WebhookDelivery{TaskID: "t1", URL: "http://example.com", Signed: true}
+	if _, ok := deliverySecret(d); ok {
+		t.Error("expected no secret before it is remembered")
+	}
+	rememberWebhookSecret("t1", "http://example.com", "secret")
+	t.Cleanup(func() { forgetWebhookSecretsBefore(time.Now().Add(time.Hour)) })
+	if secret, ok := deliverySecret(d); !ok || secret != "secret" {
+		t.Errorf("expected secret, got %q, %v", secret, ok)
+	}
+	if _, ok := deliverySecret(&database.WebhookDelivery{TaskID: "t2", URL: "http://example.com", Signed: true}); ok {
+		t.Error("expected the secret of another task not to be used")
+	}
+	forgetWebhookSecretsBefore(time.Now().Add(time.Hour))
+	if _, ok := deliverySecret(d); ok {
+		t.Error("expected the secret to be forgotten")
+	}
+}
+
+// TestWebhookEventSubscriptions tests event validation and subscription matching
+func TestWebhookEventSubscriptions(t *testing.T) {
+	if err := validateWebhookEvents([]string{WebhookEventStarted, webhookEventAll}); err != nil {
+		t.Errorf("expected valid events, got %v", err)
+	}
+	if err := validateWebhookEvents([]string{"finished"}); err == nil {
+		t.Error("expected error for unknown event")
+	}
+
+	defaults := webhookTarget{URL: "http://example.com"}
+	if !defaults.subscribed(WebhookEventCompleted) || !defaults.subscribed(WebhookEventCancelled) {
+		t.Error("expected terminal events to be subscribed by default")
+	}
+	if defaults.subscribed(WebhookEventProgress) {
+		t.Error("expected progress not to be subscribed by default")
+	}
+	custom := webhookTarget{URL: "http://example.com", Events: []string{WebhookEventFileSaved}}
+	if !custom.subscribed(WebhookEventFileSaved) || custom.subscribed(WebhookEventCompleted) {
+		t.Error("expected only the listed events to be subscribed")
+	}
+	all := webhookTarget{URL: "http://example.com", Events: []string{webhookEventAll}}
+	for _, e := range webhookEvents {
+		if !all.subscribed(e) {
+			t.Errorf("expected * to subscribe %s", e)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	handlers, _ := setupTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, OpenAPIPath, nil)
+	w := httptest.NewRecorder()
+	handlers.OpenAPIHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var doc struct {
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to decode document: %v", err)
+	}
+
+	// 每个请求和响应类型都有 schema
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "types.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if _, ok := ts.Type.(*ast.StructType); !ok || ts.Name.Name == "APIError" {
+				continue
+			}
+			if _, ok := doc.Components.Schemas[ts.Name.Name]; !ok {
+				t.Errorf("schema %s is missing", ts.Name.Name)
+			}
+		}
+	}
+	for ref, schema := range doc.Components.Schemas {
+		if schema == nil {
+			t.Errorf("schema %s is empty", ref)
+		}
+	}
+
+	oneOf, _ := doc.Components.Schemas["CreateTaskRequest"]["oneOf"].([]any)
+	if len(oneOf) != len(taskParamTypes) {
+		t.Errorf("CreateTaskRequest has %d variants, want %d", len(oneOf), len(taskParamTypes))
+	}
+	for _, path := range []string{"/api/v1/tasks", "/api/v1/tasks/{id}", "/api/v1/tasks/{id}/events", "/api/v1/storages/{name}/send", "/api/v1/users/{id}/rules"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("path %s is missing", path)
+		}
+	}
+}
+
+// jsonShape 返回类型的 JSON 结构, 用于比较客户端与服务端的类型
+func jsonShape(t reflect.Type) any {
+	switch t {
+	case reflect.TypeFor[time.Time]():
+		return "time"
+	case reflect.TypeFor[json.RawMessage](), reflect.TypeFor[any]():
+		return "any"
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonShape(t.Elem())
+	case reflect.Slice:
+		return []any{jsonShape(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"*": jsonShape(t.Elem())}
+	case reflect.Struct:
+		fields := map[string]any{}
+		for i := range t.NumField() {
+			// 标签包含 omitempty, 一并比较
+			fields[t.Field(i).Tag.Get("json")] = jsonShape(t.Field(i).Type)
+		}
+		return fields
+	}
+	return t.Kind().String()
+}
+
+func TestAPIClientTypes(t *testing.T) {
+	pairs := []struct {
+		server, client any
+	}{
+		{CreateTaskRequest{}, apiclient.CreateTaskRequest{}},
+		{CreateTaskResponse{}, apiclient.CreateTaskResponse{}},
+		{BatchCreateTaskRequest{}, apiclient.BatchCreateTaskRequest{}},
+		{BatchCreateTaskResponse{}, apiclient.BatchCreateTaskResponse{}},
+		{TaskInfoResponse{}, apiclient.TaskInfoResponse{}},
+		{TaskEvent{}, apiclient.TaskEvent{}},
+		{TasksListResponse{}, apiclient.TasksListResponse{}},
+		{StoragesResponse{}, apiclient.StoragesResponse{}},
+		{TaskTypesResponse{}, apiclient.TaskTypesResponse{}},
+		{MessageResponse{}, apiclient.MessageResponse{}},
+		{HealthResponse{}, apiclient.HealthResponse{}},
+		{ErrorResponse{}, apiclient.ErrorResponse{}},
+		{WebhookPayload{}, apiclient.WebhookPayload{}},
+		{WebhookDeliveriesResponse{}, apiclient.WebhookDeliveriesResponse{}},
+		{AuditLogsResponse{}, apiclient.AuditLogsResponse{}},
+		{ImportRulesResponse{}, apiclient.ImportRulesResponse{}},
+		{SendFileRequest{}, apiclient.SendFileRequest{}},
+		{FilesResponse{}, apiclient.FilesResponse{}},
+		{MoveFileRequest{}, apiclient.MoveFileRequest{}},
+		{UsersResponse{}, apiclient.UsersResponse{}},
+		{UpdateUserRequest{}, apiclient.UpdateUserRequest{}},
+		{DirRequest{}, apiclient.DirRequest{}},
+		{DirsResponse{}, apiclient.DirsResponse{}},
+		{RuleRequest{}, apiclient.RuleRequest{}},
+		{RuleResponse{}, apiclient.RuleResponse{}},
+		{WatchRequest{}, apiclient.WatchRequest{}},
+		{UpdateWatchRequest{}, apiclient.UpdateWatchRequest{}},
+		{WatchesResponse{}, apiclient.WatchesResponse{}},
+		{DirectLinksParams{}, apiclient.DirectLinksParams{}},
+		{YTDLPParams{}, apiclient.YTDLPParams{}},
+		{Aria2Params{}, apiclient.Aria2Params{}},
+		{ParsedParams{}, apiclient.ParsedParams{}},
+		{TransferParams{}, apiclient.TransferParams{}},
+		{TGFilesParams{}, apiclient.TGFilesParams{}},
+		{TPHPicsParams{}, apiclient.TPHPicsParams{}},
+	}
+	for _, p := range pairs {
+		server, client := reflect.TypeOf(p.server), reflect.TypeOf(p.client)
+		if !reflect.DeepEqual(jsonShape(server), jsonShape(client)) {
+			t.Errorf("apiclient.%s does not match %s", client.Name(), server.Name())
+		}
+	}
+	for _, tt := range taskParamTypes {
+		if !slices.Contains([]apiclient.TaskType{
+			apiclient.TaskTypeDirectlinks, apiclient.TaskTypeYtdlp, apiclient.TaskTypeAria2,
+			apiclient.TaskTypeParseditem, apiclient.TaskTypeTgfiles, apiclient.TaskTypeTphpics,
+			apiclient.TaskTypeTransfer,
+		}, apiclient.TaskType(tt.Type)) {
+			t.Errorf("apiclient has no task type %s", tt.Type)
+		}
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	s := &idempotencyStore{entries: make(map[string]*idempotencyEntry)}
+	principal := &Principal{UserID: 42}
+	calls := 0
+	create := func() (*CreateTaskResponse, *APIError) {
+		calls++
+		return &CreateTaskResponse{TaskID: fmt.Sprintf("task-%d", calls), Status: TaskStatusQueued}, nil
+	}
+	req := &CreateTaskRequest{Type: tasktype.TaskTypeDirectlinks, Storage: "local", IdempotencyKey: "k1", Params: json.RawMessage(`{"urls": ["a"]}`)}
+
+	first, replayed, apiErr := s.do(principal, req, create)
+	if apiErr != nil || replayed {
+		t.Fatalf("first call: replayed=%v err=%v", replayed, apiErr)
+	}
+	// 空白不同的 params 视为相同的请求
+	again := *req
+	again.Params = json.RawMessage(`{"urls":["a"]}`)
+	second, replayed, apiErr := s.do(principal, &again, create)
+	if apiErr != nil || !replayed || second.TaskID != first.TaskID || calls != 1 {
+		t.Fatalf("retry: task=%v replayed=%v err=%v calls=%d", second, replayed, apiErr, calls)
+	}
+
+	other := *req
+	other.Storage = "other"
+	if _, _, apiErr := s.do(principal, &other, create); apiErr == nil || apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with different request: %v", apiErr)
+	}
+	if _, replayed, _ := s.do(&Principal{UserID: 7}, req, create); replayed {
+		t.Error("keys are shared between callers")
+	}
+
+	// 创建失败的键可以重试
+	failing := &CreateTaskRequest{Type: tasktype.TaskTypeDirectlinks, Storage: "local", IdempotencyKey: "k2"}
+	s.do(principal, failing, func() (*CreateTaskResponse, *APIError) {
+		return nil, &APIError{http.StatusBadRequest, "task_creation_failed", "boom"}
+	})
+	if _, replayed, apiErr := s.do(principal, failing, create); apiErr != nil || replayed {
+		t.Errorf("retry after failure: replayed=%v err=%v", replayed, apiErr)
+	}
+}
+
+func TestBatchCreateTasksHandler(t *testing.T) {
+	handlers, _ := setupTestServer(t)
+
+	body, _ := json.Marshal(BatchCreateTaskRequest{Tasks: []CreateTaskRequest{
+		{Storage: "local"},
+		{Type: tasktype.TaskTypeDirectlinks},
+		{Type: tasktype.TaskTypeDirectlinks, Storage: "local", WebhookEvents: []string{"nope"}},
+	}})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks:batch", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handlers.BatchCreateTasksHandler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	var resp BatchCreateTaskResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 3 || resp.Failed != 3 || resp.Succeeded != 0 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	for i, result := range resp.Results {
+		if result.Index != i || result.Status != http.StatusBadRequest || result.Error == nil {
+			t.Errorf("unexpected result %d: %+v", i, result)
+		}
+	}
+
+	for _, body := range []string{`{"tasks": []}`, `not json`} {
+		rr := httptest.NewRecorder()
+		handlers.BatchCreateTasksHandler(rr, httptest.NewRequest(http.MethodPost, "/api/v1/tasks:batch", strings.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("body %q: expected status 400, got %d", body, rr.Code)
+		}
+	}
+}
+
+func TestStorageFileHandlers(t *testing.T) {
+	handlers, _ := setupTestServer(t)
+
+	dir := t.TempDir()
+	stor := new(local.Local)
+	if err := stor.Init(t.Context(), &storcfg.LocalStorageConfig{
+		BaseConfig: storcfg.BaseConfig{Name: "apitest", Enable: true},
+		BasePath:   dir,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	storage.Storages["apitest"] = stor
+	t.Cleanup(func() { delete(storage.Storages, "apitest") })
+	os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("hello world"), 0o644)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/storages/{name}/files", handlers.ListFilesHandler)
+		mux.HandleFunc("GET /api/v1/storages/{name}/file", handlers.DownloadFileHandler)
+		mux.HandleFunc("DELETE /api/v1/storages/{name}/file", handlers.DeleteFileHandler)
+		mux.HandleFunc("POST /api/v1/storages/{name}/file:move", handlers.MoveFileHandler)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "/api/v1/storages/apitest/files?path=/", "")
+	var list FilesResponse
+	json.NewDecoder(rr.Body).Decode(&list)
+	if rr.Code != http.StatusOK || list.Total != 2 || !list.Files[0].IsDir || list.Files[1].Path != "/b.txt" {
+		t.Fatalf("list: status %d, %+v", rr.Code, list)
+	}
+
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/storages/apitest/file?path=b.txt", nil)
+	req.Header.Set("Range", "bytes=6-")
+	req.SetPathValue("name", "apitest")
+	handlers.DownloadFileHandler(rr, req)
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != "world" {
+		t.Errorf("range download: status %d, body %q", rr.Code, rr.Body.String())
+	}
+
+	if rr := serve(http.MethodGet, "/api/v1/storages/apitest/file?path=/missing.txt", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("missing file: expected status 404, got %d", rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/api/v1/storages/nope/files", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown storage: expected status 404, got %d", rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/api/v1/storages/apitest/files?path=../..", ""); rr.Code != http.StatusOK {
+		t.Errorf("path above root: expected the root, got status %d", rr.Code)
+	}
+
+	if rr := serve(http.MethodPost, "/api/v1/storages/apitest/file:move", `{"path":"b.txt","destination":"sub/c.txt"}`); rr.Code != http.StatusOK {
+		t.Fatalf("move: status %d, body %s", rr.Code, rr.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "c.txt")); err != nil {
+		t.Errorf("moved file is missing: %v", err)
+	}
+	if rr := serve(http.MethodDelete, "/api/v1/storages/apitest/file?path=/", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("delete root: expected status 400, got %d", rr.Code)
+	}
+	if rr := serve(http.MethodDelete, "/api/v1/storages/apitest/file?path=sub", ""); rr.Code != http.StatusOK {
+		t.Errorf("delete: status %d, body %s", rr.Code, rr.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub")); !os.IsNotExist(err) {
+		t.Errorf("deleted directory still exists: %v", err)
+	}
+}
+
+func TestApplyUserSettings(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/1", nil)
+	ptr := func(s string) *string { return &s }
+	newUser := func() *database.User {
+		return &database.User{
+			ChatID:         1,
+			DefaultStorage: "local",
+			Dirs: []database.Dir{
+				{Model: gorm.Model{ID: 3}, StorageName: "local", Path: "a"},
+				{Model: gorm.Model{ID: 4}, StorageName: "other", Path: "b"},
+			},
+		}
+	}
+
+	user := newUser()
+	dirID, silent := uint(3), true
+	if err := applyUserSettings(req, user, &UpdateUserRequest{
+		Silent:           &silent,
+		DefaultDir:       &dirID,
+		FilenameStrategy: ptr("MESSAGE"),
+		FilenameTemplate: ptr("{{.msgid}}"),
+		ConflictStrategy: ptr("skip"),
+	}); err != nil {
+		t.Fatalf("applyUserSettings() error = %v", err)
+	}
+	if !user.Silent || user.DefaultDir != 3 || user.FilenameStrategy != "message" || user.ConflictStrategy != "skip" {
+		t.Errorf("unexpected settings %+v", user)
+	}
+
+	otherDir, missingDir := uint(4), uint(9)
+	for name, r := range map[string]*UpdateUserRequest{
+		"filename strategy":      {FilenameStrategy: ptr("nope")},
+		"filename template":      {FilenameTemplate: ptr("{{")},
+		"conflict strategy":      {ConflictStrategy: ptr("nope")},
+		"missing dir":            {DefaultDir: &missingDir},
+		"dir of another storage": {DefaultDir: &otherDir},
+		"silent without storage": {Silent: &silent, DefaultStorage: ptr("")},
+	} {
+		if err := applyUserSettings(req, newUser(), r); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestApplyWatchSettings(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/1/watches/2", nil)
+	ptr := func(s string) *string { return &s }
+	user := &database.User{ChatID: 1}
+
+	chat := &database.WatchChat{ChatID: 2, Filter: "msgre:a", DirPath: "old"}
+	if err := applyWatchSettings(req, user, chat, &UpdateWatchRequest{
+		Filter:           ptr("expr:media:video size<2GB"),
+		FilenameStrategy: ptr("template"),
+	}); err != nil {
+		t.Fatalf("applyWatchSettings() error = %v", err)
+	}
+	if chat.Filter != "expr:media:video and size<2GB" || chat.FilenameStrategy != "template" || chat.DirPath != "old" {
+		t.Errorf("unexpected settings %+v", chat)
+	}
+	if err := applyWatchSettings(req, user, chat, &UpdateWatchRequest{Filter: ptr(""), Dir: ptr("")}); err != nil || chat.Filter != "" || chat.DirPath != "" {
+		t.Errorf("clearing settings: error = %v, %+v", err, chat)
+	}
+
+	for name, r := range map[string]*UpdateWatchRequest{
+		"filter format":     {Filter: ptr("msgre")},
+		"filter type":       {Filter: ptr("foo:bar")},
+		"filename strategy": {FilenameStrategy: ptr("nope")},
+		"dir template":      {Dir: ptr("{{.Nope")},
+	} {
+		if err := applyWatchSettings(req, user, &database.WatchChat{}, r); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, http.StatusInternalServerError, "internal_error", "boom")
+	})
+	mux.HandleFunc("/api/v1/storages", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, MessageResponse{Message: "ok"})
+	})
+	// 与 AuthMiddleware 一样复制请求, 路由仍应传回外层
+	handler := tracingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recordRoute(mux).ServeHTTP(w, r.WithContext(r.Context()))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/tasks/abc", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/storages", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	// 使用路由而不是路径作为名称
+	if spans[0].Name() != "GET /api/v1/tasks/{id}" || spans[0].Status().Code != codes.Error {
+		t.Errorf("span = %q %v, want GET /api/v1/tasks/{id} with error status", spans[0].Name(), spans[0].Status())
+	}
+	if spans[1].Name() != "GET /api/v1/storages" || spans[1].Status().Code != codes.Unset {
+		t.Errorf("span = %q %v, want GET /api/v1/storages", spans[1].Name(), spans[1].Status())
+	}
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	var got []audit.Actor
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/tasks", func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, audit.ActorFrom(r.Context()))
+		WriteJSON(w, http.StatusOK, MessageResponse{Message: "ok"})
+	})
+	handler := auditMiddleware(recordRoute(mux))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", nil)
+	req = req.WithContext(withPrincipal(req.Context(), &Principal{UserID: 42, KeyID: 7}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// 未经过认证中间件的请求视为配置中的 token
+	want := []audit.Actor{
+		{UserID: 0, Source: audit.SourceAPI},
+		{UserID: 42, Source: audit.SourceAPI, KeyID: 7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("actors = %+v, want %+v", got, want)
+	}
+}
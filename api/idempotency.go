@@ -0,0 +1,116 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader 为创建任务请求的幂等键请求头
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// 返回原来的任务时设置的响应头
+	idempotentReplayedHeader = "Idempotent-Replayed"
+
+	idempotencyKeyMaxLen = 255
+	// 幂等键的保留时间, 与任务记录的保留时间一致
+	idempotencyKeyTTL = 24 * time.Hour
+
+	// 批量创建的任务数和请求体大小上限
+	maxBatchTasks    = 1000
+	maxBatchBodySize = 16 << 20
+)
+
+// idempotencyEntry 为幂等键对应的创建结果, 创建完成前 resp 为 nil
+type idempotencyEntry struct {
+	fingerprint string
+	resp        *CreateTaskResponse
+	expiresAt   time.Time
+}
+
+// idempotencyStore 记录幂等键创建的任务. 键按调用者隔离, 不同的密钥用户可以使用相同的键
+type idempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+var idempotencyKeys = &idempotencyStore{entries: make(map[string]*idempotencyEntry)}
+
+// requestFingerprint 返回请求内容的摘要, 用于发现以相同的键提交不同的请求
+func requestFingerprint(req *CreateTaskRequest) string {
+	r := *req
+	r.IdempotencyKey = ""
+	// RawMessage 在编码时会被压缩, 空白不同的 params 视为相同
+	data, _ := json.Marshal(&r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// do 在键未使用过时调用 create 创建任务并记录结果, 否则返回原来的任务.
+// 创建失败时不记录, 客户端可以使用相同的键重试
+func (s *idempotencyStore) do(principal *Principal, req *CreateTaskRequest, create func() (*CreateTaskResponse, *APIError)) (*CreateTaskResponse, bool, *APIError) {
+	if len(req.IdempotencyKey) > idempotencyKeyMaxLen {
+		return nil, false, &APIError{http.StatusBadRequest, "invalid_request", "idempotency key is longer than " + strconv.Itoa(idempotencyKeyMaxLen) + " characters"}
+	}
+	key := strconv.FormatInt(principal.UserID, 10) + ":" + req.IdempotencyKey
+	fingerprint := requestFingerprint(req)
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.entries[key]
+	if ok && entry.resp != nil && now.After(entry.expiresAt) {
+		delete(s.entries, key)
+		ok = false
+	}
+	if ok {
+		orig := entry.resp
+		s.mu.Unlock()
+		if entry.fingerprint != fingerprint {
+			return nil, false, &APIError{http.StatusUnprocessableEntity, "idempotency_key_reused", "idempotency key was used for a different request"}
+		}
+		if orig == nil {
+			return nil, false, &APIError{http.StatusConflict, "idempotency_key_in_use", "a request with this idempotency key is in progress"}
+		}
+		return replayTask(orig), true, nil
+	}
+	entry = &idempotencyEntry{fingerprint: fingerprint}
+	s.entries[key] = entry
+	s.mu.Unlock()
+
+	resp, apiErr := create()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if apiErr != nil {
+		delete(s.entries, key)
+		return nil, false, apiErr
+	}
+	entry.resp = resp
+	entry.expiresAt = time.Now().Add(idempotencyKeyTTL)
+	return resp, false, nil
+}
+
+// replayTask 返回原来的任务, 状态更新为任务当前的状态
+func replayTask(orig *CreateTaskResponse) *CreateTaskResponse {
+	resp := *orig
+	if task, ok := GetTask(resp.TaskID); ok {
+		resp.Status, _, _, _, _, _, _, _ = task.snapshot()
+	}
+	return &resp
+}
+
+// cleanupIdempotencyKeys 删除过期的幂等键
+func cleanupIdempotencyKeys() {
+	now := time.Now()
+	idempotencyKeys.mu.Lock()
+	defer idempotencyKeys.mu.Unlock()
+	for key, entry := range idempotencyKeys.entries {
+		if entry.resp != nil && now.After(entry.expiresAt) {
+			delete(idempotencyKeys.entries, key)
+		}
+	}
+}
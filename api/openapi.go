@@ -0,0 +1,487 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/common/utils/streamutil"
+	"github.com/krau/SaveAny-Bot/pkg/apikey"
+	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
+	"github.com/krau/SaveAny-Bot/pkg/ruleset"
+)
+
+// OpenAPIPath 为 OpenAPI 文档的路径, 无需认证即可访问
+const OpenAPIPath = "/api/v1/openapi.json"
+
+// openAPISchemas 通过反射 API 类型的 json 标签生成 JSON Schema.
+// 结构体作为 components 中的命名 schema 引用, 没有 omitempty 的非指针字段视为必填
+type openAPISchemas struct {
+	components map[string]any
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// schemaEnums 为字符串枚举类型的取值
+var schemaEnums = map[reflect.Type]func() []string{
+	reflect.TypeFor[TaskStatus](): func() []string {
+		return []string{
+			string(TaskStatusQueued),
+			string(TaskStatusRunning),
+			string(TaskStatusCompleted),
+			string(TaskStatusFailed),
+			string(TaskStatusCancelled),
+		}
+	},
+	reflect.TypeFor[tasktype.TaskType](): func() []string {
+		values := make([]string, 0, len(taskParamTypes))
+		for _, t := range taskParamTypes {
+			values = append(values, string(t.Type))
+		}
+		return values
+	},
+}
+
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// schemaName 返回类型在 components 中的名称, 其他包的类型加上包名前缀, 如 RulesetDocument
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if pkg == reflect.TypeFor[TaskStatus]().PkgPath() || t == reflect.TypeFor[tasktype.TaskType]() {
+		return t.Name()
+	}
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + t.Name()
+}
+
+func (s *openAPISchemas) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+	if _, ok := schemaEnums[t]; ok {
+		return s.ref(t)
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schema(t.Elem())
+	case reflect.Struct:
+		return s.ref(t)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	// interface 等任意值
+	return map[string]any{}
+}
+
+// ref 注册类型的命名 schema 并返回引用
+func (s *openAPISchemas) ref(t reflect.Type) map[string]any {
+	name := schemaName(t)
+	if _, ok := s.components[name]; !ok {
+		// 先占位, 避免自引用的类型无限递归
+		s.components[name] = nil
+		if enum, ok := schemaEnums[t]; ok {
+			s.components[name] = map[string]any{"type": "string", "enum": enum()}
+		} else {
+			s.components[name] = s.object(t)
+		}
+	}
+	return schemaRef(name)
+}
+
+func (s *openAPISchemas) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	s.fields(t, properties, &required)
+	obj := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
+
+func (s *openAPISchemas) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			s.fields(f.Type, properties, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = s.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// createTaskVariantName 返回任务类型对应的创建请求 schema 名称, 如 CreateDirectlinksTaskRequest
+func createTaskVariantName(t tasktype.TaskType) string {
+	s := string(t)
+	return "Create" + strings.ToUpper(s[:1]) + s[1:] + "TaskRequest"
+}
+
+// createTaskSchemas 注册每种任务类型的创建请求, CreateTaskRequest 为按 type 区分的 oneOf
+func (s *openAPISchemas) createTaskSchemas() {
+	base := s.object(reflect.TypeFor[CreateTaskRequest]())
+	variants := make([]any, 0, len(taskParamTypes))
+	mapping := map[string]any{}
+	for _, t := range taskParamTypes {
+		properties := map[string]any{}
+		for k, v := range base["properties"].(map[string]any) {
+			properties[k] = v
+		}
+		properties["type"] = map[string]any{"type": "string", "enum": []string{string(t.Type)}}
+		properties["params"] = s.schema(reflect.TypeOf(t.Params))
+
+		name := createTaskVariantName(t.Type)
+		s.components[name] = map[string]any{
+			"type":       "object",
+			"properties": properties,
+			"required":   base["required"],
+		}
+		variants = append(variants, schemaRef(name))
+		mapping[string(t.Type)] = "#/components/schemas/" + name
+	}
+	s.components["CreateTaskRequest"] = map[string]any{
+		"oneOf": variants,
+		"discriminator": map[string]any{
+			"propertyName": "type",
+			"mapping":      mapping,
+		},
+	}
+}
+
+// openAPIOperation 描述一个接口, 用于生成 paths
+type openAPIOperation struct {
+	Method  string
+	Path    string
+	ID      string
+	Summary string
+	// Scope 为调用所需的 API 密钥权限, 为空时任意密钥均可调用
+	Scope apikey.Scope
+	// Public 表示无需认证, Signed 表示签名的链接无需认证
+	Public      bool
+	Signed      bool
+	Params      []map[string]any
+	Body        any // reflect.Type 或 map 形式的 content
+	Status      int
+	Response    any // reflect.Type 或 map 形式的 content, 为 nil 时没有响应体
+	Description string
+}
+
+func pathParam(name, typ, description string) map[string]any {
+	return map[string]any{
+		"name": name, "in": "path", "required": true, "description": description,
+		"schema": map[string]any{"type": typ},
+	}
+}
+
+func queryParam(name, typ, description string) map[string]any {
+	return map[string]any{
+		"name": name, "in": "query", "description": description,
+		"schema": map[string]any{"type": typ},
+	}
+}
+
+func headerParam(name, description string) map[string]any {
+	return map[string]any{
+		"name": name, "in": "header", "description": description,
+		"schema": map[string]any{"type": "string", "maxLength": idempotencyKeyMaxLen},
+	}
+}
+
+// openAPIOperations 列出所有接口, 新增路由时需要同步更新
+func openAPIOperations() []openAPIOperation {
+	taskID := pathParam("id", "string", "Task ID")
+	userID := pathParam("id", "integer", "Telegram user ID")
+	dirID := pathParam("dir_id", "integer", "Dir ID")
+	ruleID := pathParam("rule_id", "integer", "Rule ID")
+	watchChatID := pathParam("chat_id", "integer", "Watched chat ID")
+	storageName := pathParam("name", "string", "Storage name")
+	filePath := queryParam("path", "string", "File path in the storage")
+	filePath["required"] = true
+	idempotencyKey := headerParam(IdempotencyKeyHeader,
+		"Repeated requests with the same key return the original task instead of creating a new one. Keys are kept for 24 hours.")
+	return []openAPIOperation{
+		{Method: http.MethodGet, Path: "/health", ID: "healthCheck", Summary: "Health check",
+			Response: reflect.TypeFor[HealthResponse]()},
+		{Method: http.MethodGet, Path: "/metrics", ID: "getMetrics", Summary: "Prometheus metrics",
+			Scope: apikey.ScopeAdmin, Description: "Task, queue, storage, parser and Telegram flood wait metrics in the Prometheus text format.",
+			Response: map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}}},
+		{Method: http.MethodGet, Path: OpenAPIPath, ID: "getOpenAPI", Summary: "This document", Public: true,
+			Response: map[string]any{"application/json": map[string]any{"schema": map[string]any{"type": "object"}}}},
+		{Method: http.MethodPost, Path: "/api/v1/tasks", ID: "createTask", Summary: "Create a task",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{idempotencyKey}, Body: reflect.TypeFor[CreateTaskRequest](),
+			Description: "The response has the Idempotent-Replayed header when the idempotency key was used before.",
+			Status:      http.StatusCreated, Response: reflect.TypeFor[CreateTaskResponse]()},
+		{Method: http.MethodPost, Path: "/api/v1/tasks:batch", ID: "createTasks", Summary: "Create tasks in a batch",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{headerParam(IdempotencyKeyHeader,
+				"Prefix for the idempotency keys of tasks without one; task i uses <key>:<i>.")},
+			Body:        reflect.TypeFor[BatchCreateTaskRequest](),
+			Description: "Each task is validated and created on its own; results are in request order. At most 1000 tasks.",
+			Response:    reflect.TypeFor[BatchCreateTaskResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/tasks", ID: "listTasks", Summary: "List tasks",
+			Scope: apikey.ScopeTasksRead, Response: reflect.TypeFor[TasksListResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/tasks/{id}", ID: "getTask", Summary: "Get a task",
+			Scope: apikey.ScopeTasksRead, Params: []map[string]any{taskID}, Response: reflect.TypeFor[TaskInfoResponse]()},
+		{Method: http.MethodDelete, Path: "/api/v1/tasks/{id}", ID: "cancelTask", Summary: "Cancel a task",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{taskID}, Response: reflect.TypeFor[MessageResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/tasks/{id}/events", ID: "streamTaskEvents", Summary: "Stream task events (SSE)",
+			Scope: apikey.ScopeTasksRead, Params: []map[string]any{taskID},
+			Description: "Server-Sent Events. Each event is named after TaskEvent.event and carries a TaskEvent as data.",
+			Response:    map[string]any{"text/event-stream": map[string]any{"schema": schemaRef("TaskEvent")}}},
+		{Method: http.MethodGet, Path: "/api/v1/tasks/{id}/ws", ID: "watchTaskEvents", Summary: "Stream task events (WebSocket)",
+			Scope: apikey.ScopeTasksRead, Params: []map[string]any{taskID}, Status: http.StatusSwitchingProtocols,
+			Description: "WebSocket upgrade. Each message is a TaskEvent encoded as JSON."},
+		{Method: http.MethodGet, Path: "/api/v1/task-types", ID: "listTaskTypes", Summary: "List task types",
+			Scope: apikey.ScopeTasksRead, Response: reflect.TypeFor[TaskTypesResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/storages", ID: "listStorages", Summary: "List storages",
+			Scope: apikey.ScopeStoragesRead, Response: reflect.TypeFor[StoragesResponse]()},
+		{Method: http.MethodPost, Path: "/api/v1/storages/{name}/send", ID: "sendFile", Summary: "Send a stored file to a Telegram chat",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{storageName},
+			Body: reflect.TypeFor[SendFileRequest](), Status: http.StatusCreated, Response: reflect.TypeFor[CreateTaskResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/storages/{name}/files", ID: "listFiles", Summary: "List a directory of a storage",
+			Scope: apikey.ScopeStoragesRead, Params: []map[string]any{storageName, queryParam("path", "string", "Directory, defaults to the root")},
+			Response: reflect.TypeFor[FilesResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/storages/{name}/file", ID: "downloadFile", Summary: "Download a file of a storage",
+			Scope: apikey.ScopeStoragesRead, Params: []map[string]any{storageName, filePath},
+			Description: "Supports Range requests when the storage returns seekable content; otherwise Accept-Ranges is none.",
+			Response:    map[string]any{"application/octet-stream": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}}},
+		{Method: http.MethodDelete, Path: "/api/v1/storages/{name}/file", ID: "deleteFile", Summary: "Delete a file or directory of a storage",
+			Scope: apikey.ScopeStoragesWrite, Params: []map[string]any{storageName, filePath}, Response: reflect.TypeFor[MessageResponse]()},
+		{Method: http.MethodPost, Path: "/api/v1/storages/{name}/file:move", ID: "moveFile", Summary: "Move or rename a file within a storage",
+			Scope: apikey.ScopeStoragesWrite, Params: []map[string]any{storageName},
+			Body: reflect.TypeFor[MoveFileRequest](), Response: reflect.TypeFor[MessageResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/webhooks/deliveries", ID: "listWebhookDeliveries", Summary: "List webhook deliveries",
+			Scope: apikey.ScopeTasksRead, Params: []map[string]any{
+				queryParam("task_id", "string", "Only deliveries of this task"),
+				queryParam("failed", "boolean", "Only failed deliveries"),
+				queryParam("limit", "integer", "Maximum number of deliveries, 1-1000, default 100"),
+			}, Response: reflect.TypeFor[WebhookDeliveriesResponse]()},
+		{Method: http.MethodPost, Path: "/api/v1/webhooks/deliveries/{id}/redeliver", ID: "redeliverWebhook", Summary: "Redeliver a webhook",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{pathParam("id", "integer", "Delivery ID")},
+			Response: reflect.TypeFor[WebhookDeliveryResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/users/{id}/rules", ID: "exportRules", Summary: "Export rules, dirs and filename settings",
+			Scope: apikey.ScopeTasksRead, Params: []map[string]any{userID, queryParam("format", "string", "json (default) or toml")},
+			Response: map[string]any{
+				"application/json": map[string]any{"schema": schemaRef(schemaName(reflect.TypeFor[ruleset.Document]()))},
+				"application/toml": map[string]any{"schema": map[string]any{"type": "string"}},
+			}},
+		{Method: http.MethodPut, Path: "/api/v1/users/{id}/rules", ID: "importRules", Summary: "Import rules, dirs and filename settings",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{userID, queryParam("mode", "string", "merge (default) or replace")},
+			Body: map[string]any{
+				"application/json": map[string]any{"schema": schemaRef(schemaName(reflect.TypeFor[ruleset.Document]()))},
+				"application/toml": map[string]any{"schema": map[string]any{"type": "string"}},
+			}, Response: reflect.TypeFor[ImportRulesResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/users", ID: "listUsers", Summary: "List users with their settings",
+			Scope: apikey.ScopeAdmin, Response: reflect.TypeFor[UsersResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/audit", ID: "listAuditLogs", Summary: "List the audit log of user actions",
+			Scope: apikey.ScopeAdmin, Params: []map[string]any{
+				queryParam("actor", "integer", "Only actions of this Telegram user ID, 0 is the API token of the config"),
+				queryParam("action", "string", "Only this action or actions under it, e.g. rule matches rule.create"),
+				queryParam("source", "string", "Only actions from bot, api or system"),
+				queryParam("since", "string", "Only actions at or after this RFC 3339 time"),
+				queryParam("before", "integer", "Only entries with a smaller ID, for paging"),
+				queryParam("limit", "integer", "Maximum number of entries, 1-1000, default 100"),
+			}, Description: "Entries are newest first. Bot commands and callbacks, task creation and cancellation, API calls other than GET, " +
+				"and changes to rules, dirs, storages and settings are recorded.",
+			Response: reflect.TypeFor[AuditLogsResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/users/{id}", ID: "getUser", Summary: "Get the settings, dirs, rules and watches of a user",
+			Scope: apikey.ScopeTasksRead, Params: []map[string]any{userID}, Response: reflect.TypeFor[UserResponse]()},
+		{Method: http.MethodPatch, Path: "/api/v1/users/{id}", ID: "updateUser", Summary: "Update the settings of a user",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{userID}, Body: reflect.TypeFor[UpdateUserRequest](),
+			Description: "Only the given fields are changed. Changing default_storage clears default_dir.",
+			Response:    reflect.TypeFor[UserResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/users/{id}/dirs", ID: "listUserDirs", Summary: "List the dirs of a user",
+			Scope: apikey.ScopeTasksRead, Params: []map[string]any{userID}, Response: reflect.TypeFor[DirsResponse]()},
+		{Method: http.MethodPost, Path: "/api/v1/users/{id}/dirs", ID: "createUserDir", Summary: "Add a dir for a user",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{userID}, Body: reflect.TypeFor[DirRequest](),
+			Status: http.StatusCreated, Response: reflect.TypeFor[DirResponse]()},
+		{Method: http.MethodDelete, Path: "/api/v1/users/{id}/dirs/{dir_id}", ID: "deleteUserDir", Summary: "Delete a dir of a user",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{userID, dirID}, Response: reflect.TypeFor[MessageResponse]()},
+		{Method: http.MethodPost, Path: "/api/v1/users/{id}/rules", ID: "createUserRule", Summary: "Add a rule for a user",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{userID}, Body: reflect.TypeFor[RuleRequest](),
+			Status: http.StatusCreated, Response: reflect.TypeFor[RuleResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/users/{id}/rules/{rule_id}", ID: "getUserRule", Summary: "Get a rule of a user",
+			Scope: apikey.ScopeTasksRead, Params: []map[string]any{userID, ruleID}, Response: reflect.TypeFor[RuleResponse]()},
+		{Method: http.MethodPut, Path: "/api/v1/users/{id}/rules/{rule_id}", ID: "updateUserRule", Summary: "Replace a rule of a user",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{userID, ruleID}, Body: reflect.TypeFor[RuleRequest](),
+			Response: reflect.TypeFor[RuleResponse]()},
+		{Method: http.MethodDelete, Path: "/api/v1/users/{id}/rules/{rule_id}", ID: "deleteUserRule", Summary: "Delete a rule of a user",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{userID, ruleID}, Response: reflect.TypeFor[MessageResponse]()},
+		{Method: http.MethodGet, Path: "/api/v1/users/{id}/watches", ID: "listUserWatches", Summary: "List the chats watched by a user",
+			Scope: apikey.ScopeTasksRead, Params: []map[string]any{userID}, Response: reflect.TypeFor[WatchesResponse]()},
+		{Method: http.MethodPost, Path: "/api/v1/users/{id}/watches", ID: "createUserWatch", Summary: "Watch a chat for a user",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{userID}, Body: reflect.TypeFor[WatchRequest](),
+			Description: "Backfilling history needs the userbot and is only available in the bot.",
+			Status:      http.StatusCreated, Response: reflect.TypeFor[WatchResponse]()},
+		{Method: http.MethodPatch, Path: "/api/v1/users/{id}/watches/{chat_id}", ID: "updateUserWatch", Summary: "Update the settings of a watched chat",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{userID, watchChatID}, Body: reflect.TypeFor[UpdateWatchRequest](),
+			Description: "Only the given fields are changed; an empty string restores the default.",
+			Response:    reflect.TypeFor[WatchResponse]()},
+		{Method: http.MethodDelete, Path: "/api/v1/users/{id}/watches/{chat_id}", ID: "deleteUserWatch", Summary: "Stop watching a chat",
+			Scope: apikey.ScopeTasksWrite, Params: []map[string]any{userID, watchChatID}, Response: reflect.TypeFor[MessageResponse]()},
+		{Method: http.MethodGet, Path: streamutil.PathPrefix + "{chat_id}/{msg_id}", ID: "streamFile", Summary: "Stream a Telegram file",
+			Scope: apikey.ScopeAdmin, Signed: true, Params: []map[string]any{
+				pathParam("chat_id", "integer", "Chat ID"),
+				pathParam("msg_id", "integer", "Message ID"),
+				queryParam("exp", "integer", "Expiry of a signed link"),
+				queryParam("sig", "string", "Signature of a signed link, replaces authentication"),
+			},
+			Description: "Supports Range requests. Signed links need no authorization header.",
+			Response:    map[string]any{"application/octet-stream": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}}},
+	}
+}
+
+// content 返回请求体或响应体的 content
+func (s *openAPISchemas) content(v any) map[string]any {
+	if t, ok := v.(reflect.Type); ok {
+		return map[string]any{"application/json": map[string]any{"schema": s.schema(t)}}
+	}
+	return v.(map[string]any)
+}
+
+// buildOpenAPI 生成 OpenAPI 3.1 文档
+func buildOpenAPI() map[string]any {
+	s := &openAPISchemas{components: map[string]any{}}
+	s.createTaskSchemas()
+	errorContent := s.content(reflect.TypeFor[ErrorResponse]())
+
+	paths := map[string]any{}
+	for _, op := range openAPIOperations() {
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		if op.Response != nil {
+			success["content"] = s.content(op.Response)
+		}
+		operation := map[string]any{
+			"operationId": op.ID,
+			"summary":     op.Summary,
+			"responses": map[string]any{
+				strconv.Itoa(status): success,
+				"default":            map[string]any{"description": "Error", "content": errorContent},
+			},
+		}
+		description := op.Description
+		switch {
+		case op.Public:
+			operation["security"] = []any{}
+		case op.Signed:
+			operation["security"] = []any{map[string]any{"bearerAuth": []string{}}, map[string]any{}}
+		}
+		if op.Scope != "" {
+			operation["x-required-scope"] = string(op.Scope)
+			description = strings.TrimSpace(description + " Requires scope `" + string(op.Scope) + "`.")
+		}
+		if description != "" {
+			operation["description"] = description
+		}
+		if len(op.Params) > 0 {
+			operation["parameters"] = op.Params
+		}
+		if op.Body != nil {
+			operation["requestBody"] = map[string]any{"required": true, "content": s.content(op.Body)}
+		}
+		item, ok := paths[op.Path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	// 以下类型只通过事件流, 规则文件和回调出现, 需要单独注册
+	s.schema(reflect.TypeFor[TaskEvent]())
+	s.schema(reflect.TypeFor[ruleset.Document]())
+	webhookPayload := s.content(reflect.TypeFor[WebhookPayload]())
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "SaveAny-Bot API",
+			"version": "v1",
+		},
+		"paths": paths,
+		"webhooks": map[string]any{
+			"task": map[string]any{
+				"post": map[string]any{
+					"operationId": "taskWebhook",
+					"summary":     "Task event sent to the webhook of a task and to global webhooks",
+					"description": "Signed with X-SaveAny-Signature when a secret is set.",
+					"requestBody": map[string]any{"required": true, "content": webhookPayload},
+					"responses": map[string]any{
+						"2XX": map[string]any{"description": "Delivered"},
+					},
+				},
+			},
+		},
+		"components": map[string]any{
+			"schemas": s.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "The API token from the config, or an API key created with /apikey.",
+				},
+			},
+		},
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+	}
+}
+
+var openAPIDocument = sync.OnceValues(func() ([]byte, error) {
+	return json.Marshal(buildOpenAPI())
+})
+
+// OpenAPIHandler 返回描述所有接口的 OpenAPI 文档
+func (h *Handlers) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := openAPIDocument()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
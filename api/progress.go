@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"sync"
 	"time"
 
@@ -28,7 +27,10 @@ type TaskProgressInfo struct {
 	UpdatedAt       time.Time
 	StartedAt       time.Time
 	Webhook         string
-	webhookNotified bool
+	// UserID 为创建任务的用户, 使用配置中的 token 创建时为 0
+	UserID int64
+	// sinks 为订阅任务事件的客户端, 在更新状态后收到每个事件
+	sinks map[*taskevent.Sink]struct{}
 }
 
 // progressStore holds all API tasks. Entries are removed a fixed duration after
@@ -44,8 +46,8 @@ var store = &progressStore{
 	retention: 24 * time.Hour,
 }
 
-// RegisterTask registers a new API task and returns its progress info.
-func RegisterTask(taskID, taskType, storage, path, title, webhook string) *TaskProgressInfo {
+// RegisterTask registers a new API task of the user and returns its progress info.
+func RegisterTask(taskID, taskType, storage, path, title, webhook string, userID int64) *TaskProgressInfo {
 	info := &TaskProgressInfo{
 		TaskID:    taskID,
 		Type:      taskType,
@@ -56,6 +58,7 @@ func RegisterTask(taskID, taskType, storage, path, title, webhook string) *TaskP
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		Webhook:   webhook,
+		UserID:    userID,
 	}
 
 	store.mu.Lock()
@@ -100,8 +103,7 @@ func CleanupExpired() {
 	defer store.mu.Unlock()
 	for id, info := range store.tasks {
 		info.mu.Lock()
-		terminal := info.Status == TaskStatusCompleted || info.Status == TaskStatusFailed || info.Status == TaskStatusCancelled
-		stale := terminal && now.Sub(info.UpdatedAt) > store.retention
+		stale := isTerminalStatus(info.Status) && now.Sub(info.UpdatedAt) > store.retention
 		info.mu.Unlock()
 		if stale {
 			delete(store.tasks, id)
@@ -121,6 +123,7 @@ func StartCleanupLoop(ctx interface{ Done() <-chan struct{} }) {
 				return
 			case <-ticker.C:
 				CleanupExpired()
+				cleanupIdempotencyKeys()
 			}
 		}
 	}()
@@ -146,6 +149,23 @@ func (t *TaskProgressInfo) SetError(err string) {
 	t.mu.Unlock()
 }
 
+// AddSink subscribes a sink to the events of the task, after the task state
+// has been updated. The returned function removes the sink.
+func (t *TaskProgressInfo) AddSink(sink taskevent.Sink) (remove func()) {
+	key := &sink
+	t.mu.Lock()
+	if t.sinks == nil {
+		t.sinks = make(map[*taskevent.Sink]struct{})
+	}
+	t.sinks[key] = struct{}{}
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		delete(t.sinks, key)
+		t.mu.Unlock()
+	}
+}
+
 // snapshot returns a point-in-time copy of the fields needed to render a
 // response, so callers never touch the mutex directly.
 func (t *TaskProgressInfo) snapshot() (status TaskStatus, total, downloaded int64, totalFiles, downloadedFiles int, startedAt time.Time, err string, updatedAt time.Time) {
@@ -155,7 +175,7 @@ func (t *TaskProgressInfo) snapshot() (status TaskStatus, total, downloaded int6
 }
 
 // Emit implements taskevent.Sink. It translates task lifecycle events into
-// status/progress updates and fires the webhook on terminal transitions.
+// status/progress updates and forwards them to the subscribed sinks.
 func (t *TaskProgressInfo) Emit(e taskevent.Event) {
 	t.mu.Lock()
 	switch e.Phase {
@@ -188,14 +208,13 @@ func (t *TaskProgressInfo) Emit(e taskevent.Event) {
 		}
 	}
 	t.UpdatedAt = time.Now()
-	notify := t.Webhook != "" && !t.webhookNotified && (t.Status == TaskStatusCompleted || t.Status == TaskStatusFailed)
-	if notify {
-		t.webhookNotified = true
+	sinks := make([]taskevent.Sink, 0, len(t.sinks))
+	for s := range t.sinks {
+		sinks = append(sinks, *s)
 	}
 	t.mu.Unlock()
 
-	if notify {
-		payload := CreateWebhookPayload(t.TaskID, t.Type, t.Status, t.Storage, t.Path, e.Err)
-		SendWebhook(context.Background(), payload)
+	for _, s := range sinks {
+		s.Emit(e)
 	}
 }
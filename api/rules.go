@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/ruleset"
+	"gorm.io/gorm"
+)
+
+// 规则文件的大小上限
+const maxRulesetBodySize = 1 << 20
+
+// ImportRulesResponse 导入规则响应
+type ImportRulesResponse struct {
+	Mode  string `json:"mode"`
+	Rules int    `json:"rules"`
+	Dirs  int    `json:"dirs"`
+}
+
+// GetUserRulesHandler 导出用户的规则, 文件夹和文件名设置, 通过 ?format=toml 选择 TOML 格式
+func (h *Handlers) GetUserRulesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	format, err := ruleset.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	doc := ruleutil.DocumentFromUser(user)
+	if format == ruleset.FormatJSON {
+		WriteJSON(w, http.StatusOK, doc)
+		return
+	}
+	data, err := ruleset.Encode(doc, format)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/toml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// PutUserRulesHandler 导入用户的规则, 文件夹和文件名设置.
+// 请求体为 JSON 或 TOML 文档, 默认与现有规则合并, ?mode=replace 时替换
+func (h *Handlers) PutUserRulesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	switch mode {
+	case "":
+		mode = "merge"
+	case "merge", "replace":
+	default:
+		WriteError(w, http.StatusBadRequest, "invalid_request", "mode must be merge or replace")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRulesetBodySize))
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "failed to read request body: "+err.Error())
+		return
+	}
+	doc, err := ruleset.Decode(body)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	result, err := ruleutil.Import(r.Context(), user, doc, mode == "replace")
+	if err != nil {
+		WriteError(w, http.StatusUnprocessableEntity, "invalid_rules", err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusOK, ImportRulesResponse{
+		Mode:  mode,
+		Rules: result.Rules,
+		Dirs:  result.Dirs,
+	})
+}
+
+// userFromPath 根据路径中的聊天 ID 获取用户, 失败或调用者无权访问该用户时写入错误响应
+func userFromPath(w http.ResponseWriter, r *http.Request) (*database.User, bool) {
+	chatID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "invalid user id: "+r.PathValue("id"))
+		return nil, false
+	}
+	if !principalFrom(r).CanManageUser(chatID) {
+		WriteError(w, http.StatusForbidden, "forbidden", "cannot access user: "+r.PathValue("id"))
+		return nil, false
+	}
+	user, err := database.GetUserByChatID(r.Context(), chatID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		WriteError(w, http.StatusNotFound, "user_not_found", "user not found: "+r.PathValue("id"))
+		return nil, false
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return nil, false
+	}
+	return user, true
+}
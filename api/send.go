@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/core/tasks/transfer"
+	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
+	"github.com/krau/SaveAny-Bot/storage"
+	"github.com/rs/xid"
+)
+
+// SendFileHandler 将存储中的文件或目录上传到 Telegram 聊天
+func (h *Handlers) SendFileHandler(w http.ResponseWriter, r *http.Request) {
+	var req SendFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "failed to decode request body: "+err.Error())
+		return
+	}
+	if req.Path == "" {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "path is required")
+		return
+	}
+	if req.ChatID == 0 {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "chat_id is required")
+		return
+	}
+	if !principalFrom(r).CanSendToChat(req.ChatID) {
+		WriteError(w, http.StatusForbidden, "forbidden", "api keys can only send files to the chat of their user")
+		return
+	}
+	if err := validateWebhookEvents(req.WebhookEvents); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	stor, ok := storage.GetStorage(r.PathValue("name"))
+	if !ok || !principalFrom(r).CanUseStorage(r.PathValue("name")) {
+		WriteError(w, http.StatusNotFound, "storage_not_found", "storage not found: "+r.PathValue("name"))
+		return
+	}
+
+	resp, err := h.factory.forActor(principalFrom(r).auditActor()).SendToChat(stor, &req)
+	if errors.Is(err, os.ErrNotExist) {
+		WriteError(w, http.StatusNotFound, "file_not_found", err.Error())
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "task_creation_failed", err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusCreated, resp)
+}
+
+// SendToChat 创建将存储中的文件发送到 Telegram 聊天的传输任务
+func (f *TaskFactory) SendToChat(stor storage.Storage, req *SendFileRequest) (*CreateTaskResponse, error) {
+	clientCtx, err := getClientContext()
+	if err != nil {
+		return nil, err
+	}
+	target, err := storage.NewTelegramChatStorage(f.ctx, req.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create telegram storage: %w", err)
+	}
+	elems, err := transfer.NewSendElements(f.ctx, stor, req.Path, target)
+	if err != nil {
+		return nil, err
+	}
+
+	taskID := xid.New().String()
+	createdAt := time.Now()
+	// Telegram 存储上传时需要从 context 中获取客户端
+	taskCtx := tgutil.ExtWithContext(f.ctx, clientCtx)
+	task := transfer.NewTransferTask(taskID, taskCtx, elems, nil, true)
+	sub := &TaskFactory{ctx: taskCtx, userID: f.userID}
+	if err := sub.registerAndEnqueueTask(task, tasktype.TaskTypeTransfer, stor.Name(), req.Path, req.webhook()); err != nil {
+		return nil, err
+	}
+	return &CreateTaskResponse{
+		TaskID:    taskID,
+		Type:      tasktype.TaskTypeTransfer,
+		Status:    TaskStatusQueued,
+		CreatedAt: createdAt,
+	}, nil
+}
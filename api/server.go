@@ -5,10 +5,17 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/common/utils/streamutil"
 	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/pkg/apikey"
+	"github.com/krau/SaveAny-Bot/pkg/metrics"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
 )
 
 // Server API 服务器
@@ -30,36 +37,84 @@ func NewServer(ctx context.Context) *Server {
 	// 健康检查
 	mux.HandleFunc("/health", handlers.HealthCheckHandler)
 
+	// Prometheus 指标
+	mux.Handle("GET /metrics", requireScope(apikey.ScopeAdmin, metrics.Handler().ServeHTTP))
+
 	// API v1 路由
+	mux.HandleFunc("GET "+OpenAPIPath, handlers.OpenAPIHandler)
 	mux.HandleFunc("/api/v1/tasks", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			handlers.ListTasksHandler(w, r)
+			requireScope(apikey.ScopeTasksRead, handlers.ListTasksHandler)(w, r)
 		case http.MethodPost:
-			handlers.CreateTaskHandler(w, r)
+			requireScope(apikey.ScopeTasksWrite, handlers.CreateTaskHandler)(w, r)
 		default:
 			MethodNotAllowedHandler(w, r)
 		}
 	})
+	mux.HandleFunc("POST /api/v1/tasks:batch", requireScope(apikey.ScopeTasksWrite, handlers.BatchCreateTasksHandler))
 	mux.HandleFunc("/api/v1/tasks/", func(w http.ResponseWriter, r *http.Request) {
 		// 根据方法和路径分发
 		switch r.Method {
 		case http.MethodGet:
-			handlers.GetTaskHandler(w, r)
+			requireScope(apikey.ScopeTasksRead, handlers.GetTaskHandler)(w, r)
 		case http.MethodDelete:
-			handlers.CancelTaskHandler(w, r)
+			requireScope(apikey.ScopeTasksWrite, handlers.CancelTaskHandler)(w, r)
 		default:
 			MethodNotAllowedHandler(w, r)
 		}
 	})
-	mux.HandleFunc("/api/v1/storages", handlers.ListStoragesHandler)
-	mux.HandleFunc("/api/v1/task-types", handlers.GetTaskTypesHandler)
+	mux.HandleFunc("GET /api/v1/tasks/{id}/events", requireScope(apikey.ScopeTasksRead, handlers.TaskEventsHandler))
+	mux.HandleFunc("GET /api/v1/tasks/{id}/ws", requireScope(apikey.ScopeTasksRead, handlers.TaskEventsWebSocketHandler))
+	mux.HandleFunc("/api/v1/storages", requireScope(apikey.ScopeStoragesRead, handlers.ListStoragesHandler))
+	mux.HandleFunc("POST /api/v1/storages/{name}/send", requireScope(apikey.ScopeTasksWrite, handlers.SendFileHandler))
+	mux.HandleFunc("GET /api/v1/storages/{name}/files", requireScope(apikey.ScopeStoragesRead, handlers.ListFilesHandler))
+	mux.HandleFunc("GET /api/v1/storages/{name}/file", requireScope(apikey.ScopeStoragesRead, handlers.DownloadFileHandler))
+	mux.HandleFunc("DELETE /api/v1/storages/{name}/file", requireScope(apikey.ScopeStoragesWrite, handlers.DeleteFileHandler))
+	mux.HandleFunc("POST /api/v1/storages/{name}/file:move", requireScope(apikey.ScopeStoragesWrite, handlers.MoveFileHandler))
+	mux.HandleFunc("GET /api/v1/webhooks/deliveries", requireScope(apikey.ScopeTasksRead, handlers.ListWebhookDeliveriesHandler))
+	mux.HandleFunc("POST /api/v1/webhooks/deliveries/{id}/redeliver", requireScope(apikey.ScopeTasksWrite, handlers.RedeliverWebhookHandler))
+	mux.HandleFunc("/api/v1/task-types", requireScope(apikey.ScopeTasksRead, handlers.GetTaskTypesHandler))
+	// 规则决定任务的保存位置, 除 admin 外只能访问自己的规则, 见 userFromPath
+	mux.HandleFunc("GET /api/v1/users/{id}/rules", requireScope(apikey.ScopeTasksRead, handlers.GetUserRulesHandler))
+	mux.HandleFunc("PUT /api/v1/users/{id}/rules", requireScope(apikey.ScopeTasksWrite, handlers.PutUserRulesHandler))
+	mux.HandleFunc("GET /api/v1/users", requireScope(apikey.ScopeAdmin, handlers.ListUsersHandler))
+	mux.HandleFunc("GET /api/v1/audit", requireScope(apikey.ScopeAdmin, handlers.ListAuditLogsHandler))
+	mux.HandleFunc("GET /api/v1/users/{id}", requireScope(apikey.ScopeTasksRead, handlers.GetUserHandler))
+	mux.HandleFunc("PATCH /api/v1/users/{id}", requireScope(apikey.ScopeTasksWrite, handlers.UpdateUserHandler))
+	mux.HandleFunc("GET /api/v1/users/{id}/dirs", requireScope(apikey.ScopeTasksRead, handlers.ListUserDirsHandler))
+	mux.HandleFunc("POST /api/v1/users/{id}/dirs", requireScope(apikey.ScopeTasksWrite, handlers.CreateUserDirHandler))
+	mux.HandleFunc("DELETE /api/v1/users/{id}/dirs/{dir_id}", requireScope(apikey.ScopeTasksWrite, handlers.DeleteUserDirHandler))
+	mux.HandleFunc("POST /api/v1/users/{id}/rules", requireScope(apikey.ScopeTasksWrite, handlers.CreateUserRuleHandler))
+	mux.HandleFunc("GET /api/v1/users/{id}/rules/{rule_id}", requireScope(apikey.ScopeTasksRead, handlers.GetUserRuleHandler))
+	mux.HandleFunc("PUT /api/v1/users/{id}/rules/{rule_id}", requireScope(apikey.ScopeTasksWrite, handlers.UpdateUserRuleHandler))
+	mux.HandleFunc("DELETE /api/v1/users/{id}/rules/{rule_id}", requireScope(apikey.ScopeTasksWrite, handlers.DeleteUserRuleHandler))
+	mux.HandleFunc("GET /api/v1/users/{id}/watches", requireScope(apikey.ScopeTasksRead, handlers.ListUserWatchesHandler))
+	mux.HandleFunc("POST /api/v1/users/{id}/watches", requireScope(apikey.ScopeTasksWrite, handlers.CreateUserWatchHandler))
+	mux.HandleFunc("PATCH /api/v1/users/{id}/watches/{chat_id}", requireScope(apikey.ScopeTasksWrite, handlers.UpdateUserWatchHandler))
+	mux.HandleFunc("DELETE /api/v1/users/{id}/watches/{chat_id}", requireScope(apikey.ScopeTasksWrite, handlers.DeleteUserWatchHandler))
+	mux.HandleFunc(streamutil.PathPrefix, func(w http.ResponseWriter, r *http.Request) {
+		// 未签名的链接可以读取任意聊天的文件, 需要 admin
+		if r.URL.Query().Get("sig") == "" {
+			requireScope(apikey.ScopeAdmin, handlers.StreamHandler)(w, r)
+			return
+		}
+		handlers.StreamHandler(w, r)
+	})
+
+	// 只读 WebDAV
+	if prefix := webdavPrefix(); prefix != "" {
+		mux.Handle(prefix+"/", NewWebDAVHandler(prefix))
+	}
 
 	// 404 处理
 	mux.HandleFunc("/", NotFoundHandler)
 
 	// Apply middleware chain.
-	var handler http.Handler = mux
+	var handler http.Handler = recordRoute(mux)
+
+	// 审计日志使用认证中间件得到的调用者
+	handler = auditMiddleware(handler)
 
 	// Apply auth middleware when a token is configured.
 	token := cfg.Token
@@ -70,10 +125,14 @@ func NewServer(ctx context.Context) *Server {
 	// Add logging middleware.
 	handler = loggingMiddleware(handler)
 
+	// 链路追踪, 未启用时使用 no-op tracer
+	handler = tracingMiddleware(handler)
+
 	// Add recovery middleware.
 	handler = recoveryMiddleware(handler)
 
 	return &Server{
+		// 文件流, WebDAV 和任务事件流等长连接在各自的处理器中清除 WriteTimeout
 		httpServer: &http.Server{
 			Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
 			Handler:      handler,
@@ -131,6 +190,61 @@ func loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// tracingMiddleware 为每个请求创建 span, 延续请求头中的链路
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.StartServer(r.Context(), r.Header, r.Method,
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLPath(r.URL.Path),
+		)
+		defer span.End()
+		r, pattern := withRouteRecorder(r.WithContext(ctx))
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(wrapped, r)
+
+		// 使用路由作为 span 名称, 避免路径参数导致基数过高
+		if route := routePattern(*pattern); route != "" {
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(wrapped.statusCode))
+		if wrapped.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrapped.statusCode))
+		}
+	})
+}
+
+type routeKey struct{}
+
+// withRouteRecorder 返回在上下文中带有路由记录位置的请求, 外层中间件已放入的位置会被复用
+func withRouteRecorder(r *http.Request) (*http.Request, *string) {
+	if pattern, ok := r.Context().Value(routeKey{}).(*string); ok {
+		return r, pattern
+	}
+	pattern := new(string)
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, pattern)), pattern
+}
+
+// recordRoute 在 ServeMux 匹配后把 Pattern 写入上下文中的位置.
+// 认证等中间件通过 WithContext 复制了请求, 外层的请求看不到 ServeMux 设置的 Pattern
+func recordRoute(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+		if pattern, ok := r.Context().Value(routeKey{}).(*string); ok {
+			*pattern = r.Pattern
+		}
+	})
+}
+
+// routePattern 返回去掉方法前缀的路由
+func routePattern(pattern string) string {
+	if _, route, ok := strings.Cut(pattern, " "); ok {
+		return route
+	}
+	return pattern
+}
+
 // recoveryMiddleware 恢复中间件
 func recoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -155,11 +269,20 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Start initializes and starts the API server. It refuses to start without a
 // token, since an open download proxy is a security risk.
 func Start(ctx context.Context) error {
 	cfg := config.C().API
 
+	// 全局 Webhook 在未启用 API 服务时也会发送
+	registerGlobalWebhooks(ctx)
+	startDeliveryCleanupLoop(ctx)
+
 	if !cfg.Enable {
 		return nil
 	}
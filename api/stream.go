@@ -0,0 +1,96 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/common/tdler"
+	"github.com/krau/SaveAny-Bot/common/utils/streamutil"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+)
+
+// StreamHandler 以 HTTP 流式传输消息中的媒体文件，支持 Range 请求
+// 路径格式: /api/v1/stream/:chat_id/:msg_id[?exp=..&sig=..]
+func (h *Handlers) StreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "only GET and HEAD methods are allowed")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, streamutil.PathPrefix), "/"), "/")
+	if len(parts) != 2 {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "path must be /api/v1/stream/{chat_id}/{msg_id}")
+		return
+	}
+	chatID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "invalid chat ID")
+		return
+	}
+	msgID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "invalid message ID")
+		return
+	}
+
+	// 带签名的链接跳过了 token 认证, 此处校验签名
+	query := r.URL.Query()
+	if sig := query.Get("sig"); sig != "" && !streamutil.Verify(chatID, msgID, query.Get("exp"), sig) {
+		WriteError(w, http.StatusForbidden, "forbidden", "invalid or expired signature")
+		return
+	}
+
+	msgCtx, err := getMessageWithContext(r.Context(), chatID, msgID)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "message_not_found", "failed to get message: "+err.Error())
+		return
+	}
+	msg := msgCtx.Message
+	media, ok := msg.GetMedia()
+	if !ok {
+		WriteError(w, http.StatusNotFound, "media_not_found", "message has no media")
+		return
+	}
+	file, err := tfile.FromMediaMessage(media, msgCtx.Client.Raw, msg)
+	if err != nil {
+		WriteError(w, http.StatusUnprocessableEntity, "unsupported_media", err.Error())
+		return
+	}
+	name := file.Name()
+	if name == "" {
+		name = tgutil.GenFileNameFromMessage(*msg)
+	}
+
+	// 流式响应可能远超服务器的 WriteTimeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.FromContext(r.Context()).Debugf("Failed to clear write deadline: %v", err)
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": name}))
+
+	if file.Size() > 0 {
+		modTime := time.Unix(int64(msg.GetDate()), 0)
+		http.ServeContent(w, r, name, modTime, tdler.NewReader(r.Context(), file))
+		return
+	}
+
+	// 图片等大小未知的文件不支持 Range, 直接整体传输
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err := tdler.Stream(r.Context(), file, w); err != nil {
+		log.FromContext(r.Context()).Errorf("Failed to stream file %s: %v", name, err)
+	}
+}
@@ -21,11 +21,36 @@ const (
 
 // CreateTaskRequest 创建任务请求
 type CreateTaskRequest struct {
-	Type    tasktype.TaskType `json:"type"`
-	Storage string            `json:"storage"`
-	Path    string            `json:"path"`
-	Webhook string            `json:"webhook,omitempty"`
-	Params  json.RawMessage   `json:"params"`
+	Type           tasktype.TaskType `json:"type"`
+	Storage        string            `json:"storage"`
+	Path           string            `json:"path"`
+	Webhook        string            `json:"webhook,omitempty"`
+	WebhookSecret  string            `json:"webhook_secret,omitempty"`  // 用于签名 Webhook 请求
+	WebhookEvents  []string          `json:"webhook_events,omitempty"`  // 订阅的事件, 为空时为 completed, failed, cancelled
+	IdempotencyKey string            `json:"idempotency_key,omitempty"` // 同 Idempotency-Key 请求头, 重复提交时返回原来的任务
+	Params         json.RawMessage   `json:"params"`
+}
+
+// BatchCreateTaskRequest 批量创建任务请求
+type BatchCreateTaskRequest struct {
+	Tasks []CreateTaskRequest `json:"tasks"`
+}
+
+// BatchTaskResult 批量创建中单个任务的结果
+type BatchTaskResult struct {
+	Index int `json:"index"`
+	// Status 为单独创建该任务时的 HTTP 状态码
+	Status   int                 `json:"status"`
+	Task     *CreateTaskResponse `json:"task,omitempty"`
+	Replayed bool                `json:"replayed,omitempty"` // 幂等键已使用过, task 为原来的任务
+	Error    *ErrorResponse      `json:"error,omitempty"`
+}
+
+// BatchCreateTaskResponse 批量创建任务响应, 结果与请求的顺序一致
+type BatchCreateTaskResponse struct {
+	Results   []BatchTaskResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
 }
 
 // CreateTaskResponse 创建任务响应
@@ -44,6 +69,8 @@ type TaskProgress struct {
 	DownloadedFiles int     `json:"downloaded_files,omitempty"`
 	Percent         float64 `json:"percent,omitempty"`
 	SpeedMBPS       float64 `json:"speed_mbps,omitempty"`
+	// ETASeconds 为按平均速度估算的剩余时间, 无法估算时省略
+	ETASeconds float64 `json:"eta_seconds,omitempty"`
 }
 
 // TaskInfoResponse 任务信息响应
@@ -60,6 +87,26 @@ type TaskInfoResponse struct {
 	UpdatedAt time.Time         `json:"updated_at"`
 }
 
+// 任务事件的类型
+const (
+	TaskEventStatus   = "status" // 连接时的当前状态
+	TaskEventStart    = "start"
+	TaskEventProgress = "progress"
+	TaskEventFile     = "file" // 完成了一个文件
+	TaskEventDone     = "done"
+	TaskEventError    = "error"
+)
+
+// TaskEvent 任务事件, 通过 SSE 和 WebSocket 推送
+type TaskEvent struct {
+	Event    string        `json:"event"`
+	TaskID   string        `json:"task_id"`
+	Status   TaskStatus    `json:"status"`
+	Progress *TaskProgress `json:"progress,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Time     time.Time     `json:"time"`
+}
+
 // TasksListResponse 任务列表响应
 type TasksListResponse struct {
 	Tasks []TaskInfoResponse `json:"tasks"`
@@ -77,15 +124,213 @@ type StorageInfo struct {
 	Type string `json:"type"`
 }
 
+// FileEntry 存储中的文件或目录
+type FileEntry struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	IsDir   bool      `json:"is_dir"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// FilesResponse 目录内容响应, 目录在前, 按名称排序
+type FilesResponse struct {
+	Storage string      `json:"storage"`
+	Path    string      `json:"path"`
+	Files   []FileEntry `json:"files"`
+	Total   int         `json:"total"`
+}
+
+// MoveFileRequest 移动或重命名文件的请求
+type MoveFileRequest struct {
+	Path        string `json:"path"`
+	Destination string `json:"destination"`
+}
+
+// UserResponse 用户的设置, 文件夹, 规则和监听的聊天
+type UserResponse struct {
+	ChatID           int64           `json:"chat_id"`
+	Silent           bool            `json:"silent"`
+	DefaultStorage   string          `json:"default_storage"`
+	DefaultDir       uint            `json:"default_dir"` // 默认文件夹的 ID, 0 表示存储的根目录
+	ApplyRule        bool            `json:"apply_rule"`
+	FilenameStrategy string          `json:"filename_strategy"`
+	FilenameTemplate string          `json:"filename_template"`
+	ConflictStrategy string          `json:"conflict_strategy"`
+	Dirs             []DirResponse   `json:"dirs"`
+	Rules            []RuleResponse  `json:"rules"`
+	Watches          []WatchResponse `json:"watches"`
+}
+
+// UsersResponse 用户列表响应
+type UsersResponse struct {
+	Users []UserResponse `json:"users"`
+	Total int            `json:"total"`
+}
+
+// UpdateUserRequest 更新用户设置的请求, 只修改提供的字段, 空字符串表示恢复默认
+type UpdateUserRequest struct {
+	Silent           *bool   `json:"silent,omitempty"`
+	DefaultStorage   *string `json:"default_storage,omitempty"`
+	DefaultDir       *uint   `json:"default_dir,omitempty"` // 必须是默认存储中的文件夹
+	ApplyRule        *bool   `json:"apply_rule,omitempty"`
+	FilenameStrategy *string `json:"filename_strategy,omitempty"`
+	FilenameTemplate *string `json:"filename_template,omitempty"`
+	ConflictStrategy *string `json:"conflict_strategy,omitempty"`
+}
+
+// DirRequest 添加文件夹的请求
+type DirRequest struct {
+	Storage string `json:"storage"`
+	Path    string `json:"path"`
+}
+
+// DirResponse 用户的文件夹
+type DirResponse struct {
+	ID      uint   `json:"id"`
+	Storage string `json:"storage"`
+	Path    string `json:"path"`
+}
+
+// DirsResponse 文件夹列表响应
+type DirsResponse struct {
+	Dirs  []DirResponse `json:"dirs"`
+	Total int           `json:"total"`
+}
+
+// RuleRequest 创建或替换规则的请求, 字段与规则文件中的规则一致
+type RuleRequest struct {
+	Type        string `json:"type"`
+	Data        string `json:"data"`
+	Storage     string `json:"storage"`
+	Dir         string `json:"dir"`
+	Priority    int    `json:"priority,omitempty"`
+	Rename      string `json:"rename,omitempty"`
+	Skip        bool   `json:"skip,omitempty"`
+	Conflict    string `json:"conflict,omitempty"`
+	PostProcess string `json:"post,omitempty"`
+	NotifyChat  int64  `json:"notify,omitempty"`
+}
+
+// RuleResponse 用户的规则
+type RuleResponse struct {
+	ID          uint   `json:"id"`
+	Type        string `json:"type"`
+	Data        string `json:"data"`
+	Storage     string `json:"storage"`
+	Dir         string `json:"dir"`
+	Priority    int    `json:"priority"`
+	Rename      string `json:"rename,omitempty"`
+	Skip        bool   `json:"skip,omitempty"`
+	Conflict    string `json:"conflict,omitempty"`
+	PostProcess string `json:"post,omitempty"`
+	NotifyChat  int64  `json:"notify,omitempty"`
+}
+
+// WatchRequest 监听聊天的请求, 除 chat_id 外均可省略, 省略时使用聊天或用户的默认设置
+type WatchRequest struct {
+	ChatID           int64  `json:"chat_id"`
+	Filter           string `json:"filter,omitempty"` // msgre:<正则> 或 expr:<表达式>
+	Storage          string `json:"storage,omitempty"`
+	Dir              string `json:"dir,omitempty"`
+	FilenameStrategy string `json:"filename_strategy,omitempty"`
+}
+
+// UpdateWatchRequest 修改监听设置的请求, 只修改提供的字段, 空字符串表示恢复默认
+type UpdateWatchRequest struct {
+	Filter           *string `json:"filter,omitempty"`
+	Storage          *string `json:"storage,omitempty"`
+	Dir              *string `json:"dir,omitempty"`
+	FilenameStrategy *string `json:"filename_strategy,omitempty"`
+}
+
+// WatchResponse 监听的聊天
+type WatchResponse struct {
+	ChatID           int64  `json:"chat_id"`
+	Filter           string `json:"filter,omitempty"`
+	Storage          string `json:"storage,omitempty"`
+	Dir              string `json:"dir,omitempty"`
+	FilenameStrategy string `json:"filename_strategy,omitempty"`
+	// 进行中的历史回填, 下一条和最后一条消息的 ID
+	BackfillNext  int `json:"backfill_next,omitempty"`
+	BackfillUntil int `json:"backfill_until,omitempty"`
+}
+
+// WatchesResponse 监听列表响应
+type WatchesResponse struct {
+	Watches []WatchResponse `json:"watches"`
+	Total   int             `json:"total"`
+}
+
 // WebhookPayload Webhook 回调负载
 type WebhookPayload struct {
-	TaskID      string     `json:"task_id"`
-	Type        string     `json:"type"`
-	Status      TaskStatus `json:"status"`
-	Storage     string     `json:"storage"`
-	Path        string     `json:"path"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
-	Error       string     `json:"error,omitempty"`
+	Event       string        `json:"event"`
+	TaskID      string        `json:"task_id"`
+	Type        string        `json:"type"`
+	Title       string        `json:"title,omitempty"`
+	Status      TaskStatus    `json:"status"`
+	Storage     string        `json:"storage"`
+	Path        string        `json:"path"`
+	Progress    *TaskProgress `json:"progress,omitempty"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Timestamp   time.Time     `json:"timestamp"`
+}
+
+// WebhookDeliveryResponse Webhook 投递记录
+type WebhookDeliveryResponse struct {
+	ID           uint            `json:"id"`
+	TaskID       string          `json:"task_id"`
+	Event        string          `json:"event"`
+	URL          string          `json:"url"`
+	Signed       bool            `json:"signed"`
+	StatusCode   int             `json:"status_code"`
+	Error        string          `json:"error,omitempty"`
+	Attempts     int             `json:"attempts"`
+	Success      bool            `json:"success"`
+	RedeliveryOf uint            `json:"redelivery_of,omitempty"`
+	Payload      json.RawMessage `json:"payload"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// WebhookDeliveriesResponse Webhook 投递记录列表响应
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	Total      int                       `json:"total"`
+}
+
+// AuditLogResponse 一条审计记录
+type AuditLogResponse struct {
+	ID      uint            `json:"id"`
+	Time    time.Time       `json:"time"`
+	ActorID int64           `json:"actor_id"` // telegram user id, 配置中的 token 和系统操作为 0
+	Source  string          `json:"source"`   // bot, api 或 system
+	KeyID   uint            `json:"key_id,omitempty"`
+	Action  string          `json:"action"`
+	Target  string          `json:"target,omitempty"`
+	Details json.RawMessage `json:"details,omitempty"`
+}
+
+// AuditLogsResponse 审计记录列表响应
+type AuditLogsResponse struct {
+	Logs  []AuditLogResponse `json:"logs"`
+	Total int                `json:"total"`
+}
+
+// TaskTypesResponse 任务类型列表响应
+type TaskTypesResponse struct {
+	Types []tasktype.TaskType `json:"types"`
+}
+
+// MessageResponse 只包含提示信息的响应
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// HealthResponse 健康检查响应
+type HealthResponse struct {
+	Status string `json:"status"`
 }
 
 // ErrorResponse 错误响应
@@ -122,6 +367,20 @@ func WriteError(w http.ResponseWriter, statusCode int, errCode, message string)
 
 // Task 参数结构体
 
+// taskParamTypes 为 API 支持的任务类型及其 params 结构, 用于任务类型列表和 OpenAPI 文档
+var taskParamTypes = []struct {
+	Type   tasktype.TaskType
+	Params any
+}{
+	{tasktype.TaskTypeDirectlinks, DirectLinksParams{}},
+	{tasktype.TaskTypeYtdlp, YTDLPParams{}},
+	{tasktype.TaskTypeAria2, Aria2Params{}},
+	{tasktype.TaskTypeParseditem, ParsedParams{}},
+	{tasktype.TaskTypeTgfiles, TGFilesParams{}},
+	{tasktype.TaskTypeTphpics, TPHPicsParams{}},
+	{tasktype.TaskTypeTransfer, TransferParams{}},
+}
+
 // DirectLinksParams directlinks 任务参数
 type DirectLinksParams struct {
 	URLs []string `json:"urls"`
@@ -161,3 +420,12 @@ type TGFilesParams struct {
 type TPHPicsParams struct {
 	TelegraphURL string `json:"telegraph_url"`
 }
+
+// SendFileRequest 将存储中的文件发送到 Telegram 聊天的请求
+type SendFileRequest struct {
+	Path          string   `json:"path"`
+	ChatID        int64    `json:"chat_id"`
+	Webhook       string   `json:"webhook,omitempty"`
+	WebhookSecret string   `json:"webhook_secret,omitempty"`
+	WebhookEvents []string `json:"webhook_events,omitempty"`
+}
@@ -0,0 +1,588 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/watchutil"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
+	"github.com/krau/SaveAny-Bot/pkg/enums/fnamest"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/krau/SaveAny-Bot/pkg/ruleset"
+	"github.com/krau/SaveAny-Bot/pkg/tcbdata"
+	"github.com/krau/SaveAny-Bot/storage"
+	"gorm.io/gorm"
+)
+
+func userResponse(user *database.User) UserResponse {
+	resp := UserResponse{
+		ChatID:           user.ChatID,
+		Silent:           user.Silent,
+		DefaultStorage:   user.DefaultStorage,
+		DefaultDir:       user.DefaultDir,
+		ApplyRule:        user.ApplyRule,
+		FilenameStrategy: user.FilenameStrategy,
+		FilenameTemplate: user.FilenameTemplate,
+		ConflictStrategy: user.ConflictStrategy,
+		Dirs:             make([]DirResponse, 0, len(user.Dirs)),
+		Rules:            make([]RuleResponse, 0, len(user.Rules)),
+		Watches:          make([]WatchResponse, 0, len(user.WatchChats)),
+	}
+	for _, d := range user.Dirs {
+		resp.Dirs = append(resp.Dirs, dirResponse(&d))
+	}
+	for _, r := range user.Rules {
+		resp.Rules = append(resp.Rules, ruleResponse(&r))
+	}
+	for _, c := range user.WatchChats {
+		resp.Watches = append(resp.Watches, watchResponse(&c))
+	}
+	return resp
+}
+
+func dirResponse(d *database.Dir) DirResponse {
+	return DirResponse{ID: d.ID, Storage: d.StorageName, Path: d.Path}
+}
+
+func ruleResponse(r *database.Rule) RuleResponse {
+	return RuleResponse{
+		ID:          r.ID,
+		Type:        r.Type,
+		Data:        r.Data,
+		Storage:     r.StorageName,
+		Dir:         r.DirPath,
+		Priority:    r.Priority,
+		Rename:      r.Rename,
+		Skip:        r.Skip,
+		Conflict:    r.Conflict,
+		PostProcess: r.PostProcess,
+		NotifyChat:  r.NotifyChatID,
+	}
+}
+
+func watchResponse(c *database.WatchChat) WatchResponse {
+	return WatchResponse{
+		ChatID:           c.ChatID,
+		Filter:           c.Filter,
+		Storage:          c.StorageName,
+		Dir:              c.DirPath,
+		FilenameStrategy: c.FilenameStrategy,
+		BackfillNext:     c.BackfillNext,
+		BackfillUntil:    c.BackfillUntil,
+	}
+}
+
+// checkUserStorage 检查用户和调用者能否使用存储, 与 bot 中选择存储时的校验一致
+func checkUserStorage(r *http.Request, user *database.User, name string) error {
+	if !principalFrom(r).CanUseStorage(name) {
+		return fmt.Errorf("no storage %s for user %d", name, user.ChatID)
+	}
+	_, err := storage.GetStorageByUserIDAndName(r.Context(), user.ChatID, name)
+	return err
+}
+
+// idFromPath 解析路径中的数据库 ID
+func idFromPath(w http.ResponseWriter, r *http.Request, name string) (uint, bool) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 64)
+	if err != nil || id == 0 {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "invalid "+name+": "+r.PathValue(name))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// ListUsersHandler 列出所有用户, 需要 admin 权限
+func (h *Handlers) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	users, err := database.GetAllUsers(r.Context())
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	resp := UsersResponse{Users: make([]UserResponse, 0, len(users)), Total: len(users)}
+	for _, u := range users {
+		resp.Users = append(resp.Users, userResponse(&u))
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetUserHandler 返回用户的设置, 文件夹, 规则和监听的聊天
+func (h *Handlers) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	WriteJSON(w, http.StatusOK, userResponse(user))
+}
+
+// UpdateUserHandler 修改用户的设置, 只修改请求中提供的字段
+func (h *Handlers) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	var req UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "failed to decode request body: "+err.Error())
+		return
+	}
+	if err := applyUserSettings(r, user, &req); err != nil {
+		WriteError(w, http.StatusUnprocessableEntity, "invalid_settings", err.Error())
+		return
+	}
+	if err := database.UpdateUserSettings(r.Context(), user); err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	log.FromContext(r.Context()).Infof("Updated settings of user %d via API", user.ChatID)
+	target := strconv.FormatInt(user.ChatID, 10)
+	audit.Record(r.Context(), audit.ActionSettingsUpdate, target, map[string]any{"settings": &req})
+	if req.DefaultStorage != nil {
+		audit.Record(r.Context(), audit.ActionStorageDefault, user.DefaultStorage, map[string]any{"user": user.ChatID})
+	}
+	WriteJSON(w, http.StatusOK, userResponse(user))
+}
+
+// applyUserSettings 校验请求中的设置并写入 user, 校验与 bot 中的 /storage, /config 和 /silent 一致
+func applyUserSettings(r *http.Request, user *database.User, req *UpdateUserRequest) error {
+	if req.DefaultStorage != nil && *req.DefaultStorage != user.DefaultStorage {
+		if name := *req.DefaultStorage; name != "" {
+			if err := checkUserStorage(r, user, name); err != nil {
+				return fmt.Errorf("default_storage: %w", err)
+			}
+		}
+		user.DefaultStorage = *req.DefaultStorage
+		// 默认文件夹属于原来的存储
+		user.DefaultDir = 0
+	}
+	if req.DefaultDir != nil {
+		if id := *req.DefaultDir; id != 0 {
+			i := slices.IndexFunc(user.Dirs, func(d database.Dir) bool { return d.ID == id })
+			if i < 0 {
+				return fmt.Errorf("default_dir: dir %d not found", id)
+			}
+			if user.Dirs[i].StorageName != user.DefaultStorage {
+				return fmt.Errorf("default_dir: dir %d is not in the default storage %q", id, user.DefaultStorage)
+			}
+		}
+		user.DefaultDir = *req.DefaultDir
+	}
+	if req.ApplyRule != nil {
+		user.ApplyRule = *req.ApplyRule
+	}
+	if req.FilenameStrategy != nil {
+		value := *req.FilenameStrategy
+		if value != "" {
+			st, err := fnamest.ParseFnameST(value)
+			if err != nil {
+				return fmt.Errorf("filename_strategy: %w", err)
+			}
+			value = st.String()
+		}
+		user.FilenameStrategy = value
+	}
+	if req.FilenameTemplate != nil {
+		if tmpl := *req.FilenameTemplate; tmpl != "" {
+			if _, err := fnametmpl.Parse(tmpl); err != nil {
+				return fmt.Errorf("filename_template: %w", err)
+			}
+		}
+		user.FilenameTemplate = *req.FilenameTemplate
+	}
+	if req.ConflictStrategy != nil {
+		if st := *req.ConflictStrategy; st != "" && !tcbdata.IsConflictStrategy(st) {
+			return fmt.Errorf("conflict_strategy: invalid conflict strategy %q, available: %s", st, strings.Join(tcbdata.ConflictStrategyValues(), ", "))
+		}
+		user.ConflictStrategy = *req.ConflictStrategy
+	}
+	if req.Silent != nil {
+		user.Silent = *req.Silent
+	}
+	// 与 /silent 一致, 静默模式需要默认存储或聊天的默认设置
+	if (req.Silent != nil || req.DefaultStorage != nil) && user.Silent && user.DefaultStorage == "" && len(user.ChatDefaults) == 0 {
+		return errors.New("silent: default storage is not set")
+	}
+	return nil
+}
+
+// ListUserDirsHandler 列出用户的文件夹
+func (h *Handlers) ListUserDirsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	resp := DirsResponse{Dirs: make([]DirResponse, 0, len(user.Dirs)), Total: len(user.Dirs)}
+	for _, d := range user.Dirs {
+		resp.Dirs = append(resp.Dirs, dirResponse(&d))
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// CreateUserDirHandler 为用户添加文件夹, 同 /dir add
+func (h *Handlers) CreateUserDirHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	var req DirRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "failed to decode request body: "+err.Error())
+		return
+	}
+	if req.Storage == "" || req.Path == "" {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "storage and path are required")
+		return
+	}
+	if err := checkUserStorage(r, user, req.Storage); err != nil {
+		WriteError(w, http.StatusUnprocessableEntity, "invalid_dir", err.Error())
+		return
+	}
+	dir := &database.Dir{UserID: user.ID, StorageName: req.Storage, Path: req.Path}
+	if err := database.CreateDir(r.Context(), dir); err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	audit.Record(r.Context(), audit.ActionDirCreate, dir.StorageName+":"+dir.Path, map[string]any{"user": user.ChatID, "id": dir.ID})
+	WriteJSON(w, http.StatusCreated, dirResponse(dir))
+}
+
+// DeleteUserDirHandler 删除用户的文件夹, 该文件夹为默认文件夹时一并清除
+func (h *Handlers) DeleteUserDirHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	id, ok := idFromPath(w, r, "dir_id")
+	if !ok {
+		return
+	}
+	err := database.DeleteUserDir(r.Context(), user.ID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		WriteError(w, http.StatusNotFound, "dir_not_found", "dir not found: "+r.PathValue("dir_id"))
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	audit.Record(r.Context(), audit.ActionDirDelete, r.PathValue("dir_id"), map[string]any{"user": user.ChatID})
+	WriteJSON(w, http.StatusOK, MessageResponse{Message: "dir deleted"})
+}
+
+// ruleFromRequest 校验请求中的规则, 校验与 /rule add 和导入规则文件一致
+func ruleFromRequest(r *http.Request, user *database.User, req *RuleRequest) (*database.Rule, error) {
+	rr := ruleset.Rule{
+		Type:        req.Type,
+		Data:        req.Data,
+		Storage:     req.Storage,
+		Dir:         req.Dir,
+		Priority:    req.Priority,
+		Rename:      req.Rename,
+		Skip:        req.Skip,
+		Conflict:    req.Conflict,
+		PostProcess: req.PostProcess,
+		NotifyChat:  req.NotifyChat,
+	}
+	hasStorage := func(name string) bool {
+		return config.C().HasStorage(user.ChatID, name) && principalFrom(r).CanUseStorage(name)
+	}
+	checkActions := func(actions rule.Actions) error {
+		return ruleutil.ValidateActions(user.ChatID, actions)
+	}
+	if err := rr.Validate(hasStorage, checkActions); err != nil {
+		return nil, err
+	}
+	model := ruleutil.RuleModel(rr, user.ID)
+	return &model, nil
+}
+
+// ruleFromPath 返回路径中的规则, 规则不属于该用户时视为不存在
+func ruleFromPath(w http.ResponseWriter, r *http.Request, user *database.User) (*database.Rule, bool) {
+	id, ok := idFromPath(w, r, "rule_id")
+	if !ok {
+		return nil, false
+	}
+	i := slices.IndexFunc(user.Rules, func(ru database.Rule) bool { return ru.ID == id })
+	if i < 0 {
+		WriteError(w, http.StatusNotFound, "rule_not_found", "rule not found: "+r.PathValue("rule_id"))
+		return nil, false
+	}
+	return &user.Rules[i], true
+}
+
+// CreateUserRuleHandler 为用户添加一条规则, 同 /rule add
+func (h *Handlers) CreateUserRuleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	var req RuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "failed to decode request body: "+err.Error())
+		return
+	}
+	ru, err := ruleFromRequest(r, user, &req)
+	if err != nil {
+		WriteError(w, http.StatusUnprocessableEntity, "invalid_rule", err.Error())
+		return
+	}
+	if err := database.CreateRule(r.Context(), ru); err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	audit.Record(r.Context(), audit.ActionRuleCreate, strconv.FormatUint(uint64(ru.ID), 10), ruleAuditDetails(user, ru))
+	WriteJSON(w, http.StatusCreated, ruleResponse(ru))
+}
+
+// GetUserRuleHandler 返回用户的一条规则
+func (h *Handlers) GetUserRuleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	ru, ok := ruleFromPath(w, r, user)
+	if !ok {
+		return
+	}
+	WriteJSON(w, http.StatusOK, ruleResponse(ru))
+}
+
+// UpdateUserRuleHandler 替换用户的一条规则
+func (h *Handlers) UpdateUserRuleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	orig, ok := ruleFromPath(w, r, user)
+	if !ok {
+		return
+	}
+	var req RuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "failed to decode request body: "+err.Error())
+		return
+	}
+	ru, err := ruleFromRequest(r, user, &req)
+	if err != nil {
+		WriteError(w, http.StatusUnprocessableEntity, "invalid_rule", err.Error())
+		return
+	}
+	ru.Model = orig.Model
+	err = database.UpdateRule(r.Context(), ru)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		WriteError(w, http.StatusNotFound, "rule_not_found", "rule not found: "+r.PathValue("rule_id"))
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	audit.Record(r.Context(), audit.ActionRuleUpdate, r.PathValue("rule_id"), ruleAuditDetails(user, ru))
+	WriteJSON(w, http.StatusOK, ruleResponse(ru))
+}
+
+// ruleAuditDetails 返回审计日志中记录的规则内容, 与 bot 中的 /rule add 一致
+func ruleAuditDetails(user *database.User, ru *database.Rule) map[string]any {
+	return map[string]any{
+		"user":     user.ChatID,
+		"type":     ru.Type,
+		"data":     ru.Data,
+		"storage":  ru.StorageName,
+		"dir":      ru.DirPath,
+		"priority": ru.Priority,
+	}
+}
+
+// DeleteUserRuleHandler 删除用户的一条规则, 同 /rule del
+func (h *Handlers) DeleteUserRuleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	id, ok := idFromPath(w, r, "rule_id")
+	if !ok {
+		return
+	}
+	err := database.DeleteUserRule(r.Context(), user.ID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		WriteError(w, http.StatusNotFound, "rule_not_found", "rule not found: "+r.PathValue("rule_id"))
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	audit.Record(r.Context(), audit.ActionRuleDelete, r.PathValue("rule_id"), map[string]any{"user": user.ChatID})
+	WriteJSON(w, http.StatusOK, MessageResponse{Message: "rule deleted"})
+}
+
+// applyWatchSettings 校验请求中的监听设置并写入 chat, 校验与 /watch edit 一致
+func applyWatchSettings(r *http.Request, user *database.User, chat *database.WatchChat, req *UpdateWatchRequest) error {
+	if req.Filter != nil {
+		filter := *req.Filter
+		if filter != "" {
+			var err error
+			if filter, err = watchutil.ParseFilter(filter); err != nil {
+				return fmt.Errorf("filter: %w", err)
+			}
+		}
+		chat.Filter = filter
+	}
+	if req.Storage != nil {
+		if name := *req.Storage; name != "" {
+			if err := checkUserStorage(r, user, name); err != nil {
+				return fmt.Errorf("storage: %w", err)
+			}
+		}
+		chat.StorageName = *req.Storage
+	}
+	if req.Dir != nil {
+		if err := watchutil.ValidateDir(*req.Dir); err != nil {
+			return fmt.Errorf("dir: %w", err)
+		}
+		chat.DirPath = *req.Dir
+	}
+	if req.FilenameStrategy != nil {
+		st, err := watchutil.ParseFilenameStrategy(*req.FilenameStrategy)
+		if err != nil {
+			return fmt.Errorf("filename_strategy: %w", err)
+		}
+		chat.FilenameStrategy = st
+	}
+	return nil
+}
+
+// watchFromPath 返回路径中用户监听的聊天
+func watchFromPath(w http.ResponseWriter, r *http.Request, user *database.User) (*database.WatchChat, bool) {
+	chatID, err := strconv.ParseInt(r.PathValue("chat_id"), 10, 64)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "invalid chat_id: "+r.PathValue("chat_id"))
+		return nil, false
+	}
+	i := slices.IndexFunc(user.WatchChats, func(c database.WatchChat) bool { return c.ChatID == chatID })
+	if i < 0 {
+		WriteError(w, http.StatusNotFound, "watch_not_found", "not watching chat: "+r.PathValue("chat_id"))
+		return nil, false
+	}
+	return &user.WatchChats[i], true
+}
+
+// ListUserWatchesHandler 列出用户监听的聊天
+func (h *Handlers) ListUserWatchesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	resp := WatchesResponse{Watches: make([]WatchResponse, 0, len(user.WatchChats)), Total: len(user.WatchChats)}
+	for _, c := range user.WatchChats {
+		resp.Watches = append(resp.Watches, watchResponse(&c))
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// CreateUserWatchHandler 监听一个聊天, 同 /watch. 历史回填需要 userbot, 只能在 bot 中发起
+func (h *Handlers) CreateUserWatchHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	var req WatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "failed to decode request body: "+err.Error())
+		return
+	}
+	if req.ChatID == 0 {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "chat_id is required")
+		return
+	}
+	if slices.ContainsFunc(user.WatchChats, func(c database.WatchChat) bool { return c.ChatID == req.ChatID }) {
+		WriteError(w, http.StatusConflict, "already_watching", "already watching chat: "+strconv.FormatInt(req.ChatID, 10))
+		return
+	}
+	chat := database.WatchChat{UserID: user.ID, ChatID: req.ChatID}
+	if err := applyWatchSettings(r, user, &chat, &UpdateWatchRequest{
+		Filter:           &req.Filter,
+		Storage:          &req.Storage,
+		Dir:              &req.Dir,
+		FilenameStrategy: &req.FilenameStrategy,
+	}); err != nil {
+		WriteError(w, http.StatusUnprocessableEntity, "invalid_watch", err.Error())
+		return
+	}
+	// 与 /watch 一致, 没有指定存储时需要默认存储或聊天的默认设置
+	if chat.StorageName == "" && user.DefaultStorage == "" && user.ChatDefaultFor(rule.NormalizeChatID(chat.ChatID)) == nil {
+		WriteError(w, http.StatusUnprocessableEntity, "invalid_watch", "storage: default storage is not set")
+		return
+	}
+	if err := user.WatchChat(r.Context(), chat); err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	audit.Record(r.Context(), audit.ActionWatchCreate, strconv.FormatInt(chat.ChatID, 10), watchAuditDetails(user, &chat))
+	WriteJSON(w, http.StatusCreated, watchResponse(&chat))
+}
+
+// UpdateUserWatchHandler 修改监听的设置, 只修改请求中提供的字段, 同 /watch edit
+func (h *Handlers) UpdateUserWatchHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	chat, ok := watchFromPath(w, r, user)
+	if !ok {
+		return
+	}
+	var req UpdateWatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "failed to decode request body: "+err.Error())
+		return
+	}
+	if err := applyWatchSettings(r, user, chat, &req); err != nil {
+		WriteError(w, http.StatusUnprocessableEntity, "invalid_watch", err.Error())
+		return
+	}
+	if err := database.UpdateWatchChatSettings(r.Context(), chat); err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	audit.Record(r.Context(), audit.ActionWatchUpdate, r.PathValue("chat_id"), watchAuditDetails(user, chat))
+	WriteJSON(w, http.StatusOK, watchResponse(chat))
+}
+
+// watchAuditDetails 返回审计日志中记录的监听设置
+func watchAuditDetails(user *database.User, chat *database.WatchChat) map[string]any {
+	return map[string]any{
+		"user":              user.ChatID,
+		"filter":            chat.Filter,
+		"storage":           chat.StorageName,
+		"dir":               chat.DirPath,
+		"filename_strategy": chat.FilenameStrategy,
+	}
+}
+
+// DeleteUserWatchHandler 停止监听一个聊天, 同 /unwatch
+func (h *Handlers) DeleteUserWatchHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromPath(w, r)
+	if !ok {
+		return
+	}
+	chat, ok := watchFromPath(w, r, user)
+	if !ok {
+		return
+	}
+	if err := user.UnwatchChat(r.Context(), chat.ChatID); err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	audit.Record(r.Context(), audit.ActionWatchDelete, r.PathValue("chat_id"), map[string]any{"user": user.ChatID})
+	WriteJSON(w, http.StatusOK, MessageResponse{Message: "chat unwatched"})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/pkg/davfs"
+	"github.com/krau/SaveAny-Bot/storage"
+	"golang.org/x/net/webdav"
+)
+
+// webdavPrefix 返回 WebDAV 的挂载路径, 未启用时返回空字符串
+func webdavPrefix() string {
+	cfg := config.C().API.WebDAV
+	if !cfg.Enable {
+		return ""
+	}
+	prefix := "/" + strings.Trim(cfg.Prefix, "/")
+	if prefix == "/" {
+		prefix = "/dav"
+	}
+	return prefix
+}
+
+// isWebDAVPath 判断请求是否属于 WebDAV 服务, 这些请求使用 Basic 认证
+func isWebDAVPath(path string) bool {
+	prefix := webdavPrefix()
+	return prefix != "" && (path == prefix || strings.HasPrefix(path, prefix+"/"))
+}
+
+// NewWebDAVHandler 创建只读 WebDAV 处理器, 每个用户只能看到自己有权限的存储
+func NewWebDAVHandler(prefix string) http.Handler {
+	locks := webdav.NewMemLS()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := webdavUser(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="SaveAny-Bot"`)
+			WriteError(w, http.StatusUnauthorized, "unauthorized", "invalid username or password")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodOptions, http.MethodGet, http.MethodHead, "PROPFIND", "LOCK", "UNLOCK":
+		default:
+			WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "webdav is read-only")
+			return
+		}
+
+		// 大文件下载可能远超服务器的 WriteTimeout
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			log.FromContext(r.Context()).Debugf("Failed to clear write deadline: %v", err)
+		}
+
+		handler := &webdav.Handler{
+			Prefix:     prefix,
+			FileSystem: davfs.New(webdavStorages(userID)),
+			LockSystem: locks,
+			Logger: func(r *http.Request, err error) {
+				if err != nil {
+					log.Debugf("WebDAV %s %s: %v", r.Method, r.URL.Path, err)
+				}
+			},
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// webdavUser 校验 Basic 认证, 用户名为 telegram user id, 密码为该用户的 webdav_password
+func webdavUser(r *http.Request) (int64, bool) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return 0, false
+	}
+	userID, err := strconv.ParseInt(username, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	expected := config.C().GetWebDAVPassword(userID)
+	if expected == "" {
+		return 0, false
+	}
+	return userID, subtle.ConstantTimeCompare([]byte(password), []byte(expected)) == 1
+}
+
+// webdavStorages 返回用户有权限且支持列举和读取的存储
+func webdavStorages(userID int64) map[string]davfs.Storage {
+	stors := make(map[string]davfs.Storage)
+	for name, stor := range storage.AllStorages() {
+		if !config.C().HasStorage(userID, name) {
+			continue
+		}
+		// StorageListable 与 StorageReadable 均实现的存储才能挂载
+		if dav, ok := stor.(davfs.Storage); ok {
+			stors[name] = dav
+		}
+	}
+	return stors
+}
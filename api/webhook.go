@@ -3,112 +3,547 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/core"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/apikey"
+	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+	"gorm.io/gorm"
 )
 
+// Webhook 事件
+const (
+	WebhookEventStarted   = "started"
+	WebhookEventProgress  = "progress" // 进度达到 25%, 50%, 75%
+	WebhookEventFileSaved = "file_saved"
+	WebhookEventCompleted = "completed"
+	WebhookEventFailed    = "failed"
+	WebhookEventCancelled = "cancelled"
+	// webhookEventAll 订阅全部事件
+	webhookEventAll = "*"
+)
+
+// Webhook 请求头
+const (
+	WebhookHeaderEvent     = "X-SaveAny-Event"
+	WebhookHeaderDelivery  = "X-SaveAny-Delivery"
+	WebhookHeaderTimestamp = "X-SaveAny-Timestamp"
+	// WebhookHeaderSignature 为 sha256=<hex>, 对 "<timestamp>.<body>" 计算 HMAC-SHA256
+	WebhookHeaderSignature = "X-SaveAny-Signature"
+)
+
+var (
+	webhookEvents = []string{
+		WebhookEventStarted, WebhookEventProgress, WebhookEventFileSaved,
+		WebhookEventCompleted, WebhookEventFailed, WebhookEventCancelled,
+	}
+	defaultWebhookEvents = []string{WebhookEventCompleted, WebhookEventFailed, WebhookEventCancelled}
+	webhookMilestones    = []int{25, 50, 75}
+)
+
+// 投递记录的保留时间
+const webhookDeliveryRetention = 7 * 24 * time.Hour
+
 // webhookClient Webhook 客户端
 var webhookClient = &http.Client{
 	Timeout: 30 * time.Second,
 }
 
-// SendWebhook 发送 Webhook 回调
-func SendWebhook(ctx context.Context, payload *WebhookPayload) {
-	if payload == nil || payload.TaskID == "" {
-		return
+// validateWebhookEvents 检查订阅的事件名
+func validateWebhookEvents(events []string) error {
+	for _, e := range events {
+		if e != webhookEventAll && !slices.Contains(webhookEvents, e) {
+			return fmt.Errorf("unknown webhook event %q, available: %v", e, webhookEvents)
+		}
 	}
+	return nil
+}
 
-	// 获取任务信息以获取 webhook URL
-	info, ok := GetTask(payload.TaskID)
-	if !ok || info.Webhook == "" {
-		return
+// webhookTarget 是一个接收事件的 Webhook
+type webhookTarget struct {
+	URL    string
+	Secret string
+	Events []string // 为空时使用 defaultWebhookEvents
+	Global bool     // 配置中的全局 Webhook
+}
+
+type taskWebhookKey struct {
+	TaskID string
+	URL    string
+}
+
+type taskWebhookSecret struct {
+	Secret   string
+	LastUsed time.Time
+}
+
+// 任务的 Webhook 的密钥只保存在内存中, 供重新投递时签名, 与投递记录一同过期
+var (
+	taskWebhookSecretsMu sync.Mutex
+	taskWebhookSecrets   = make(map[taskWebhookKey]taskWebhookSecret)
+)
+
+func rememberWebhookSecret(taskID, url, secret string) {
+	taskWebhookSecretsMu.Lock()
+	defer taskWebhookSecretsMu.Unlock()
+	taskWebhookSecrets[taskWebhookKey{TaskID: taskID, URL: url}] = taskWebhookSecret{Secret: secret, LastUsed: time.Now()}
+}
+
+// forgetWebhookSecretsBefore 删除最后一次使用早于 t 的密钥
+func forgetWebhookSecretsBefore(t time.Time) {
+	taskWebhookSecretsMu.Lock()
+	defer taskWebhookSecretsMu.Unlock()
+	maps.DeleteFunc(taskWebhookSecrets, func(_ taskWebhookKey, s taskWebhookSecret) bool {
+		return s.LastUsed.Before(t)
+	})
+}
+
+// deliverySecret 返回重新投递时用于签名的密钥. 密钥已不可用 (如全局 Webhook 已从配置中删除, 或重启后的任务 Webhook) 时 ok 为 false
+func deliverySecret(d *database.WebhookDelivery) (secret string, ok bool) {
+	if !d.Signed {
+		return "", true
+	}
+	if d.Global {
+		for _, hook := range config.C().API.Webhooks {
+			if hook.URL == d.URL && hook.Secret != "" {
+				return hook.Secret, true
+			}
+		}
+		return "", false
+	}
+	taskWebhookSecretsMu.Lock()
+	defer taskWebhookSecretsMu.Unlock()
+	key := taskWebhookKey{TaskID: d.TaskID, URL: d.URL}
+	s, ok := taskWebhookSecrets[key]
+	if !ok {
+		return "", false
 	}
+	s.LastUsed = time.Now()
+	taskWebhookSecrets[key] = s
+	return s.Secret, true
+}
 
-	webhookURL := info.Webhook
+func (t webhookTarget) subscribed(event string) bool {
+	events := t.Events
+	if len(events) == 0 {
+		events = defaultWebhookEvents
+	}
+	return slices.Contains(events, event) || slices.Contains(events, webhookEventAll)
+}
 
-	// Async send with retries.
-	go func() {
-		var logger *log.Logger
-		if ctx != nil {
-			logger = log.FromContext(ctx).With("task_id", payload.TaskID)
-		} else {
-			logger = log.Default().With("task_id", payload.TaskID)
+func newWebhookTarget(url, secret string, events []string) *webhookTarget {
+	if url == "" {
+		return nil
+	}
+	return &webhookTarget{URL: url, Secret: secret, Events: events}
+}
+
+func (r *CreateTaskRequest) webhook() *webhookTarget {
+	return newWebhookTarget(r.Webhook, r.WebhookSecret, r.WebhookEvents)
+}
+
+func (r *SendFileRequest) webhook() *webhookTarget {
+	return newWebhookTarget(r.Webhook, r.WebhookSecret, r.WebhookEvents)
+}
+
+// webhookTask 是 Webhook 负载中的任务信息
+type webhookTask struct {
+	TaskID  string
+	Type    string
+	Title   string
+	Storage string
+	Path    string
+	// UserID 为创建任务的用户, 不是通过 API 创建的任务为 0
+	UserID int64
+}
+
+type webhookTaskKey struct{}
+
+// withWebhookTask 在 context 中保存 API 任务的信息, 供全局 Webhook 使用
+func withWebhookTask(ctx context.Context, task webhookTask) context.Context {
+	return context.WithValue(ctx, webhookTaskKey{}, task)
+}
+
+// webhookSink 将一个任务的事件转换为 Webhook 事件并投递
+type webhookSink struct {
+	ctx     context.Context
+	task    webhookTask
+	targets []webhookTarget
+
+	mu              sync.Mutex
+	startedAt       time.Time
+	totalBytes      int64
+	downloadedBytes int64
+	totalFiles      int
+	downloadedFiles int
+	milestone       int
+	done            bool
+}
+
+var _ taskevent.Sink = (*webhookSink)(nil)
+
+func newWebhookSink(ctx context.Context, task webhookTask, targets []webhookTarget) *webhookSink {
+	return &webhookSink{
+		ctx:     context.WithoutCancel(ctx),
+		task:    task,
+		targets: targets,
+	}
+}
+
+// Emit implements taskevent.Sink.
+func (s *webhookSink) Emit(e taskevent.Event) {
+	// 任务中创建的子任务继承 context, 只处理自己的事件
+	if e.TaskID != "" && e.TaskID != s.task.TaskID {
+		return
+	}
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	if e.TotalBytes > 0 {
+		s.totalBytes = e.TotalBytes
+	}
+	if e.TotalFiles > 0 {
+		s.totalFiles = e.TotalFiles
+	}
+	var events []string
+	status := TaskStatusRunning
+	switch e.Phase {
+	case taskevent.PhaseStart:
+		if s.startedAt.IsZero() {
+			s.startedAt = time.Now()
+			events = append(events, WebhookEventStarted)
+		}
+	case taskevent.PhaseProgress:
+		s.downloadedBytes = e.DownloadedBytes
+		if e.DownloadedFiles > s.downloadedFiles {
+			s.downloadedFiles = e.DownloadedFiles
+			events = append(events, WebhookEventFileSaved)
 		}
-		if ctx == nil {
-			ctx = context.Background()
+		if p := buildTaskProgress(s.totalBytes, s.downloadedBytes, s.totalFiles, s.downloadedFiles, s.startedAt); p != nil {
+			// 一次跨过多个里程碑时只发送一次
+			reached := 0
+			for _, m := range webhookMilestones {
+				if p.Percent >= float64(m) {
+					reached = m
+				}
+			}
+			if reached > s.milestone {
+				s.milestone = reached
+				events = append(events, WebhookEventProgress)
+			}
 		}
+	case taskevent.PhaseDone:
+		s.done = true
+		switch {
+		case e.Err == nil:
+			status = TaskStatusCompleted
+			events = append(events, WebhookEventCompleted)
+		case errors.Is(e.Err, context.Canceled):
+			status = TaskStatusCancelled
+			events = append(events, WebhookEventCancelled)
+		default:
+			status = TaskStatusFailed
+			events = append(events, WebhookEventFailed)
+		}
+	}
+	progress := buildTaskProgress(s.totalBytes, s.downloadedBytes, s.totalFiles, s.downloadedFiles, s.startedAt)
+	s.mu.Unlock()
 
-		payloadBytes, err := json.Marshal(payload)
-		if err != nil {
-			logger.Errorf("Failed to marshal webhook payload: %v", err)
-			return
+	for _, event := range events {
+		payload := &WebhookPayload{
+			Event:     event,
+			TaskID:    s.task.TaskID,
+			Type:      s.task.Type,
+			Title:     s.task.Title,
+			Status:    status,
+			Storage:   s.task.Storage,
+			Path:      s.task.Path,
+			Progress:  progress,
+			Timestamp: time.Now(),
+		}
+		if e.Phase == taskevent.PhaseDone {
+			payload.CompletedAt = &payload.Timestamp
+			if e.Err != nil {
+				payload.Error = e.Err.Error()
+			}
+		}
+		for _, target := range s.targets {
+			if target.subscribed(event) {
+				deliverWebhook(s.ctx, target, s.task.UserID, payload)
+			}
 		}
+	}
+}
 
+// deliverWebhook 记录投递并在后台发送, 失败时重试
+func deliverWebhook(ctx context.Context, target webhookTarget, userID int64, payload *WebhookPayload) {
+	logger := log.FromContext(ctx).With("task_id", payload.TaskID, "event", payload.Event)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logger.Errorf("Failed to marshal webhook payload: %v", err)
+		return
+	}
+	d := &database.WebhookDelivery{
+		TaskID:  payload.TaskID,
+		Event:   payload.Event,
+		URL:     target.URL,
+		UserID:  userID,
+		Payload: string(body),
+		Global:  target.Global,
+		Signed:  target.Secret != "",
+	}
+	if d.Signed && !d.Global {
+		rememberWebhookSecret(d.TaskID, d.URL, target.Secret)
+	}
+	if err := database.CreateWebhookDelivery(ctx, d); err != nil {
+		// 无法记录时仍然发送
+		logger.Errorf("Failed to save webhook delivery: %v", err)
+	}
+
+	go func() {
 		// 重试 3 次, 指数退避 (100ms/400ms/1.6s)
 		const maxAttempts = 3
-		const requestTimeout = 30 * time.Second
 		backoff := 100 * time.Millisecond
 		for i := range maxAttempts {
-			reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
-			req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, webhookURL, bytes.NewBuffer(payloadBytes))
-			if err != nil {
-				cancel()
-				logger.Errorf("Failed to create webhook request: %v", err)
-				return
+			sendWebhookRequest(ctx, d, target.Secret)
+			if d.Success {
+				logger.Debugf("Webhook sent successfully: %s", d.URL)
+				break
 			}
+			logger.Warnf("Webhook delivery failed (attempt %d/%d): %s", i+1, maxAttempts, deliveryFailure(d))
+			if i < maxAttempts-1 {
+				time.Sleep(backoff)
+			}
+			backoff *= 4
+		}
+		if d.ID == 0 {
+			return
+		}
+		if err := database.UpdateWebhookDelivery(ctx, d); err != nil {
+			logger.Errorf("Failed to update webhook delivery %d: %v", d.ID, err)
+		}
+	}()
+}
 
-			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("User-Agent", "SaveAny-Bot/1.0")
+// sendWebhookRequest 发送一次请求, 将结果写入 d. secret 为空时不签名
+func sendWebhookRequest(ctx context.Context, d *database.WebhookDelivery, secret string) {
+	d.Attempts++
+	d.StatusCode = 0
+	d.Error = ""
+	d.Success = false
 
-			resp, err := webhookClient.Do(req)
-			cancel()
-			if err != nil {
-				logger.Warnf("Webhook request failed (attempt %d/%d): %v", i+1, maxAttempts, err)
-				if i < maxAttempts-1 {
-					time.Sleep(backoff)
-				}
-				backoff *= 4
-				continue
-			}
-			resp.Body.Close()
+	reqCtx, cancel := context.WithTimeout(ctx, webhookClient.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, d.URL, bytes.NewBufferString(d.Payload))
+	if err != nil {
+		d.Error = err.Error()
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SaveAny-Bot/1.0")
+	req.Header.Set(WebhookHeaderEvent, d.Event)
+	if d.ID != 0 {
+		req.Header.Set(WebhookHeaderDelivery, strconv.FormatUint(uint64(d.ID), 10))
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set(WebhookHeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if secret != "" {
+		req.Header.Set(WebhookHeaderSignature, signWebhook(secret, timestamp, []byte(d.Payload)))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		d.Error = err.Error()
+		return
+	}
+	resp.Body.Close()
+	d.StatusCode = resp.StatusCode
+	d.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+func deliveryFailure(d *database.WebhookDelivery) string {
+	if d.Error != "" {
+		return d.Error
+	}
+	return "status " + strconv.Itoa(d.StatusCode)
+}
 
-			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-				logger.Debugf("Webhook sent successfully: %s", webhookURL)
+// signWebhook 返回请求的签名, 接收方应以相同方式计算并用常数时间比较
+func signWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// cleanupWebhookDeliveries 删除超过保留时间的投递记录和不再需要的密钥
+func cleanupWebhookDeliveries() {
+	before := time.Now().Add(-webhookDeliveryRetention)
+	forgetWebhookSecretsBefore(before)
+	if err := database.DeleteWebhookDeliveriesBefore(context.Background(), before); err != nil {
+		log.Warnf("Failed to clean up webhook deliveries: %v", err)
+	}
+}
+
+// startDeliveryCleanupLoop 定期清理投递记录. 全局 Webhook 在未启用 API 服务时也会投递, 因此不依赖 StartCleanupLoop
+func startDeliveryCleanupLoop(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(10 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
 				return
+			case <-ticker.C:
+				cleanupWebhookDeliveries()
 			}
+		}
+	}()
+}
 
-			logger.Warnf("Webhook returned non-2xx status (attempt %d/%d): %d", i+1, maxAttempts, resp.StatusCode)
-			if i < maxAttempts-1 {
-				time.Sleep(backoff)
+// registerGlobalWebhooks 为所有任务注册配置中的全局 Webhook
+func registerGlobalWebhooks(ctx context.Context) {
+	logger := log.FromContext(ctx)
+	var targets []webhookTarget
+	for _, hook := range config.C().API.Webhooks {
+		if hook.URL == "" {
+			continue
+		}
+		if err := validateWebhookEvents(hook.Events); err != nil {
+			logger.Warnf("Skipping global webhook %s: %s", hook.URL, err)
+			continue
+		}
+		targets = append(targets, webhookTarget{URL: hook.URL, Secret: hook.Secret, Events: hook.Events, Global: true})
+	}
+	if len(targets) == 0 {
+		return
+	}
+	core.RegisterSinkFactory(func(taskCtx context.Context, task core.Executable) taskevent.Sink {
+		// 子任务继承父任务的 context, 只使用属于该任务的信息
+		info, ok := taskCtx.Value(webhookTaskKey{}).(webhookTask)
+		if !ok || info.TaskID != task.TaskID() {
+			info = webhookTask{
+				TaskID: task.TaskID(),
+				Type:   task.Type().String(),
+				Title:  task.Title(),
 			}
-			backoff *= 4
 		}
+		return newWebhookSink(ctx, info, targets)
+	})
+	logger.Infof("Registered %d global webhooks", len(targets))
+}
 
-		logger.Errorf("Failed to send webhook after %d attempts", maxAttempts)
-	}()
+// canAccessDelivery 检查调用者能否查看和重新投递记录, 除 admin 外只能访问自己创建的任务的记录.
+// 不是通过 API 创建的任务 (如从 Bot 创建的任务) 只有 admin 可以访问
+func canAccessDelivery(p *Principal, d *database.WebhookDelivery) bool {
+	return p.Has(apikey.ScopeAdmin) || (d.UserID != 0 && d.UserID == p.UserID)
 }
 
-// CreateWebhookPayload creates a Webhook payload.
-func CreateWebhookPayload(taskID string, taskType string, status TaskStatus, storage, path string, err error) *WebhookPayload {
-	payload := &WebhookPayload{
-		TaskID:  taskID,
-		Type:    taskType,
-		Status:  status,
-		Storage: storage,
-		Path:    path,
+func toDeliveryResponse(d *database.WebhookDelivery) WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:           d.ID,
+		TaskID:       d.TaskID,
+		Event:        d.Event,
+		URL:          d.URL,
+		Signed:       d.Signed,
+		StatusCode:   d.StatusCode,
+		Error:        d.Error,
+		Attempts:     d.Attempts,
+		Success:      d.Success,
+		RedeliveryOf: d.RedeliveryOf,
+		Payload:      json.RawMessage(d.Payload),
+		CreatedAt:    d.CreatedAt,
+		UpdatedAt:    d.UpdatedAt,
 	}
+}
 
-	if status == TaskStatusCompleted || status == TaskStatusFailed {
-		now := time.Now()
-		payload.CompletedAt = &now
+// ListWebhookDeliveriesHandler 列出 Webhook 投递记录, 支持 ?task_id=, ?failed=true 和 ?limit=
+func (h *Handlers) ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := database.WebhookDeliveryFilter{
+		TaskID:     query.Get("task_id"),
+		FailedOnly: query.Get("failed") == "true",
+		Limit:      100,
 	}
-
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 1000 {
+			WriteError(w, http.StatusBadRequest, "invalid_request", "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+	deliveries, err := database.ListWebhookDeliveries(r.Context(), filter)
 	if err != nil {
-		payload.Error = err.Error()
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
 	}
+	principal := principalFrom(r)
+	resp := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		if canAccessDelivery(principal, &deliveries[i]) {
+			resp = append(resp, toDeliveryResponse(&deliveries[i]))
+		}
+	}
+	WriteJSON(w, http.StatusOK, WebhookDeliveriesResponse{Deliveries: resp, Total: len(resp)})
+}
 
-	return payload
+// RedeliverWebhookHandler 重新投递一条记录的负载, 同步发送一次并返回新的记录
+func (h *Handlers) RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "invalid delivery id: "+r.PathValue("id"))
+		return
+	}
+	orig, err := database.GetWebhookDelivery(r.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !canAccessDelivery(principalFrom(r), orig)) {
+		WriteError(w, http.StatusNotFound, "delivery_not_found", "delivery not found: "+r.PathValue("id"))
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	secret, ok := deliverySecret(orig)
+	if !ok {
+		WriteError(w, http.StatusConflict, "webhook_secret_unavailable", "the secret to sign delivery "+r.PathValue("id")+" is no longer available")
+		return
+	}
+	d := &database.WebhookDelivery{
+		TaskID:       orig.TaskID,
+		Event:        orig.Event,
+		URL:          orig.URL,
+		UserID:       orig.UserID,
+		Payload:      orig.Payload,
+		Global:       orig.Global,
+		Signed:       orig.Signed,
+		RedeliveryOf: orig.ID,
+	}
+	if err := database.CreateWebhookDelivery(r.Context(), d); err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	sendWebhookRequest(r.Context(), d, secret)
+	if err := database.UpdateWebhookDelivery(r.Context(), d); err != nil {
+		WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusOK, toDeliveryResponse(d))
 }
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/common/utils/strutil"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/apikey"
+	"gorm.io/gorm"
+)
+
+// /apikey list
+// /apikey new <name> [scopes] [expiry]
+// /apikey revoke <id>
+func handleAPIKeyCmd(ctx *ext.Context, update *ext.Update) error {
+	args := strutil.ParseArgsRespectQuotes(update.EffectiveMessage.Text)
+	if len(args) < 2 {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyHelp)), nil)
+		return dispatcher.EndGroups
+	}
+	userID := update.GetUserChat().GetID()
+	switch args[1] {
+	case "list":
+		return handleAPIKeyList(ctx, update, userID)
+	case "new":
+		return handleAPIKeyNew(ctx, update, userID, args[2:])
+	case "revoke":
+		return handleAPIKeyRevoke(ctx, update, userID, args[2:])
+	}
+	ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyHelp)), nil)
+	return dispatcher.EndGroups
+}
+
+func handleAPIKeyNew(ctx *ext.Context, update *ext.Update, userID int64, args []string) error {
+	if len(args) < 1 {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyHelp)), nil)
+		return dispatcher.EndGroups
+	}
+	name := args[0]
+	scopes := apikey.DefaultScopes()
+	var expiresAt *time.Time
+	// scopes 和有效期都是可选的, 按能否解析区分
+	for _, arg := range args[1:] {
+		if s, err := apikey.ParseScopes(arg); err == nil {
+			scopes = s
+			continue
+		}
+		exp, err := apikey.ParseExpiry(arg, time.Now())
+		if err != nil {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyErrorInvalidArg, map[string]any{
+				"Value":  arg,
+				"Scopes": apikey.FormatScopes(apikey.Scopes()),
+			})), nil)
+			return dispatcher.EndGroups
+		}
+		expiresAt = &exp
+	}
+	if slices.Contains(scopes, apikey.ScopeAdmin) && !config.C().IsAdmin(userID) {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyErrorAdminOnly)), nil)
+		return dispatcher.EndGroups
+	}
+	token, err := apikey.Generate()
+	if err != nil {
+		log.FromContext(ctx).Errorf("Failed to generate API key: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyErrorCreateFailed, map[string]any{"Error": err.Error()})), nil)
+		return dispatcher.EndGroups
+	}
+	key := &database.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Hash:      apikey.Hash(token),
+		Hint:      apikey.Hint(token),
+		Scopes:    apikey.FormatScopes(scopes),
+		ExpiresAt: expiresAt,
+	}
+	if err := database.CreateAPIKey(ctx, key); err != nil {
+		log.FromContext(ctx).Errorf("Failed to save API key: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyErrorCreateFailed, map[string]any{"Error": err.Error()})), nil)
+		return dispatcher.EndGroups
+	}
+	ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyInfoCreated, map[string]any{
+		"ID":      key.ID,
+		"Name":    key.Name,
+		"Scopes":  key.Scopes,
+		"Expires": formatAPIKeyExpiry(key.ExpiresAt),
+		"Token":   token,
+	})), nil)
+	return dispatcher.EndGroups
+}
+
+func handleAPIKeyList(ctx *ext.Context, update *ext.Update, userID int64) error {
+	keys, err := database.GetAPIKeysByUserID(ctx, userID)
+	if err != nil {
+		log.FromContext(ctx).Errorf("Failed to get API keys: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyErrorListFailed, map[string]any{"Error": err.Error()})), nil)
+		return dispatcher.EndGroups
+	}
+	if len(keys) == 0 {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyInfoListEmpty)), nil)
+		return dispatcher.EndGroups
+	}
+	var sb strings.Builder
+	sb.WriteString(i18n.T(i18nk.BotMsgApikeyInfoListHeader))
+	for _, key := range keys {
+		lastUsed := i18n.T(i18nk.BotMsgApikeyInfoNever)
+		if key.LastUsedAt != nil {
+			lastUsed = key.LastUsedAt.Format(time.DateTime)
+		}
+		sb.WriteString(i18n.T(i18nk.BotMsgApikeyInfoListItem, map[string]any{
+			"ID":       key.ID,
+			"Name":     key.Name,
+			"Hint":     key.Hint,
+			"Scopes":   key.Scopes,
+			"Expires":  formatAPIKeyExpiry(key.ExpiresAt),
+			"LastUsed": lastUsed,
+		}))
+		sb.WriteString("\n")
+	}
+	ctx.Reply(update, ext.ReplyTextString(sb.String()), nil)
+	return dispatcher.EndGroups
+}
+
+func handleAPIKeyRevoke(ctx *ext.Context, update *ext.Update, userID int64, args []string) error {
+	if len(args) < 1 {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyHelp)), nil)
+		return dispatcher.EndGroups
+	}
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyErrorNotFound, map[string]any{"ID": args[0]})), nil)
+		return dispatcher.EndGroups
+	}
+	if err := database.DeleteAPIKey(ctx, userID, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyErrorNotFound, map[string]any{"ID": args[0]})), nil)
+			return dispatcher.EndGroups
+		}
+		log.FromContext(ctx).Errorf("Failed to delete API key: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyErrorRevokeFailed, map[string]any{"Error": err.Error()})), nil)
+		return dispatcher.EndGroups
+	}
+	ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgApikeyInfoRevoked, map[string]any{"ID": id})), nil)
+	return dispatcher.EndGroups
+}
+
+func formatAPIKeyExpiry(t *time.Time) string {
+	if t == nil {
+		return i18n.T(i18nk.BotMsgApikeyInfoNever)
+	}
+	return t.Format(time.DateTime)
+}
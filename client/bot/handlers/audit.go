@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/common/cache"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
+	"github.com/krau/SaveAny-Bot/pkg/tcbdata"
+)
+
+// /audit 显示的记录数
+const auditListLimit = 20
+
+// auditCommand 在审计日志中记录命令的调用
+func auditCommand(cmd string, handler func(*ext.Context, *ext.Update) error) func(*ext.Context, *ext.Update) error {
+	return func(ctx *ext.Context, update *ext.Update) error {
+		audit.Record(ctx, audit.ActionCommand, "/"+cmd, map[string]any{"text": update.EffectiveMessage.Text})
+		return handler(ctx, update)
+	}
+}
+
+// auditCallback 在审计日志中记录按钮回调, 回调数据仍在缓存中时记录其内容
+func auditCallback(handler func(*ext.Context, *ext.Update) error) func(*ext.Context, *ext.Update) error {
+	return func(ctx *ext.Context, update *ext.Update) error {
+		data := string(update.CallbackQuery.Data)
+		args := strings.Fields(data)
+		target := ""
+		if len(args) > 0 {
+			target = args[0]
+		}
+		details := map[string]any{"data": data}
+		if len(args) >= 2 {
+			callbackAuditDetails(details, args)
+		}
+		audit.Record(ctx, audit.ActionCallback, target, details)
+		return handler(ctx, update)
+	}
+}
+
+func callbackAuditDetails(details map[string]any, args []string) {
+	switch args[0] {
+	case tcbdata.TypeAdd:
+		data, ok := cache.Get[tcbdata.Add](args[1])
+		if !ok {
+			return
+		}
+		details["task_type"] = data.TaskType.String()
+		details["storage"] = data.SelectedStorName
+		if data.DirID != 0 {
+			details["dir_id"] = data.DirID
+		}
+		if data.SelectedDirPath != "" {
+			details["dir"] = data.SelectedDirPath
+		}
+		items := len(data.Files) + len(data.TphPics) + len(data.DirectLinks) + len(data.Aria2URIs) + len(data.YtdlpURLs) + len(data.TransferFiles)
+		if data.ParsedItem != nil {
+			items++
+		}
+		details["items"] = items
+	case tcbdata.TypeSetDefault:
+		data, ok := cache.Get[tcbdata.SetDefaultStorage](args[1])
+		if !ok {
+			return
+		}
+		details["storage"] = data.StorageName
+		if data.DirID != 0 {
+			details["dir_id"] = data.DirID
+		}
+	case tcbdata.TypeBrowse:
+		data, ok := cache.Get[tcbdata.Browse](args[1])
+		if !ok {
+			return
+		}
+		details["storage"] = data.StorageName
+		details["path"] = data.Path
+		if len(args) >= 4 {
+			if index, err := strconv.Atoi(args[3]); err == nil && index >= 0 && index < len(data.Files) {
+				details["file"] = data.Files[index].Path
+			}
+		}
+	}
+}
+
+// /audit [user_id] [action]
+func handleAuditCmd(ctx *ext.Context, update *ext.Update) error {
+	if !config.C().IsAdmin(update.GetUserChat().GetID()) {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgAuditErrorNotAdmin, nil)), nil)
+		return dispatcher.EndGroups
+	}
+	filter := database.AuditLogFilter{Limit: auditListLimit}
+	for _, arg := range strings.Fields(update.EffectiveMessage.Text)[1:] {
+		if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+			filter.ActorID = &id
+		} else {
+			filter.Action = arg
+		}
+	}
+	logs, err := database.ListAuditLogs(ctx, filter)
+	if err != nil {
+		log.FromContext(ctx).Errorf("Failed to get audit logs: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgAuditErrorListFailed, map[string]any{"Error": err.Error()})), nil)
+		return dispatcher.EndGroups
+	}
+	if len(logs) == 0 {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgAuditInfoListEmpty, nil)), nil)
+		return dispatcher.EndGroups
+	}
+	var sb strings.Builder
+	sb.WriteString(i18n.T(i18nk.BotMsgAuditInfoListHeader, nil))
+	for _, l := range logs {
+		sb.WriteString(i18n.T(i18nk.BotMsgAuditInfoListItem, map[string]any{
+			"Time":   l.CreatedAt.Format(time.DateTime),
+			"Source": l.Source,
+			"Actor":  l.ActorID,
+			"Action": l.Action,
+			"Target": l.Target,
+		}))
+		sb.WriteString("\n")
+	}
+	ctx.Reply(update, ext.ReplyTextString(sb.String()), nil)
+	return dispatcher.EndGroups
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/re"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/common/utils/strutil"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/krau/SaveAny-Bot/storage"
+	"gorm.io/gorm"
+)
+
+// /chatdefault
+// /chatdefault <chat> <storage> [dir]
+// /chatdefault del <chat>
+func handleChatDefaultCmd(ctx *ext.Context, update *ext.Update) error {
+	logger := log.FromContext(ctx)
+	args := strutil.ParseArgsRespectQuotes(update.EffectiveMessage.Text)
+	user, err := database.GetUserByChatID(ctx, update.GetUserChat().GetID())
+	if err != nil {
+		logger.Errorf("Failed to get user: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorGetUserFailed)), nil)
+		return dispatcher.EndGroups
+	}
+	if len(args) < 2 {
+		ctx.Reply(update, ext.ReplyTextString(buildChatDefaultList(ctx, user)), nil)
+		return dispatcher.EndGroups
+	}
+	if args[1] == "del" {
+		if len(args) < 3 {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgChatdefaultHelp)), nil)
+			return dispatcher.EndGroups
+		}
+		chatID, err := tgutil.ParseChatID(ctx, args[2])
+		if err != nil {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorInvalidIdOrUsername, map[string]any{"Error": err.Error()})), nil)
+			return dispatcher.EndGroups
+		}
+		chatID = rule.NormalizeChatID(chatID)
+		if err := database.DeleteChatDefault(ctx, user.ID, chatID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgChatdefaultErrorNotFound, map[string]any{"Chat": args[2]})), nil)
+				return dispatcher.EndGroups
+			}
+			logger.Errorf("Failed to delete chat default: %s", err)
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgChatdefaultErrorSaveFailed, map[string]any{"Error": err.Error()})), nil)
+			return dispatcher.EndGroups
+		}
+		// 删除来源聊天的默认设置时存储为空
+		audit.Record(ctx, audit.ActionStorageDefault, "", map[string]any{"chat": chatID})
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgChatdefaultInfoDeleted, map[string]any{"Chat": args[2]})), nil)
+		return dispatcher.EndGroups
+	}
+	if len(args) < 3 {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgChatdefaultHelp)), nil)
+		return dispatcher.EndGroups
+	}
+	chatID, err := tgutil.ParseChatID(ctx, args[1])
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorInvalidIdOrUsername, map[string]any{"Error": err.Error()})), nil)
+		return dispatcher.EndGroups
+	}
+	chatID = rule.NormalizeChatID(chatID)
+	storageName := args[2]
+	if _, err := storage.GetStorageByUserIDAndName(ctx, user.ChatID, storageName); err != nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorGetStorageFailed, map[string]any{"Error": err.Error()})), nil)
+		return dispatcher.EndGroups
+	}
+	dirPath := ""
+	if len(args) > 3 {
+		dirPath = strings.Join(args[3:], " ")
+	}
+	if fnametmpl.IsTemplate(dirPath) {
+		if _, err := fnametmpl.Parse(dirPath); err != nil {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgChatdefaultErrorInvalidDir, map[string]any{"Error": err.Error()})), nil)
+			return dispatcher.EndGroups
+		}
+	}
+	if err := database.SetChatDefault(ctx, user.ID, chatID, storageName, dirPath); err != nil {
+		logger.Errorf("Failed to set chat default: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgChatdefaultErrorSaveFailed, map[string]any{"Error": err.Error()})), nil)
+		return dispatcher.EndGroups
+	}
+	audit.Record(ctx, audit.ActionStorageDefault, storageName, map[string]any{"chat": chatID, "dir": dirPath})
+	ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgChatdefaultInfoSet, map[string]any{
+		"Chat":    args[1],
+		"Storage": storageName,
+		"Dir":     dirPath,
+	})), nil)
+	return dispatcher.EndGroups
+}
+
+func buildChatDefaultList(ctx *ext.Context, user *database.User) string {
+	if len(user.ChatDefaults) == 0 {
+		return i18n.T(i18nk.BotMsgChatdefaultHelp) + "\n\n" + i18n.T(i18nk.BotMsgChatdefaultInfoListEmpty)
+	}
+	var sb strings.Builder
+	sb.WriteString(i18n.T(i18nk.BotMsgChatdefaultInfoListHeader))
+	for _, cd := range user.ChatDefaults {
+		chat := fmt.Sprintf("%d", cd.ChatID)
+		if name := tgutil.PeerName(ctx, cd.ChatID); name != "" {
+			chat += " (" + name + ")"
+		}
+		sb.WriteString(fmt.Sprintf("- %s → %s:%s\n", chat, cd.StorageName, cd.DirPath))
+	}
+	return sb.String()
+}
+
+// 返回消息被转发时的来源聊天, 不是转发的消息时返回 0
+func forwardSourceChat(msg *tg.Message) int64 {
+	if msg == nil {
+		return 0
+	}
+	fwd, ok := msg.GetFwdFrom()
+	if !ok {
+		return 0
+	}
+	from, ok := fwd.GetFromID()
+	if !ok {
+		return 0
+	}
+	return rule.NormalizeChatID(tgutil.ChatIdFromPeer(from))
+}
+
+// 返回静默模式下保存的文件的来源聊天: 转发来源, 或消息链接所在的聊天.
+// 对回复消息使用的命令, 以被回复的消息为准
+func silentSourceChats(ctx *ext.Context, update *ext.Update) []int64 {
+	msg := update.EffectiveMessage.Message
+	if strings.HasPrefix(msg.GetMessage(), "/") {
+		if reply := update.EffectiveMessage.ReplyToMessage; reply != nil && reply.Message != nil {
+			msg = reply.Message
+		}
+	}
+	var chats []int64
+	if id := forwardSourceChat(msg); id != 0 {
+		chats = append(chats, id)
+	}
+	if link := re.TgMessageLinkRegexp.FindString(msg.GetMessage()); link != "" {
+		if chatID, _, err := tgutil.ParseMessageLink(ctx, link); err == nil {
+			chats = append(chats, rule.NormalizeChatID(chatID))
+		}
+	}
+	return chats
+}
+
+// 返回来源聊天的默认存储和目录, 未设置或存储不可用时返回 nil
+func chatDefaultStorage(ctx *ext.Context, user *database.User, chatIDs ...int64) (storage.Storage, *database.ChatDefault) {
+	cd := user.ChatDefaultFor(chatIDs...)
+	if cd == nil {
+		return nil, nil
+	}
+	stor, err := storage.GetStorageByUserIDAndName(ctx, user.ChatID, cd.StorageName)
+	if err != nil {
+		log.FromContext(ctx).Warnf("Failed to get storage %s of the default for chat %d: %s", cd.StorageName, cd.ChatID, err)
+		return nil, nil
+	}
+	return stor, cd
+}
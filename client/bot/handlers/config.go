@@ -3,7 +3,6 @@ package handlers
 import (
 	"fmt"
 	"strings"
-	"text/template"
 
 	"github.com/celestix/gotgproto/dispatcher"
 	"github.com/celestix/gotgproto/ext"
@@ -13,7 +12,9 @@ import (
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
 	"github.com/krau/SaveAny-Bot/pkg/enums/fnamest"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
 	"github.com/krau/SaveAny-Bot/pkg/tcbdata"
 )
 
@@ -80,6 +81,7 @@ func handleConfigFnameSTCallback(ctx *ext.Context, update *ext.Update) error {
 		if err := database.UpdateUser(ctx, user); err != nil {
 			return err
 		}
+		audit.Record(ctx, audit.ActionSettingsUpdate, "filename_strategy", map[string]any{"filename_strategy": user.FilenameStrategy})
 		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
 			ID: update.CallbackQuery.GetMsgID(),
 			Message: i18n.T(i18nk.BotMsgConfigInfoFilenameStrategySet, map[string]any{
@@ -133,6 +135,7 @@ func handleConfigConflictSTCallback(ctx *ext.Context, update *ext.Update) error
 		if err := database.UpdateUser(ctx, user); err != nil {
 			return err
 		}
+		audit.Record(ctx, audit.ActionSettingsUpdate, "conflict_strategy", map[string]any{"conflict_strategy": selected})
 		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
 			ID: update.CallbackQuery.GetMsgID(),
 			Message: i18n.T(i18nk.BotMsgConfigInfoConflictStrategySet, map[string]any{
@@ -184,7 +187,7 @@ func handleConfigFnameTmpl(ctx *ext.Context, update *ext.Update) error {
 		return dispatcher.EndGroups
 	}
 	newTmpl := strings.Join(args[1:], " ")
-	_, err = template.New("filename").Parse(newTmpl)
+	_, err = fnametmpl.Parse(newTmpl)
 	if err != nil {
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgConfigErrorInvalidTemplate, map[string]any{
 			"Error": err.Error(),
@@ -195,6 +198,7 @@ func handleConfigFnameTmpl(ctx *ext.Context, update *ext.Update) error {
 	if err := database.UpdateUser(ctx, user); err != nil {
 		return err
 	}
+	audit.Record(ctx, audit.ActionSettingsUpdate, "filename_template", map[string]any{"filename_template": newTmpl})
 	ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgConfigInfoTemplateUpdated, nil)), nil)
 	return dispatcher.EndGroups
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
 	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
 	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
 	"github.com/krau/SaveAny-Bot/storage"
 )
 
@@ -54,6 +55,7 @@ func handleDirCmd(ctx *ext.Context, update *ext.Update) error {
 			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgDirErrorCreateDirFailed)), nil)
 			return dispatcher.EndGroups
 		}
+		audit.Record(ctx, audit.ActionDirCreate, args[2]+":"+args[3], nil)
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgDirInfoCreateDirSuccess)), nil)
 	case "del":
 		// /dir del 3
@@ -71,6 +73,7 @@ func handleDirCmd(ctx *ext.Context, update *ext.Update) error {
 			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgDirErrorDeleteDirFailed)), nil)
 			return dispatcher.EndGroups
 		}
+		audit.Record(ctx, audit.ActionDirDelete, args[2], nil)
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgDirInfoDeleteDirSuccess)), nil)
 	default:
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgDirErrorUnknownOperation)), nil)
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/common/utils/strutil"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/core"
+	"github.com/krau/SaveAny-Bot/core/tasks/transfer"
+	"github.com/krau/SaveAny-Bot/storage"
+	"github.com/rs/xid"
+)
+
+func handleGetCmd(ctx *ext.Context, update *ext.Update) error {
+	logger := log.FromContext(ctx)
+	args := strutil.ParseArgsRespectQuotes(update.EffectiveMessage.Text)
+	if len(args) < 2 || !strings.Contains(args[1], ":") {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgGetUsage, nil)), nil)
+		return dispatcher.EndGroups
+	}
+	userID := update.GetUserChat().GetID()
+	storName, filePath, _ := strings.Cut(args[1], ":")
+	sourceStorage, err := storage.GetStorageByUserIDAndName(ctx, userID, storName)
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgGetErrorStorageNotFound, map[string]any{
+			"StorageName": storName,
+			"Error":       err.Error(),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+	chatStorage, err := storage.NewTelegramChatStorage(ctx, userID)
+	if err != nil {
+		logger.Errorf("Failed to create telegram chat storage: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgGetErrorGetFailed, map[string]any{
+			"Error": err.Error(),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+	elems, err := transfer.NewSendElements(ctx, sourceStorage, filePath, chatStorage)
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgGetErrorGetFailed, map[string]any{
+			"Error": err.Error(),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+
+	msg, err := ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgGetInfoSendingFiles, map[string]any{
+		"Count": len(elems),
+	})), nil)
+	if err != nil {
+		logger.Errorf("Failed to reply: %s", err)
+		return dispatcher.EndGroups
+	}
+	injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
+	task := transfer.NewTransferTask(
+		xid.New().String(),
+		injectCtx,
+		elems,
+		transfer.NewProgressTracker(msg.ID, userID),
+		true,
+	)
+	if err := core.AddTask(injectCtx, task); err != nil {
+		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+			ID: msg.ID,
+			Message: i18n.T(i18nk.BotMsgCommonErrorTaskAddFailed, map[string]any{
+				"Error": err.Error(),
+			}),
+		})
+	}
+	return dispatcher.EndGroups
+}
@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/msgelem"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/shortcut"
+	"github.com/krau/SaveAny-Bot/common/cache"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/common/utils/dlutil"
+	"github.com/krau/SaveAny-Bot/common/utils/strutil"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/core"
+	"github.com/krau/SaveAny-Bot/core/tasks/transfer"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
+	"github.com/krau/SaveAny-Bot/pkg/storagetypes"
+	"github.com/krau/SaveAny-Bot/pkg/tcbdata"
+	"github.com/krau/SaveAny-Bot/storage"
+	"github.com/rs/xid"
+)
+
+const lsPageSize = 10
+
+func handleLsCmd(ctx *ext.Context, update *ext.Update) error {
+	args := strutil.ParseArgsRespectQuotes(update.EffectiveMessage.Text)
+	userID := update.GetUserChat().GetID()
+	if len(args) < 2 {
+		names := make([]string, 0)
+		for _, stor := range storage.GetUserStorages(ctx, userID) {
+			if _, ok := stor.(storage.StorageListable); ok {
+				names = append(names, stor.Name())
+			}
+		}
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgLsUsage, map[string]any{
+			"Storages": strings.Join(names, ", "),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+
+	storName, dirPath, _ := strings.Cut(args[1], ":")
+	data := tcbdata.Browse{StorageName: storName, Path: cleanBrowsePath(dirPath)}
+	if errText := loadBrowseFiles(ctx, userID, &data); errText != "" {
+		ctx.Reply(update, ext.ReplyTextString(errText), nil)
+		return dispatcher.EndGroups
+	}
+	dataid := xid.New().String()
+	if err := cache.Set(dataid, data); err != nil {
+		return err
+	}
+	text, markup := buildBrowseMessage(dataid, data)
+	ctx.Reply(update, ext.ReplyTextString(text), &ext.ReplyOpts{Markup: markup})
+	return dispatcher.EndGroups
+}
+
+// handleLsCallback handles "ls <dataid> <action> [index]" callbacks of the
+// file browser message.
+func handleLsCallback(ctx *ext.Context, update *ext.Update) error {
+	args := strings.Fields(string(update.CallbackQuery.Data))
+	if len(args) < 3 {
+		return fmt.Errorf("invalid callback data: %q", update.CallbackQuery.Data)
+	}
+	dataid, action := args[1], args[2]
+	data, err := shortcut.GetCallbackDataWithAnswer[tcbdata.Browse](ctx, update, dataid)
+	if err != nil {
+		return err
+	}
+	queryID := update.CallbackQuery.GetQueryID()
+	userID := update.CallbackQuery.GetUserID()
+	msgID := update.CallbackQuery.GetMsgID()
+	alert := func(text string) error {
+		ctx.AnswerCallback(msgelem.AlertCallbackAnswer(queryID, text))
+		return dispatcher.EndGroups
+	}
+
+	index := -1
+	if len(args) >= 4 {
+		index, err = strconv.Atoi(args[3])
+		if err != nil {
+			return fmt.Errorf("invalid callback data: %q", update.CallbackQuery.Data)
+		}
+	}
+	if action != "page" && index >= len(data.Files) {
+		return alert(i18n.T(i18nk.BotMsgLsErrorFileNotFound, nil))
+	}
+
+	switch action {
+	case "page":
+		data.Page = index
+	case "up", "refresh":
+		if action == "up" {
+			data.Path = cleanBrowsePath(path.Dir(data.Path))
+		}
+		data.Page = 0
+		if errText := loadBrowseFiles(ctx, userID, &data); errText != "" {
+			return alert(errText)
+		}
+	case "back":
+	case "open":
+		file := data.Files[index]
+		if file.IsDir {
+			data.Path = cleanBrowsePath(file.Path)
+			data.Page = 0
+			if errText := loadBrowseFiles(ctx, userID, &data); errText != "" {
+				return alert(errText)
+			}
+			break
+		}
+		stor, err := storage.GetStorageByUserIDAndName(ctx, userID, data.StorageName)
+		if err != nil {
+			return alert(err.Error())
+		}
+		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+			ID: msgID,
+			Message: i18n.T(i18nk.BotMsgLsInfoFile, map[string]any{
+				"Name":        file.Name,
+				"StorageName": data.StorageName,
+				"Path":        file.Path,
+				"Size":        dlutil.FormatSize(file.Size),
+				"ModTime":     file.ModTime.Format(time.DateTime),
+			}),
+			ReplyMarkup: buildBrowseFileMarkup(dataid, index, stor),
+		})
+		return dispatcher.EndGroups
+	case "rm":
+		file := data.Files[index]
+		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+			ID: msgID,
+			Message: i18n.T(i18nk.BotMsgLsPromptConfirmDelete, map[string]any{
+				"StorageName": data.StorageName,
+				"Path":        file.Path,
+			}),
+			ReplyMarkup: &tg.ReplyInlineMarkup{Rows: []tg.KeyboardButtonRow{{Buttons: []tg.KeyboardButtonClass{
+				browseButton(i18n.T(i18nk.BotMsgLsButtonConfirmDelete, nil), dataid, "rmok", index),
+				browseButton(i18n.T(i18nk.BotMsgLsButtonBack, nil), dataid, "back", -1),
+			}}}},
+		})
+		return dispatcher.EndGroups
+	case "rmok":
+		file := data.Files[index]
+		stor, err := storage.GetStorageByUserIDAndName(ctx, userID, data.StorageName)
+		if err != nil {
+			return alert(err.Error())
+		}
+		deletable, ok := stor.(storage.StorageDeletable)
+		if !ok {
+			return alert(i18n.T(i18nk.BotMsgLsErrorNotSupported, nil))
+		}
+		if err := deletable.Delete(ctx, file.Path); err != nil {
+			log.FromContext(ctx).Errorf("Failed to delete %s:%s: %s", data.StorageName, file.Path, err)
+			return alert(i18n.T(i18nk.BotMsgLsErrorDeleteFailed, map[string]any{"Error": err.Error()}))
+		}
+		audit.Record(ctx, audit.ActionStorageDelete, data.StorageName+":"+file.Path, nil)
+		ctx.AnswerCallback(&tg.MessagesSetBotCallbackAnswerRequest{
+			QueryID: queryID,
+			Message: i18n.T(i18nk.BotMsgLsInfoDeleted, map[string]any{"Name": file.Name}),
+		})
+		if errText := loadBrowseFiles(ctx, userID, &data); errText != "" {
+			return alert(errText)
+		}
+	case "mv":
+		file := data.Files[index]
+		prompt, err := ctx.SendMessage(userID, &tg.MessagesSendMessageRequest{
+			Message: i18n.T(i18nk.BotMsgLsPromptRename, map[string]any{
+				"StorageName": data.StorageName,
+				"Path":        file.Path,
+			}),
+			ReplyMarkup: &tg.ReplyKeyboardForceReply{SingleUse: true, Placeholder: file.Name},
+		})
+		if err != nil {
+			return err
+		}
+		if err := cache.Set(browseRenameKey(userID, prompt.ID), tcbdata.BrowseRename{
+			StorageName: data.StorageName,
+			Path:        file.Path,
+		}); err != nil {
+			return err
+		}
+		ctx.AnswerCallback(&tg.MessagesSetBotCallbackAnswerRequest{QueryID: queryID})
+		return dispatcher.EndGroups
+	case "dl":
+		return handleLsDownload(ctx, update, data, index)
+	case "tf":
+		return editTransferSelectStorage(ctx, userID, msgID, data.StorageName, data.Path, data.Files, nil)
+	default:
+		return fmt.Errorf("unknown ls action: %s", action)
+	}
+
+	if err := cache.Set(dataid, data); err != nil {
+		return err
+	}
+	text, markup := buildBrowseMessage(dataid, data)
+	ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+		ID:          msgID,
+		Message:     text,
+		ReplyMarkup: markup,
+	})
+	return dispatcher.EndGroups
+}
+
+// handleLsDownload sends a stored file back to the chat as a transfer task
+// whose target is the user's own chat.
+func handleLsDownload(ctx *ext.Context, update *ext.Update, data tcbdata.Browse, index int) error {
+	userID := update.CallbackQuery.GetUserID()
+	file := data.Files[index]
+	alert := func(text string) error {
+		ctx.AnswerCallback(msgelem.AlertCallbackAnswer(update.CallbackQuery.GetQueryID(), text))
+		return dispatcher.EndGroups
+	}
+	sourceStorage, err := storage.GetStorageByUserIDAndName(ctx, userID, data.StorageName)
+	if err != nil {
+		return alert(err.Error())
+	}
+	if _, ok := sourceStorage.(storage.StorageReadable); !ok {
+		return alert(i18n.T(i18nk.BotMsgLsErrorNotSupported, nil))
+	}
+	chatStorage, err := storage.NewTelegramChatStorage(ctx, userID)
+	if err != nil {
+		return alert(i18n.T(i18nk.BotMsgLsErrorDownloadFailed, map[string]any{"Error": err.Error()}))
+	}
+	ctx.AnswerCallback(&tg.MessagesSetBotCallbackAnswerRequest{QueryID: update.CallbackQuery.GetQueryID()})
+
+	msg, err := ctx.SendMessage(userID, &tg.MessagesSendMessageRequest{
+		Message: i18n.T(i18nk.BotMsgLsInfoSendingFile, map[string]any{"Name": file.Name}),
+	})
+	if err != nil {
+		return err
+	}
+	elem := transfer.NewTaskElement(sourceStorage, file, chatStorage, "")
+	taskID := xid.New().String()
+	injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
+	task := transfer.NewTransferTask(
+		taskID,
+		injectCtx,
+		[]transfer.TaskElement{*elem},
+		transfer.NewProgressTracker(msg.ID, userID),
+		false,
+	)
+	if err := core.AddTask(injectCtx, task); err != nil {
+		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+			ID:      msg.ID,
+			Message: i18n.T(i18nk.BotMsgLsErrorDownloadFailed, map[string]any{"Error": err.Error()}),
+		})
+	}
+	return dispatcher.EndGroups
+}
+
+// handleLsRenameReply renames the file of a rename prompt the user replied to.
+// Replies to other messages are passed on to the next handlers.
+func handleLsRenameReply(ctx *ext.Context, update *ext.Update) error {
+	replyTo := update.EffectiveMessage.ReplyToMessage
+	if replyTo == nil {
+		return dispatcher.ContinueGroups
+	}
+	userID := update.GetUserChat().GetID()
+	data, ok := cache.Get[tcbdata.BrowseRename](browseRenameKey(userID, replyTo.ID))
+	if !ok {
+		return dispatcher.ContinueGroups
+	}
+
+	newName := strings.TrimSpace(update.EffectiveMessage.Text)
+	if newName == "" || newName == "." || newName == ".." || strings.ContainsAny(newName, `/\`) {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgLsErrorInvalidName, nil)), nil)
+		return dispatcher.EndGroups
+	}
+	stor, err := storage.GetStorageByUserIDAndName(ctx, userID, data.StorageName)
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgLsErrorStorageNotFound, map[string]any{
+			"StorageName": data.StorageName,
+			"Error":       err.Error(),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+	movable, ok := stor.(storage.StorageMovable)
+	if !ok {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgLsErrorNotSupported, nil)), nil)
+		return dispatcher.EndGroups
+	}
+	dstPath := path.Join(path.Dir(data.Path), newName)
+	if err := movable.Move(ctx, data.Path, dstPath); err != nil {
+		log.FromContext(ctx).Errorf("Failed to rename %s:%s: %s", data.StorageName, data.Path, err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgLsErrorRenameFailed, map[string]any{
+			"Error": err.Error(),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+	audit.Record(ctx, audit.ActionStorageMove, data.StorageName+":"+data.Path, map[string]any{"destination": dstPath})
+	ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgLsInfoRenamed, map[string]any{
+		"StorageName": data.StorageName,
+		"Path":        dstPath,
+	})), nil)
+	return dispatcher.EndGroups
+}
+
+func browseRenameKey(chatID int64, msgID int) string {
+	return fmt.Sprintf("lsmv:%d:%d", chatID, msgID)
+}
+
+func cleanBrowsePath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// loadBrowseFiles lists data.Path into data.Files, returning a user facing
+// error text on failure.
+func loadBrowseFiles(ctx *ext.Context, userID int64, data *tcbdata.Browse) string {
+	stor, err := storage.GetStorageByUserIDAndName(ctx, userID, data.StorageName)
+	if err != nil {
+		return i18n.T(i18nk.BotMsgLsErrorStorageNotFound, map[string]any{
+			"StorageName": data.StorageName,
+			"Error":       err.Error(),
+		})
+	}
+	listable, ok := stor.(storage.StorageListable)
+	if !ok {
+		return i18n.T(i18nk.BotMsgLsErrorStorageNotListable, map[string]any{
+			"StorageName": data.StorageName,
+		})
+	}
+	files, err := listable.ListFiles(ctx, data.Path)
+	if err != nil {
+		log.FromContext(ctx).Errorf("Failed to list %s:%s: %s", data.StorageName, data.Path, err)
+		return i18n.T(i18nk.BotMsgLsErrorListFilesFailed, map[string]any{"Error": err.Error()})
+	}
+	slices.SortStableFunc(files, func(a, b storagetypes.FileInfo) int {
+		if a.IsDir != b.IsDir {
+			if a.IsDir {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	data.Files = files
+	return ""
+}
+
+func browseButton(text, dataid, action string, index int) *tg.KeyboardButtonCallback {
+	data := fmt.Sprintf("%s %s %s", tcbdata.TypeBrowse, dataid, action)
+	if index >= 0 {
+		data += " " + strconv.Itoa(index)
+	}
+	return &tg.KeyboardButtonCallback{Text: text, Data: []byte(data)}
+}
+
+func buildBrowseMessage(dataid string, data tcbdata.Browse) (string, *tg.ReplyInlineMarkup) {
+	markup := &tg.ReplyInlineMarkup{}
+	pages := max(1, (len(data.Files)+lsPageSize-1)/lsPageSize)
+	page := min(max(data.Page, 0), pages-1)
+
+	var text string
+	if len(data.Files) == 0 {
+		text = i18n.T(i18nk.BotMsgLsInfoDirEmpty, map[string]any{
+			"StorageName": data.StorageName,
+			"Path":        data.Path,
+		})
+	} else {
+		text = i18n.T(i18nk.BotMsgLsInfoDir, map[string]any{
+			"StorageName": data.StorageName,
+			"Path":        data.Path,
+			"Page":        page + 1,
+			"Pages":       pages,
+			"Count":       len(data.Files),
+		})
+	}
+
+	for i := page * lsPageSize; i < min((page+1)*lsPageSize, len(data.Files)); i++ {
+		file := data.Files[i]
+		label := "📁 " + file.Name
+		if !file.IsDir {
+			label = fmt.Sprintf("📄 %s · %s", file.Name, dlutil.FormatSize(file.Size))
+		}
+		markup.Rows = append(markup.Rows, tg.KeyboardButtonRow{Buttons: []tg.KeyboardButtonClass{
+			browseButton(label, dataid, "open", i),
+		}})
+	}
+
+	nav := make([]tg.KeyboardButtonClass, 0, 3)
+	if page > 0 {
+		nav = append(nav, browseButton("◀️", dataid, "page", page-1))
+	}
+	if data.Path != "/" {
+		nav = append(nav, browseButton(i18n.T(i18nk.BotMsgLsButtonUp, nil), dataid, "up", -1))
+	}
+	if page < pages-1 {
+		nav = append(nav, browseButton("▶️", dataid, "page", page+1))
+	}
+	if len(nav) > 0 {
+		markup.Rows = append(markup.Rows, tg.KeyboardButtonRow{Buttons: nav})
+	}
+
+	actions := []tg.KeyboardButtonClass{browseButton(i18n.T(i18nk.BotMsgLsButtonRefresh, nil), dataid, "refresh", -1)}
+	if slices.ContainsFunc(data.Files, func(f storagetypes.FileInfo) bool { return !f.IsDir }) {
+		actions = append(actions, browseButton(i18n.T(i18nk.BotMsgLsButtonTransfer, nil), dataid, "tf", -1))
+	}
+	markup.Rows = append(markup.Rows, tg.KeyboardButtonRow{Buttons: actions})
+	return text, markup
+}
+
+// buildBrowseFileMarkup shows the actions the storage supports for a file.
+func buildBrowseFileMarkup(dataid string, index int, stor storage.Storage) *tg.ReplyInlineMarkup {
+	buttons := make([]tg.KeyboardButtonClass, 0, 4)
+	if _, ok := stor.(storage.StorageReadable); ok {
+		buttons = append(buttons, browseButton(i18n.T(i18nk.BotMsgLsButtonDownload, nil), dataid, "dl", index))
+	}
+	if _, ok := stor.(storage.StorageMovable); ok {
+		buttons = append(buttons, browseButton(i18n.T(i18nk.BotMsgLsButtonRename, nil), dataid, "mv", index))
+	}
+	if _, ok := stor.(storage.StorageDeletable); ok {
+		buttons = append(buttons, browseButton(i18n.T(i18nk.BotMsgLsButtonDelete, nil), dataid, "rm", index))
+	}
+	return &tg.ReplyInlineMarkup{Rows: []tg.KeyboardButtonRow{
+		{Buttons: buttons},
+		{Buttons: []tg.KeyboardButtonClass{browseButton(i18n.T(i18nk.BotMsgLsButtonBack, nil), dataid, "back", -1)}},
+	}}
+}
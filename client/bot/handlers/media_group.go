@@ -107,6 +107,9 @@ func processMediaGroup(ctx *ext.Context, update *ext.Update, key mediaGroupKey)
 	logger.Debugf("Processing media group %d with %d items", key.groupID, len(items))
 
 	userId := update.GetUserChat().GetID()
+	if user, err := database.GetUserByChatID(ctx, userId); err == nil {
+		mediautil.RenameAlbum(ctx, user, items)
+	}
 	msg, err := ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgMediaGroupInfoSavingFiles, nil)), nil)
 	if err != nil {
 		logger.Errorf("Failed to reply: %s", err)
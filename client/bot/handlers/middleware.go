@@ -9,6 +9,7 @@ import (
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
 	"github.com/krau/SaveAny-Bot/storage"
 )
 
@@ -18,6 +19,8 @@ func checkPermission(ctx *ext.Context, update *ext.Update) error {
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorNoPermission, nil)), nil)
 		return dispatcher.EndGroups
 	}
+	// 之后的处理器共用同一个 ext.Context, 其中的操作记录为该用户所为
+	ctx.Context = audit.WithActor(ctx.Context, audit.Actor{UserID: userID, Source: audit.SourceBot})
 
 	return dispatcher.ContinueGroups
 }
@@ -46,6 +49,16 @@ func handleSilentMode(next func(*ext.Context, *ext.Update) error, handler func(*
 		if !user.Silent {
 			return next(ctx, update)
 		}
+		// 来源聊天的默认设置优先于用户的默认存储
+		if stor, cd := chatDefaultStorage(ctx, user, silentSourceChats(ctx, update)...); stor != nil {
+			ctx.Context = dirutil.WithContext(ctx.Context, &database.Dir{
+				UserID:      user.ID,
+				StorageName: cd.StorageName,
+				Path:        cd.DirPath,
+			})
+			ctx.Context = storage.WithContext(ctx.Context, stor)
+			return handler(ctx, update)
+		}
 		if user.DefaultStorage == "" {
 			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorDefaultStorageNotSet, nil)), nil)
 			return next(ctx, update)
@@ -4,7 +4,6 @@ package handlers
 
 import (
 	"errors"
-	"path"
 	"strings"
 
 	"github.com/celestix/gotgproto/dispatcher"
@@ -16,7 +15,6 @@ import (
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/shortcut"
 	"github.com/krau/SaveAny-Bot/common/i18n"
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
-	"github.com/krau/SaveAny-Bot/common/utils/fsutil"
 	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
 	"github.com/krau/SaveAny-Bot/parsers"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
@@ -56,7 +54,7 @@ func handleTextMessage(ctx *ext.Context, u *ext.Update) error {
 		return err
 	}
 
-	item, err := pser.Parse(ctx, source)
+	item, err := parsers.Parse(ctx, pser, source)
 	if errors.Is(err, parsers.ErrNoParserFound) {
 		return dispatcher.EndGroups
 	}
@@ -138,12 +136,5 @@ func handleSilentSaveText(ctx *ext.Context, u *ext.Update) error {
 		logger.Errorf("Failed to send message: %s", err)
 		return dispatcher.EndGroups
 	}
-	dirPath := ""
-	if len(item.Resources) > 1 {
-		dirPath = fsutil.NormalizePathname(item.Title)
-	}
-	if p := dirutil.PathFromContext(ctx); p != "" {
-		dirPath = path.Join(p, dirPath)
-	}
-	return shortcut.CreateAndAddParsedTaskWithEdit(ctx, stor, dirPath, item, msg.ID, userID)
+	return shortcut.CreateAndAddParsedTaskWithEdit(ctx, stor, dirutil.PathFromContext(ctx), item, msg.ID, userID)
 }
@@ -32,8 +32,11 @@ var CommandHandlers = []DescCommandHandler{
 	{"aria2dl", i18nk.BotMsgCmdAria2dl, handleAria2DlCmd},
 	{"ytdlp", i18nk.BotMsgCmdYtdlp, handleYtdlpCmd},
 	{"transfer", i18nk.BotMsgCmdTransfer, handleTransferCmd},
+	{"ls", i18nk.BotMsgCmdLs, handleLsCmd},
+	{"get", i18nk.BotMsgCmdGet, handleGetCmd},
 	{"task", i18nk.BotMsgCmdTask, handleTaskCmd},
 	{"cancel", i18nk.BotMsgCmdCancel, handleCancelCmd},
+	{"stream", i18nk.BotMsgCmdStream, handleStreamCmd},
 	{"config", i18nk.BotMsgCmdConfig, handleConfigCmd},
 	{"fnametmpl", i18nk.BotMsgCmdFnametmpl, handleConfigFnameTmpl},
 	{"help", i18nk.BotMsgCmdHelp, handleHelpCmd},
@@ -41,6 +44,9 @@ var CommandHandlers = []DescCommandHandler{
 	{"watch", i18nk.BotMsgCmdWatch, handleWatchCmd},
 	{"unwatch", i18nk.BotMsgCmdUnwatch, handleUnwatchCmd},
 	{"lswatch", i18nk.BotMsgCmdLswatch, handleLswatchCmd},
+	{"chatdefault", i18nk.BotMsgCmdChatdefault, handleChatDefaultCmd},
+	{"apikey", i18nk.BotMsgCmdApikey, handleAPIKeyCmd},
+	{"audit", i18nk.BotMsgCmdAudit, handleAuditCmd},
 	{"syncpeers", i18nk.BotMsgCmdSyncpeers, handleSyncpeersCmd},
 	{"update", i18nk.BotMsgCmdUpdate, handleUpdateCmd},
 }
@@ -54,13 +60,15 @@ func Register(disp dispatcher.Dispatcher) {
 	}))
 	disp.AddHandler(handlers.NewMessage(filters.Message.All, checkPermission))
 	for _, info := range CommandHandlers {
-		disp.AddHandler(handlers.NewCommand(info.Cmd, info.handler))
+		disp.AddHandler(handlers.NewCommand(info.Cmd, auditCommand(info.Cmd, info.handler)))
 	}
-	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix("update"), withPermission(handleUpdateCallback)))
-	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeAdd), withPermission(handleAddCallback)))
-	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeSetDefault), withPermission(handleSetDefaultCallback)))
-	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeCancel), withPermission(handleCancelCallback)))
-	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeConfig), withPermission(handleConfigCallback)))
+	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix("update"), withPermission(auditCallback(handleUpdateCallback))))
+	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeAdd), withPermission(auditCallback(handleAddCallback))))
+	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeSetDefault), withPermission(auditCallback(handleSetDefaultCallback))))
+	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeCancel), withPermission(auditCallback(handleCancelCallback))))
+	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeConfig), withPermission(auditCallback(handleConfigCallback))))
+	disp.AddHandler(handlers.NewCallbackQuery(filters.CallbackQuery.Prefix(tcbdata.TypeBrowse), withPermission(auditCallback(handleLsCallback))))
+	disp.AddHandler(handlers.NewMessage(filters.Message.Text, handleLsRenameReply))
 	disp.AddHandler(handlers.NewMessage(sabotfilters.RegexUrl(regexp.MustCompile(re.TgMessageLinkRegexString)), handleSilentMode(handleMessageLink, handleSilentSaveLink)))
 	disp.AddHandler(handlers.NewMessage(sabotfilters.RegexUrl(regexp.MustCompile(re.TelegraphUrlRegexString)), handleSilentMode(handleTelegraphUrlMessage, handleSilentSaveTelegraph)))
 	disp.AddHandler(handlers.NewMessage(filters.Message.Media, handleSilentMode(handleMediaMessage, handleSilentSaveMedia)))
@@ -68,5 +76,6 @@ func Register(disp dispatcher.Dispatcher) {
 
 	if config.C().Telegram.Userbot.Enable {
 		go listenMediaMessageEvent(userclient.GetMediaMessageCh())
+		go resumeWatchBackfills()
 	}
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bytes"
 	"fmt"
 	"strconv"
 	"strings"
@@ -9,13 +10,21 @@ import (
 	"github.com/celestix/gotgproto/ext"
 	"github.com/charmbracelet/log"
 	"github.com/duke-git/lancet/v2/slice"
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/uploader"
+	"github.com/gotd/td/tg"
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/msgelem"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
 	"github.com/krau/SaveAny-Bot/common/i18n"
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/common/tdler"
 	"github.com/krau/SaveAny-Bot/common/utils/strutil"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
 	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/krau/SaveAny-Bot/pkg/ruleset"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
 )
 
 func handleRuleCmd(ctx *ext.Context, update *ext.Update) error {
@@ -40,13 +49,14 @@ func handleRuleCmd(ctx *ext.Context, update *ext.Update) error {
 			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleErrorUpdateUserFailed, nil)), nil)
 			return dispatcher.EndGroups
 		}
+		audit.Record(ctx, audit.ActionSettingsUpdate, "apply_rule", map[string]any{"apply_rule": applyRule})
 		if applyRule {
 			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleInfoRuleModeEnabled, nil)), nil)
 		} else {
 			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleInfoRuleModeDisabled, nil)), nil)
 		}
 	case "add":
-		// /rule add <type> <data> <storage> <dirpath>
+		// /rule add <type> <data> <storage> <dirpath> [priority] [actions...]
 		if len(args) < 6 {
 			ctx.Reply(update, ext.ReplyTextStyledTextArray(msgelem.BuildRuleHelpStyling(user.ApplyRule, user.Rules)), nil)
 			return dispatcher.EndGroups
@@ -71,19 +81,62 @@ func handleRuleCmd(ctx *ext.Context, update *ext.Update) error {
 		ruleData := args[3]
 		storageName := args[4]
 		dirPath := args[5]
+		if _, err := rule.Compile(ruleType.String(), ruleData); err != nil {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleErrorInvalidRule, map[string]any{
+				"Error": err.Error(),
+			})), nil)
+			return dispatcher.EndGroups
+		}
+		if storageName != rule.RuleStorNameChosen && !config.C().HasStorage(user.ChatID, storageName) {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleErrorStorageNotFound, map[string]any{
+				"Storage": storageName,
+			})), nil)
+			return dispatcher.EndGroups
+		}
+		rest := args[6:]
+		priority := 0
+		if len(rest) > 0 {
+			if p, err := strconv.Atoi(rest[0]); err == nil {
+				priority = p
+				rest = rest[1:]
+			}
+		}
+		actions, err := rule.ParseActions(rest)
+		if err == nil {
+			err = ruleutil.ValidateActions(user.ChatID, actions)
+		}
+		if err != nil {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleErrorInvalidRule, map[string]any{
+				"Error": err.Error(),
+			})), nil)
+			return dispatcher.EndGroups
+		}
 
 		rd := &database.Rule{
-			Type:        ruleType.String(),
-			Data:        ruleData,
-			StorageName: storageName,
-			DirPath:     dirPath,
-			UserID:      user.ID,
+			Type:         ruleType.String(),
+			Data:         ruleData,
+			StorageName:  storageName,
+			DirPath:      dirPath,
+			Priority:     priority,
+			UserID:       user.ID,
+			Rename:       actions.Rename,
+			Skip:         actions.Skip,
+			Conflict:     actions.Conflict,
+			PostProcess:  actions.PostProcess,
+			NotifyChatID: actions.NotifyChat,
 		}
 		if err := database.CreateRule(ctx, rd); err != nil {
 			logger.Errorf("failed to create rule: %s", err)
 			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleErrorCreateRuleFailed, nil)), nil)
 			return dispatcher.EndGroups
 		}
+		audit.Record(ctx, audit.ActionRuleCreate, strconv.FormatUint(uint64(rd.ID), 10), map[string]any{
+			"type":     rd.Type,
+			"data":     rd.Data,
+			"storage":  rd.StorageName,
+			"dir":      rd.DirPath,
+			"priority": rd.Priority,
+		})
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleInfoCreateRuleSuccess, nil)), nil)
 	case "preset":
 		// /rule preset <storage> [base_path]
@@ -122,9 +175,45 @@ func handleRuleCmd(ctx *ext.Context, update *ext.Update) error {
 			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleErrorCreateRuleFailed, nil)), nil)
 			return dispatcher.EndGroups
 		}
+		audit.Record(ctx, audit.ActionRuleImport, strconv.FormatInt(user.ChatID, 10), map[string]any{"preset": storageName, "rules": imported})
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleInfoPresetImported, map[string]any{
 			"Count": imported,
 		})), nil)
+	case "test":
+		// 回复一条消息: /rule test, 或 /rule test <filename|url>
+		return handleRuleTest(ctx, update, user, args[2:])
+	case "export":
+		// /rule export [json|toml]
+		return handleRuleExport(ctx, update, user, args[2:])
+	case "import":
+		// 回复一个规则文件: /rule import [replace]
+		return handleRuleImport(ctx, update, user, args[2:])
+	case "priority":
+		// /rule priority <id> <priority>
+		if len(args) < 4 {
+			ctx.Reply(update, ext.ReplyTextStyledTextArray(msgelem.BuildRuleHelpStyling(user.ApplyRule, user.Rules)), nil)
+			return dispatcher.EndGroups
+		}
+		id, err := strconv.Atoi(args[2])
+		if err != nil {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleErrorInvalidRuleId, nil)), nil)
+			return dispatcher.EndGroups
+		}
+		priority, err := strconv.Atoi(args[3])
+		if err != nil {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleErrorInvalidPriority, nil)), nil)
+			return dispatcher.EndGroups
+		}
+		if err := database.UpdateRulePriority(ctx, user.ID, uint(id), priority); err != nil {
+			logger.Errorf("failed to update priority of rule %d: %s", id, err)
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleErrorUpdatePriorityFailed, nil)), nil)
+			return dispatcher.EndGroups
+		}
+		audit.Record(ctx, audit.ActionRuleUpdate, args[2], map[string]any{"priority": priority})
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleInfoPriorityUpdated, map[string]any{
+			"ID":       id,
+			"Priority": priority,
+		})), nil)
 	case "del":
 		// /rule del <id>
 		if len(args) < 3 {
@@ -142,6 +231,7 @@ func handleRuleCmd(ctx *ext.Context, update *ext.Update) error {
 			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleErrorDeleteRuleFailed, nil)), nil)
 			return dispatcher.EndGroups
 		}
+		audit.Record(ctx, audit.ActionRuleDelete, ruleID, nil)
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleInfoDeleteRuleSuccess, nil)), nil)
 	default:
 		ctx.Reply(update, ext.ReplyTextStyledTextArray(msgelem.BuildRuleHelpStyling(user.ApplyRule, user.Rules)), nil)
@@ -149,3 +239,109 @@ func handleRuleCmd(ctx *ext.Context, update *ext.Update) error {
 	}
 	return dispatcher.EndGroups
 }
+
+// 规则文件的大小上限
+const maxRulesetFileSize = 1 << 20
+
+func handleRuleExport(ctx *ext.Context, update *ext.Update, user *database.User, args []string) error {
+	logger := log.FromContext(ctx)
+	formatArg := ""
+	if len(args) > 0 {
+		formatArg = args[0]
+	}
+	format, err := ruleset.ParseFormat(formatArg)
+	if err == nil {
+		var data []byte
+		data, err = ruleset.Encode(ruleutil.DocumentFromUser(user), format)
+		if err == nil {
+			err = sendRulesetFile(ctx, update, data, format)
+		}
+	}
+	if err != nil {
+		logger.Errorf("Failed to export rules: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleErrorExportFailed, map[string]any{
+			"Error": err.Error(),
+		})), nil)
+	}
+	return dispatcher.EndGroups
+}
+
+func sendRulesetFile(ctx *ext.Context, update *ext.Update, data []byte, format ruleset.Format) error {
+	name := fmt.Sprintf("saveany-rules-%d.%s", update.GetUserChat().GetID(), format)
+	mime := "application/json"
+	if format == ruleset.FormatTOML {
+		mime = "application/toml"
+	}
+	upler := uploader.NewUploader(ctx.Raw)
+	file, err := upler.FromBytes(ctx, name, data)
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	doc := message.UploadedDocument(file).
+		Filename(name).
+		ForceFile(true).
+		MIME(mime)
+	_, err = ctx.Sender.
+		WithUploader(upler).
+		To(update.GetUserChat().AsInputPeer()).
+		Reply(update.EffectiveMessage.ID).
+		Media(ctx, doc)
+	return err
+}
+
+func handleRuleImport(ctx *ext.Context, update *ext.Update, user *database.User, args []string) error {
+	logger := log.FromContext(ctx)
+	replace := len(args) > 0 && strings.EqualFold(args[0], "replace")
+	replyTo := update.EffectiveMessage.ReplyToMessage
+	if replyTo == nil || replyTo.Message == nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleImportPromptReply, nil)), nil)
+		return dispatcher.EndGroups
+	}
+	if _, ok := replyTo.Media.(*tg.MessageMediaDocument); !ok {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleImportPromptReply, nil)), nil)
+		return dispatcher.EndGroups
+	}
+	file, err := tfile.FromMediaMessage(replyTo.Media, ctx.Raw, replyTo.Message)
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorGetFileFailed, map[string]any{
+			"Error": err.Error(),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+	if file.Size() > maxRulesetFileSize {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleImportErrorTooLarge, nil)), nil)
+		return dispatcher.EndGroups
+	}
+	var buf bytes.Buffer
+	if err := tdler.Stream(ctx, file, &buf); err != nil {
+		logger.Errorf("Failed to download rule file: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorGetFileFailed, map[string]any{
+			"Error": err.Error(),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+	doc, err := ruleset.Decode(buf.Bytes())
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleImportErrorInvalid, map[string]any{
+			"Error": err.Error(),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+	result, err := ruleutil.Import(ctx, user, doc, replace)
+	if err != nil {
+		logger.Errorf("Failed to import rules: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleImportErrorInvalid, map[string]any{
+			"Error": err.Error(),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+	key := i18nk.BotMsgRuleImportInfoMerged
+	if replace {
+		key = i18nk.BotMsgRuleImportInfoReplaced
+	}
+	ctx.Reply(update, ext.ReplyTextString(i18n.T(key, map[string]any{
+		"Rules": result.Rules,
+		"Dirs":  result.Dirs,
+	})), nil)
+	return dispatcher.EndGroups
+}
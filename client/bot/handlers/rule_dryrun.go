@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/mediautil"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+)
+
+// /rule test: 对回复的消息, 文件名或链接试运行规则, 逐条说明每个规则的结果, 不会创建任务
+func handleRuleTest(ctx *ext.Context, update *ext.Update, user *database.User, args []string) error {
+	var (
+		in       *rule.Input
+		file     tfile.TGFileMessage
+		isLink   bool
+		subject  string
+		fileName string
+	)
+	if target := strings.TrimSpace(strings.Join(args, " ")); target != "" {
+		// /rule test <filename|url>
+		if u, err := url.Parse(target); err == nil && u.Scheme != "" && u.Host != "" {
+			in = ruleutil.InputFromURL(target)
+			isLink = true
+		} else {
+			in = &rule.Input{FileName: target, FileSize: -1}
+		}
+		subject = target
+		fileName = in.FileName
+	} else {
+		replyTo := update.EffectiveMessage.ReplyToMessage
+		if replyTo == nil || replyTo.Message == nil || !mediautil.IsSupported(replyTo.Media) {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgRuleTestPromptReply, nil)), nil)
+			return dispatcher.EndGroups
+		}
+		var err error
+		file, err = tfile.FromMediaMessage(replyTo.Media, ctx.Raw, replyTo.Message, mediautil.TfileOptions(ctx, user, replyTo.Message)...)
+		if err != nil {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorGetFileFailed, map[string]any{
+				"Error": err.Error(),
+			})), nil)
+			return dispatcher.EndGroups
+		}
+		in = ruleutil.InputFromFile(ctx, file)
+		subject = file.Name()
+		fileName = file.Name()
+	}
+
+	var sb strings.Builder
+	if !user.ApplyRule {
+		sb.WriteString(i18n.T(i18nk.BotMsgRuleTestModeDisabled, nil))
+	}
+	sb.WriteString(i18n.T(i18nk.BotMsgRuleTestHeader, map[string]any{
+		"Target": subject,
+	}))
+	evals := ruleutil.Evaluate(user.Rules, in)
+	if len(evals) == 0 {
+		sb.WriteString(i18n.T(i18nk.BotMsgRuleTestNoRules, nil))
+	}
+	// 逐条展示每个规则的结果, 与实际保存时一样由第一个匹配的规则决定
+	var winner *rule.Rule
+	for _, ev := range evals {
+		switch {
+		case ev.Err != nil:
+			sb.WriteString(i18n.T(i18nk.BotMsgRuleTestRuleFailed, map[string]any{
+				"ID":    ev.Rule.ID,
+				"Error": ev.Err.Error(),
+			}))
+		case ev.Matched:
+			sb.WriteString(i18n.T(i18nk.BotMsgRuleTestRuleMatched, map[string]any{
+				"ID":      ev.Rule.ID,
+				"Reasons": rule.FormatReasons(ev.Reasons),
+			}))
+			if winner == nil {
+				winner = ev.Compiled
+			}
+		default:
+			sb.WriteString(i18n.T(i18nk.BotMsgRuleTestRuleNotMatched, map[string]any{
+				"ID":      ev.Rule.ID,
+				"Reasons": rule.FormatReasons(ev.Reasons),
+			}))
+		}
+	}
+	if !user.ApplyRule {
+		winner = nil
+	}
+	if winner != nil && winner.Actions.Skip {
+		sb.WriteString(i18n.T(i18nk.BotMsgRuleTestSkipped, map[string]any{
+			"ID": winner.ID,
+		}))
+		ctx.Reply(update, ext.ReplyTextString(sb.String()), nil)
+		return dispatcher.EndGroups
+	}
+
+	// 静默模式下与 handleSilentMode 一样使用来源聊天或用户的默认存储和目录, 否则由用户在保存时选择
+	storageName := i18n.T(i18nk.BotMsgRuleTestStorageChosen, nil)
+	dirPath := ""
+	if user.Silent {
+		if _, cd := chatDefaultStorage(ctx, user, silentSourceChats(ctx, update)...); cd != nil {
+			storageName = cd.StorageName
+			dirPath = cd.DirPath
+		} else if user.DefaultStorage != "" {
+			storageName = user.DefaultStorage
+			if user.DefaultDir != 0 {
+				if dir, err := database.GetDirByID(ctx, user.DefaultDir); err == nil {
+					dirPath = dir.Path
+				}
+			}
+		}
+	}
+	if winner == nil {
+		sb.WriteString(i18n.T(i18nk.BotMsgRuleTestNoMatch, nil))
+	} else {
+		sb.WriteString(i18n.T(i18nk.BotMsgRuleTestRuleUsed, map[string]any{
+			"ID": winner.ID,
+		}))
+		if stor := ruleutil.MatchedStorName(winner.StorageName); stor.Usable() {
+			storageName = stor.String()
+		}
+		switch dir := ruleutil.MatchedDirPath(winner.DirPath); {
+		case dir.NeedNewForAlbum():
+			if in.IsAlbum {
+				dirPath = path.Join(dirPath, i18n.T(i18nk.BotMsgRuleTestAlbumDir, nil))
+			}
+		case dir != "":
+			dirPath = dir.String()
+		}
+		// 重命名只对 Telegram 文件生效
+		if winner.Actions.Rename != "" && !isLink {
+			if file != nil {
+				ruleutil.Rename(ctx, winner, file)
+				fileName = file.Name()
+			} else {
+				data := &fnametmpl.Data{}
+				data.SetFile(fileName, 0)
+				if name, err := fnametmpl.Render(winner.Actions.Rename, data); err == nil {
+					fileName = name
+				}
+			}
+		}
+	}
+	if dirPath == "" {
+		dirPath = "/"
+	}
+	if fileName == "" {
+		fileName = i18n.T(i18nk.BotMsgRuleTestNameUnknown, nil)
+	}
+	sb.WriteString(i18n.T(i18nk.BotMsgRuleTestResult, map[string]any{
+		"Storage": storageName,
+		"Dir":     dirPath,
+		"Name":    fileName,
+	}))
+	if winner != nil && !winner.Actions.IsZero() {
+		sb.WriteString(i18n.T(i18nk.BotMsgRuleTestActions, map[string]any{
+			"Actions": winner.Actions.String(),
+		}))
+	}
+	ctx.Reply(update, ext.ReplyTextString(sb.String()), nil)
+	return dispatcher.EndGroups
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/krau/SaveAny-Bot/common/i18n"
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
 	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
 	"github.com/krau/SaveAny-Bot/pkg/tcbdata"
 	"github.com/krau/SaveAny-Bot/storage"
 )
@@ -24,7 +25,7 @@ func handleSilentCmd(ctx *ext.Context, update *ext.Update) error {
 		})), nil)
 		return nil
 	}
-	if !user.Silent && user.DefaultStorage == "" {
+	if !user.Silent && user.DefaultStorage == "" && len(user.ChatDefaults) == 0 {
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorDefaultStorageNotSet, nil)), nil)
 		return nil
 	}
@@ -35,6 +36,7 @@ func handleSilentCmd(ctx *ext.Context, update *ext.Update) error {
 		})), nil)
 		return nil
 	}
+	audit.Record(ctx, audit.ActionSettingsUpdate, "silent", map[string]any{"silent": user.Silent})
 	if user.Silent {
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonInfoSilentModeOn, nil)), nil)
 	} else {
@@ -118,6 +120,11 @@ func handleSetDefaultCallback(ctx *ext.Context, update *ext.Update) error {
 			"Error": err.Error(),
 		}))
 	}
+	details := map[string]any{}
+	if dir != nil {
+		details["dir"] = dir.Path
+	}
+	audit.Record(ctx, audit.ActionStorageDefault, selectedStorage.Name(), details)
 	msg := i18n.T(i18nk.BotMsgCommonInfoDefaultStorageSet, map[string]any{
 		"Name": selectedStorage.Name(),
 	})
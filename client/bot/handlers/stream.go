@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/common/utils/streamutil"
+)
+
+func handleStreamCmd(ctx *ext.Context, update *ext.Update) error {
+	replyTo := update.EffectiveMessage.ReplyToMessage
+	if replyTo == nil || replyTo.Message == nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgStreamUsage, nil)), nil)
+		return dispatcher.EndGroups
+	}
+	if _, ok := replyTo.Message.GetMedia(); !ok {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgStreamErrorNoMedia, nil)), nil)
+		return dispatcher.EndGroups
+	}
+	link, expiresAt, err := streamutil.URL(update.EffectiveChat().GetID(), replyTo.Message.ID)
+	if err != nil {
+		log.FromContext(ctx).Errorf("Failed to generate stream link: %v", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgStreamErrorLinkFailed, map[string]any{
+			"Error": err.Error(),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+	ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgStreamInfoLink, map[string]any{
+		"URL":    link,
+		"Expire": expiresAt.Format(time.DateTime),
+	})), nil)
+	return dispatcher.EndGroups
+}
@@ -101,6 +101,21 @@ func handleTransferCmd(ctx *ext.Context, update *ext.Update) error {
 		}
 	}
 
+	return editTransferSelectStorage(ctx, userID, replied.ID, sourceStorageName, sourcePath, files, filter)
+}
+
+// editTransferSelectStorage filters the listed files and edits msgID into the
+// target storage selection of a transfer task.
+func editTransferSelectStorage(
+	ctx *ext.Context,
+	userID int64,
+	msgID int,
+	sourceStorageName, sourcePath string,
+	files []storagetypes.FileInfo,
+	filter *regexp.Regexp,
+) error {
+	logger := log.FromContext(ctx)
+
 	// Filter files
 	filteredFiles := make([]storagetypes.FileInfo, 0)
 	for _, file := range files {
@@ -114,8 +129,8 @@ func handleTransferCmd(ctx *ext.Context, update *ext.Update) error {
 	}
 
 	if len(filteredFiles) == 0 {
-		ctx.EditMessage(update.EffectiveChat().GetID(), &tg.MessagesEditMessageRequest{
-			ID:      replied.ID,
+		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+			ID:      msgID,
 			Message: i18n.T(i18nk.BotMsgTransferErrorNoFilesToTransfer, nil),
 		})
 		return dispatcher.EndGroups
@@ -138,15 +153,15 @@ func handleTransferCmd(ctx *ext.Context, update *ext.Update) error {
 	})
 	if err != nil {
 		logger.Errorf("Failed to build storage selection keyboard: %s", err)
-		ctx.EditMessage(update.EffectiveChat().GetID(), &tg.MessagesEditMessageRequest{
-			ID:      replied.ID,
+		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+			ID:      msgID,
 			Message: i18n.T(i18nk.BotMsgTransferErrorBuildStorageSelectKeyboardFailed, map[string]any{"Error": err}),
 		})
 		return dispatcher.EndGroups
 	}
 
-	ctx.EditMessage(update.EffectiveChat().GetID(), &tg.MessagesEditMessageRequest{
-		ID: replied.ID,
+	ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+		ID: msgID,
 		Message: i18n.T(i18nk.BotMsgTransferInfoFilesSelectStorage, map[string]any{
 			"Count":  len(filteredFiles),
 			"SizeMB": fmt.Sprintf("%.2f", float64(totalSize)/(1024*1024)),
@@ -1,18 +1,22 @@
 package mediautil
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 	"strings"
-	"text/template"
 	"time"
 
+	"github.com/celestix/gotgproto/ext"
 	"github.com/charmbracelet/log"
 	"github.com/gotd/td/tg"
 	"github.com/krau/SaveAny-Bot/common/utils/strutil"
 	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
 	"github.com/krau/SaveAny-Bot/database"
 	"github.com/krau/SaveAny-Bot/pkg/enums/fnamest"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
 	"github.com/krau/SaveAny-Bot/pkg/tfile"
 )
 
@@ -25,28 +29,6 @@ func IsSupported(media tg.MessageMediaClass) bool {
 	}
 }
 
-type FilenameTemplateData struct {
-	MsgID    string `json:"msgid,omitempty"`
-	MsgTags  string `json:"msgtags,omitempty"`
-	MsgGen   string `json:"msggen,omitempty"`
-	MsgDate  string `json:"msgdate,omitempty"`
-	MsgRaw   string `json:"msgraw,omitempty"`
-	OrigName string `json:"origname,omitempty"`
-	ChatID   string `json:"chatid,omitempty"`
-}
-
-func (f FilenameTemplateData) ToMap() map[string]string {
-	return map[string]string{
-		"msgid":    f.MsgID,
-		"msgtags":  f.MsgTags,
-		"msggen":   f.MsgGen,
-		"msgraw":   f.MsgRaw,
-		"msgdate":  f.MsgDate,
-		"origname": f.OrigName,
-		"chatid":   f.ChatID,
-	}
-}
-
 func TfileOptions(ctx context.Context, user *database.User, message *tg.Message) []tfile.TGFileOption {
 	opts := make([]tfile.TGFileOption, 0)
 	var fnameOpt tfile.TGFileOption
@@ -59,21 +41,13 @@ func TfileOptions(ctx context.Context, user *database.User, message *tg.Message)
 			fnameOpt = tfile.WithNameIfEmpty(tgutil.GenFileNameFromMessage(*message))
 			break
 		}
-		tmpl, err := template.New("filename").Parse(user.FilenameTemplate)
-		if err != nil {
-			log.FromContext(ctx).Errorf("failed to parse filename template: %s", err)
-			fnameOpt = tfile.WithNameIfEmpty(tgutil.GenFileNameFromMessage(*message))
-			break
-		}
-		data := BuildFilenameTemplateData(message)
-		var sb strings.Builder
-		err = tmpl.Execute(&sb, data)
+		name, err := fnametmpl.Render(user.FilenameTemplate, BuildTemplateData(ctx, message))
 		if err != nil {
-			log.FromContext(ctx).Errorf("failed to execute filename template: %s", err)
+			log.FromContext(ctx).Errorf("failed to render filename template: %s", err)
 			fnameOpt = tfile.WithNameIfEmpty(tgutil.GenFileNameFromMessage(*message))
 			break
 		}
-		fnameOpt = tfile.WithName(sb.String())
+		fnameOpt = tfile.WithName(name)
 	default:
 		fnameOpt = tfile.WithNameIfEmpty(tgutil.GenFileNameFromMessage(*message))
 	}
@@ -81,62 +55,203 @@ func TfileOptions(ctx context.Context, user *database.User, message *tg.Message)
 	return opts
 }
 
-func BuildFilenameTemplateData(message *tg.Message) map[string]string {
-	data := FilenameTemplateData{
-		MsgID: func() string {
-			id := message.GetID()
-			if id == 0 {
-				return ""
-			}
-			return fmt.Sprintf("%d", id)
-		}(),
-		MsgTags: func() string {
-			tags := strutil.ExtractTagsFromText(message.GetMessage())
-			if len(tags) == 0 {
-				return ""
-			}
-			return strings.Join(tags, "_")
-		}(),
-		MsgGen: tgutil.GenFileNameFromMessage(*message),
-		OrigName: func() string {
-			f, _ := tgutil.GetMediaFileName(message.Media)
-			return f
-		}(),
-		MsgDate: func() string {
-			date := message.GetDate()
-			if date == 0 {
-				return ""
-			}
-			t := time.Unix(int64(date), 0)
-			return t.Format("2006-01-02_15-04-05")
-		}(),
-		MsgRaw: message.GetMessage(),
-		ChatID: func() string {
-			// 如果消息是频道的(从消息链接中fetch的) 直接使用其chat id,
-			// 无论它是否是从其他来源转发的
-			if message.GetPost() {
-				peer := message.GetPeerID()
-				switch p := peer.(type) {
-				case *tg.PeerChannel:
-					return intToStringOmitZero(p.ChannelID)
-				default: // impossible case
-					return intToStringOmitZero(tgutil.ChatIdFromPeer(peer))
-				}
-			}
-			fwdHeader, ok := message.GetFwdFrom()
-			if !ok {
-				return intToStringOmitZero(tgutil.ChatIdFromPeer(message.GetPeerID()))
-			}
-			fwdFrom, ok := fwdHeader.GetFromID()
-			if !ok {
-				return intToStringOmitZero(tgutil.ChatIdFromPeer(message.GetPeerID()))
-			}
-			return intToStringOmitZero(tgutil.ChatIdFromPeer(fwdFrom))
-		}(),
-	}.ToMap()
+// BuildTemplateData builds the filename template data of a message. Names of
+// chats and users are looked up with the client in ctx when available.
+func BuildTemplateData(ctx context.Context, message *tg.Message) *fnametmpl.Data {
+	data := &fnametmpl.Data{
+		MsgID:   intToStringOmitZero(int64(message.GetID())),
+		MsgTags: strings.Join(strutil.ExtractTagsFromText(message.GetMessage()), "_"),
+		MsgGen:  tgutil.GenFileNameFromMessage(*message),
+		MsgRaw:  message.GetMessage(),
+		ChatID:  templateChatID(message),
+	}
+	if date := message.GetDate(); date != 0 {
+		data.Time = time.Unix(int64(date), 0)
+		data.MsgDate = data.Time.Format("2006-01-02_15-04-05")
+	}
+	origName, _ := tgutil.GetMediaFileName(message.Media)
+	data.SetFile(origName, mediaSize(message.Media))
+	mediaType, _ := MediaInfo(message.Media)
+	data.MediaType = string(mediaType)
+
+	extCtx, _ := ctx.(*ext.Context)
+	if extCtx == nil {
+		extCtx = tgutil.ExtFromContext(ctx)
+	}
+	chatID := tgutil.ChatIdFromPeer(message.GetPeerID())
+	data.ChatTitle = fnametmpl.Sanitize(tgutil.PeerName(extCtx, chatID))
+	data.ChatUsername = PeerUsername(extCtx, chatID)
+	switch {
+	case message.GetPost():
+		// 频道消息的发送者为频道本身, 有署名时使用署名
+		data.SenderName = data.ChatTitle
+		if author, ok := message.GetPostAuthor(); ok && author != "" {
+			data.SenderName = fnametmpl.Sanitize(author)
+		}
+		data.SenderUsername = data.ChatUsername
+	default:
+		senderID := chatID
+		if from, ok := message.GetFromID(); ok {
+			senderID = tgutil.ChatIdFromPeer(from)
+		}
+		data.SenderName = fnametmpl.Sanitize(tgutil.PeerName(extCtx, senderID))
+		data.SenderUsername = PeerUsername(extCtx, senderID)
+	}
+	if fwd, ok := message.GetFwdFrom(); ok {
+		if name, ok := fwd.GetFromName(); ok && name != "" {
+			data.ForwardFrom = fnametmpl.Sanitize(name)
+		} else if from, ok := fwd.GetFromID(); ok {
+			data.ForwardFrom = fnametmpl.Sanitize(tgutil.PeerName(extCtx, tgutil.ChatIdFromPeer(from)))
+		}
+	}
 	return data
 }
 
+// 如果消息是频道的(从消息链接中fetch的) 直接使用其chat id,
+// 无论它是否是从其他来源转发的, 否则优先使用转发来源的 chat id
+func templateChatID(message *tg.Message) string {
+	if message.GetPost() {
+		peer := message.GetPeerID()
+		switch p := peer.(type) {
+		case *tg.PeerChannel:
+			return intToStringOmitZero(p.ChannelID)
+		default: // impossible case
+			return intToStringOmitZero(tgutil.ChatIdFromPeer(peer))
+		}
+	}
+	fwdHeader, ok := message.GetFwdFrom()
+	if !ok {
+		return intToStringOmitZero(tgutil.ChatIdFromPeer(message.GetPeerID()))
+	}
+	fwdFrom, ok := fwdHeader.GetFromID()
+	if !ok {
+		return intToStringOmitZero(tgutil.ChatIdFromPeer(message.GetPeerID()))
+	}
+	return intToStringOmitZero(tgutil.ChatIdFromPeer(fwdFrom))
+}
+
+// BuildFileTemplateData builds the template data of a file, using its current
+// name and size.
+func BuildFileTemplateData(ctx context.Context, file tfile.TGFileMessage) *fnametmpl.Data {
+	data := &fnametmpl.Data{}
+	if msg := file.Message(); msg != nil {
+		data = BuildTemplateData(ctx, msg)
+	}
+	if data.OrigName == "" {
+		data.OrigName = file.Name()
+	}
+	data.SetFile(file.Name(), file.Size())
+	return data
+}
+
+// ResolveDir renders a directory template for a file. Paths without template
+// actions are returned unchanged; when rendering fails the static prefix of
+// the template is used.
+func ResolveDir(ctx context.Context, dirPath string, file tfile.TGFileMessage) string {
+	if !fnametmpl.IsTemplate(dirPath) {
+		return dirPath
+	}
+	dir, err := fnametmpl.RenderDir(dirPath, BuildFileTemplateData(ctx, file))
+	if err != nil {
+		log.FromContext(ctx).Warnf("Failed to render directory template %q: %s", dirPath, err)
+		return fnametmpl.StaticPrefix(dirPath)
+	}
+	return dir
+}
+
+// RenameAlbum renders the filename template of the user again for the files
+// of an album, with their index in the album. Only needed when the template
+// uses AlbumIndex.
+func RenameAlbum(ctx context.Context, user *database.User, files []tfile.TGFileMessage) {
+	if user.FilenameStrategy != fnamest.Template.String() || !strings.Contains(user.FilenameTemplate, "AlbumIndex") {
+		return
+	}
+	tmpl, err := fnametmpl.Parse(user.FilenameTemplate)
+	if err != nil {
+		return
+	}
+	sorted := slices.Clone(files)
+	slices.SortStableFunc(sorted, func(a, b tfile.TGFileMessage) int {
+		return cmp.Compare(a.Message().GetID(), b.Message().GetID())
+	})
+	for i, file := range sorted {
+		if file.Message() == nil {
+			continue
+		}
+		data := BuildTemplateData(ctx, file.Message())
+		data.AlbumIndex = i + 1
+		name, err := tmpl.Execute(data)
+		if err != nil {
+			log.FromContext(ctx).Errorf("failed to render filename template: %s", err)
+			continue
+		}
+		file.SetName(name)
+	}
+}
+
+func mediaSize(media tg.MessageMediaClass) int64 {
+	if m, ok := media.(*tg.MessageMediaDocument); ok {
+		if doc, ok := m.Document.(*tg.Document); ok {
+			return doc.Size
+		}
+	}
+	return 0
+}
+
+// PeerUsername returns the username of a peer known to the peer storage, or an
+// empty string.
+func PeerUsername(extCtx *ext.Context, id int64) string {
+	if extCtx == nil || extCtx.PeerStorage == nil || id == 0 {
+		return ""
+	}
+	peer := extCtx.PeerStorage.GetPeerById(id)
+	if peer == nil {
+		return ""
+	}
+	return peer.Username
+}
+
+// MediaInfo returns the media type and MIME type of a message media.
+func MediaInfo(media tg.MessageMediaClass) (rule.MediaType, string) {
+	switch m := media.(type) {
+	case *tg.MessageMediaPhoto:
+		return rule.MediaPhoto, "image/jpeg"
+	case *tg.MessageMediaDocument:
+		doc, ok := m.Document.(*tg.Document)
+		if !ok {
+			return rule.MediaDocument, ""
+		}
+		var sticker, animated, video, audio, voice bool
+		for _, attr := range doc.Attributes {
+			switch a := attr.(type) {
+			case *tg.DocumentAttributeSticker:
+				sticker = true
+			case *tg.DocumentAttributeAnimated:
+				animated = true
+			case *tg.DocumentAttributeVideo:
+				video = true
+			case *tg.DocumentAttributeAudio:
+				audio = true
+				voice = a.Voice
+			}
+		}
+		switch {
+		case sticker:
+			return rule.MediaSticker, doc.MimeType
+		case animated:
+			return rule.MediaAnimation, doc.MimeType
+		case video:
+			return rule.MediaVideo, doc.MimeType
+		case voice:
+			return rule.MediaVoice, doc.MimeType
+		case audio:
+			return rule.MediaAudio, doc.MimeType
+		}
+		return rule.MediaDocument, doc.MimeType
+	}
+	return "", ""
+}
+
 func intToStringOmitZero(i int64) string {
 	if i == 0 {
 		return ""
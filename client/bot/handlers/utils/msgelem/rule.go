@@ -1,10 +1,13 @@
 package msgelem
 
 import (
+	"cmp"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/gotd/td/telegram/message/styling"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
 	"github.com/krau/SaveAny-Bot/common/i18n"
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
 	"github.com/krau/SaveAny-Bot/database"
@@ -24,6 +27,14 @@ func BuildRuleHelpStyling(enabled bool, rules []database.Rule) []styling.StyledT
 		styling.Plain(i18n.T(i18nk.BotMsgRuleHelpSwitchSuffix, nil)),
 		styling.Code("add"),
 		styling.Plain(i18n.T(i18nk.BotMsgRuleHelpAddSuffix, nil)),
+		styling.Code("test"),
+		styling.Plain(i18n.T(i18nk.BotMsgRuleHelpTestSuffix, nil)),
+		styling.Code("priority"),
+		styling.Plain(i18n.T(i18nk.BotMsgRuleHelpPrioritySuffix, nil)),
+		styling.Code("export"),
+		styling.Plain(i18n.T(i18nk.BotMsgRuleHelpExportSuffix, nil)),
+		styling.Code("import"),
+		styling.Plain(i18n.T(i18nk.BotMsgRuleHelpImportSuffix, nil)),
 		styling.Code("preset"),
 		styling.Plain(i18n.T(i18nk.BotMsgRuleHelpPresetSuffix, nil)),
 		styling.Code("del"),
@@ -31,8 +42,22 @@ func BuildRuleHelpStyling(enabled bool, rules []database.Rule) []styling.StyledT
 		styling.Plain(i18n.T(i18nk.BotMsgRuleHelpExistingRulesPrefix, nil)),
 		styling.Blockquote(func() string {
 			var sb strings.Builder
-			for _, rule := range rules {
+			// 按匹配顺序列出
+			sorted := slices.Clone(rules)
+			slices.SortStableFunc(sorted, func(a, b database.Rule) int {
+				if c := cmp.Compare(b.Priority, a.Priority); c != 0 {
+					return c
+				}
+				return cmp.Compare(b.ID, a.ID)
+			})
+			for _, rule := range sorted {
 				ruleText := fmt.Sprintf("%s %s %s %s", rule.Type, rule.Data, rule.StorageName, rule.DirPath)
+				if rule.Priority != 0 {
+					ruleText = fmt.Sprintf("[%d] %s", rule.Priority, ruleText)
+				}
+				if actions := ruleutil.ActionsOf(rule); !actions.IsZero() {
+					ruleText += " " + actions.String()
+				}
 				sb.WriteString(fmt.Sprintf("%d: %s\n", rule.ID, ruleText))
 			}
 			return sb.String()
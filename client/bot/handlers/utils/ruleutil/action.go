@@ -0,0 +1,147 @@
+package ruleutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/conflictutil"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/mediautil"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/core"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+)
+
+// RenderName renders a rename template for a Telegram file. The template data
+// is the same as the filename template of the user.
+func RenderName(ctx context.Context, tmpl string, file tfile.TGFileMessage) (string, error) {
+	name, err := fnametmpl.Render(tmpl, mediautil.BuildFileTemplateData(ctx, file))
+	if err != nil {
+		return "", fmt.Errorf("failed to render filename template: %w", err)
+	}
+	return name, nil
+}
+
+// Rename applies the rename action of a matched rule to the file. Failures are
+// logged and keep the original name.
+func Rename(ctx context.Context, ru *rule.Rule, file tfile.TGFileMessage) {
+	if ru == nil || ru.Actions.Rename == "" {
+		return
+	}
+	name, err := RenderName(ctx, ru.Actions.Rename, file)
+	if err != nil {
+		log.FromContext(ctx).Warnf("Failed to rename %s by rule %d: %s", file.Name(), ru.ID, err)
+		return
+	}
+	file.SetName(name)
+}
+
+type actionGroup struct {
+	rule    rule.Rule
+	storage string
+	paths   []string
+}
+
+// ActionSet collects the files of a task by the rule that matched them, to run
+// the post-processors and notifications of the rules after the task succeeds.
+type ActionSet struct {
+	extCtx     *ext.Context
+	userChatID int64
+	groups     []*actionGroup
+}
+
+// NewActionSet returns an action set for the rules of the user with the chat ID.
+func NewActionSet(extCtx *ext.Context, userChatID int64) *ActionSet {
+	return &ActionSet{extCtx: extCtx, userChatID: userChatID}
+}
+
+// Add records a file saved by the rule. Rules without a post-processor or a
+// notification are ignored.
+func (s *ActionSet) Add(ru *rule.Rule, storageName, filePath string) {
+	if ru == nil || (ru.Actions.PostProcess == "" && ru.Actions.NotifyChat == 0) {
+		return
+	}
+	for _, g := range s.groups {
+		if g.rule.ID == ru.ID && g.storage == storageName {
+			g.paths = append(g.paths, filePath)
+			return
+		}
+	}
+	s.groups = append(s.groups, &actionGroup{rule: *ru, storage: storageName, paths: []string{filePath}})
+}
+
+// Attach registers the actions as a task event sink on the task context.
+func (s *ActionSet) Attach(ctx context.Context) context.Context {
+	if len(s.groups) == 0 {
+		return ctx
+	}
+	return taskevent.WithSink(ctx, &actionSink{extCtx: s.extCtx, userChatID: s.userChatID, groups: s.groups})
+}
+
+type actionSink struct {
+	extCtx     *ext.Context
+	userChatID int64
+	groups     []*actionGroup
+	once       sync.Once
+}
+
+func (a *actionSink) Emit(e taskevent.Event) {
+	if e.Phase != taskevent.PhaseDone || e.Err != nil {
+		return
+	}
+	// 后处理可能耗时较长, 不阻塞任务队列
+	a.once.Do(func() { go a.run() })
+}
+
+func (a *actionSink) run() {
+	logger := log.FromContext(a.extCtx)
+	for _, g := range a.groups {
+		act := g.rule.Actions
+		if act.PostProcess != "" {
+			cmd, ok := config.C().Hook.PostProcess[act.PostProcess]
+			if !ok {
+				logger.Warnf("Post-processor %s of rule %d is not configured", act.PostProcess, g.rule.ID)
+			}
+			for _, p := range g.paths {
+				if !ok {
+					break
+				}
+				env := []string{
+					"SAB_RULE_ID=" + fmt.Sprint(g.rule.ID),
+					"SAB_STORAGE=" + g.storage,
+					"SAB_PATH=" + p,
+				}
+				if err := core.ExecCommandStringWithEnv(a.extCtx, cmd, env); err != nil {
+					logger.Errorf("Post-processor %s failed for %s: %s", act.PostProcess, p, err)
+				}
+			}
+		}
+		// 规则创建后配置可能已修改, 发送前再次检查
+		if act.NotifyChat != 0 && !CanNotify(a.userChatID, act.NotifyChat) {
+			logger.Warnf("Chat %d of rule %d can not be notified", act.NotifyChat, g.rule.ID)
+			continue
+		}
+		if act.NotifyChat != 0 {
+			files := make([]string, 0, len(g.paths))
+			for _, p := range g.paths {
+				files = append(files, conflictutil.FormatPath(g.storage, p))
+			}
+			if _, err := a.extCtx.SendMessage(act.NotifyChat, &tg.MessagesSendMessageRequest{
+				Message: i18n.T(i18nk.BotMsgRuleNotifySaved, map[string]any{
+					"ID":    g.rule.ID,
+					"Files": conflictutil.FormatPaths(files),
+				}),
+			}); err != nil {
+				logger.Errorf("Failed to notify chat %d for rule %d: %s", act.NotifyChat, g.rule.ID, err)
+			}
+		}
+	}
+}
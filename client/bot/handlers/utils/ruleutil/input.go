@@ -0,0 +1,98 @@
+package ruleutil
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/celestix/gotgproto/ext"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/mediautil"
+	"github.com/krau/SaveAny-Bot/common/utils/strutil"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/pkg/parser"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+)
+
+// InputFromFile collects the rule attributes of a Telegram file and its
+// message. Usernames are looked up in the peer storage of the client in ctx.
+func InputFromFile(ctx context.Context, file tfile.TGFileMessage) *rule.Input {
+	in := &rule.Input{
+		FileName: file.Name(),
+		FileSize: file.Size(),
+	}
+	msg := file.Message()
+	if msg == nil {
+		return in
+	}
+	extCtx, _ := ctx.(*ext.Context)
+	if extCtx == nil {
+		extCtx = tgutil.ExtFromContext(ctx)
+	}
+
+	in.MediaType, in.MIMEType = mediautil.MediaInfo(msg.Media)
+	in.ChatID = tgutil.ChatIdFromPeer(msg.GetPeerID())
+	in.ChatUsername = mediautil.PeerUsername(extCtx, in.ChatID)
+	if from, ok := msg.GetFromID(); ok {
+		in.SenderID = tgutil.ChatIdFromPeer(from)
+	} else if _, ok := msg.GetPeerID().(*tg.PeerUser); ok {
+		in.SenderID = in.ChatID
+	}
+	in.SenderUsername = mediautil.PeerUsername(extCtx, in.SenderID)
+	if fwd, ok := msg.GetFwdFrom(); ok {
+		in.Forwarded = true
+		if from, ok := fwd.GetFromID(); ok {
+			in.ForwardFromID = tgutil.ChatIdFromPeer(from)
+			in.ForwardFromUsername = mediautil.PeerUsername(extCtx, in.ForwardFromID)
+		}
+		in.ForwardFromName, _ = fwd.GetFromName()
+	}
+	in.Text = msg.GetMessage()
+	in.Tags = strutil.ExtractTagsFromText(in.Text)
+	if date := msg.GetDate(); date != 0 {
+		in.Date = time.Unix(int64(date), 0)
+	}
+	in.IsAlbum = msg.GroupedID != 0
+	return in
+}
+
+// InputFromURL collects the rule attributes of a link. The file name is taken
+// from the last element of the URL path.
+func InputFromURL(rawURL string) *rule.Input {
+	in := &rule.Input{
+		FileSize: -1,
+		URL:      rawURL,
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		if name := path.Base(u.Path); name != "/" && name != "." {
+			in.FileName = name
+		}
+	}
+	return in
+}
+
+// InputFromItem collects the rule attributes of a parsed item. File attributes
+// are taken from its first resource.
+func InputFromItem(item *parser.Item) *rule.Input {
+	in := InputFromURL(item.URL)
+	in.FileName = ""
+	in.Site = item.Site
+	in.Author = item.Author
+	in.Tags = item.Tags
+	in.Text = strings.TrimSpace(item.Title + "\n" + item.Description)
+	if len(item.Resources) > 0 {
+		res := item.Resources[0]
+		in.FileName = res.Filename
+		if in.FileName == "" && res.Extension != "" {
+			in.FileName = "." + strings.TrimPrefix(res.Extension, ".")
+		}
+		in.MIMEType = res.MimeType
+		if res.Size > 0 {
+			in.FileSize = res.Size
+		}
+	}
+	return in
+}
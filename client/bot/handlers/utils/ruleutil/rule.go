@@ -3,8 +3,6 @@ package ruleutil
 import (
 	"context"
 
-	"github.com/duke-git/lancet/v2/convertor"
-
 	"github.com/charmbracelet/log"
 	"github.com/krau/SaveAny-Bot/database"
 	"github.com/krau/SaveAny-Bot/pkg/rule"
@@ -27,14 +25,14 @@ func NewInput(file tfile.TGFileMessage, opts ...ruleInputOption) *ruleInput {
 	return input
 }
 
-type matchedStorName string
+type MatchedStorName string
 
-func (m matchedStorName) String() string {
+func (m MatchedStorName) String() string {
 	return string(m)
 }
 
 // can we use this storage name directly?
-func (m matchedStorName) Usable() bool {
+func (m MatchedStorName) Usable() bool {
 	return m != "" && m != rule.RuleStorNameChosen
 }
 
@@ -48,66 +46,108 @@ func (m MatchedDirPath) NeedNewForAlbum() bool {
 	return m != "" && m == rule.RuleDirPathNewForAlbum
 }
 
-func ApplyRule(ctx context.Context, rules []database.Rule, inputs *ruleInput) (matched bool, matchedStorageName matchedStorName, dirPath MatchedDirPath) {
+// Compile compiles the stored rules in evaluation order. Rules that fail to
+// compile are logged and skipped.
+func Compile(ctx context.Context, rules []database.Rule) []rule.Rule {
+	compiled := make([]rule.Rule, 0, len(rules))
+	for _, ur := range rules {
+		ru, err := compileRule(ur)
+		if err != nil {
+			log.FromContext(ctx).Errorf("Failed to compile rule %d: %s", ur.ID, err)
+			continue
+		}
+		compiled = append(compiled, ru)
+	}
+	rule.Sort(compiled)
+	return compiled
+}
+
+// compileRule compiles a stored rule. On error the returned rule only has the
+// ID and the priority, so it can still be sorted.
+func compileRule(ur database.Rule) (rule.Rule, error) {
+	ru := rule.Rule{
+		ID:       ur.ID,
+		Priority: ur.Priority,
+	}
+	expr, err := rule.Compile(ur.Type, ur.Data)
+	if err != nil {
+		return ru, err
+	}
+	ru.StorageName = ur.StorageName
+	ru.DirPath = ur.DirPath
+	ru.Expr = expr
+	ru.Actions = ActionsOf(ur)
+	return ru, nil
+}
+
+// ActionsOf returns the actions stored in a rule.
+func ActionsOf(ur database.Rule) rule.Actions {
+	return rule.Actions{
+		Rename:      ur.Rename,
+		Skip:        ur.Skip,
+		Conflict:    ur.Conflict,
+		PostProcess: ur.PostProcess,
+		NotifyChat:  ur.NotifyChatID,
+	}
+}
+
+// Match evaluates the rules by priority and returns the first matching rule.
+func Match(ctx context.Context, rules []database.Rule, inputs *ruleInput) (*rule.Rule, bool) {
 	if inputs == nil || len(rules) == 0 {
+		return nil, false
+	}
+	return MatchInput(ctx, rules, InputFromFile(ctx, inputs.File))
+}
+
+// MatchInput is like Match, for sources that are not Telegram files.
+func MatchInput(ctx context.Context, rules []database.Rule, in *rule.Input) (*rule.Rule, bool) {
+	if in == nil || len(rules) == 0 {
+		return nil, false
+	}
+	return rule.First(Compile(ctx, rules), in)
+}
+
+// ApplyRule evaluates the rules by priority and returns the target of the
+// first matching rule.
+func ApplyRule(ctx context.Context, rules []database.Rule, inputs *ruleInput) (matched bool, matchedStorageName MatchedStorName, dirPath MatchedDirPath) {
+	ru, ok := Match(ctx, rules, inputs)
+	if !ok {
 		return false, "", ""
 	}
-	logger := log.FromContext(ctx)
+	return true, MatchedStorName(ru.StorageName), MatchedDirPath(ru.DirPath)
+}
+
+// Evaluation is the result of a single rule for an input, see Evaluate.
+type Evaluation struct {
+	Rule database.Rule
+	// Err is set when the rule fails to compile; such rules never match.
+	Err     error
+	Matched bool
+	Reasons []rule.Reason
+	// Compiled is set when the rule compiles.
+	Compiled *rule.Rule
+}
+
+// Evaluate evaluates every rule in priority order without stopping at the
+// first match, to explain how an input would be routed. The first matching
+// evaluation is the rule that Match would return.
+func Evaluate(rules []database.Rule, in *rule.Input) []Evaluation {
+	byID := make(map[uint]Evaluation, len(rules))
+	compiled := make([]rule.Rule, 0, len(rules))
 	for _, ur := range rules {
-		switch ur.Type {
-		case rule.FileNameRegex.String():
-			ru, err := rule.NewRuleFileNameRegex(ur.StorageName, ur.DirPath, ur.Data)
-			if err != nil {
-				logger.Errorf("Failed to create rule: %s", err)
-				continue
-			}
-			ok, err := ru.Match(inputs.File)
-			if err != nil {
-				logger.Errorf("Failed to match rule: %s", err)
-				continue
-			}
-			if ok {
-				dirPath = MatchedDirPath(ru.StoragePath())
-				matchedStorageName = matchedStorName(ru.StorageName())
-			}
-		case rule.MessageRegex.String():
-			ru, err := rule.NewRuleMessageRegex(ur.StorageName, ur.DirPath, ur.Data)
-			if err != nil {
-				logger.Errorf("Failed to create rule: %s", err)
-				continue
-			}
-			ok, err := ru.Match(inputs.File.Message().GetMessage())
-			if err != nil {
-				logger.Errorf("Failed to match rule: %s", err)
-				continue
-			}
-			if ok {
-				dirPath = MatchedDirPath(ru.StoragePath())
-				matchedStorageName = matchedStorName(ru.StorageName())
-			}
-		case rule.IsAlbum.String():
-			matchAlbum, err := convertor.ToBool(ur.Data)
-			if err != nil {
-				matchAlbum = false
-			}
-			ru, err := rule.NewRuleMediaType(ur.StorageName, ur.DirPath, matchAlbum)
-			if err != nil {
-				logger.Errorf("Failed to create rule: %s", err)
-				continue
-			}
-			ok, err := ru.Match(inputs.File.Message().GroupedID != 0)
-			if err != nil {
-				logger.Errorf("Failed to match rule: %s", err)
-				continue
-			}
-			if ok {
-				dirPath = MatchedDirPath(ru.StoragePath())
-				matchedStorageName = matchedStorName(ru.StorageName())
-			}
-		}
+		ru, err := compileRule(ur)
+		byID[ur.ID] = Evaluation{Rule: ur, Err: err}
+		compiled = append(compiled, ru)
 	}
-	if matchedStorageName != "" || dirPath != "" {
-		return true, matchedStorageName, dirPath
+	rule.Sort(compiled)
+	evals := make([]Evaluation, 0, len(compiled))
+	for i := range compiled {
+		ev := byID[compiled[i].ID]
+		if ev.Err == nil {
+			ev.Compiled = &compiled[i]
+			ev.Matched, ev.Reasons = rule.Explain(compiled[i].Expr, in)
+		}
+		evals = append(evals, ev)
 	}
-	return false, "", ""
+	return evals
 }
@@ -0,0 +1,161 @@
+package ruleutil
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/krau/SaveAny-Bot/pkg/ruleset"
+	"github.com/krau/SaveAny-Bot/pkg/tcbdata"
+)
+
+// CanNotify reports whether the rules of a user may message a chat: the chat
+// of the user, or one listed in the [hook] notify_chats config.
+func CanNotify(userChatID, chatID int64) bool {
+	return chatID == userChatID || slices.Contains(config.C().Hook.NotifyChats, chatID)
+}
+
+// ValidateActions checks the conflict strategy, post-processor, notified chat
+// and filename template referenced by the actions of a rule of a user.
+func ValidateActions(userChatID int64, actions rule.Actions) error {
+	if actions.Conflict != "" && !tcbdata.IsConflictStrategy(actions.Conflict) {
+		return fmt.Errorf("invalid conflict strategy %q, available: %s", actions.Conflict, strings.Join(tcbdata.ConflictStrategyValues(), ", "))
+	}
+	if actions.PostProcess != "" {
+		if _, ok := config.C().Hook.PostProcess[actions.PostProcess]; !ok {
+			return fmt.Errorf("post-processor %q is not configured in [hook.postprocess]", actions.PostProcess)
+		}
+	}
+	if actions.NotifyChat != 0 && !CanNotify(userChatID, actions.NotifyChat) {
+		return fmt.Errorf("chat %d can not be notified, use your own chat or a chat in the [hook] notify_chats config", actions.NotifyChat)
+	}
+	if actions.Rename != "" {
+		if _, err := fnametmpl.Parse(actions.Rename); err != nil {
+			return fmt.Errorf("invalid filename template: %w", err)
+		}
+	}
+	return nil
+}
+
+// DocumentFromUser builds a rule document from a user loaded with its rules
+// and dirs.
+func DocumentFromUser(user *database.User) *ruleset.Document {
+	applyRule := user.ApplyRule
+	doc := &ruleset.Document{
+		Version:          ruleset.Version,
+		ApplyRule:        &applyRule,
+		FilenameStrategy: user.FilenameStrategy,
+		FilenameTemplate: user.FilenameTemplate,
+		Dirs:             make([]ruleset.Dir, 0, len(user.Dirs)),
+		Rules:            make([]ruleset.Rule, 0, len(user.Rules)),
+	}
+	for _, d := range user.Dirs {
+		doc.Dirs = append(doc.Dirs, ruleset.Dir{Storage: d.StorageName, Path: d.Path})
+	}
+	for _, r := range user.Rules {
+		doc.Rules = append(doc.Rules, ruleset.Rule{
+			Type:        r.Type,
+			Data:        r.Data,
+			Storage:     r.StorageName,
+			Dir:         r.DirPath,
+			Priority:    r.Priority,
+			Rename:      r.Rename,
+			Skip:        r.Skip,
+			Conflict:    r.Conflict,
+			PostProcess: r.PostProcess,
+			NotifyChat:  r.NotifyChatID,
+		})
+	}
+	return doc
+}
+
+// RuleModel converts a rule of a document to a database row for the user.
+func RuleModel(r ruleset.Rule, userID uint) database.Rule {
+	return database.Rule{
+		UserID:       userID,
+		Type:         strings.ToUpper(r.Type),
+		Data:         r.Data,
+		StorageName:  r.Storage,
+		DirPath:      r.Dir,
+		Priority:     r.Priority,
+		Rename:       r.Rename,
+		Skip:         r.Skip,
+		Conflict:     r.Conflict,
+		PostProcess:  r.PostProcess,
+		NotifyChatID: r.NotifyChat,
+	}
+}
+
+// documentModels converts a document to database rows for the user.
+func documentModels(doc *ruleset.Document, userID uint) ([]database.Rule, []database.Dir) {
+	rules := make([]database.Rule, 0, len(doc.Rules))
+	for _, r := range doc.Rules {
+		rules = append(rules, RuleModel(r, userID))
+	}
+	dirs := make([]database.Dir, 0, len(doc.Dirs))
+	for _, dir := range doc.Dirs {
+		dirs = append(dirs, database.Dir{
+			UserID:      userID,
+			StorageName: dir.Storage,
+			Path:        dir.Path,
+		})
+	}
+	return rules, dirs
+}
+
+// ImportResult reports what an import added.
+type ImportResult struct {
+	Rules int `json:"rules"`
+	Dirs  int `json:"dirs"`
+}
+
+// Import validates the document against the storages of the user and the
+// config, then merges it into the settings of the user, or replaces them.
+// Nothing is written when any rule is invalid.
+func Import(ctx context.Context, user *database.User, doc *ruleset.Document, replace bool) (*ImportResult, error) {
+	hasStorage := func(name string) bool {
+		return config.C().HasStorage(user.ChatID, name)
+	}
+	checkActions := func(actions rule.Actions) error {
+		return ValidateActions(user.ChatID, actions)
+	}
+	if err := doc.Validate(hasStorage, checkActions); err != nil {
+		return nil, err
+	}
+	rules, dirs := documentModels(doc, user.ID)
+	settings := importedSettings(user, doc)
+	addedRules, addedDirs, err := database.ImportUserSettings(ctx, &settings, rules, dirs, replace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import settings: %w", err)
+	}
+	audit.Record(ctx, audit.ActionRuleImport, strconv.FormatInt(user.ChatID, 10), map[string]any{
+		"replace": replace,
+		"rules":   addedRules,
+		"dirs":    addedDirs,
+	})
+	return &ImportResult{Rules: addedRules, Dirs: addedDirs}, nil
+}
+
+// importedSettings returns the settings of the user with those set in the
+// document. Settings the document leaves out are kept, so merging a shared
+// document does not turn off the rules of the user.
+func importedSettings(user *database.User, doc *ruleset.Document) database.User {
+	settings := *user
+	if doc.ApplyRule != nil {
+		settings.ApplyRule = *doc.ApplyRule
+	}
+	if doc.FilenameStrategy != "" {
+		settings.FilenameStrategy = doc.FilenameStrategy
+	}
+	if doc.FilenameTemplate != "" {
+		settings.FilenameTemplate = doc.FilenameTemplate
+	}
+	return settings
+}
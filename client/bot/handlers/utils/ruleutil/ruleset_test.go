@@ -0,0 +1,59 @@
+package ruleutil
+
+import (
+	"testing"
+
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/ruleset"
+)
+
+func TestImportedSettingsKeepsApplyRule(t *testing.T) {
+	user := &database.User{ApplyRule: true, FilenameStrategy: "template", FilenameTemplate: "{{.msgid}}"}
+
+	// 共享的规则文件没有 apply_rule 时, 合并导入不会关闭用户的规则
+	doc, err := ruleset.Decode([]byte(`{"version": 1, "rules": []}`))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	got := importedSettings(user, doc)
+	if !got.ApplyRule || got.FilenameStrategy != "template" || got.FilenameTemplate != "{{.msgid}}" {
+		t.Errorf("settings changed by a document without them: %+v", got)
+	}
+
+	off := false
+	doc.ApplyRule = &off
+	if got := importedSettings(user, doc); got.ApplyRule {
+		t.Error("apply_rule = false in the document was not applied")
+	}
+	if !user.ApplyRule {
+		t.Error("the user was modified")
+	}
+}
+
+func TestDocumentModels(t *testing.T) {
+	user := &database.User{
+		ApplyRule: true,
+		Dirs:      []database.Dir{{StorageName: "local", Path: "/videos"}},
+		Rules: []database.Rule{
+			{Type: "FILENAME-REGEX", Data: `\.mp4$`, StorageName: "local", DirPath: "/videos", Priority: 5, Conflict: "rename"},
+			{Type: "EXPR", Data: `ext:pdf`, StorageName: "CHOSEN", DirPath: "/docs", Skip: true, NotifyChatID: -100123},
+		},
+	}
+	doc := DocumentFromUser(user)
+	if doc.ApplyRule == nil || !*doc.ApplyRule {
+		t.Errorf("apply_rule = %v, want true", doc.ApplyRule)
+	}
+	rules, dirs := documentModels(doc, 7)
+	if len(dirs) != 1 || dirs[0].UserID != 7 || dirs[0].StorageName != "local" || dirs[0].Path != "/videos" {
+		t.Errorf("dirs = %+v", dirs)
+	}
+	if len(rules) != len(user.Rules) {
+		t.Fatalf("got %d rules, want %d", len(rules), len(user.Rules))
+	}
+	for i, want := range user.Rules {
+		want.UserID = 7
+		if rules[i] != want {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want)
+		}
+	}
+}
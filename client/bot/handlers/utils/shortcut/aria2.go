@@ -5,6 +5,7 @@ import (
 	"github.com/celestix/gotgproto/ext"
 	"github.com/charmbracelet/log"
 	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
 	"github.com/krau/SaveAny-Bot/common/i18n"
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
 	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
@@ -32,6 +33,12 @@ func CreateAndAddAria2TaskWithEdit(ctx *ext.Context, stor storage.Storage, dirPa
 		return dispatcher.EndGroups
 	}
 
+	stor, dirPath, matchedRule, nameTmpl, ok := routeByRuleWithEdit(ctx, userID, msgID, stor, dirPath, ruleutil.InputFromURL(uris[0]))
+	if !ok {
+		return dispatcher.EndGroups
+	}
+	injectCtx = withRuleActions(ctx, injectCtx, userID, matchedRule, stor.Name(), dirPath)
+
 	gid, err := aria2Client.AddURI(ctx, uris, nil)
 	if err != nil {
 		logger.Errorf("Failed to add aria2 download: %s", err)
@@ -47,6 +54,7 @@ func CreateAndAddAria2TaskWithEdit(ctx *ext.Context, stor storage.Storage, dirPa
 
 	// Create task with the GID
 	task := aria2dl.NewTask(xid.New().String(), injectCtx, gid, uris, aria2Client, stor, dirPath, aria2dl.NewProgress(msgID, userID))
+	task.NameTmpl = nameTmpl
 	if err := core.AddTask(injectCtx, task); err != nil {
 		logger.Errorf("Failed to add task: %s", err)
 		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
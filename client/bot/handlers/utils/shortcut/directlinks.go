@@ -5,18 +5,34 @@ import (
 	"github.com/celestix/gotgproto/ext"
 	"github.com/charmbracelet/log"
 	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
 	"github.com/krau/SaveAny-Bot/common/i18n"
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
 	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
 	"github.com/krau/SaveAny-Bot/core"
 	"github.com/krau/SaveAny-Bot/core/tasks/directlinks"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
 	"github.com/krau/SaveAny-Bot/storage"
 	"github.com/rs/xid"
 )
 
 func CreateAndAddDirectTaskWithEdit(ctx *ext.Context, stor storage.Storage, dirPath string, links []string, msgID int, userID int64) error {
-	injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
+	var (
+		matchedRule *rule.Rule
+		nameTmpl    *fnametmpl.Template
+	)
+	if len(links) > 0 {
+		var ok bool
+		// 多个链接时以第一个链接为准
+		stor, dirPath, matchedRule, nameTmpl, ok = routeByRuleWithEdit(ctx, userID, msgID, stor, dirPath, ruleutil.InputFromURL(links[0]))
+		if !ok {
+			return dispatcher.EndGroups
+		}
+	}
+	injectCtx := withRuleActions(ctx, tgutil.ExtWithContext(ctx.Context, ctx), userID, matchedRule, stor.Name(), dirPath)
 	task := directlinks.NewTask(xid.New().String(), injectCtx, links, stor, dirPath, directlinks.NewProgress(msgID, userID))
+	task.NameTmpl = nameTmpl
 	if err := core.AddTask(injectCtx, task); err != nil {
 		log.FromContext(ctx).Errorf("Failed to add task: %s", err)
 		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
@@ -1,13 +1,17 @@
 package shortcut
 
 import (
+	"path"
+
 	"github.com/celestix/gotgproto/dispatcher"
 	"github.com/celestix/gotgproto/ext"
 	"github.com/charmbracelet/log"
 	"github.com/gotd/td/tg"
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/msgelem"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
 	"github.com/krau/SaveAny-Bot/common/i18n"
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/common/utils/fsutil"
 	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
 	"github.com/krau/SaveAny-Bot/core"
 	parsed "github.com/krau/SaveAny-Bot/core/tasks/parsed"
@@ -16,9 +20,18 @@ import (
 	"github.com/rs/xid"
 )
 
+// 多个资源时会在 dirPath 下以标题新建目录
 func CreateAndAddParsedTaskWithEdit(ctx *ext.Context, stor storage.Storage, dirPath string, item *parser.Item, msgID int, userID int64) error {
-	injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
+	stor, dirPath, matchedRule, nameTmpl, ok := routeByRuleWithEdit(ctx, userID, msgID, stor, dirPath, ruleutil.InputFromItem(item))
+	if !ok {
+		return dispatcher.EndGroups
+	}
+	if len(item.Resources) > 1 {
+		dirPath = path.Join(dirPath, fsutil.NormalizePathname(item.Title))
+	}
+	injectCtx := withRuleActions(ctx, tgutil.ExtWithContext(ctx.Context, ctx), userID, matchedRule, stor.Name(), dirPath)
 	task := parsed.NewTask(xid.New().String(), injectCtx, stor, dirPath, item, parsed.NewProgress(msgID, userID))
+	task.NameTmpl = nameTmpl
 	if err := core.AddTask(injectCtx, task); err != nil {
 		log.FromContext(ctx).Errorf("Failed to add task: %s", err)
 		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
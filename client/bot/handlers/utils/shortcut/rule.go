@@ -0,0 +1,132 @@
+package shortcut
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/enums/fnamest"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/krau/SaveAny-Bot/storage"
+)
+
+// 根据用户的规则为非 Telegram 文件的任务选择存储和目录, 未启用规则或未匹配时返回原值.
+// 同时返回任务使用的文件名模板, 为 nil 时不重命名.
+// 出错或规则要求跳过时以编辑消息的方式反馈, 并返回 false
+func routeByRuleWithEdit(ctx *ext.Context, userID int64, msgID int, stor storage.Storage, dirPath string, in *rule.Input) (storage.Storage, string, *rule.Rule, *fnametmpl.Template, bool) {
+	logger := log.FromContext(ctx)
+	user, err := database.GetUserByChatID(ctx, userID)
+	if err != nil {
+		logger.Errorf("Failed to get user by chat ID: %s", err)
+		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+			ID: msgID,
+			Message: i18n.T(i18nk.BotMsgCommonErrorGetUserWithErrFailed, map[string]any{
+				"Error": err.Error(),
+			}),
+		})
+		return nil, "", nil, nil, false
+	}
+	if !user.ApplyRule || len(user.Rules) == 0 {
+		return stor, resolveInputDir(ctx, dirPath, in), nil, nameTemplate(ctx, user, nil), true
+	}
+	ru, matched := ruleutil.MatchInput(ctx, user.Rules, in)
+	if !matched {
+		return stor, resolveInputDir(ctx, dirPath, in), nil, nameTemplate(ctx, user, nil), true
+	}
+	if ru.Actions.Skip {
+		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+			ID: msgID,
+			Message: i18n.T(i18nk.BotMsgRuleInfoSkippedByRule, map[string]any{
+				"Files": in.URL,
+			}),
+		})
+		return nil, "", nil, nil, false
+	}
+	matchedStorageName := ruleutil.MatchedStorName(ru.StorageName)
+	matchedDirPath := ruleutil.MatchedDirPath(ru.DirPath)
+	// NEW-FOR-ALBUM 只对媒体组有效
+	if matchedDirPath != "" && !matchedDirPath.NeedNewForAlbum() {
+		dirPath = matchedDirPath.String()
+	}
+	if matchedStorageName.Usable() {
+		stor, err = storage.GetStorageByUserIDAndName(ctx, user.ChatID, matchedStorageName.String())
+		if err != nil {
+			logger.Errorf("Failed to get storage by user ID and name: %s", err)
+			ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+				ID: msgID,
+				Message: i18n.T(i18nk.BotMsgCommonErrorGetStorageFailed, map[string]any{
+					"Error": err.Error(),
+				}),
+			})
+			return nil, "", nil, nil, false
+		}
+	}
+	return stor, resolveInputDir(ctx, dirPath, in), ru, nameTemplate(ctx, user, ru), true
+}
+
+// 渲染目录模板, 非 Telegram 文件的任务只有链接和解析结果的变量可用
+func resolveInputDir(ctx *ext.Context, dirPath string, in *rule.Input) string {
+	if !fnametmpl.IsTemplate(dirPath) {
+		return dirPath
+	}
+	data := &fnametmpl.Data{
+		Time:   time.Now(),
+		Site:   in.Site,
+		Author: fnametmpl.Sanitize(in.Author),
+	}
+	// 解析结果和 Telegraph 页面的文本以标题开头
+	title, _, _ := strings.Cut(in.Text, "\n")
+	data.Title = fnametmpl.Sanitize(title)
+	data.SetURL(in.URL)
+	for _, tag := range in.Tags {
+		data.Tags = append(data.Tags, fnametmpl.Sanitize(tag))
+	}
+	dir, err := fnametmpl.RenderDir(dirPath, data)
+	if err != nil {
+		log.FromContext(ctx).Warnf("Failed to render directory template %q: %s", dirPath, err)
+		return fnametmpl.StaticPrefix(dirPath)
+	}
+	return dir
+}
+
+// 返回非 Telegram 文件任务的文件名模板, 规则的重命名动作优先于用户的文件名模板.
+// 用户的文件名模板使用了 Telegram 消息的变量时不对这些任务生效.
+// 模板无效时记录日志并返回 nil
+func nameTemplate(ctx *ext.Context, user *database.User, ru *rule.Rule) *fnametmpl.Template {
+	text := ""
+	fromUser := false
+	if ru != nil && ru.Actions.Rename != "" {
+		text = ru.Actions.Rename
+	} else if user.FilenameStrategy == fnamest.Template.String() {
+		text = user.FilenameTemplate
+		fromUser = true
+	}
+	if text == "" {
+		return nil
+	}
+	tmpl, err := fnametmpl.Parse(text)
+	if err != nil {
+		log.FromContext(ctx).Warnf("Invalid filename template %q: %s", text, err)
+		return nil
+	}
+	if fromUser && tmpl.UsesMessage() {
+		log.FromContext(ctx).Debugf("Filename template %q uses message variables, keeping original names", text)
+		return nil
+	}
+	return tmpl
+}
+
+// 为任务附加规则的后处理和通知动作
+func withRuleActions(ctx *ext.Context, taskCtx context.Context, userID int64, ru *rule.Rule, storageName, filePath string) context.Context {
+	actions := ruleutil.NewActionSet(ctx, userID)
+	actions.Add(ru, storageName, filePath)
+	return actions.Attach(taskCtx)
+}
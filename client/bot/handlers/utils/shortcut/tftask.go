@@ -9,6 +9,7 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/gotd/td/tg"
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/conflictutil"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/mediautil"
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/msgelem"
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
 	"github.com/krau/SaveAny-Bot/common/i18n"
@@ -19,6 +20,7 @@ import (
 	tftask "github.com/krau/SaveAny-Bot/core/tasks/tfile"
 	"github.com/krau/SaveAny-Bot/database"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
 	"github.com/krau/SaveAny-Bot/pkg/tcbdata"
 	"github.com/krau/SaveAny-Bot/pkg/tfile"
 	"github.com/krau/SaveAny-Bot/storage"
@@ -41,15 +43,29 @@ func CreateAndAddTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor storage
 		return dispatcher.EndGroups
 	}
 	strategy = conflictutil.ResolveStrategy(user, strategy)
+	var matchedRule *rule.Rule
 	if user.ApplyRule && user.Rules != nil {
-		matched, matchedStorageName, matchedDirPath := ruleutil.ApplyRule(ctx, user.Rules, ruleutil.NewInput(file))
+		ru, matched := ruleutil.Match(ctx, user.Rules, ruleutil.NewInput(file))
 		if !matched {
 			goto startCreateTask
 		}
+		matchedRule = ru
+		if ru.Actions.Skip {
+			ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
+				ID: trackMsgID,
+				Message: i18n.T(i18nk.BotMsgRuleInfoSkippedByRule, map[string]any{
+					"Files": file.Name(),
+				}),
+			})
+			return dispatcher.EndGroups
+		}
+		strategy = ruleConflictStrategy(strategy, conflictStrategy, ru)
+		ruleutil.Rename(ctx, ru, file)
+		matchedDirPath := ruleutil.MatchedDirPath(ru.DirPath)
 		if matchedDirPath != "" {
 			dirPath = matchedDirPath.String()
 		}
-		if matchedStorageName.Usable() {
+		if matchedStorageName := ruleutil.MatchedStorName(ru.StorageName); matchedStorageName.Usable() {
 			stor, err = storage.GetStorageByUserIDAndName(ctx, user.ChatID, matchedStorageName.String())
 			if err != nil {
 				logger.Errorf("Failed to get storage by user ID and name: %s", err)
@@ -64,7 +80,7 @@ func CreateAndAddTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor storage
 		}
 	}
 startCreateTask:
-	storagePath := path.Join(dirPath, file.Name())
+	storagePath := path.Join(mediautil.ResolveDir(ctx, dirPath, file), file.Name())
 	if strategy == tcbdata.ConflictStrategyAsk || strategy == tcbdata.ConflictStrategySkip {
 		exists := stor.Exists(ctx, storagePath)
 		if exists && strategy == tcbdata.ConflictStrategyAsk {
@@ -85,6 +101,9 @@ startCreateTask:
 	if strategy == tcbdata.ConflictStrategyOverwrite {
 		injectCtx = storage.WithOverwrite(injectCtx)
 	}
+	actions := ruleutil.NewActionSet(ctx, userID)
+	actions.Add(matchedRule, stor.Name(), storagePath)
+	injectCtx = actions.Attach(injectCtx)
 	taskid := xid.New().String()
 	task, err := tftask.NewTGFileTask(taskid, injectCtx, file, stor, storagePath,
 		tftask.NewProgressTrack(
@@ -139,32 +158,46 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 
 	useRule := user.ApplyRule && user.Rules != nil
 
-	applyRule := func(file tfile.TGFileMessage) (string, ruleutil.MatchedDirPath) {
+	applyRule := func(file tfile.TGFileMessage) (string, ruleutil.MatchedDirPath, *rule.Rule) {
 		if !useRule {
-			return stor.Name(), ruleutil.MatchedDirPath(dirPath)
+			return stor.Name(), ruleutil.MatchedDirPath(dirPath), nil
 		}
-		matched, storName, dirP := ruleutil.ApplyRule(ctx, user.Rules, ruleutil.NewInput(file))
+		ru, matched := ruleutil.Match(ctx, user.Rules, ruleutil.NewInput(file))
 		if !matched {
-			return stor.Name(), ruleutil.MatchedDirPath(dirPath)
+			return stor.Name(), ruleutil.MatchedDirPath(dirPath), nil
 		}
+		storName := ruleutil.MatchedStorName(ru.StorageName)
 		storname := storName.String()
 		if !storName.Usable() {
 			storname = stor.Name()
 		}
-		return storname, dirP
+		return storname, ruleutil.MatchedDirPath(ru.DirPath), ru
 	}
 
 	skipped := make([]string, 0)
+	ruleSkipped := make([]string, 0)
 	conflicts := make([]string, 0)
 	elems := make([]batchtfile.TaskElement, 0, len(files))
+	actions := ruleutil.NewActionSet(ctx, userID)
 	type albumFile struct {
-		file    tfile.TGFileMessage
-		storage storage.Storage
-		dirPath string
+		file     tfile.TGFileMessage
+		storage  storage.Storage
+		dirPath  string
+		rule     *rule.Rule
+		strategy string
 	}
 	albumFiles := make(map[int64][]albumFile, 0)
 	for _, file := range files {
-		storName, matchedDirPath := applyRule(file)
+		storName, matchedDirPath, matchedRule := applyRule(file)
+		fileStrategy := strategy
+		if matchedRule != nil {
+			if matchedRule.Actions.Skip {
+				ruleSkipped = append(ruleSkipped, file.Name())
+				continue
+			}
+			fileStrategy = ruleConflictStrategy(strategy, conflictStrategy, matchedRule)
+			ruleutil.Rename(ctx, matchedRule, file)
+		}
 		fileStor := stor
 		if storName != stor.Name() && storName != "" {
 			fileStor, err = storage.GetStorageByUserIDAndName(ctx, user.ChatID, storName)
@@ -180,10 +213,10 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 			}
 		}
 		if !matchedDirPath.NeedNewForAlbum() {
-			storPath := path.Join(matchedDirPath.String(), file.Name())
-			if strategy == tcbdata.ConflictStrategyAsk || strategy == tcbdata.ConflictStrategySkip {
+			storPath := path.Join(mediautil.ResolveDir(ctx, matchedDirPath.String(), file), file.Name())
+			if fileStrategy == tcbdata.ConflictStrategyAsk || fileStrategy == tcbdata.ConflictStrategySkip {
 				exists := fileStor.Exists(ctx, storPath)
-				if exists && strategy == tcbdata.ConflictStrategyAsk {
+				if exists && fileStrategy == tcbdata.ConflictStrategyAsk {
 					conflicts = append(conflicts, conflictutil.FormatPath(fileStor.Name(), storPath))
 					continue
 				}
@@ -203,7 +236,9 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 				})
 				return dispatcher.EndGroups
 			}
+			elem.Overwrite = fileStrategy == tcbdata.ConflictStrategyOverwrite
 			elems = append(elems, *elem)
+			actions.Add(matchedRule, fileStor.Name(), storPath)
 		} else {
 			groupId, isGroup := file.Message().GetGroupedID()
 			if !isGroup || groupId == 0 {
@@ -218,9 +253,11 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 				albumFiles[groupId] = make([]albumFile, 0)
 			}
 			albumFiles[groupId] = append(albumFiles[groupId], albumFile{
-				file:    file,
-				storage: fileStor,
-				dirPath: fileDirPath,
+				file:     file,
+				storage:  fileStor,
+				dirPath:  fileDirPath,
+				rule:     matchedRule,
+				strategy: fileStrategy,
 			})
 		}
 	}
@@ -233,10 +270,10 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 		albumDir := strings.TrimSuffix(path.Base(afiles[0].file.Name()), path.Ext(afiles[0].file.Name()))
 		albumStor := afiles[0].storage
 		for _, af := range afiles {
-			afstorPath := path.Join(af.dirPath, albumDir, af.file.Name())
-			if strategy == tcbdata.ConflictStrategyAsk || strategy == tcbdata.ConflictStrategySkip {
+			afstorPath := path.Join(mediautil.ResolveDir(ctx, af.dirPath, af.file), albumDir, af.file.Name())
+			if af.strategy == tcbdata.ConflictStrategyAsk || af.strategy == tcbdata.ConflictStrategySkip {
 				exists := albumStor.Exists(ctx, afstorPath)
-				if exists && strategy == tcbdata.ConflictStrategyAsk {
+				if exists && af.strategy == tcbdata.ConflictStrategyAsk {
 					conflicts = append(conflicts, conflictutil.FormatPath(albumStor.Name(), afstorPath))
 					continue
 				}
@@ -256,7 +293,9 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 				})
 				return dispatcher.EndGroups
 			}
+			elem.Overwrite = af.strategy == tcbdata.ConflictStrategyOverwrite
 			elems = append(elems, *elem)
+			actions.Add(af.rule, albumStor.Name(), afstorPath)
 		}
 	}
 
@@ -264,16 +303,19 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 		return promptTGFileConflictStrategy(ctx, userID, stor.Name(), dirPath, files, true, conflicts, trackMsgID)
 	}
 
+	// 覆盖策略由每个文件的 Overwrite 决定, 以支持规则指定的冲突策略
 	injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
-	if strategy == tcbdata.ConflictStrategyOverwrite {
-		injectCtx = storage.WithOverwrite(injectCtx)
-	}
+	injectCtx = actions.Attach(injectCtx)
 	if len(elems) == 0 {
+		text := i18n.T(i18nk.BotMsgCommonInfoAllConflictFilesSkipped, map[string]any{
+			"Skipped": strings.Join(skipped, "\n"),
+		})
+		if len(skipped) == 0 {
+			text = buildRuleSkippedMessage(ruleSkipped)
+		}
 		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
-			ID: trackMsgID,
-			Message: i18n.T(i18nk.BotMsgCommonInfoAllConflictFilesSkipped, map[string]any{
-				"Skipped": strings.Join(skipped, "\n"),
-			}),
+			ID:          trackMsgID,
+			Message:     text,
 			ReplyMarkup: nil,
 		})
 		return dispatcher.EndGroups
@@ -292,7 +334,7 @@ func CreateAndAddBatchTGFileTaskWithEdit(ctx *ext.Context, userID int64, stor st
 	}
 	ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
 		ID:          trackMsgID,
-		Message:     buildBatchAddedMessage(len(elems), skipped),
+		Message:     strings.TrimSpace(buildBatchAddedMessage(len(elems), skipped) + "\n" + buildRuleSkippedMessage(ruleSkipped)),
 		ReplyMarkup: nil,
 	})
 	return dispatcher.EndGroups
@@ -318,6 +360,14 @@ func promptTGFileConflictStrategy(ctx *ext.Context, userID int64, storageName, d
 	return dispatcher.EndGroups
 }
 
+// 规则指定的冲突策略仅在用户未手动选择时生效
+func ruleConflictStrategy(resolved string, selected []string, ru *rule.Rule) string {
+	if tcbdata.IsConflictStrategy(selectedConflictStrategy(selected)) || !tcbdata.IsConflictStrategy(ru.Actions.Conflict) {
+		return resolved
+	}
+	return ru.Actions.Conflict
+}
+
 func selectedConflictStrategy(strategies []string) string {
 	if len(strategies) == 0 {
 		return ""
@@ -336,3 +386,12 @@ func buildBatchAddedMessage(count int, skipped []string) string {
 		"Skipped": strings.Join(skipped, "\n"),
 	})
 }
+
+func buildRuleSkippedMessage(skipped []string) string {
+	if len(skipped) == 0 {
+		return ""
+	}
+	return i18n.T(i18nk.BotMsgRuleInfoSkippedByRule, map[string]any{
+		"Files": strings.Join(skipped, "\n"),
+	})
+}
@@ -1,11 +1,15 @@
 package shortcut
 
 import (
+	"path"
+	"strings"
+
 	"github.com/celestix/gotgproto/dispatcher"
 	"github.com/celestix/gotgproto/ext"
 	"github.com/charmbracelet/log"
 	"github.com/gotd/td/tg"
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/msgelem"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
 	"github.com/krau/SaveAny-Bot/common/i18n"
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
 	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
@@ -26,7 +30,18 @@ func CreateAndAddtelegraphWithEdit(
 	stor storage.Storage,
 	trackMsgID int) error {
 
-	injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
+	in := ruleutil.InputFromURL(tphpage.Url)
+	in.Site = "telegraph"
+	in.Author = tphpage.AuthorName
+	in.Text = strings.TrimSpace(tphpage.Title + "\n" + tphpage.Description)
+	// 规则只替换页面目录的上级目录
+	stor, parentDir, matchedRule, nameTmpl, ok := routeByRuleWithEdit(ctx, userID, trackMsgID, stor, path.Dir(dirPath), in)
+	if !ok {
+		return dispatcher.EndGroups
+	}
+	dirPath = path.Join(parentDir, path.Base(dirPath))
+
+	injectCtx := withRuleActions(ctx, tgutil.ExtWithContext(ctx.Context, ctx), userID, matchedRule, stor.Name(), dirPath)
 	task := tphtask.NewTask(xid.New().String(),
 		injectCtx,
 		tphpage.Path,
@@ -36,6 +51,8 @@ func CreateAndAddtelegraphWithEdit(
 		tphutil.DefaultClient(),
 		tphtask.NewProgress(trackMsgID, userID),
 	)
+	task.PageTitle = tphpage.Title
+	task.NameTmpl = nameTmpl
 	if err := core.AddTask(injectCtx, task); err != nil {
 		log.FromContext(ctx).Errorf("Failed to add task: %s", err)
 		ctx.EditMessage(userID, &tg.MessagesEditMessageRequest{
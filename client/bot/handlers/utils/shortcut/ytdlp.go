@@ -7,6 +7,7 @@ import (
 	"github.com/gotd/td/tg"
 	"github.com/rs/xid"
 
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
 	"github.com/krau/SaveAny-Bot/common/i18n"
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
 	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
@@ -29,6 +30,12 @@ func CreateAndAddYtdlpTaskWithEdit(ctx *ext.Context, stor storage.Storage, dirPa
 		return dispatcher.EndGroups
 	}
 
+	stor, dirPath, matchedRule, nameTmpl, ok := routeByRuleWithEdit(ctx, userID, msgID, stor, dirPath, ruleutil.InputFromURL(urls[0]))
+	if !ok {
+		return dispatcher.EndGroups
+	}
+	injectCtx = withRuleActions(ctx, injectCtx, userID, matchedRule, stor.Name(), dirPath)
+
 	logger.Infof("Creating yt-dlp task for %d URL(s) with %d flag(s)", len(urls), len(flags))
 
 	// Create yt-dlp task
@@ -41,6 +48,7 @@ func CreateAndAddYtdlpTaskWithEdit(ctx *ext.Context, stor storage.Storage, dirPa
 		dirPath,
 		ytdlp.NewProgress(msgID, userID),
 	)
+	task.NameTmpl = nameTmpl
 
 	// Add task to queue
 	if err := core.AddTask(injectCtx, task); err != nil {
@@ -0,0 +1,67 @@
+package watchutil
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/krau/SaveAny-Bot/pkg/enums/fnamest"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+)
+
+const (
+	FilterMsgRegex = "msgre"
+	FilterExpr     = "expr"
+)
+
+var (
+	ErrFilterFormat = errors.New("invalid filter format")
+	ErrFilterType   = errors.New("unsupported filter type")
+)
+
+// ParseFilter 校验监听的过滤器, 格式为 <type>:<data>, 返回规范化后的过滤器.
+// msgre 匹配消息文本, expr 为与规则相同的表达式, 可以按媒体类型, 大小, 标签和发送者等过滤
+func ParseFilter(arg string) (string, error) {
+	filterType, filterData, _ := strings.Cut(strings.TrimSpace(arg), ":")
+	if filterType == "" || filterData == "" {
+		return "", ErrFilterFormat
+	}
+	switch filterType {
+	case FilterMsgRegex:
+		if _, err := regexp.Compile(filterData); err != nil {
+			return "", err
+		}
+	case FilterExpr:
+		expr, err := rule.Parse(filterData)
+		if err != nil {
+			return "", err
+		}
+		filterData = expr.String()
+	default:
+		return "", ErrFilterType
+	}
+	return filterType + ":" + filterData, nil
+}
+
+// ValidateDir 校验监听的保存目录, 目录可以是文件名模板
+func ValidateDir(dir string) error {
+	if fnametmpl.IsTemplate(dir) {
+		if _, err := fnametmpl.Parse(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseFilenameStrategy 校验监听的文件名策略, 返回规范化后的名称. 空值表示使用用户的设置
+func ParseFilenameStrategy(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	st, err := fnamest.ParseFnameST(value)
+	if err != nil {
+		return "", err
+	}
+	return st.String(), nil
+}
@@ -0,0 +1,43 @@
+package watchutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"msgre:.*plana.*", "msgre:.*plana.*", nil},
+		{"msgre:a:b", "msgre:a:b", nil},
+		{"expr:media:video,photo size<2GB", "expr:media:video,photo and size<2GB", nil},
+		{"expr:tag:art or sender:@someone", "expr:tag:art or sender:@someone", nil},
+		{"msgre", "", ErrFilterFormat},
+		{"foo:bar", "", ErrFilterType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseFilter(tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseFilter(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) error = %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFilter(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+	for _, input := range []string{"msgre:(", "expr:size>abc"} {
+		if _, err := ParseFilter(input); err == nil {
+			t.Errorf("ParseFilter(%q) expected error", input)
+		}
+	}
+}
@@ -1,28 +1,36 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"path"
-	"regexp"
+	"strconv"
 	"strings"
 	"sync"
-	"text/template"
 	"time"
 
 	"github.com/celestix/gotgproto/dispatcher"
 	"github.com/celestix/gotgproto/ext"
 	"github.com/charmbracelet/log"
+	"github.com/gotd/td/tg"
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/mediautil"
 	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/watchutil"
 	userclient "github.com/krau/SaveAny-Bot/client/user"
 	"github.com/krau/SaveAny-Bot/common/i18n"
 	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
 	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/core"
+	"github.com/krau/SaveAny-Bot/core/tasks/backfill"
 	coretfile "github.com/krau/SaveAny-Bot/core/tasks/tfile"
 	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
 	"github.com/krau/SaveAny-Bot/pkg/enums/fnamest"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/krau/SaveAny-Bot/pkg/tcbdata"
 	"github.com/krau/SaveAny-Bot/pkg/tfile"
 	"github.com/krau/SaveAny-Bot/storage"
 	"github.com/rs/xid"
@@ -30,7 +38,10 @@ import (
 
 func handleWatchCmd(ctx *ext.Context, update *ext.Update) error {
 	logger := log.FromContext(ctx)
-	args := strings.Split(update.EffectiveMessage.Text, " ")
+	args, backfillReq, backfillFrom := parseBackfillArg(strings.Split(update.EffectiveMessage.Text, " "))
+	if len(args) > 1 && args[1] == "edit" {
+		return handleWatchEditCmd(ctx, update)
+	}
 	if len(args) < 2 {
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchHelpText)), nil)
 		return dispatcher.EndGroups
@@ -42,60 +53,112 @@ func handleWatchCmd(ctx *ext.Context, update *ext.Update) error {
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorGetUserFailed)), nil)
 		return dispatcher.EndGroups
 	}
-	if user.DefaultStorage == "" {
-		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorDefaultStorageNotSet)), nil)
-		return dispatcher.EndGroups
-	}
 	chatArg := args[1]
 	chatID, err := tgutil.ParseChatID(ctx, chatArg)
 	if err != nil {
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorInvalidIdOrUsername, map[string]any{"Error": err.Error()})), nil)
 		return dispatcher.EndGroups
 	}
+	if user.DefaultStorage == "" && user.ChatDefaultFor(rule.NormalizeChatID(chatID)) == nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorDefaultStorageNotSet)), nil)
+		return dispatcher.EndGroups
+	}
+	uctx := userclient.GetCtx()
+	if backfillReq && uctx == nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorBackfillUserbotRequired)), nil)
+		return dispatcher.EndGroups
+	}
 	watching, err := user.WatchingChat(ctx, chatID)
 	if err != nil {
 		logger.Errorf("Failed to check if user is watching chat %d: %s", chatID, err)
 		return dispatcher.EndGroups
 	}
-	if watching {
+	if watching && !backfillReq {
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchInfoAlreadyWatchingChat)), nil)
 		return dispatcher.EndGroups
 	}
 	filter := ""
-	if len(args) > 2 {
-		filterArg := strings.Join(args[2:], " ")
-		filterType, _, _ := strings.Cut(filterArg, ":")
-		filterData := strings.Split(filterArg, ":")[1]
-		if filterType == "" || filterData == "" {
-			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorFilterFormatInvalid)), nil)
+	if len(args) > 2 && !watching {
+		filter, err = watchutil.ParseFilter(strings.Join(args[2:], " "))
+		if err != nil {
+			ctx.Reply(update, ext.ReplyTextString(watchFilterErrorText(err)), nil)
 			return dispatcher.EndGroups
 		}
-		switch filterType {
-		case "msgre":
-			_, err := regexp.Compile(filterData)
-			if err != nil {
-				ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorInvalidRegex, map[string]any{"Error": err.Error()})), nil)
-				return dispatcher.EndGroups
-			}
-			filter = filterType + ":" + filterData
-		default:
-			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorFilterTypeUnsupported)), nil)
+	}
+	if !watching {
+		if err := user.WatchChat(ctx, database.WatchChat{
+			UserID: user.ID,
+			ChatID: chatID,
+			Filter: filter,
+		}); err != nil {
+			logger.Errorf("Failed to watch chat %d: %s", chatID, err)
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorWatchChatFailed, map[string]any{"Error": err.Error()})), nil)
 			return dispatcher.EndGroups
 		}
+		audit.Record(ctx, audit.ActionWatchCreate, strconv.FormatInt(chatID, 10), map[string]any{"filter": filter})
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchInfoWatchChatStarted, map[string]any{"Chat": chatArg})), nil)
 	}
-	if err := user.WatchChat(ctx, database.WatchChat{
-		UserID: user.ID,
-		ChatID: chatID,
-		Filter: filter,
-	}); err != nil {
-		logger.Errorf("Failed to watch chat %d: %s", chatID, err)
-		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorWatchChatFailed, map[string]any{"Error": err.Error()})), nil)
-		return dispatcher.EndGroups
+	if backfillReq {
+		handleWatchBackfill(ctx, update, user, uctx, chatArg, chatID, backfillFrom)
 	}
-	ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchInfoWatchChatStarted, map[string]any{"Chat": chatArg})), nil)
 	return dispatcher.EndGroups
 }
 
+func handleWatchBackfill(ctx *ext.Context, update *ext.Update, user *database.User, uctx *ext.Context, chatArg string, chatID int64, from string) {
+	logger := log.FromContext(ctx)
+	chat, err := user.GetWatchChat(ctx, chatID)
+	if err != nil {
+		logger.Errorf("Failed to get watch chat %d: %s", chatID, err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorBackfillFailed, map[string]any{"Error": err.Error()})), nil)
+		return
+	}
+	if chat.BackfillUntil > 0 {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorBackfillRunning)), nil)
+		return
+	}
+	start, err := backfillStart(uctx, chatID, from)
+	if err != nil {
+		if errors.Is(err, errInvalidBackfillFrom) {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorBackfillFromInvalid, map[string]any{"Value": from})), nil)
+			return
+		}
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorBackfillFailed, map[string]any{"Error": err.Error()})), nil)
+		return
+	}
+	// 回填到当前最新的消息, 之后的消息由监听处理
+	until, err := tgutil.GetLastMessageIDBefore(uctx, chatID, time.Time{})
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorBackfillFailed, map[string]any{"Error": err.Error()})), nil)
+		return
+	}
+	if start > until {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchInfoBackfillNothing)), nil)
+		return
+	}
+	if err := database.UpdateWatchChatBackfill(ctx, chat.ID, start, until); err != nil {
+		logger.Errorf("Failed to save backfill checkpoint of chat %d: %s", chatID, err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorBackfillFailed, map[string]any{"Error": err.Error()})), nil)
+		return
+	}
+	chat.BackfillNext, chat.BackfillUntil = start, until
+	replied, err := ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchInfoBackfillStarted, map[string]any{
+		"Chat":  chatArg,
+		"From":  start,
+		"Until": until,
+	})), nil)
+	if err != nil {
+		logger.Errorf("Failed to reply: %s", err)
+		return
+	}
+	injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
+	progress := backfill.NewProgress(replied.ID, update.GetUserChat().GetID())
+	if err := startWatchBackfill(injectCtx, uctx, chat, progress); err != nil {
+		logger.Errorf("Failed to add backfill task: %s", err)
+		database.UpdateWatchChatBackfill(ctx, chat.ID, 0, 0)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorBackfillFailed, map[string]any{"Error": err.Error()})), nil)
+	}
+}
+
 func handleLswatchCmd(ctx *ext.Context, update *ext.Update) error {
 	logger := log.FromContext(ctx)
 	userChatID := update.GetUserChat().GetID()
@@ -115,12 +178,17 @@ func handleLswatchCmd(ctx *ext.Context, update *ext.Update) error {
 	for _, chat := range chats {
 		sb.WriteString("- ")
 		sb.WriteString(fmt.Sprintf("%d", chat.ChatID))
-		if chat.Filter != "" {
-			sb.WriteString(i18n.T(i18nk.BotMsgWatchInfoWatchListFilterPrefix))
-			sb.WriteString(chat.Filter)
-			sb.WriteString(")")
+		if name := tgutil.PeerName(ctx, chat.ChatID); name != "" {
+			sb.WriteString(" (" + name + ")")
+		}
+		if chat.BackfillUntil > 0 {
+			sb.WriteString(i18n.T(i18nk.BotMsgWatchInfoWatchListBackfill, map[string]any{
+				"Next":  chat.BackfillNext,
+				"Until": chat.BackfillUntil,
+			}))
 		}
 		sb.WriteString("\n")
+		sb.WriteString(formatWatchSettings(&chat))
 	}
 	ctx.Reply(update, ext.ReplyTextString(sb.String()), nil)
 	return dispatcher.EndGroups
@@ -151,6 +219,7 @@ func handleUnwatchCmd(ctx *ext.Context, update *ext.Update) error {
 		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorUnwatchChatFailed, map[string]any{"Error": err.Error()})), nil)
 		return dispatcher.EndGroups
 	}
+	audit.Record(ctx, audit.ActionWatchDelete, strconv.FormatInt(chatID, 10), nil)
 	ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchInfoWatchChatStopped, map[string]any{"Chat": chatArg})), nil)
 	return dispatcher.EndGroups
 }
@@ -202,6 +271,50 @@ func (w *watchMediaGroupHandler) addFile(chatID int64, userID uint, file tfile.T
 	})
 }
 
+// 返回监听的消息使用的存储和目录. 优先使用监听的设置, 监听只设置了目录时与默认的存储组合使用
+func watchDefaultStorage(ctx *ext.Context, user *database.User, chat *database.WatchChat, msg *tg.Message, chatID int64) (storage.Storage, string, bool) {
+	if chat.StorageName != "" {
+		stor, err := storage.GetStorageByUserIDAndName(ctx, user.ChatID, chat.StorageName)
+		if err == nil {
+			return stor, chat.DirPath, true
+		}
+		log.FromContext(ctx).Warnf("Failed to get storage %s of the watch on chat %d, using the defaults: %s", chat.StorageName, chat.ChatID, err)
+	}
+	stor, dirPath, ok := watchInheritedStorage(ctx, user, msg, chatID)
+	if ok && chat.DirPath != "" {
+		dirPath = chat.DirPath
+	}
+	return stor, dirPath, ok
+}
+
+// 返回监听的消息默认使用的存储和目录. 依次使用转发来源和所在聊天的默认设置, 最后使用用户的默认存储
+func watchInheritedStorage(ctx *ext.Context, user *database.User, msg *tg.Message, chatID int64) (storage.Storage, string, bool) {
+	logger := log.FromContext(ctx)
+	if stor, cd := chatDefaultStorage(ctx, user, forwardSourceChat(msg), rule.NormalizeChatID(chatID)); stor != nil {
+		return stor, cd.DirPath, true
+	}
+	if user.DefaultStorage == "" {
+		logger.Warnf("User %d has no default storage set, skipping media message handling", user.ID)
+		return nil, "", false
+	}
+	stor, err := storage.GetStorageByUserIDAndName(ctx, user.ChatID, user.DefaultStorage)
+	if err != nil {
+		logger.Errorf("Failed to get storage by user ID %d and name %s: %v", user.ChatID, user.DefaultStorage, err)
+		return nil, "", false
+	}
+	// Resolve the default directory path from user.DefaultDir
+	var defaultDirPath string
+	if user.DefaultDir != 0 {
+		dir, err := database.GetDirByID(ctx, user.DefaultDir)
+		if err != nil {
+			logger.Warnf("Failed to get default dir for user %d: %v, using root", user.ChatID, err)
+		} else {
+			defaultDirPath = dir.Path
+		}
+	}
+	return stor, defaultDirPath, true
+}
+
 func listenMediaMessageEvent(ch chan userclient.MediaMessageEvent) {
 	if userclient.GetCtx() == nil {
 		return
@@ -210,130 +323,133 @@ func listenMediaMessageEvent(ch chan userclient.MediaMessageEvent) {
 	for event := range ch {
 		logger.Debug("Received media message event", "chat_id", event.ChatID, "file_name", event.File.Name())
 		ctx := event.Ctx
-		file := event.File
 		chats, err := database.GetWatchChatsByChatID(ctx, event.ChatID)
 		if err != nil {
 			logger.Errorf("Failed to get watch chats for chat ID %d: %v", event.ChatID, err)
 			continue
 		}
-		msgText := event.File.Message().GetMessage()
 		for _, chat := range chats {
-			if chat.Filter != "" {
-				filter := strings.Split(chat.Filter, ":")
-				if len(filter) != 2 {
-					logger.Warnf("Invalid filter format in chat %d, skipping", chat.ChatID)
-					continue
-				}
-				filterType := filter[0]
-				filterData := filter[1]
-				switch filterType {
-				case "msgre": // [TODO] enums for filter types
-					if ok, err := regexp.MatchString(filterData, msgText); err != nil {
-						continue
-					} else if !ok {
-						continue
-					}
-				default:
-					logger.Warnf("Unsupported filter type %s in chat %d, skipping", filterType, chat.ChatID)
-					continue
-				}
-			}
-			user, err := database.GetUserByID(ctx, chat.UserID)
-			if err != nil {
-				logger.Errorf("Failed to get user by ID %d: %v", chat.UserID, err)
-				continue
-			}
-			if user.DefaultStorage == "" {
-				logger.Warnf("User %d has no default storage set, skipping media message handling", chat.UserID)
-				continue
-			}
-			stor, err := storage.GetStorageByUserIDAndName(ctx, user.ChatID, user.DefaultStorage)
-			if err != nil {
-				logger.Errorf("Failed to get storage by user ID %d and name %s: %v", user.ChatID, user.DefaultStorage, err)
-				continue
-			}
-			// Resolve the default directory path from user.DefaultDir
-			var defaultDirPath string
-			if user.DefaultDir != 0 {
-				dir, err := database.GetDirByID(ctx, user.DefaultDir)
-				if err != nil {
-					logger.Warnf("Failed to get default dir for user %d: %v, using root", user.ChatID, err)
-				} else {
-					defaultDirPath = dir.Path
-				}
-			}
-			switch user.FilenameStrategy {
-			case fnamest.Message.String():
-				file.SetName(tgutil.GenFileNameFromMessage(*file.Message()))
-			case fnamest.Template.String():
-				if user.FilenameTemplate == "" {
-					logger.Warnf("Empty filename template for user %d, using default filename", user.ChatID)
-					break
-				}
-				message := file.Message()
-				tmpl, err := template.New("filename").Parse(user.FilenameTemplate)
-				if err != nil {
-					logger.Errorf("Failed to parse filename template for user %d: %s", user.ChatID, err)
-					break
-				}
-				data := mediautil.BuildFilenameTemplateData(message)
-				var sb strings.Builder
-				err = tmpl.Execute(&sb, data)
-				if err != nil {
-					log.FromContext(ctx).Errorf("failed to execute filename template: %s", err)
-					break
-				}
-				file.SetName(sb.String())
-			}
+			saveWatchedFile(ctx, chat, event.ChatID, event.File)
+		}
+	}
+}
 
-			// Check if this is a media group and if rules specify NEW-FOR-ALBUM
-			groupID, isGroup := file.Message().GetGroupedID()
-			needAlbumHandling := false
-			if isGroup && groupID != 0 && user.ApplyRule && user.Rules != nil {
-				_, _, matchedDirPath := ruleutil.ApplyRule(ctx, user.Rules, ruleutil.NewInput(file))
-				needAlbumHandling = matchedDirPath.NeedNewForAlbum()
-			}
+// 按监听设置保存一个文件, 返回是否已加入任务队列 (相册中的文件会稍后加入)
+func saveWatchedFile(ctx *ext.Context, chat *database.WatchChat, chatID int64, file tfile.TGFileMessage) bool {
+	logger := log.FromContext(ctx)
+	if !matchWatchFilter(ctx, chat, file) {
+		return false
+	}
+	user, err := database.GetUserByID(ctx, chat.UserID)
+	if err != nil {
+		logger.Errorf("Failed to get user by ID %d: %v", chat.UserID, err)
+		return false
+	}
+	if chat.FilenameStrategy != "" {
+		// 监听的文件名策略优先于用户的设置
+		u := *user
+		u.FilenameStrategy = chat.FilenameStrategy
+		user = &u
+	}
+	stor, defaultDirPath, ok := watchDefaultStorage(ctx, user, chat, file.Message(), chatID)
+	if !ok {
+		return false
+	}
+	switch user.FilenameStrategy {
+	case fnamest.Message.String():
+		file.SetName(tgutil.GenFileNameFromMessage(*file.Message()))
+	case fnamest.Template.String():
+		if user.FilenameTemplate == "" {
+			logger.Warnf("Empty filename template for user %d, using default filename", user.ChatID)
+			break
+		}
+		name, err := fnametmpl.Render(user.FilenameTemplate, mediautil.BuildTemplateData(ctx, file.Message()))
+		if err != nil {
+			logger.Errorf("Failed to render filename template for user %d: %s", user.ChatID, err)
+			break
+		}
+		file.SetName(name)
+	}
 
-			if needAlbumHandling {
-				// For media groups with NEW-FOR-ALBUM rule, collect all files of the same group
-				watchMediaGroupMgr.addFile(event.ChatID, user.ID, file, time.Duration(max(config.C().Telegram.MediaGroupTimeout, 1))*time.Second, func(files []tfile.TGFileMessage) {
-					processWatchMediaGroup(ctx, user, stor, defaultDirPath, files)
-				})
-				continue
-			}
+	// Check if this is a media group and if rules specify NEW-FOR-ALBUM
+	groupID, isGroup := file.Message().GetGroupedID()
+	needAlbumHandling := false
+	if isGroup && groupID != 0 && user.ApplyRule && user.Rules != nil {
+		_, _, matchedDirPath := ruleutil.ApplyRule(ctx, user.Rules, ruleutil.NewInput(file))
+		needAlbumHandling = matchedDirPath.NeedNewForAlbum()
+	}
 
-			// Process single file or media group without album folder creation
-			dirPath := defaultDirPath
-			if user.ApplyRule && user.Rules != nil {
-				matched, matchedStorageName, matchedDirPath := ruleutil.ApplyRule(ctx, user.Rules, ruleutil.NewInput(file))
-				if !matched {
-					goto startCreateTask
-				}
-				dirPath = matchedDirPath.String()
-				if matchedStorageName.Usable() {
-					stor, err = storage.GetStorageByUserIDAndName(ctx, user.ChatID, matchedStorageName.String())
-					if err != nil {
-						logger.Errorf("Failed to get storage by user ID and name: %s", err)
-						continue
-					}
-				}
-			}
-		startCreateTask:
-			storagePath := path.Join(dirPath, file.Name())
-			injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
-			taskid := xid.New().String()
-			task, err := coretfile.NewTGFileTask(taskid, injectCtx, file, stor, storagePath, nil)
-			if err != nil {
-				logger.Errorf("create task failed: %s", err)
-				continue
+	if needAlbumHandling {
+		// For media groups with NEW-FOR-ALBUM rule, collect all files of the same group
+		// 回填等待相册的任务加入队列后才继续
+		release := core.HoldTaskGroup(ctx, fmt.Sprintf("album:%d:%d", chatID, user.ID))
+		watchMediaGroupMgr.addFile(chatID, user.ID, file, time.Duration(max(config.C().Telegram.MediaGroupTimeout, 1))*time.Second, func(files []tfile.TGFileMessage) {
+			processWatchMediaGroup(ctx, user, stor, defaultDirPath, files)
+			release()
+		})
+		return true
+	}
+
+	// Process single file or media group without album folder creation
+	dirPath := defaultDirPath
+	var matchedRule *rule.Rule
+	if user.ApplyRule && user.Rules != nil {
+		if ru, matched := ruleutil.Match(ctx, user.Rules, ruleutil.NewInput(file)); matched {
+			if ru.Actions.Skip {
+				logger.Infof("Skipped %s in chat %d by rule %d", file.Name(), chatID, ru.ID)
+				return false
 			}
-			if err := core.AddTask(injectCtx, task); err != nil {
-				logger.Errorf("add task failed: %s", err)
-				continue
+			matchedRule = ru
+			ruleutil.Rename(ctx, ru, file)
+			dirPath = ru.DirPath
+			if matchedStorageName := ruleutil.MatchedStorName(ru.StorageName); matchedStorageName.Usable() {
+				stor, err = storage.GetStorageByUserIDAndName(ctx, user.ChatID, matchedStorageName.String())
+				if err != nil {
+					logger.Errorf("Failed to get storage by user ID and name: %s", err)
+					return false
+				}
 			}
-			logger.Infof("Added media message task for user %d in chat %d: %s", chat.UserID, event.ChatID, file.Name())
 		}
 	}
+	storagePath := path.Join(mediautil.ResolveDir(ctx, dirPath, file), file.Name())
+	injectCtx := tgutil.ExtWithContext(ctx.Context, ctx)
+	if matchedRule != nil {
+		var ok bool
+		injectCtx, ok = applyWatchConflict(ctx, injectCtx, matchedRule, stor, storagePath)
+		if !ok {
+			return false
+		}
+		actions := ruleutil.NewActionSet(ctx, user.ChatID)
+		actions.Add(matchedRule, stor.Name(), storagePath)
+		injectCtx = actions.Attach(injectCtx)
+	}
+	taskid := xid.New().String()
+	task, err := coretfile.NewTGFileTask(taskid, injectCtx, file, stor, storagePath, nil)
+	if err != nil {
+		logger.Errorf("create task failed: %s", err)
+		return false
+	}
+	if err := core.AddTask(injectCtx, task); err != nil {
+		logger.Errorf("add task failed: %s", err)
+		return false
+	}
+	logger.Infof("Added media message task for user %d in chat %d: %s", chat.UserID, chatID, file.Name())
+	return true
+}
+
+// applyWatchConflict 应用规则的冲突策略. 监听模式下无法询问, 仅支持规则指定的覆盖与跳过,
+// 返回 false 表示跳过该文件
+func applyWatchConflict(ctx *ext.Context, taskCtx context.Context, ru *rule.Rule, stor storage.Storage, storagePath string) (context.Context, bool) {
+	switch ru.Actions.Conflict {
+	case tcbdata.ConflictStrategyOverwrite:
+		return storage.WithOverwrite(taskCtx), true
+	case tcbdata.ConflictStrategySkip:
+		if stor.Exists(ctx, storagePath) {
+			log.FromContext(ctx).Infof("Skipped existing file %s by rule %d", storagePath, ru.ID)
+			return taskCtx, false
+		}
+	}
+	return taskCtx, true
 }
 
 func processWatchMediaGroup(ctx *ext.Context, user *database.User, stor storage.Storage, dirPath string, files []tfile.TGFileMessage) {
@@ -341,34 +457,44 @@ func processWatchMediaGroup(ctx *ext.Context, user *database.User, stor storage.
 	if len(files) == 0 {
 		return
 	}
+	mediautil.RenameAlbum(ctx, user, files)
 
 	useRule := user.ApplyRule && user.Rules != nil
 
-	applyRule := func(file tfile.TGFileMessage) (string, ruleutil.MatchedDirPath) {
+	applyRule := func(file tfile.TGFileMessage) (string, ruleutil.MatchedDirPath, *rule.Rule) {
 		if !useRule {
-			return stor.Name(), ruleutil.MatchedDirPath(dirPath)
+			return stor.Name(), ruleutil.MatchedDirPath(dirPath), nil
 		}
-		matched, storName, dirP := ruleutil.ApplyRule(ctx, user.Rules, ruleutil.NewInput(file))
+		ru, matched := ruleutil.Match(ctx, user.Rules, ruleutil.NewInput(file))
 		if !matched {
-			return stor.Name(), ruleutil.MatchedDirPath(dirPath)
+			return stor.Name(), ruleutil.MatchedDirPath(dirPath), nil
 		}
+		storName := ruleutil.MatchedStorName(ru.StorageName)
 		storname := storName.String()
 		if !storName.Usable() {
 			storname = stor.Name()
 		}
-		return storname, dirP
+		return storname, ruleutil.MatchedDirPath(ru.DirPath), ru
 	}
 
 	type albumFile struct {
 		file    tfile.TGFileMessage
 		storage storage.Storage
 		dirPath string
+		rule    *rule.Rule
 	}
 	albumFiles := make(map[int64][]albumFile)
 
 	// Collect files by group ID
 	for _, file := range files {
-		storName, ruleDirPath := applyRule(file)
+		storName, ruleDirPath, matchedRule := applyRule(file)
+		if matchedRule != nil {
+			if matchedRule.Actions.Skip {
+				logger.Infof("Skipped %s by rule %d", file.Name(), matchedRule.ID)
+				continue
+			}
+			ruleutil.Rename(ctx, matchedRule, file)
+		}
 		fileStor := stor
 		if storName != stor.Name() && storName != "" {
 			var err error
@@ -399,6 +525,7 @@ func processWatchMediaGroup(ctx *ext.Context, user *database.User, stor storage.
 			file:    file,
 			storage: fileStor,
 			dirPath: effectiveDirPath,
+			rule:    matchedRule,
 		})
 	}
 
@@ -417,14 +544,25 @@ func processWatchMediaGroup(ctx *ext.Context, user *database.User, stor storage.
 		logger.Infof("Creating album folder for group %d: %s with %d files", groupID, albumDir, len(afiles))
 
 		for _, af := range afiles {
-			afstorPath := path.Join(af.dirPath, albumDir, af.file.Name())
+			afstorPath := path.Join(mediautil.ResolveDir(ctx, af.dirPath, af.file), albumDir, af.file.Name())
+			taskCtx := injectCtx
+			if af.rule != nil {
+				var ok bool
+				taskCtx, ok = applyWatchConflict(ctx, taskCtx, af.rule, albumStor, afstorPath)
+				if !ok {
+					continue
+				}
+				actions := ruleutil.NewActionSet(ctx, user.ChatID)
+				actions.Add(af.rule, albumStor.Name(), afstorPath)
+				taskCtx = actions.Attach(taskCtx)
+			}
 			taskid := xid.New().String()
-			task, err := coretfile.NewTGFileTask(taskid, injectCtx, af.file, albumStor, afstorPath, nil)
+			task, err := coretfile.NewTGFileTask(taskid, taskCtx, af.file, albumStor, afstorPath, nil)
 			if err != nil {
 				logger.Errorf("create task failed for album file: %s", err)
 				continue
 			}
-			if err := core.AddTask(injectCtx, task); err != nil {
+			if err := core.AddTask(taskCtx, task); err != nil {
 				logger.Errorf("add task failed: %s", err)
 				continue
 			}
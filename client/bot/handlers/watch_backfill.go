@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/mediautil"
+	userclient "github.com/krau/SaveAny-Bot/client/user"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/core"
+	"github.com/krau/SaveAny-Bot/core/tasks/backfill"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+	"github.com/rs/xid"
+)
+
+const backfillFlag = "--backfill"
+
+var errInvalidBackfillFrom = errors.New("invalid backfill start")
+
+// parseBackfillArg 从 /watch 的参数中取出 --backfill [from], 返回其余的参数.
+// 紧跟的参数不是过滤器 (type:expr) 时作为回填起点
+func parseBackfillArg(args []string) ([]string, bool, string) {
+	i := slices.Index(args, backfillFlag)
+	if i < 0 {
+		return args, false, ""
+	}
+	rest := slices.Clone(args[:i])
+	from := ""
+	next := i + 1
+	if next < len(args) && !strings.Contains(args[next], ":") {
+		from = args[next]
+		next++
+	}
+	return append(rest, args[next:]...), true, from
+}
+
+// backfillStart 将回填起点解析为消息 ID, from 可以是日期 (YYYY-MM-DD) 或消息 ID, 为空时从第一条消息开始
+func backfillStart(uctx *ext.Context, chatID int64, from string) (int, error) {
+	if from == "" {
+		return 1, nil
+	}
+	if id, err := strconv.Atoi(from); err == nil {
+		if id < 1 {
+			return 0, errInvalidBackfillFrom
+		}
+		return id, nil
+	}
+	date, err := time.ParseInLocation(time.DateOnly, from, time.Local)
+	if err != nil {
+		return 0, errInvalidBackfillFrom
+	}
+	last, err := tgutil.GetLastMessageIDBefore(uctx, chatID, date)
+	if err != nil {
+		return 0, err
+	}
+	return last + 1, nil
+}
+
+// startWatchBackfill 添加回填监听聊天历史消息的任务, 匹配的媒体消息按监听设置加入任务队列
+func startWatchBackfill(ctx context.Context, uctx *ext.Context, chat *database.WatchChat, progress backfill.ProgressTracker) error {
+	handle := func(ctx context.Context, msgs []*tg.Message) int {
+		// 每批重新读取监听的设置, 使回填期间的修改生效
+		if latest, err := database.GetWatchChatByID(ctx, chat.ID); err == nil {
+			chat = latest
+		}
+		// 文件任务不随回填取消, 只携带回填的任务组
+		fctx := *uctx
+		fctx.Context = core.WithTaskGroup(uctx.Context, core.TaskGroupFrom(ctx))
+		queued := 0
+		for _, msg := range msgs {
+			media, ok := msg.GetMedia()
+			if !ok || !mediautil.IsSupported(media) {
+				continue
+			}
+			file, err := tfile.FromMediaMessage(media, uctx.Raw, msg, tfile.WithNameIfEmpty(tgutil.GenFileNameFromMessage(*msg)))
+			if err != nil {
+				log.FromContext(uctx).Errorf("Failed to get file from message %d in chat %d: %s", msg.GetID(), chat.ChatID, err)
+				continue
+			}
+			if saveWatchedFile(&fctx, chat, chat.ChatID, file) {
+				queued++
+			}
+		}
+		return queued
+	}
+	checkpoint := func(ctx context.Context, next, until int) error {
+		return database.UpdateWatchChatBackfill(ctx, chat.ID, next, until)
+	}
+	task := backfill.NewTask(xid.New().String(), ctx, uctx, chat.ChatID, chat.BackfillNext, chat.BackfillUntil, handle, checkpoint, progress)
+	return core.AddTask(ctx, task)
+}
+
+// resumeWatchBackfills 继续重启前未完成的回填
+func resumeWatchBackfills() {
+	uctx := userclient.GetCtx()
+	if uctx == nil {
+		return
+	}
+	logger := log.FromContext(uctx)
+	chats, err := database.GetBackfillingWatchChats(uctx)
+	if err != nil {
+		logger.Errorf("Failed to get unfinished backfills: %s", err)
+		return
+	}
+	injectCtx := tgutil.ExtWithContext(uctx.Context, uctx)
+	for _, chat := range chats {
+		if err := startWatchBackfill(injectCtx, uctx, chat, nil); err != nil {
+			logger.Errorf("Failed to resume backfill of chat %d: %s", chat.ChatID, err)
+			continue
+		}
+		logger.Infof("Resumed backfill of chat %d from message %d", chat.ChatID, chat.BackfillNext)
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseBackfillArg(t *testing.T) {
+	tests := []struct {
+		input    string
+		args     []string
+		backfill bool
+		from     string
+	}{
+		{"/watch @chat", []string{"/watch", "@chat"}, false, ""},
+		{"/watch @chat --backfill", []string{"/watch", "@chat"}, true, ""},
+		{"/watch @chat --backfill 2024-01-01", []string{"/watch", "@chat"}, true, "2024-01-01"},
+		{"/watch @chat --backfill 1200 msgre:.*a.*", []string{"/watch", "@chat", "msgre:.*a.*"}, true, "1200"},
+		{"/watch @chat --backfill msgre:.*a.*", []string{"/watch", "@chat", "msgre:.*a.*"}, true, ""},
+		{"/watch @chat msgre:.*a.* --backfill", []string{"/watch", "@chat", "msgre:.*a.*"}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			args, backfill, from := parseBackfillArg(strings.Split(tt.input, " "))
+			if !slices.Equal(args, tt.args) || backfill != tt.backfill || from != tt.from {
+				t.Errorf("parseBackfillArg(%q) = %q, %v, %q; want %q, %v, %q", tt.input, args, backfill, from, tt.args, tt.backfill, tt.from)
+			}
+		})
+	}
+}
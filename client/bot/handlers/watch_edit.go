@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/celestix/gotgproto/dispatcher"
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/ruleutil"
+	"github.com/krau/SaveAny-Bot/client/bot/handlers/utils/watchutil"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/database"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
+	"github.com/krau/SaveAny-Bot/pkg/enums/fnamest"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+	"github.com/krau/SaveAny-Bot/storage"
+	"gorm.io/gorm"
+)
+
+func watchFilterErrorText(err error) string {
+	switch {
+	case errors.Is(err, watchutil.ErrFilterFormat):
+		return i18n.T(i18nk.BotMsgWatchErrorFilterFormatInvalid)
+	case errors.Is(err, watchutil.ErrFilterType):
+		return i18n.T(i18nk.BotMsgWatchErrorFilterTypeUnsupported)
+	}
+	return i18n.T(i18nk.BotMsgWatchErrorFilterInvalid, map[string]any{"Error": err.Error()})
+}
+
+// 检查文件是否匹配监听的过滤器
+func matchWatchFilter(ctx *ext.Context, chat *database.WatchChat, file tfile.TGFileMessage) bool {
+	if chat.Filter == "" {
+		return true
+	}
+	logger := log.FromContext(ctx)
+	filterType, filterData, _ := strings.Cut(chat.Filter, ":")
+	switch filterType {
+	case watchutil.FilterMsgRegex:
+		ok, err := regexp.MatchString(filterData, file.Message().GetMessage())
+		return err == nil && ok
+	case watchutil.FilterExpr:
+		expr, err := rule.Parse(filterData)
+		if err != nil {
+			logger.Warnf("Invalid filter expression in chat %d, skipping: %s", chat.ChatID, err)
+			return false
+		}
+		return expr.Match(ruleutil.InputFromFile(ctx, file))
+	default:
+		logger.Warnf("Unsupported filter type %s in chat %d, skipping", filterType, chat.ChatID)
+		return false
+	}
+}
+
+// /watch edit <chat>
+// /watch edit <chat> <storage|dir|fname|filter> <value|->
+func handleWatchEditCmd(ctx *ext.Context, update *ext.Update) error {
+	logger := log.FromContext(ctx)
+	// 值中可能包含空格和引号, 保留原样
+	args := strings.SplitN(strings.TrimSpace(update.EffectiveMessage.Text), " ", 5)
+	if len(args) < 3 {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchHelpEdit)), nil)
+		return dispatcher.EndGroups
+	}
+	user, err := database.GetUserByChatID(ctx, update.GetUserChat().GetID())
+	if err != nil {
+		logger.Errorf("Failed to get user: %s", err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorGetUserFailed)), nil)
+		return dispatcher.EndGroups
+	}
+	chatArg := args[2]
+	chatID, err := tgutil.ParseChatID(ctx, chatArg)
+	if err != nil {
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorInvalidIdOrUsername, map[string]any{"Error": err.Error()})), nil)
+		return dispatcher.EndGroups
+	}
+	chat, err := user.GetWatchChat(ctx, chatID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorNotWatching, map[string]any{"Chat": chatArg})), nil)
+			return dispatcher.EndGroups
+		}
+		logger.Errorf("Failed to get watch chat %d: %s", chatID, err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorUpdateFailed, map[string]any{"Error": err.Error()})), nil)
+		return dispatcher.EndGroups
+	}
+	if len(args) < 5 {
+		if len(args) == 4 {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchHelpEdit)), nil)
+			return dispatcher.EndGroups
+		}
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchInfoSettings, map[string]any{
+			"Chat":     chatArg,
+			"Settings": formatWatchSettings(chat),
+		})), nil)
+		return dispatcher.EndGroups
+	}
+	value := strings.TrimSpace(args[4])
+	if value == "-" {
+		value = ""
+	}
+	switch args[3] {
+	case "storage":
+		if value != "" {
+			if _, err := storage.GetStorageByUserIDAndName(ctx, user.ChatID, value); err != nil {
+				ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgCommonErrorGetStorageFailed, map[string]any{"Error": err.Error()})), nil)
+				return dispatcher.EndGroups
+			}
+		}
+		chat.StorageName = value
+	case "dir":
+		if err := watchutil.ValidateDir(value); err != nil {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgChatdefaultErrorInvalidDir, map[string]any{"Error": err.Error()})), nil)
+			return dispatcher.EndGroups
+		}
+		chat.DirPath = value
+	case "fname":
+		st, err := watchutil.ParseFilenameStrategy(value)
+		if err != nil {
+			ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorFnameInvalid, map[string]any{
+				"Value":     value,
+				"Available": strings.Join(fnamest.FnameSTNames(), ", "),
+			})), nil)
+			return dispatcher.EndGroups
+		}
+		chat.FilenameStrategy = st
+	case "filter":
+		if value != "" {
+			value, err = watchutil.ParseFilter(value)
+			if err != nil {
+				ctx.Reply(update, ext.ReplyTextString(watchFilterErrorText(err)), nil)
+				return dispatcher.EndGroups
+			}
+		}
+		chat.Filter = value
+	default:
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchHelpEdit)), nil)
+		return dispatcher.EndGroups
+	}
+	if err := database.UpdateWatchChatSettings(ctx, chat); err != nil {
+		logger.Errorf("Failed to update watch chat %d: %s", chatID, err)
+		ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchErrorUpdateFailed, map[string]any{"Error": err.Error()})), nil)
+		return dispatcher.EndGroups
+	}
+	audit.Record(ctx, audit.ActionWatchUpdate, strconv.FormatInt(chatID, 10), map[string]any{args[3]: value})
+	ctx.Reply(update, ext.ReplyTextString(i18n.T(i18nk.BotMsgWatchInfoSettingsUpdated, map[string]any{
+		"Chat":     chatArg,
+		"Settings": formatWatchSettings(chat),
+	})), nil)
+	return dispatcher.EndGroups
+}
+
+// 格式化监听的设置, 每项一行, 未设置的项显示为默认
+func formatWatchSettings(chat *database.WatchChat) string {
+	orDefault := func(v string) string {
+		if v == "" {
+			return i18n.T(i18nk.BotMsgWatchInfoSettingDefault)
+		}
+		return v
+	}
+	return i18n.T(i18nk.BotMsgWatchInfoSettingsList, map[string]any{
+		"Storage": orDefault(chat.StorageName),
+		"Dir":     orDefault(chat.DirPath),
+		"Fname":   orDefault(chat.FilenameStrategy),
+		"Filter":  orDefault(chat.Filter),
+	}) + "\n"
+}
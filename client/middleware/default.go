@@ -18,6 +18,7 @@ func NewDefaultMiddlewares(ctx context.Context, timeout time.Duration) []telegra
 		recovery.New(ctx, func() backoff.BackOff { return newBackoff(timeout) }),
 		retry.New(config.C().Telegram.RpcRetry),
 		floodwait.NewSimpleWaiter(),
+		floodWaitMetrics(),
 	}
 }
 
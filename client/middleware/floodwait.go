@@ -14,6 +14,7 @@ func NewFloodWaitMiddlewares(maxRetries uint) []telegram.Middleware {
 	ratelimiter := ratelimit.New(rate.Every(time.Millisecond*100), 5)
 	return []telegram.Middleware{
 		waiter,
+		floodWaitMetrics(),
 		ratelimiter,
 	}
 }
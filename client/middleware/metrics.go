@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"github.com/krau/SaveAny-Bot/pkg/metrics"
+)
+
+// floodWaitMetrics 统计 Telegram 返回的 FLOOD_WAIT, 需要放在 floodwait 中间件之后, 以便记录每次重试前的错误
+func floodWaitMetrics() telegram.Middleware {
+	return telegram.MiddlewareFunc(func(next tg.Invoker) telegram.InvokeFunc {
+		return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			err := next.Invoke(ctx, input, output)
+			if d, ok := tgerr.AsFloodWait(err); ok {
+				metrics.ObserveFloodWait(d)
+			}
+			return err
+		}
+	})
+}
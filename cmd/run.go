@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,6 +22,8 @@ import (
 	"github.com/krau/SaveAny-Bot/core"
 	"github.com/krau/SaveAny-Bot/database"
 	"github.com/krau/SaveAny-Bot/parsers"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
 	"github.com/krau/SaveAny-Bot/storage"
 	"github.com/spf13/cobra"
 )
@@ -47,6 +51,9 @@ func Run(cmd *cobra.Command, _ []string) {
 	}
 	logger.SetLevel(level)
 
+	shutdownTracing := initTracing(ctx)
+	defer shutdownTracing()
+
 	exitChan, err := initAll(ctx)
 	if err != nil {
 		logger.Fatal("Init failed", "error", err)
@@ -62,6 +69,9 @@ func Run(cmd *cobra.Command, _ []string) {
 	logger.Info("Exiting...")
 	defer logger.Info("Exit complete")
 	core.Close()
+	if err := audit.Close(); err != nil {
+		logger.Error("Failed to close audit log", "error", err)
+	}
 	cleanCache()
 }
 
@@ -71,6 +81,16 @@ func initAll(ctx context.Context) (<-chan struct{}, error) {
 	i18n.Init(config.C().Lang)
 	logger.Info("Initializing...")
 	database.Init(ctx)
+	if cfg := config.C().Audit; cfg.Enable {
+		if err := audit.Setup(ctx, audit.Options{
+			Store:     storeAuditEntry,
+			File:      cfg.File,
+			Prune:     database.DeleteAuditLogsBefore,
+			Retention: time.Duration(cfg.RetentionDays) * 24 * time.Hour,
+		}); err != nil {
+			logger.Fatal("Failed to set up audit log", "error", err)
+		}
+	}
 	storage.LoadStorages(ctx)
 	if config.C().Parser.PluginEnable {
 		for _, dir := range config.C().Parser.PluginDirs {
@@ -93,6 +113,56 @@ func initAll(ctx context.Context) (<-chan struct{}, error) {
 	return bot.Init(ctx), nil
 }
 
+// initTracing 启用 OpenTelemetry 链路追踪, 返回的函数在退出时导出剩余的 span
+// storeAuditEntry saves an audit entry to the database
+func storeAuditEntry(ctx context.Context, e *audit.Entry) error {
+	var details []byte
+	if len(e.Details) > 0 {
+		var err error
+		details, err = json.Marshal(e.Details)
+		if err != nil {
+			return fmt.Errorf("failed to encode details: %w", err)
+		}
+	}
+	l := &database.AuditLog{
+		ActorID: e.ActorID,
+		Source:  e.Source,
+		KeyID:   e.KeyID,
+		Action:  e.Action,
+		Target:  e.Target,
+		Details: string(details),
+	}
+	l.CreatedAt = e.Time
+	return database.CreateAuditLog(ctx, l)
+}
+
+func initTracing(ctx context.Context) func() {
+	cfg := config.C().Tracing
+	if !cfg.Enable {
+		return func() {}
+	}
+	logger := log.FromContext(ctx)
+	shutdown, err := tracing.Setup(ctx, tracing.Options{
+		Endpoint:       cfg.Endpoint,
+		Headers:        cfg.Headers,
+		SampleRatio:    cfg.SampleRatio,
+		ServiceName:    cfg.ServiceName,
+		ServiceVersion: config.Version,
+	})
+	if err != nil {
+		logger.Error("Failed to set up tracing", "error", err)
+		return func() {}
+	}
+	logger.Info("Tracing enabled", "endpoint", cfg.Endpoint)
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(shutdownCtx); err != nil {
+			logger.Error("Failed to shut down tracing", "error", err)
+		}
+	}
+}
+
 func cleanCache() {
 	if config.C().NoCleanCache {
 		return
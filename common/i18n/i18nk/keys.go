@@ -4,24 +4,55 @@ package i18nk
 type Key string
 
 const (
+	BotMsgApikeyErrorAdminOnly                            Key = "bot.msg.apikey.error_admin_only"
+	BotMsgApikeyErrorCreateFailed                         Key = "bot.msg.apikey.error_create_failed"
+	BotMsgApikeyErrorInvalidArg                           Key = "bot.msg.apikey.error_invalid_arg"
+	BotMsgApikeyErrorListFailed                           Key = "bot.msg.apikey.error_list_failed"
+	BotMsgApikeyErrorNotFound                             Key = "bot.msg.apikey.error_not_found"
+	BotMsgApikeyErrorRevokeFailed                         Key = "bot.msg.apikey.error_revoke_failed"
+	BotMsgApikeyHelp                                      Key = "bot.msg.apikey.help"
+	BotMsgApikeyInfoCreated                               Key = "bot.msg.apikey.info_created"
+	BotMsgApikeyInfoListEmpty                             Key = "bot.msg.apikey.info_list_empty"
+	BotMsgApikeyInfoListHeader                            Key = "bot.msg.apikey.info_list_header"
+	BotMsgApikeyInfoListItem                              Key = "bot.msg.apikey.info_list_item"
+	BotMsgApikeyInfoNever                                 Key = "bot.msg.apikey.info_never"
+	BotMsgApikeyInfoRevoked                               Key = "bot.msg.apikey.info_revoked"
 	BotMsgAria2ErrorAddingAria2Download                   Key = "bot.msg.aria2.error_adding_aria2_download"
 	BotMsgAria2ErrorAria2ClientInitFailed                 Key = "bot.msg.aria2.error_aria2_client_init_failed"
 	BotMsgAria2ErrorAria2NotEnabled                       Key = "bot.msg.aria2.error_aria2_not_enabled"
 	BotMsgAria2InfoAddingAria2Download                    Key = "bot.msg.aria2.info_adding_aria2_download"
 	BotMsgAria2InfoAria2DownloadAdded                     Key = "bot.msg.aria2.info_aria2_download_added"
 	BotMsgAria2InfoSelectStorage                          Key = "bot.msg.aria2.info_select_storage"
+	BotMsgAuditErrorListFailed                            Key = "bot.msg.audit.error_list_failed"
+	BotMsgAuditErrorNotAdmin                              Key = "bot.msg.audit.error_not_admin"
+	BotMsgAuditInfoListEmpty                              Key = "bot.msg.audit.info_list_empty"
+	BotMsgAuditInfoListHeader                             Key = "bot.msg.audit.info_list_header"
+	BotMsgAuditInfoListItem                               Key = "bot.msg.audit.info_list_item"
 	BotMsgCancelErrorCancelFailed                         Key = "bot.msg.cancel.error_cancel_failed"
 	BotMsgCancelInfoCancelRequested                       Key = "bot.msg.cancel.info_cancel_requested"
 	BotMsgCancelInfoCancellingTask                        Key = "bot.msg.cancel.info_cancelling_task"
 	BotMsgCancelUsage                                     Key = "bot.msg.cancel.usage"
+	BotMsgChatdefaultErrorInvalidDir                      Key = "bot.msg.chatdefault.error_invalid_dir"
+	BotMsgChatdefaultErrorNotFound                        Key = "bot.msg.chatdefault.error_not_found"
+	BotMsgChatdefaultErrorSaveFailed                      Key = "bot.msg.chatdefault.error_save_failed"
+	BotMsgChatdefaultHelp                                 Key = "bot.msg.chatdefault.help"
+	BotMsgChatdefaultInfoDeleted                          Key = "bot.msg.chatdefault.info_deleted"
+	BotMsgChatdefaultInfoListEmpty                        Key = "bot.msg.chatdefault.info_list_empty"
+	BotMsgChatdefaultInfoListHeader                       Key = "bot.msg.chatdefault.info_list_header"
+	BotMsgChatdefaultInfoSet                              Key = "bot.msg.chatdefault.info_set"
+	BotMsgCmdApikey                                       Key = "bot.msg.cmd.apikey"
 	BotMsgCmdAria2dl                                      Key = "bot.msg.cmd.aria2dl"
+	BotMsgCmdAudit                                        Key = "bot.msg.cmd.audit"
 	BotMsgCmdCancel                                       Key = "bot.msg.cmd.cancel"
+	BotMsgCmdChatdefault                                  Key = "bot.msg.cmd.chatdefault"
 	BotMsgCmdConfig                                       Key = "bot.msg.cmd.config"
 	BotMsgCmdDir                                          Key = "bot.msg.cmd.dir"
 	BotMsgCmdDl                                           Key = "bot.msg.cmd.dl"
 	BotMsgCmdFnametmpl                                    Key = "bot.msg.cmd.fnametmpl"
+	BotMsgCmdGet                                          Key = "bot.msg.cmd.get"
 	BotMsgCmdHelp                                         Key = "bot.msg.cmd.help"
 	BotMsgCmdImport                                       Key = "bot.msg.cmd.import"
+	BotMsgCmdLs                                           Key = "bot.msg.cmd.ls"
 	BotMsgCmdLswatch                                      Key = "bot.msg.cmd.lswatch"
 	BotMsgCmdParser                                       Key = "bot.msg.cmd.parser"
 	BotMsgCmdRule                                         Key = "bot.msg.cmd.rule"
@@ -29,6 +60,7 @@ const (
 	BotMsgCmdSilent                                       Key = "bot.msg.cmd.silent"
 	BotMsgCmdStart                                        Key = "bot.msg.cmd.start"
 	BotMsgCmdStorage                                      Key = "bot.msg.cmd.storage"
+	BotMsgCmdStream                                       Key = "bot.msg.cmd.stream"
 	BotMsgCmdSyncpeers                                    Key = "bot.msg.cmd.syncpeers"
 	BotMsgCmdTask                                         Key = "bot.msg.cmd.task"
 	BotMsgCmdTransfer                                     Key = "bot.msg.cmd.transfer"
@@ -121,7 +153,37 @@ const (
 	BotMsgDlErrorNoValidLinks                             Key = "bot.msg.dl.error_no_valid_links"
 	BotMsgDlInfoFilesSelectStorage                        Key = "bot.msg.dl.info_files_select_storage"
 	BotMsgDlUsage                                         Key = "bot.msg.dl.usage"
+	BotMsgGetErrorGetFailed                               Key = "bot.msg.get.error_get_failed"
+	BotMsgGetErrorStorageNotFound                         Key = "bot.msg.get.error_storage_not_found"
+	BotMsgGetInfoSendingFiles                             Key = "bot.msg.get.info_sending_files"
+	BotMsgGetUsage                                        Key = "bot.msg.get.usage"
 	BotMsgHelpTextFmt                                     Key = "bot.msg.help_text_fmt"
+	BotMsgLsButtonBack                                    Key = "bot.msg.ls.button_back"
+	BotMsgLsButtonConfirmDelete                           Key = "bot.msg.ls.button_confirm_delete"
+	BotMsgLsButtonDelete                                  Key = "bot.msg.ls.button_delete"
+	BotMsgLsButtonDownload                                Key = "bot.msg.ls.button_download"
+	BotMsgLsButtonRefresh                                 Key = "bot.msg.ls.button_refresh"
+	BotMsgLsButtonRename                                  Key = "bot.msg.ls.button_rename"
+	BotMsgLsButtonTransfer                                Key = "bot.msg.ls.button_transfer"
+	BotMsgLsButtonUp                                      Key = "bot.msg.ls.button_up"
+	BotMsgLsErrorDeleteFailed                             Key = "bot.msg.ls.error_delete_failed"
+	BotMsgLsErrorDownloadFailed                           Key = "bot.msg.ls.error_download_failed"
+	BotMsgLsErrorFileNotFound                             Key = "bot.msg.ls.error_file_not_found"
+	BotMsgLsErrorInvalidName                              Key = "bot.msg.ls.error_invalid_name"
+	BotMsgLsErrorListFilesFailed                          Key = "bot.msg.ls.error_list_files_failed"
+	BotMsgLsErrorNotSupported                             Key = "bot.msg.ls.error_not_supported"
+	BotMsgLsErrorRenameFailed                             Key = "bot.msg.ls.error_rename_failed"
+	BotMsgLsErrorStorageNotFound                          Key = "bot.msg.ls.error_storage_not_found"
+	BotMsgLsErrorStorageNotListable                       Key = "bot.msg.ls.error_storage_not_listable"
+	BotMsgLsInfoDeleted                                   Key = "bot.msg.ls.info_deleted"
+	BotMsgLsInfoDir                                       Key = "bot.msg.ls.info_dir"
+	BotMsgLsInfoDirEmpty                                  Key = "bot.msg.ls.info_dir_empty"
+	BotMsgLsInfoFile                                      Key = "bot.msg.ls.info_file"
+	BotMsgLsInfoRenamed                                   Key = "bot.msg.ls.info_renamed"
+	BotMsgLsInfoSendingFile                               Key = "bot.msg.ls.info_sending_file"
+	BotMsgLsPromptConfirmDelete                           Key = "bot.msg.ls.prompt_confirm_delete"
+	BotMsgLsPromptRename                                  Key = "bot.msg.ls.prompt_rename"
+	BotMsgLsUsage                                         Key = "bot.msg.ls.usage"
 	BotMsgMediaGroupErrorBuildStorageSelectKeyboardFailed Key = "bot.msg.media_group.error_build_storage_select_keyboard_failed"
 	BotMsgMediaGroupInfoGroupFoundFilesSelectStorage      Key = "bot.msg.media_group.info_group_found_files_select_storage"
 	BotMsgMediaGroupInfoSavingFiles                       Key = "bot.msg.media_group.info_saving_files"
@@ -150,6 +212,8 @@ const (
 	BotMsgProgressAria2Downloading                        Key = "bot.msg.progress.aria2_downloading"
 	BotMsgProgressAria2Start                              Key = "bot.msg.progress.aria2_start"
 	BotMsgProgressAvgSpeedPrefix                          Key = "bot.msg.progress.avg_speed_prefix"
+	BotMsgProgressBackfillDone                            Key = "bot.msg.progress.backfill_done"
+	BotMsgProgressBackfillProgress                        Key = "bot.msg.progress.backfill_progress"
 	BotMsgProgressBatchCanceled                           Key = "bot.msg.progress.batch_canceled"
 	BotMsgProgressBatchDone                               Key = "bot.msg.progress.batch_done"
 	BotMsgProgressBatchDoneWithSkipped                    Key = "bot.msg.progress.batch_done_with_skipped"
@@ -221,10 +285,14 @@ const (
 	BotMsgProgressYtdlpStart                              Key = "bot.msg.progress.ytdlp_start"
 	BotMsgRuleErrorCreateRuleFailed                       Key = "bot.msg.rule.error_create_rule_failed"
 	BotMsgRuleErrorDeleteRuleFailed                       Key = "bot.msg.rule.error_delete_rule_failed"
+	BotMsgRuleErrorExportFailed                           Key = "bot.msg.rule.error_export_failed"
 	BotMsgRuleErrorGetUserRulesFailed                     Key = "bot.msg.rule.error_get_user_rules_failed"
+	BotMsgRuleErrorInvalidPriority                        Key = "bot.msg.rule.error_invalid_priority"
+	BotMsgRuleErrorInvalidRule                            Key = "bot.msg.rule.error_invalid_rule"
 	BotMsgRuleErrorInvalidRuleId                          Key = "bot.msg.rule.error_invalid_rule_id"
 	BotMsgRuleErrorInvalidRuleType                        Key = "bot.msg.rule.error_invalid_rule_type"
 	BotMsgRuleErrorStorageNotFound                        Key = "bot.msg.rule.error_storage_not_found"
+	BotMsgRuleErrorUpdatePriorityFailed                   Key = "bot.msg.rule.error_update_priority_failed"
 	BotMsgRuleErrorUpdateUserFailed                       Key = "bot.msg.rule.error_update_user_failed"
 	BotMsgRuleHelpAddSuffix                               Key = "bot.msg.rule.help_add_suffix"
 	BotMsgRuleHelpAvailableOps                            Key = "bot.msg.rule.help_available_ops"
@@ -232,20 +300,51 @@ const (
 	BotMsgRuleHelpCurrentModeEnabled                      Key = "bot.msg.rule.help_current_mode_enabled"
 	BotMsgRuleHelpDelSuffix                               Key = "bot.msg.rule.help_del_suffix"
 	BotMsgRuleHelpExistingRulesPrefix                     Key = "bot.msg.rule.help_existing_rules_prefix"
+	BotMsgRuleHelpExportSuffix                            Key = "bot.msg.rule.help_export_suffix"
+	BotMsgRuleHelpImportSuffix                            Key = "bot.msg.rule.help_import_suffix"
 	BotMsgRuleHelpPresetSuffix                            Key = "bot.msg.rule.help_preset_suffix"
+	BotMsgRuleHelpPrioritySuffix                          Key = "bot.msg.rule.help_priority_suffix"
 	BotMsgRuleHelpSwitchSuffix                            Key = "bot.msg.rule.help_switch_suffix"
+	BotMsgRuleHelpTestSuffix                              Key = "bot.msg.rule.help_test_suffix"
 	BotMsgRuleHelpUsage                                   Key = "bot.msg.rule.help_usage"
+	BotMsgRuleImportErrorInvalid                          Key = "bot.msg.rule.import_error_invalid"
+	BotMsgRuleImportErrorTooLarge                         Key = "bot.msg.rule.import_error_too_large"
+	BotMsgRuleImportInfoMerged                            Key = "bot.msg.rule.import_info_merged"
+	BotMsgRuleImportInfoReplaced                          Key = "bot.msg.rule.import_info_replaced"
+	BotMsgRuleImportPromptReply                           Key = "bot.msg.rule.import_prompt_reply"
 	BotMsgRuleInfoCreateRuleSuccess                       Key = "bot.msg.rule.info_create_rule_success"
 	BotMsgRuleInfoDeleteRuleSuccess                       Key = "bot.msg.rule.info_delete_rule_success"
 	BotMsgRuleInfoPresetImported                          Key = "bot.msg.rule.info_preset_imported"
+	BotMsgRuleInfoPriorityUpdated                         Key = "bot.msg.rule.info_priority_updated"
 	BotMsgRuleInfoRuleModeDisabled                        Key = "bot.msg.rule.info_rule_mode_disabled"
 	BotMsgRuleInfoRuleModeEnabled                         Key = "bot.msg.rule.info_rule_mode_enabled"
+	BotMsgRuleInfoSkippedByRule                           Key = "bot.msg.rule.info_skipped_by_rule"
+	BotMsgRuleNotifySaved                                 Key = "bot.msg.rule.notify_saved"
 	BotMsgRulePromptProvideRuleId                         Key = "bot.msg.rule.prompt_provide_rule_id"
 	BotMsgRulePromptProvideStorageName                    Key = "bot.msg.rule.prompt_provide_storage_name"
+	BotMsgRuleTestActions                                 Key = "bot.msg.rule.test_actions"
+	BotMsgRuleTestAlbumDir                                Key = "bot.msg.rule.test_album_dir"
+	BotMsgRuleTestHeader                                  Key = "bot.msg.rule.test_header"
+	BotMsgRuleTestModeDisabled                            Key = "bot.msg.rule.test_mode_disabled"
+	BotMsgRuleTestNameUnknown                             Key = "bot.msg.rule.test_name_unknown"
+	BotMsgRuleTestNoMatch                                 Key = "bot.msg.rule.test_no_match"
+	BotMsgRuleTestNoRules                                 Key = "bot.msg.rule.test_no_rules"
+	BotMsgRuleTestPromptReply                             Key = "bot.msg.rule.test_prompt_reply"
+	BotMsgRuleTestResult                                  Key = "bot.msg.rule.test_result"
+	BotMsgRuleTestRuleFailed                              Key = "bot.msg.rule.test_rule_failed"
+	BotMsgRuleTestRuleMatched                             Key = "bot.msg.rule.test_rule_matched"
+	BotMsgRuleTestRuleNotMatched                          Key = "bot.msg.rule.test_rule_not_matched"
+	BotMsgRuleTestRuleUsed                                Key = "bot.msg.rule.test_rule_used"
+	BotMsgRuleTestSkipped                                 Key = "bot.msg.rule.test_skipped"
+	BotMsgRuleTestStorageChosen                           Key = "bot.msg.rule.test_storage_chosen"
 	BotMsgSaveErrorInvalidIdOrUsername                    Key = "bot.msg.save.error_invalid_id_or_username"
 	BotMsgSaveHelpText                                    Key = "bot.msg.save_help_text"
 	BotMsgStorageInfoFilenamePrefix                       Key = "bot.msg.storage.info_filename_prefix"
 	BotMsgStorageInfoPromptSelectStorage                  Key = "bot.msg.storage.info_prompt_select_storage"
+	BotMsgStreamErrorLinkFailed                           Key = "bot.msg.stream.error_link_failed"
+	BotMsgStreamErrorNoMedia                              Key = "bot.msg.stream.error_no_media"
+	BotMsgStreamInfoLink                                  Key = "bot.msg.stream.info_link"
+	BotMsgStreamUsage                                     Key = "bot.msg.stream.usage"
 	BotMsgSyncpeersFailed                                 Key = "bot.msg.syncpeers.failed"
 	BotMsgSyncpeersStart                                  Key = "bot.msg.syncpeers.start"
 	BotMsgSyncpeersSuccess                                Key = "bot.msg.syncpeers.success"
@@ -301,16 +400,31 @@ const (
 	BotMsgUpdateInfoNewVersionPromptUpgrade               Key = "bot.msg.update.info_new_version_prompt_upgrade"
 	BotMsgUpdateInfoUpgradeSuccess                        Key = "bot.msg.update.info_upgrade_success"
 	BotMsgUpdateInfoUpgradingWithVersion                  Key = "bot.msg.update.info_upgrading_with_version"
+	BotMsgWatchErrorBackfillFailed                        Key = "bot.msg.watch.error_backfill_failed"
+	BotMsgWatchErrorBackfillFromInvalid                   Key = "bot.msg.watch.error_backfill_from_invalid"
+	BotMsgWatchErrorBackfillRunning                       Key = "bot.msg.watch.error_backfill_running"
+	BotMsgWatchErrorBackfillUserbotRequired               Key = "bot.msg.watch.error_backfill_userbot_required"
 	BotMsgWatchErrorFilterFormatInvalid                   Key = "bot.msg.watch.error_filter_format_invalid"
+	BotMsgWatchErrorFilterInvalid                         Key = "bot.msg.watch.error_filter_invalid"
 	BotMsgWatchErrorFilterTypeUnsupported                 Key = "bot.msg.watch.error_filter_type_unsupported"
+	BotMsgWatchErrorFnameInvalid                          Key = "bot.msg.watch.error_fname_invalid"
+	BotMsgWatchErrorNotWatching                           Key = "bot.msg.watch.error_not_watching"
 	BotMsgWatchErrorUnwatchChatFailed                     Key = "bot.msg.watch.error_unwatch_chat_failed"
 	BotMsgWatchErrorUnwatchNoChatProvided                 Key = "bot.msg.watch.error_unwatch_no_chat_provided"
+	BotMsgWatchErrorUpdateFailed                          Key = "bot.msg.watch.error_update_failed"
 	BotMsgWatchErrorWatchChatFailed                       Key = "bot.msg.watch.error_watch_chat_failed"
+	BotMsgWatchHelpEdit                                   Key = "bot.msg.watch.help_edit"
 	BotMsgWatchInfoAlreadyWatchingChat                    Key = "bot.msg.watch.info_already_watching_chat"
+	BotMsgWatchInfoBackfillNothing                        Key = "bot.msg.watch.info_backfill_nothing"
+	BotMsgWatchInfoBackfillStarted                        Key = "bot.msg.watch.info_backfill_started"
+	BotMsgWatchInfoSettingDefault                         Key = "bot.msg.watch.info_setting_default"
+	BotMsgWatchInfoSettings                               Key = "bot.msg.watch.info_settings"
+	BotMsgWatchInfoSettingsList                           Key = "bot.msg.watch.info_settings_list"
+	BotMsgWatchInfoSettingsUpdated                        Key = "bot.msg.watch.info_settings_updated"
 	BotMsgWatchInfoWatchChatStarted                       Key = "bot.msg.watch.info_watch_chat_started"
 	BotMsgWatchInfoWatchChatStopped                       Key = "bot.msg.watch.info_watch_chat_stopped"
+	BotMsgWatchInfoWatchListBackfill                      Key = "bot.msg.watch.info_watch_list_backfill"
 	BotMsgWatchInfoWatchListEmpty                         Key = "bot.msg.watch.info_watch_list_empty"
-	BotMsgWatchInfoWatchListFilterPrefix                  Key = "bot.msg.watch.info_watch_list_filter_prefix"
 	BotMsgWatchInfoWatchListHeader                        Key = "bot.msg.watch.info_watch_list_header"
 	BotMsgWatchHelpText                                   Key = "bot.msg.watch_help_text"
 	BotMsgYtdlpErrorDownloadFailed                        Key = "bot.msg.ytdlp.error_download_failed"
@@ -1,14 +1,43 @@
 package tdler
 
 import (
+	"context"
+	"io"
+
 	"github.com/gotd/td/telegram/downloader"
 	"github.com/krau/SaveAny-Bot/common/utils/dlutil"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/pkg/consts/tglimit"
 	"github.com/krau/SaveAny-Bot/pkg/tfile"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func NewDownloader(file tfile.TGFile) *downloader.Builder {
 	return downloader.NewDownloader().WithPartSize(tglimit.MaxPartSize).
 		Download(file.Dler(), file.Location()).WithThreads(dlutil.BestThreads(file.Size(), config.C().Threads))
 }
+
+// Download downloads the file to w with parallel threads, in a tracing span.
+func Download(ctx context.Context, file tfile.TGFile, w io.WriterAt) error {
+	ctx, span := startSpan(ctx, file, "parallel")
+	_, err := NewDownloader(file).Parallel(ctx, w)
+	tracing.End(span, err)
+	return err
+}
+
+// Stream downloads the file to w sequentially, in a tracing span.
+func Stream(ctx context.Context, file tfile.TGFile, w io.Writer) error {
+	ctx, span := startSpan(ctx, file, "stream")
+	_, err := NewDownloader(file).Stream(ctx, w)
+	tracing.End(span, err)
+	return err
+}
+
+func startSpan(ctx context.Context, file tfile.TGFile, mode string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "tdler.download",
+		tracing.FileNameKey.String(file.Name()),
+		tracing.FileSizeKey.Int64(file.Size()),
+		tracing.DownloadModeKey.String(mode),
+	)
+}
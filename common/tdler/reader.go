@@ -0,0 +1,87 @@
+package tdler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/pkg/consts/tglimit"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+)
+
+// Reader is an io.ReadSeeker over a Telegram file. It fetches one aligned part
+// at a time, so it can serve HTTP range requests without downloading the whole
+// file. The file size must be known.
+type Reader struct {
+	ctx       context.Context
+	file      tfile.TGFile
+	offset    int64
+	part      []byte
+	partStart int64
+}
+
+var _ io.ReadSeeker = (*Reader)(nil)
+
+func NewReader(ctx context.Context, file tfile.TGFile) *Reader {
+	return &Reader{ctx: ctx, file: file, partStart: -1}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.offset >= r.file.Size() {
+		return 0, io.EOF
+	}
+	if r.partStart < 0 || r.offset < r.partStart || r.offset >= r.partStart+int64(len(r.part)) {
+		if err := r.fetch(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.part[r.offset-r.partStart:])
+	r.offset += int64(n)
+	return n, nil
+}
+
+// fetch loads the part containing the current offset. Offsets are aligned to
+// the part size, which satisfies the upload.getFile offset/limit constraints.
+func (r *Reader) fetch() error {
+	start := r.offset - r.offset%tglimit.MaxPartSize
+	res, err := r.file.Dler().UploadGetFile(r.ctx, &tg.UploadGetFileRequest{
+		Precise:  true,
+		Location: r.file.Location(),
+		Offset:   start,
+		Limit:    tglimit.MaxPartSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get file part at %d: %w", start, err)
+	}
+	part, ok := res.(*tg.UploadFile)
+	if !ok {
+		return fmt.Errorf("unexpected file part type: %T", res)
+	}
+	if len(part.Bytes) == 0 || start+int64(len(part.Bytes)) <= r.offset {
+		return io.ErrUnexpectedEOF
+	}
+	r.part = part.Bytes
+	r.partStart = start
+	return nil
+}
+
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.offset + offset
+	case io.SeekEnd:
+		abs = r.file.Size() + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.offset = abs
+	return abs, nil
+}
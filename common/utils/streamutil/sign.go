@@ -0,0 +1,81 @@
+// Package streamutil signs and verifies the expiring links served by the API
+// stream endpoint, so they can be shared with players that cannot send an
+// Authorization header.
+package streamutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/config"
+)
+
+// PathPrefix is the route of the stream endpoint.
+const PathPrefix = "/api/v1/stream/"
+
+func sign(key []byte, chatID int64, msgID int, expires int64) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "stream:%d:%d:%d", chatID, msgID, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func verify(key []byte, chatID int64, msgID int, expires int64, sig string, now time.Time) bool {
+	if now.Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(sign(key, chatID, msgID, expires)), []byte(sig))
+}
+
+func signingKey() ([]byte, error) {
+	token := config.C().API.Token
+	if token == "" {
+		return nil, errors.New("api token is not set")
+	}
+	return []byte(token), nil
+}
+
+// Verify reports whether sig is a valid, unexpired signature for the message.
+func Verify(chatID int64, msgID int, expires string, sig string) bool {
+	key, err := signingKey()
+	if err != nil {
+		return false
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return false
+	}
+	return verify(key, chatID, msgID, exp, sig, time.Now())
+}
+
+// URL returns a signed stream link for the media of a message, valid for the
+// configured stream TTL.
+func URL(chatID int64, msgID int) (string, time.Time, error) {
+	cfg := config.C().API
+	if !cfg.Enable {
+		return "", time.Time{}, errors.New("api server is not enabled")
+	}
+	key, err := signingKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	base := strings.TrimRight(cfg.PublicURL, "/")
+	if base == "" {
+		base = fmt.Sprintf("http://%s:%d", cfg.Host, cfg.Port)
+	}
+	ttl := time.Duration(cfg.StreamTTL) * time.Second
+	if ttl <= 0 {
+		ttl = 6 * time.Hour
+	}
+	expiresAt := time.Now().Add(ttl)
+	query := url.Values{}
+	query.Set("exp", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("sig", sign(key, chatID, msgID, expiresAt.Unix()))
+	return fmt.Sprintf("%s%s%d/%d?%s", base, PathPrefix, chatID, msgID, query.Encode()), expiresAt, nil
+}
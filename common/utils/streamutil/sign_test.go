@@ -0,0 +1,29 @@
+package streamutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	key := []byte("secret")
+	now := time.Unix(1_700_000_000, 0)
+	exp := now.Add(time.Hour).Unix()
+	sig := sign(key, 123, 45, exp)
+
+	if !verify(key, 123, 45, exp, sig, now) {
+		t.Fatal("valid signature rejected")
+	}
+	if verify(key, 123, 46, exp, sig, now) {
+		t.Fatal("signature accepted for another message")
+	}
+	if verify(key, 123, 45, exp+1, sig, now) {
+		t.Fatal("signature accepted with tampered expiry")
+	}
+	if verify([]byte("other"), 123, 45, exp, sig, now) {
+		t.Fatal("signature accepted with another key")
+	}
+	if verify(key, 123, 45, exp, sig, now.Add(2*time.Hour)) {
+		t.Fatal("expired signature accepted")
+	}
+}
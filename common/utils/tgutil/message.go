@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 	"unicode/utf16"
 
@@ -244,6 +245,50 @@ func GetMessageByID(ctx *ext.Context, chatID int64, msgID int) (*tg.Message, err
 	return nil, fmt.Errorf("failed to get message by ID: chatID=%d, msgID=%d", chatID, msgID)
 }
 
+// GetLastMessageIDBefore returns the ID of the last message sent in the chat
+// before date, or the latest message of the chat when date is zero. It returns
+// 0 when there is no such message.
+func GetLastMessageIDBefore(ctx *ext.Context, chatID int64, date time.Time) (int, error) {
+	plain := constant.TDLibPeerID(chatID).ToPlain()
+	var channel, chat constant.TDLibPeerID
+	channel.Channel(plain)
+	chat.Chat(plain)
+	var peer tg.InputPeerClass
+	var err error
+	for _, id := range []int64{chatID, int64(channel), int64(chat)} {
+		if peer, err = ctx.ResolveInputPeerById(id); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve chat %d: %w", chatID, err)
+	}
+	req := &tg.MessagesGetHistoryRequest{
+		Peer:  peer,
+		Limit: 1,
+	}
+	if !date.IsZero() {
+		req.OffsetDate = int(date.Unix())
+	}
+	res, err := ctx.Raw.MessagesGetHistory(ctx, req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get history of chat %d: %w", chatID, err)
+	}
+	var msgs []tg.MessageClass
+	switch res := res.(type) {
+	case *tg.MessagesMessages:
+		msgs = res.Messages
+	case *tg.MessagesMessagesSlice:
+		msgs = res.Messages
+	case *tg.MessagesChannelMessages:
+		msgs = res.Messages
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+	return msgs[0].GetID(), nil
+}
+
 func GetGroupedMessages(ctx *ext.Context, chatID int64, msg *tg.Message) ([]*tg.Message, error) {
 	groupID, isGroup := msg.GetGroupedID()
 	if !isGroup || groupID == 0 {
@@ -1,6 +1,14 @@
 package tgutil
 
-import "github.com/gotd/td/tg"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/celestix/gotgproto/ext"
+	"github.com/charmbracelet/log"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/common/cache"
+)
 
 func ChatIdFromPeer(peer tg.PeerClass) int64 {
 	switch peer := peer.(type) {
@@ -14,3 +22,65 @@ func ChatIdFromPeer(peer tg.PeerClass) int64 {
 		return 0
 	}
 }
+
+// PeerName returns the title of a chat or the full name of a user. Names are
+// kept in the cache until its TTL expires; an empty string is returned for
+// unknown peers.
+func PeerName(ctx *ext.Context, id int64) string {
+	if ctx == nil || ctx.PeerStorage == nil || id == 0 {
+		return ""
+	}
+	key := fmt.Sprintf("peername:%d", id)
+	if name, ok := cache.Get[string](key); ok {
+		return name
+	}
+	name, err := fetchPeerName(ctx, id)
+	if err != nil {
+		log.FromContext(ctx).Debugf("Failed to get name of peer %d: %s", id, err)
+		return ""
+	}
+	if err := cache.Set(key, name); err != nil {
+		log.FromContext(ctx).Debugf("Failed to cache name of peer %d: %s", id, err)
+	}
+	return name
+}
+
+func fetchPeerName(ctx *ext.Context, id int64) (string, error) {
+	switch p := ctx.PeerStorage.GetInputPeerById(id).(type) {
+	case *tg.InputPeerChannel:
+		res, err := ctx.Raw.ChannelsGetChannels(ctx, []tg.InputChannelClass{
+			&tg.InputChannel{ChannelID: p.ChannelID, AccessHash: p.AccessHash},
+		})
+		if err != nil {
+			return "", err
+		}
+		for _, c := range res.GetChats() {
+			if ch, ok := c.(*tg.Channel); ok {
+				return ch.Title, nil
+			}
+		}
+	case *tg.InputPeerChat:
+		res, err := ctx.Raw.MessagesGetChats(ctx, []int64{p.ChatID})
+		if err != nil {
+			return "", err
+		}
+		for _, c := range res.GetChats() {
+			if chat, ok := c.(*tg.Chat); ok {
+				return chat.Title, nil
+			}
+		}
+	case *tg.InputPeerUser:
+		users, err := ctx.Raw.UsersGetUsers(ctx, []tg.InputUserClass{
+			&tg.InputUser{UserID: p.UserID, AccessHash: p.AccessHash},
+		})
+		if err != nil {
+			return "", err
+		}
+		for _, u := range users {
+			if user, ok := u.(*tg.User); ok {
+				return strings.TrimSpace(user.FirstName + " " + user.LastName), nil
+			}
+		}
+	}
+	return "", nil
+}
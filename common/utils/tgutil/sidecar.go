@@ -0,0 +1,69 @@
+package tgutil
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/common/utils/strutil"
+	"github.com/krau/SaveAny-Bot/pkg/sidecar"
+	"github.com/krau/SaveAny-Bot/pkg/tfile"
+)
+
+// SidecarFromMessage builds the sidecar metadata of a media message.
+func SidecarFromMessage(msg *tg.Message) *sidecar.Metadata {
+	if msg == nil {
+		return nil
+	}
+	meta := &sidecar.Metadata{
+		ChatID:    ChatIdFromPeer(msg.GetPeerID()),
+		MessageID: msg.GetID(),
+		Caption:   msg.GetMessage(),
+		Tags:      strutil.ExtractTagsFromText(msg.GetMessage()),
+	}
+	if date := msg.GetDate(); date != 0 {
+		meta.Date = time.Unix(int64(date), 0)
+	}
+	if _, ok := msg.GetPeerID().(*tg.PeerChannel); ok && meta.ChatID != 0 {
+		meta.SourceURL = fmt.Sprintf("https://t.me/c/%d/%d", meta.ChatID, meta.MessageID)
+	}
+	for _, e := range msg.Entities {
+		entity := sidecar.Entity{
+			Type:   strings.ToLower(strings.TrimPrefix(e.TypeName(), "messageEntity")),
+			Offset: e.GetOffset(),
+			Length: e.GetLength(),
+		}
+		if u, ok := e.(*tg.MessageEntityTextURL); ok {
+			entity.URL = u.URL
+		}
+		meta.Entities = append(meta.Entities, entity)
+	}
+	if fwd, ok := msg.GetFwdFrom(); ok {
+		meta.Extra = make(map[string]any)
+		if from, ok := fwd.GetFromID(); ok {
+			meta.Extra["forward_from_id"] = ChatIdFromPeer(from)
+		}
+		if name, ok := fwd.GetFromName(); ok {
+			meta.Extra["forward_from_name"] = name
+		}
+		if postID, ok := fwd.GetChannelPost(); ok {
+			meta.Extra["forward_post_id"] = postID
+		}
+	}
+	return meta
+}
+
+// SidecarFromFile builds the sidecar metadata of a Telegram file saved to
+// filePath. It returns nil for files without a source message.
+func SidecarFromFile(file tfile.TGFile, filePath string) *sidecar.Metadata {
+	messageFile, ok := file.(tfile.TGFileMessage)
+	if !ok || messageFile.Message() == nil {
+		return nil
+	}
+	meta := SidecarFromMessage(messageFile.Message())
+	meta.FileName = path.Base(filePath)
+	meta.FileSize = file.Size()
+	return meta
+}
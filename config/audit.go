@@ -0,0 +1,10 @@
+package config
+
+type auditConfig struct {
+	// Enable records user actions to the database, see pkg/audit.
+	Enable bool `toml:"enable" mapstructure:"enable" json:"enable"`
+	// File additionally appends entries as JSON Lines to this file. Empty disables it.
+	File string `toml:"file" mapstructure:"file" json:"file"`
+	// RetentionDays is how long entries are kept in the database, 0 keeps them forever.
+	RetentionDays int `toml:"retention_days" mapstructure:"retention_days" json:"retention_days"`
+}
@@ -2,6 +2,10 @@ package config
 
 type hookConfig struct {
 	Exec hookExecConfig `toml:"exec" mapstructure:"exec" json:"exec"`
+	// named commands that rules can run on saved files, name -> command
+	PostProcess map[string]string `toml:"postprocess" mapstructure:"postprocess" json:"postprocess"`
+	// chats that the notify action of rules can message, besides the chat of the user owning the rule
+	NotifyChats []int64 `toml:"notify_chats" mapstructure:"notify_chats" json:"notify_chats"`
 }
 
 type hookExecConfig struct {
@@ -0,0 +1,8 @@
+package config
+
+type sidecarConfig struct {
+	// Enable writes a metadata sidecar file next to every saved file.
+	Enable bool `toml:"enable" mapstructure:"enable" json:"enable"`
+	// Format is the sidecar format, either "json" or "nfo".
+	Format string `toml:"format" mapstructure:"format" json:"format"`
+}
@@ -0,0 +1,15 @@
+package config
+
+type tracingConfig struct {
+	// Enable exports OpenTelemetry traces over OTLP/HTTP.
+	Enable bool `toml:"enable" mapstructure:"enable" json:"enable"`
+	// Endpoint is the OTLP/HTTP endpoint URL, e.g. http://localhost:4318.
+	// Empty uses the OTEL_EXPORTER_OTLP_* environment variables.
+	Endpoint string `toml:"endpoint" mapstructure:"endpoint" json:"endpoint"`
+	// Headers are sent with every export request, e.g. for authentication.
+	Headers map[string]string `toml:"headers" mapstructure:"headers" json:"headers"`
+	// SampleRatio is the fraction of traces to keep, from 0 to 1.
+	SampleRatio float64 `toml:"sample_ratio" mapstructure:"sample_ratio" json:"sample_ratio"`
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string `toml:"service_name" mapstructure:"service_name" json:"service_name"`
+}
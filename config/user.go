@@ -8,6 +8,10 @@ type userConfig struct {
 	ID        int64    `toml:"id" mapstructure:"id" json:"id"`                      // telegram user id
 	Storages  []string `toml:"storages" mapstructure:"storages" json:"storages"`    // storage names
 	Blacklist bool     `toml:"blacklist" mapstructure:"blacklist" json:"blacklist"` // 黑名单模式, storage names 中的存储将不会被使用, 默认为白名单模式
+	// WebDAVPassword 用于登录 WebDAV 服务, 用户名为 telegram user id, 为空则不允许该用户登录
+	WebDAVPassword string `toml:"webdav_password" mapstructure:"webdav_password" json:"webdav_password"`
+	// Admin 管理员可以使用 /audit 查看所有用户的操作记录, 以及创建 admin 权限的 API 密钥
+	Admin bool `toml:"admin" mapstructure:"admin" json:"admin"`
 }
 
 var userIDs []int64
@@ -26,6 +30,26 @@ func (c Config) GetUsersID() []int64 {
 	return userIDs
 }
 
+// GetWebDAVPassword 返回用户的 WebDAV 密码, 未配置时返回空字符串
+func (c Config) GetWebDAVPassword(userID int64) string {
+	for _, user := range c.Users {
+		if user.ID == userID {
+			return user.WebDAVPassword
+		}
+	}
+	return ""
+}
+
+// IsAdmin 检查用户是否为管理员
+func (c Config) IsAdmin(userID int64) bool {
+	for _, user := range c.Users {
+		if user.ID == userID {
+			return user.Admin
+		}
+	}
+	return false
+}
+
 func (c Config) HasStorage(userID int64, storageName string) bool {
 	us, ok := userStorages[userID]
 	if !ok {
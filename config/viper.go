@@ -37,6 +37,9 @@ type Config struct {
 	Parser   parserConfig            `toml:"parser" mapstructure:"parser" json:"parser"`
 	Hook     hookConfig              `toml:"hook" mapstructure:"hook" json:"hook"`
 	Ytdlp    YtdlpConfig             `toml:"ytdlp" mapstructure:"ytdlp" json:"ytdlp"`
+	Sidecar  sidecarConfig           `toml:"sidecar" mapstructure:"sidecar" json:"sidecar"`
+	Tracing  tracingConfig           `toml:"tracing" mapstructure:"tracing" json:"tracing"`
+	Audit    auditConfig             `toml:"audit" mapstructure:"audit" json:"audit"`
 }
 
 type aria2Config struct {
@@ -51,6 +54,29 @@ type apiConfig struct {
 	Host   string `toml:"host" mapstructure:"host" json:"host"`
 	Port   int    `toml:"port" mapstructure:"port" json:"port"`
 	Token  string `toml:"token" mapstructure:"token" json:"token"`
+	// PublicURL is the externally reachable base URL used in links generated
+	// by the bot, e.g. https://sabot.example.com. Defaults to http://host:port.
+	PublicURL string `toml:"public_url" mapstructure:"public_url" json:"public_url"`
+	// StreamTTL is the lifetime of signed stream links in seconds.
+	StreamTTL int `toml:"stream_ttl" mapstructure:"stream_ttl" json:"stream_ttl"`
+	// WebDAV serves the storages of each user over a read-only WebDAV mount.
+	WebDAV apiWebDAVConfig `toml:"webdav" mapstructure:"webdav" json:"webdav"`
+	// Webhooks receive the events of every task, including tasks created from the bot.
+	Webhooks []apiWebhookConfig `toml:"webhooks" mapstructure:"webhooks" json:"webhooks"`
+}
+
+type apiWebhookConfig struct {
+	URL string `toml:"url" mapstructure:"url" json:"url"`
+	// Secret signs the requests with HMAC-SHA256, empty to send unsigned requests.
+	Secret string `toml:"secret" mapstructure:"secret" json:"secret"`
+	// Events to send, empty for completed, failed and cancelled.
+	Events []string `toml:"events" mapstructure:"events" json:"events"`
+}
+
+type apiWebDAVConfig struct {
+	Enable bool `toml:"enable" mapstructure:"enable" json:"enable"`
+	// Prefix is the path the WebDAV server is mounted under, e.g. /dav.
+	Prefix string `toml:"prefix" mapstructure:"prefix" json:"prefix"`
 }
 
 var cfg = &Config{}
@@ -139,13 +165,29 @@ func Init(ctx context.Context, configFile ...string) error {
 		"db.session": "data/session.db",
 
 		// API
-		"api.enable": false,
-		"api.host":   "0.0.0.0",
-		"api.port":   8080,
-		"api.token":  "",
+		"api.enable":        false,
+		"api.host":          "0.0.0.0",
+		"api.port":          8080,
+		"api.token":         "",
+		"api.stream_ttl":    21600,
+		"api.webdav.enable": false,
+		"api.webdav.prefix": "/dav",
 
 		// yt-dlp
 		"ytdlp.recode": "mp4",
+
+		// 元数据旁车文件
+		"sidecar.enable": false,
+		"sidecar.format": "json",
+
+		// OpenTelemetry 链路追踪
+		"tracing.enable":       false,
+		"tracing.sample_ratio": 1.0,
+		"tracing.service_name": "saveany-bot",
+
+		// 审计日志
+		"audit.enable":         true,
+		"audit.retention_days": 90,
 	}
 
 	for key, value := range defaultConfigs {
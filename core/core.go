@@ -7,14 +7,19 @@ import (
 
 	"github.com/charmbracelet/log"
 	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/pkg/audit"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
 	"github.com/krau/SaveAny-Bot/pkg/queue"
 	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
 )
 
 var (
 	queueOnce     sync.Once
 	queueInstance *queue.TaskQueue[Executable]
+
+	// workerSemaphore limits running tasks to the configured workers, set by Run
+	workerSemaphore chan struct{}
 )
 
 // initQueue lazily creates the shared task queue.
@@ -34,7 +39,6 @@ type Executable interface {
 
 func worker(ctx context.Context, qe *queue.TaskQueue[Executable], semaphore chan struct{}) {
 	logger := log.FromContext(ctx)
-	execHooks := config.C().Hook.Exec
 	for {
 		semaphore <- struct{}{}
 		qtask, err := qe.Get()
@@ -42,44 +46,56 @@ func worker(ctx context.Context, qe *queue.TaskQueue[Executable], semaphore chan
 			logger.Error("Failed to get task from queue:", err)
 			break // queue closed and empty
 		}
-		exe := qtask.Data
-		taskCtx := qtask.Context()
-		logger.Infof("Processing task: %s", exe.TaskID())
-		taskevent.Emit(taskCtx, taskevent.Event{TaskID: exe.TaskID(), Phase: taskevent.PhaseStart})
-		if err := ExecCommandString(taskCtx, execHooks.TaskBeforeStart); err != nil {
-			logger.Errorf("Failed to execute before start hook for task %s: %v", exe.TaskID(), err)
-		}
-		err = exe.Execute(taskCtx)
-		if err != nil {
-			if errors.Is(err, context.Canceled) {
-				logger.Infof("Task %s was canceled", exe.TaskID())
-				if err := ExecCommandString(ctx, execHooks.TaskCancel); err != nil {
-					logger.Errorf("Failed to execute cancel hook for task %s: %v", exe.TaskID(), err)
-				}
-			} else {
-				logger.Errorf("Failed to execute task %s: %v", exe.TaskID(), err)
-				if err := ExecCommandString(ctx, execHooks.TaskFail); err != nil {
-					logger.Errorf("Failed to execute fail hook for task %s: %v", exe.TaskID(), err)
-				}
+		runTask(ctx, qe, qtask)
+		<-semaphore
+	}
+}
+
+func runTask(ctx context.Context, qe *queue.TaskQueue[Executable], qtask *queue.Task[Executable]) {
+	logger := log.FromContext(ctx)
+	execHooks := config.C().Hook.Exec
+	exe := qtask.Data
+	taskCtx, span := tracing.Start(qtask.Context(), "task "+exe.Type().String(),
+		tracing.TaskIDKey.String(exe.TaskID()),
+		tracing.TaskTypeKey.String(exe.Type().String()),
+		tracing.TaskTitleKey.String(exe.Title()),
+		tracing.StorageKey.String(taskStorageName(exe)),
+	)
+	logger.Infof("Processing task: %s", exe.TaskID())
+	taskevent.Emit(taskCtx, taskevent.Event{TaskID: exe.TaskID(), Phase: taskevent.PhaseStart})
+	if err := ExecCommandString(taskCtx, execHooks.TaskBeforeStart); err != nil {
+		logger.Errorf("Failed to execute before start hook for task %s: %v", exe.TaskID(), err)
+	}
+	err := exe.Execute(taskCtx)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			logger.Infof("Task %s was canceled", exe.TaskID())
+			if err := ExecCommandString(ctx, execHooks.TaskCancel); err != nil {
+				logger.Errorf("Failed to execute cancel hook for task %s: %v", exe.TaskID(), err)
 			}
 		} else {
-			logger.Infof("Task %s completed successfully", exe.TaskID())
-			if err := ExecCommandString(ctx, execHooks.TaskSuccess); err != nil {
-				logger.Errorf("Failed to execute success hook for task %s: %v", exe.TaskID(), err)
+			logger.Errorf("Failed to execute task %s: %v", exe.TaskID(), err)
+			if err := ExecCommandString(ctx, execHooks.TaskFail); err != nil {
+				logger.Errorf("Failed to execute fail hook for task %s: %v", exe.TaskID(), err)
 			}
 		}
-		taskevent.Emit(taskCtx, taskevent.Event{TaskID: exe.TaskID(), Phase: taskevent.PhaseDone, Err: err})
-		qe.Done(qtask.ID)
-		<-semaphore
+	} else {
+		logger.Infof("Task %s completed successfully", exe.TaskID())
+		if err := ExecCommandString(ctx, execHooks.TaskSuccess); err != nil {
+			logger.Errorf("Failed to execute success hook for task %s: %v", exe.TaskID(), err)
+		}
 	}
+	taskevent.Emit(taskCtx, taskevent.Event{TaskID: exe.TaskID(), Phase: taskevent.PhaseDone, Err: err})
+	tracing.End(span, err)
+	qe.Done(qtask.ID)
 }
 
 func Run(ctx context.Context) {
 	log.FromContext(ctx).Info("Start processing tasks...")
-	semaphore := make(chan struct{}, config.C().Workers)
+	workerSemaphore = make(chan struct{}, config.C().Workers)
 	q := initQueue()
 	for range config.C().Workers {
-		go worker(ctx, q, semaphore)
+		go worker(ctx, q, workerSemaphore)
 	}
 
 }
@@ -91,13 +107,49 @@ func Close() {
 	}
 }
 
+// SinkFactory 为加入队列的任务创建 Sink, 返回 nil 表示不观察该任务
+type SinkFactory func(ctx context.Context, task Executable) taskevent.Sink
+
+var (
+	sinkFactoriesMu sync.RWMutex
+	sinkFactories   []SinkFactory
+)
+
+// RegisterSinkFactory 注册观察所有任务事件的 Sink, 包括从 Bot 创建的任务
+func RegisterSinkFactory(f SinkFactory) {
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+	sinkFactories = append(sinkFactories, f)
+}
+
 func AddTask(ctx context.Context, task Executable) error {
-	return initQueue().Add(queue.NewTask(ctx, task.TaskID(), task.Title(), task))
+	sinkFactoriesMu.RLock()
+	for _, f := range sinkFactories {
+		if sink := f(ctx, task); sink != nil {
+			ctx = taskevent.WithSink(ctx, sink)
+		}
+	}
+	sinkFactoriesMu.RUnlock()
+	if err := initQueue().Add(queue.NewTask(ctx, task.TaskID(), task.Title(), task)); err != nil {
+		return err
+	}
+	if g := TaskGroupFrom(ctx); g != nil {
+		g.add(task.TaskID())
+	}
+	audit.Record(ctx, audit.ActionTaskCreate, task.TaskID(), map[string]any{
+		"type":    task.Type().String(),
+		"title":   task.Title(),
+		"storage": taskStorageName(task),
+	})
+	return nil
 }
 
 func CancelTask(ctx context.Context, id string) error {
-	err := queueInstance.CancelTask(id)
-	return err
+	if err := queueInstance.CancelTask(id); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.ActionTaskCancel, id, nil)
+	return nil
 }
 
 func GetLength(ctx context.Context) int {
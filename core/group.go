@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/pkg/queue"
+)
+
+// TaskGroup 记录通过携带它的 context 加入队列的任务, 用于等待这些任务结束
+type TaskGroup struct {
+	mu    sync.Mutex
+	ids   []string
+	holds map[string]struct{}
+}
+
+func NewTaskGroup() *TaskGroup {
+	return &TaskGroup{holds: make(map[string]struct{})}
+}
+
+type taskGroupKey struct{}
+
+// WithTaskGroup 返回携带任务组的 context, 使用它加入队列的任务会记录到任务组中
+func WithTaskGroup(ctx context.Context, g *TaskGroup) context.Context {
+	return context.WithValue(ctx, taskGroupKey{}, g)
+}
+
+// TaskGroupFrom 返回 ctx 携带的任务组, 没有时返回 nil
+func TaskGroupFrom(ctx context.Context) *TaskGroup {
+	g, _ := ctx.Value(taskGroupKey{}).(*TaskGroup)
+	return g
+}
+
+// HoldTaskGroup 表示稍后会向 ctx 的任务组加入任务 (如等待相册的其他文件), 在调用返回的函数前任务组不会结束.
+// 相同的 key 只记录一次, ctx 未携带任务组时为空操作
+func HoldTaskGroup(ctx context.Context, key string) (release func()) {
+	g := TaskGroupFrom(ctx)
+	if g == nil {
+		return func() {}
+	}
+	g.mu.Lock()
+	g.holds[key] = struct{}{}
+	g.mu.Unlock()
+	return func() {
+		g.mu.Lock()
+		delete(g.holds, key)
+		g.mu.Unlock()
+	}
+}
+
+func (g *TaskGroup) add(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ids = append(g.ids, id)
+}
+
+// Len 返回任务组中未结束的任务数, 包括尚未释放的 hold
+func (g *TaskGroup) Len() int {
+	q := initQueue()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ids = slices.DeleteFunc(g.ids, func(id string) bool { return !q.Pending(id) })
+	return len(g.ids) + len(g.holds)
+}
+
+// 检查任务组是否结束的间隔
+const taskGroupPollInterval = time.Second
+
+// Wait 等待任务组中的任务全部结束, 只能在任务执行时调用.
+// 调用的任务占用着一个 worker 的许可, 等待期间把许可借给另一个 goroutine 执行任务,
+// 避免所有 worker 都在等待时任务组中的任务无法执行, 同时运行的任务数仍不超过 worker 数
+func (g *TaskGroup) Wait(ctx context.Context) error {
+	if g.Len() == 0 {
+		return nil
+	}
+	if sem := workerSemaphore; sem != nil {
+		<-sem
+		stopCtx, stop := context.WithCancel(context.Background())
+		defer func() {
+			stop()
+			sem <- struct{}{}
+		}()
+		go lendWorker(stopCtx, context.WithoutCancel(ctx), initQueue(), sem)
+	}
+
+	ticker := time.NewTicker(taskGroupPollInterval)
+	defer ticker.Stop()
+	for g.Len() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// lendWorker 每个任务从 sem 取得许可后执行, 直到 stopCtx 结束. 等待许可或任务时立即退出, 正在执行的任务会执行完
+func lendWorker(stopCtx, ctx context.Context, qe *queue.TaskQueue[Executable], sem chan struct{}) {
+	for {
+		select {
+		case sem <- struct{}{}:
+		case <-stopCtx.Done():
+			return
+		}
+		qtask, err := qe.GetContext(stopCtx)
+		if err != nil {
+			<-sem
+			return
+		}
+		runTask(ctx, qe, qtask)
+		<-sem
+	}
+}
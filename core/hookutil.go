@@ -8,6 +8,12 @@ import (
 )
 
 func ExecCommandString(ctx context.Context, cmd string) error {
+	return ExecCommandStringWithEnv(ctx, cmd, nil)
+}
+
+// ExecCommandStringWithEnv is like ExecCommandString, with extra environment
+// variables in "KEY=value" form.
+func ExecCommandStringWithEnv(ctx context.Context, cmd string, env []string) error {
 	if cmd == "" {
 		return nil
 	}
@@ -17,6 +23,9 @@ func ExecCommandString(ctx context.Context, cmd string) error {
 	} else {
 		execCmd = exec.CommandContext(ctx, "sh", "-c", cmd)
 	}
+	if len(env) > 0 {
+		execCmd.Env = append(os.Environ(), env...)
+	}
 	execCmd.Stdout = os.Stdout
 	execCmd.Stderr = os.Stderr
 	return execCmd.Run()
@@ -0,0 +1,23 @@
+package core
+
+import (
+	"context"
+
+	"github.com/krau/SaveAny-Bot/pkg/metrics"
+	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+)
+
+func init() {
+	metrics.SetQueueLength(func() int { return initQueue().ActiveLength() })
+	RegisterSinkFactory(func(ctx context.Context, task Executable) taskevent.Sink {
+		return metrics.TaskSink(task.TaskID(), task.Type().String(), taskStorageName(task))
+	})
+}
+
+// taskStorageName 返回任务保存到的存储, 批量任务等没有单一存储的任务返回空字符串
+func taskStorageName(task Executable) string {
+	if t, ok := task.(interface{ StorageName() string }); ok {
+		return t.StorageName()
+	}
+	return ""
+}
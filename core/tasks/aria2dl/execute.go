@@ -13,7 +13,9 @@ import (
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/pkg/aria2"
 	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
 	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
 )
 
 // Execute implements core.Executable.
@@ -152,6 +154,7 @@ func (t *Task) transferFiles(ctx context.Context) error {
 
 	logger.Infof("Transferring %d file(s) to storage %s", len(status.Files), t.Storage.Name())
 	transferredCount := 0
+	names := fnametmpl.UniqueNames{}
 
 	for _, file := range status.Files {
 		if file.Selected != "true" {
@@ -168,7 +171,11 @@ func (t *Task) transferFiles(ctx context.Context) error {
 			continue
 		}
 
-		if err := t.transferFile(ctx, file.Path); err != nil {
+		name := t.renderName(ctx, file, transferredCount+1)
+		if t.NameTmpl != nil {
+			name = names.Make(name, transferredCount+1)
+		}
+		if err := t.transferFile(ctx, file.Path, name); err != nil {
 			return err
 		}
 
@@ -184,7 +191,9 @@ func (t *Task) transferFiles(ctx context.Context) error {
 }
 
 // transferFile transfers a single file to storage
-func (t *Task) transferFile(ctx context.Context, filePath string) error {
+func (t *Task) transferFile(ctx context.Context, filePath, fileName string) (err error) {
+	ctx, span := tracing.Start(ctx, "file", tracing.FileNameKey.String(fileName))
+	defer func() { tracing.End(span, err) }()
 	logger := log.FromContext(ctx)
 
 	// Check if file exists
@@ -196,6 +205,7 @@ func (t *Task) transferFile(ctx context.Context, filePath string) error {
 		}
 		return fmt.Errorf("failed to stat file %s: %w", filePath, err)
 	}
+	span.SetAttributes(tracing.FileSizeKey.Int64(fileInfo.Size()))
 
 	// Open file
 	f, err := os.Open(filePath)
@@ -208,7 +218,6 @@ func (t *Task) transferFile(ctx context.Context, filePath string) error {
 	ctx = context.WithValue(ctx, ctxkey.ContentLength, fileInfo.Size())
 
 	// Save to storage
-	fileName := filepath.Base(filePath)
 	destPath := filepath.Join(t.StorPath, fileName)
 
 	logger.Infof("Transferring file %s to %s:%s", fileName, t.Storage.Name(), destPath)
@@ -221,6 +230,27 @@ func (t *Task) transferFile(ctx context.Context, filePath string) error {
 	return nil
 }
 
+// renderName 使用文件名模板为下载的文件命名, 渲染失败时返回原文件名
+func (t *Task) renderName(ctx context.Context, file aria2.File, index int) string {
+	fileName := filepath.Base(file.Path)
+	if t.NameTmpl == nil {
+		return fileName
+	}
+	size, _ := strconv.ParseInt(file.Length, 10, 64)
+	data := fnametmpl.NewFileData(fileName, size, index)
+	if len(file.URIs) > 0 {
+		data.SetURL(file.URIs[0].URI)
+	} else if len(t.URIs) > 0 {
+		data.SetURL(t.URIs[0])
+	}
+	name, err := t.NameTmpl.Execute(data)
+	if err != nil {
+		log.FromContext(ctx).Warnf("Failed to render filename template for %s: %v", fileName, err)
+		return fileName
+	}
+	return name
+}
+
 // removeFileIfNeeded removes a file if RemoveAfterTransfer is enabled
 func (t *Task) removeFileIfNeeded(filePath string) {
 	if config.C().Aria2.KeepFile {
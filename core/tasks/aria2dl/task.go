@@ -7,6 +7,7 @@ import (
 	"github.com/krau/SaveAny-Bot/core"
 	"github.com/krau/SaveAny-Bot/pkg/aria2"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
 	"github.com/krau/SaveAny-Bot/storage"
 )
 
@@ -21,6 +22,8 @@ type Task struct {
 	Storage     storage.Storage
 	StorPath    string
 	Progress    ProgressTracker
+	// 文件名模板, 为 nil 时使用 aria2 下载的文件名
+	NameTmpl *fnametmpl.Template
 }
 
 // Title implements core.Executable.
@@ -38,6 +41,11 @@ func (t *Task) TaskID() string {
 	return t.ID
 }
 
+// StorageName returns the name of the storage the task saves to.
+func (t *Task) StorageName() string {
+	return t.Storage.Name()
+}
+
 func NewTask(
 	id string,
 	ctx context.Context,
@@ -0,0 +1,79 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/log"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+	"github.com/krau/SaveAny-Bot/core"
+	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+)
+
+// Execute implements core.Executable.
+func (t *Task) Execute(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithPrefix(fmt.Sprintf("backfill[%s]", t.ID))
+	logger.Infof("Starting backfill of chat %d, messages %d-%d", t.chatID, t.Next, t.Until)
+	if t.Progress != nil {
+		t.Progress.OnStart(ctx, t)
+	}
+	err := t.walk(ctx)
+	if errors.Is(err, context.Canceled) {
+		// 取消的回填不在重启后继续
+		if err := t.Checkpoint(context.WithoutCancel(ctx), t.Until+1, t.Until); err != nil {
+			logger.Errorf("Failed to clear checkpoint: %v", err)
+		}
+	}
+	if err != nil {
+		logger.Errorf("Backfill stopped at message %d: %v", t.Next, err)
+	} else {
+		logger.Infof("Backfill completed, %d files queued", t.queued.Load())
+	}
+	if t.Progress != nil {
+		t.Progress.OnDone(ctx, t, err)
+	}
+	return err
+}
+
+func (t *Task) walk(ctx context.Context) error {
+	for t.Next <= t.Until {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := min(t.Next+chunkSize-1, t.Until)
+		msgs, err := tgutil.GetMessagesRange(t.Client, t.chatID, t.Next, end)
+		if err != nil {
+			return fmt.Errorf("failed to get messages %d-%d: %w", t.Next, end, err)
+		}
+		slices.SortFunc(msgs, func(a, b *tg.Message) int {
+			return a.GetID() - b.GetID()
+		})
+		if len(msgs) > 0 {
+			group := core.NewTaskGroup()
+			t.queued.Add(int64(t.Handle(core.WithTaskGroup(ctx, group), msgs)))
+			// 文件保存完成后才保存检查点, 使重启后不会丢失已加入队列的文件, 同时限制队列中的任务数
+			if err := group.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		t.scanned.Add(int64(end - t.Next + 1))
+		t.Next = end + 1
+		// 检查点无法保存时 (如已取消监听) 停止回填
+		if err := t.Checkpoint(ctx, t.Next, t.Until); err != nil {
+			return fmt.Errorf("failed to save checkpoint at message %d: %w", t.Next, err)
+		}
+		taskevent.Emit(ctx, taskevent.Event{
+			TaskID:          t.ID,
+			Phase:           taskevent.PhaseProgress,
+			TotalFiles:      int(t.TotalMessages()),
+			DownloadedFiles: int(t.Scanned()),
+		})
+		if t.Progress != nil {
+			t.Progress.OnProgress(ctx, t)
+		}
+	}
+	return nil
+}
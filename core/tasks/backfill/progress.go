@@ -0,0 +1,98 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/common/i18n"
+	"github.com/krau/SaveAny-Bot/common/i18n/i18nk"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
+)
+
+type ProgressTracker interface {
+	OnStart(ctx context.Context, info TaskInfo)
+	OnProgress(ctx context.Context, info TaskInfo)
+	OnDone(ctx context.Context, info TaskInfo, err error)
+}
+
+// 回填可能持续很久, 按时间间隔更新进度消息
+const progressInterval = 5 * time.Second
+
+type Progress struct {
+	MessageID  int
+	ChatID     int64
+	lastUpdate time.Time
+}
+
+func (p *Progress) OnStart(ctx context.Context, info TaskInfo) {
+	p.lastUpdate = time.Now()
+	p.edit(ctx, info, i18n.T(i18nk.BotMsgProgressBackfillProgress, progressData(info)), true)
+}
+
+func (p *Progress) OnProgress(ctx context.Context, info TaskInfo) {
+	if time.Since(p.lastUpdate) < progressInterval {
+		return
+	}
+	p.lastUpdate = time.Now()
+	p.edit(ctx, info, i18n.T(i18nk.BotMsgProgressBackfillProgress, progressData(info)), true)
+}
+
+func (p *Progress) OnDone(ctx context.Context, info TaskInfo, err error) {
+	if err == nil {
+		p.edit(ctx, info, i18n.T(i18nk.BotMsgProgressBackfillDone, progressData(info)), false)
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		p.edit(ctx, info, i18n.T(i18nk.BotMsgProgressTaskCanceledWithId, map[string]any{
+			"TaskID": info.TaskID(),
+		}), false)
+		return
+	}
+	p.edit(ctx, info, i18n.T(i18nk.BotMsgProgressTaskFailedWithError, map[string]any{
+		"Error": err.Error(),
+	}), false)
+}
+
+func (p *Progress) edit(ctx context.Context, info TaskInfo, text string, cancelable bool) {
+	ext := tgutil.ExtFromContext(ctx)
+	if ext == nil {
+		return
+	}
+	req := &tg.MessagesEditMessageRequest{
+		ID:      p.MessageID,
+		Message: text,
+	}
+	if cancelable {
+		req.SetReplyMarkup(&tg.ReplyInlineMarkup{
+			Rows: []tg.KeyboardButtonRow{
+				{
+					Buttons: []tg.KeyboardButtonClass{
+						tgutil.BuildCancelButton(info.TaskID()),
+					},
+				},
+			}},
+		)
+	}
+	if _, err := ext.EditMessage(p.ChatID, req); err != nil {
+		log.FromContext(ctx).Debugf("Failed to edit backfill progress message: %s", err)
+	}
+}
+
+func progressData(info TaskInfo) map[string]any {
+	return map[string]any{
+		"Chat":    info.ChatID(),
+		"Scanned": info.Scanned(),
+		"Total":   info.TotalMessages(),
+		"Queued":  info.Queued(),
+	}
+}
+
+func NewProgress(messageID int, chatID int64) *Progress {
+	return &Progress{
+		MessageID: messageID,
+		ChatID:    chatID,
+	}
+}
@@ -0,0 +1,73 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/celestix/gotgproto/ext"
+	"github.com/gotd/td/tg"
+	"github.com/krau/SaveAny-Bot/core"
+	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
+)
+
+var _ core.Executable = (*Task)(nil)
+
+// 每批读取的消息数量, 每批处理完成后保存一次检查点
+const chunkSize = 100
+
+// Handler 处理一批按 ID 升序排列的历史消息, 返回加入队列的文件数.
+// 任务需要使用 ctx 的任务组 (core.TaskGroupFrom) 加入队列, 回填在这些任务结束后才继续
+type Handler func(ctx context.Context, msgs []*tg.Message) int
+
+// Checkpointer 保存下一条要处理的消息 ID, next 大于 until 时表示回填已完成
+type Checkpointer func(ctx context.Context, next, until int) error
+
+type Task struct {
+	ID         string
+	Ctx        context.Context
+	Client     *ext.Context // 用于读取历史消息的 UserBot
+	chatID     int64
+	Next       int // 下一条要处理的消息 ID
+	Until      int // 最后一条要处理的消息 ID (含)
+	Handle     Handler
+	Checkpoint Checkpointer
+	Progress   ProgressTracker
+
+	from    int
+	scanned atomic.Int64
+	queued  atomic.Int64
+}
+
+// Title implements core.Exectable.
+func (t *Task) Title() string {
+	return fmt.Sprintf("[%s](%d:%d-%d)", t.Type(), t.chatID, t.from, t.Until)
+}
+
+func (t *Task) Type() tasktype.TaskType {
+	return tasktype.TaskTypeBackfill
+}
+
+func NewTask(
+	id string,
+	ctx context.Context,
+	client *ext.Context,
+	chatID int64,
+	next, until int,
+	handle Handler,
+	checkpoint Checkpointer,
+	progress ProgressTracker,
+) *Task {
+	return &Task{
+		ID:         id,
+		Ctx:        ctx,
+		Client:     client,
+		chatID:     chatID,
+		Next:       max(next, 1),
+		Until:      until,
+		Handle:     handle,
+		Checkpoint: checkpoint,
+		Progress:   progress,
+		from:       max(next, 1),
+	}
+}
@@ -0,0 +1,29 @@
+package backfill
+
+type TaskInfo interface {
+	TaskID() string
+	ChatID() int64
+	TotalMessages() int64
+	Scanned() int64
+	Queued() int64
+}
+
+func (t *Task) TaskID() string {
+	return t.ID
+}
+
+func (t *Task) ChatID() int64 {
+	return t.chatID
+}
+
+func (t *Task) TotalMessages() int64 {
+	return int64(t.Until - t.from + 1)
+}
+
+func (t *Task) Scanned() int64 {
+	return t.scanned.Load()
+}
+
+func (t *Task) Queued() int64 {
+	return t.queued.Load()
+}
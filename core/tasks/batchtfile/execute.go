@@ -15,10 +15,13 @@ import (
 	"github.com/krau/SaveAny-Bot/common/tdler"
 	"github.com/krau/SaveAny-Bot/common/utils/fsutil"
 	"github.com/krau/SaveAny-Bot/common/utils/ioutil"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+	"github.com/krau/SaveAny-Bot/pkg/sidecar"
 	"github.com/krau/SaveAny-Bot/pkg/storagetypes"
 	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
 	"github.com/krau/SaveAny-Bot/storage"
 	"golang.org/x/sync/errgroup"
 )
@@ -97,7 +100,7 @@ func (t *Task) executionGroups() []executionGroup {
 		end := i + 1
 		for end < len(t.elems) {
 			next := &t.elems[end]
-			if next.Storage != elem.Storage || next.sourceGroupKey != elem.sourceGroupKey {
+			if next.Storage != elem.Storage || next.sourceGroupKey != elem.sourceGroupKey || next.Overwrite != elem.Overwrite {
 				break
 			}
 			end++
@@ -134,6 +137,10 @@ func (t *Task) processElements(ctx context.Context, elems []*TaskElement) error
 }
 
 func (t *Task) processBatch(ctx context.Context, group executionGroup) error {
+	// 同一组内的 Overwrite 相同, 见 executionGroups
+	if group.elems[0].Overwrite {
+		ctx = storage.WithOverwrite(ctx)
+	}
 	defer func() {
 		for _, elem := range group.elems {
 			if err := os.Remove(elem.localPath); err != nil && !os.IsNotExist(err) {
@@ -241,6 +248,7 @@ func (t *Task) saveBatchItems(ctx context.Context, successElems []*TaskElement,
 		for index, elem := range successElems {
 			t.uploadCallback(ctx, elem.ID)(items[index].Size, items[index].Size)
 			t.markItemCompleted(elem.ID)
+			writeSidecar(ctx, elem)
 		}
 		t.notifyStateChange(ctx)
 		return nil
@@ -262,6 +270,7 @@ func (t *Task) saveBatchItems(ctx context.Context, successElems []*TaskElement,
 	for index, elem := range successElems {
 		t.uploadCallback(ctx, elem.ID)(items[index].Size, items[index].Size)
 		t.markItemCompleted(elem.ID)
+		writeSidecar(ctx, elem)
 	}
 	t.notifyStateChange(ctx)
 	return nil
@@ -286,7 +295,12 @@ func (t *Task) unmarkProcessing(id string) {
 	t.processingMu.Unlock()
 }
 
-func (t *Task) downloadElement(ctx context.Context, elem *TaskElement) error {
+func (t *Task) downloadElement(ctx context.Context, elem *TaskElement) (err error) {
+	ctx, span := tracing.Start(ctx, "file",
+		tracing.FileNameKey.String(elem.File.Name()),
+		tracing.FileSizeKey.Int64(elem.File.Size()),
+	)
+	defer func() { tracing.End(span, err) }()
 	logger := log.FromContext(ctx).WithPrefix(fmt.Sprintf("file[%s]", elem.File.Name()))
 	logger.Info("Starting file download")
 	localFile, err := fsutil.CreateFile(elem.localPath)
@@ -306,7 +320,7 @@ func (t *Task) downloadElement(ctx context.Context, elem *TaskElement) error {
 			DownloadedBytes: downloaded,
 		})
 	})
-	_, downloadErr := tdler.NewDownloader(elem.File).Parallel(ctx, wrAt)
+	downloadErr := tdler.Download(ctx, elem.File, wrAt)
 	closeErr := localFile.Close()
 	if downloadErr != nil {
 		t.markItemFailed(elem.ID, FailureStageDownload, downloadErr)
@@ -329,8 +343,16 @@ func (t *Task) downloadElement(ctx context.Context, elem *TaskElement) error {
 	return nil
 }
 
-func (t *Task) processElement(ctx context.Context, elem TaskElement) error {
+func (t *Task) processElement(ctx context.Context, elem TaskElement) (err error) {
+	ctx, span := tracing.Start(ctx, "file",
+		tracing.FileNameKey.String(elem.File.Name()),
+		tracing.FileSizeKey.Int64(elem.File.Size()),
+	)
+	defer func() { tracing.End(span, err) }()
 	logger := log.FromContext(ctx).WithPrefix(fmt.Sprintf("file[%s]", elem.File.Name()))
+	if elem.Overwrite {
+		ctx = storage.WithOverwrite(ctx)
+	}
 	if elem.stream {
 		pr, pw := io.Pipe()
 		defer pr.Close()
@@ -357,7 +379,7 @@ func (t *Task) processElement(ctx context.Context, elem TaskElement) error {
 		errg.Go(func() error {
 			defer pw.Close()
 			logger.Info("Starting file download in stream mode")
-			_, err := tdler.NewDownloader(elem.File).Stream(uploadCtx, wr)
+			err := tdler.Stream(uploadCtx, elem.File, wr)
 			if err != nil {
 				logger.Errorf("Failed to download file: %v", err)
 				t.markItemFailed(elem.ID, FailureStageDownload, err)
@@ -377,6 +399,7 @@ func (t *Task) processElement(ctx context.Context, elem TaskElement) error {
 		t.recordDownloadComplete(elem.ID, streamedBytes)
 		t.markItemCompleted(elem.ID)
 		t.notifyStateChange(ctx)
+		writeSidecar(ctx, &elem)
 		logger.Info("File downloaded successfully in stream mode")
 		return nil
 	}
@@ -403,7 +426,7 @@ func (t *Task) processElement(ctx context.Context, elem TaskElement) error {
 			DownloadedBytes: downloaded,
 		})
 	})
-	_, err = tdler.NewDownloader(elem.File).Parallel(ctx, wrAt)
+	err = tdler.Download(ctx, elem.File, wrAt)
 	if err != nil {
 		t.markItemFailed(elem.ID, FailureStageDownload, err)
 		t.notifyStateChange(ctx)
@@ -460,9 +483,17 @@ func (t *Task) processElement(ctx context.Context, elem TaskElement) error {
 		onProgress(fileStat.Size(), fileStat.Size())
 		t.markItemCompleted(elem.ID)
 		t.notifyStateChange(vctx)
+		writeSidecar(ctx, &elem)
 	} else {
 		t.markItemFailed(elem.ID, lastFailureStage, err)
 		t.notifyStateChange(vctx)
 	}
 	return err
 }
+
+func writeSidecar(ctx context.Context, elem *TaskElement) {
+	sc := config.C().Sidecar
+	sidecar.Save(ctx, sidecar.Options{Enable: sc.Enable, Format: sc.Format}, elem.Storage, elem.Path, func() *sidecar.Metadata {
+		return tgutil.SidecarFromFile(elem.File, elem.Path)
+	})
+}
@@ -36,6 +36,23 @@ func TestExecutionGroupsPreserveSourceAlbums(t *testing.T) {
 	}
 }
 
+func TestExecutionGroupsSplitOnOverwrite(t *testing.T) {
+	stor := new(tgstorage.Telegram)
+	task := Task{elems: []TaskElement{
+		{Storage: stor, sourceGroupKey: "album-1"},
+		{Storage: stor, sourceGroupKey: "album-1", Overwrite: true},
+		{Storage: stor, sourceGroupKey: "album-1", Overwrite: true},
+	}}
+
+	groups := task.executionGroups()
+	if len(groups) != 2 || len(groups[0].elems) != 1 || len(groups[1].elems) != 2 {
+		t.Fatalf("got %d groups, want elements split by overwrite", len(groups))
+	}
+	if !groups[1].elems[0].Overwrite {
+		t.Fatal("second group should overwrite")
+	}
+}
+
 func TestSourceMetadataPreservesAlbumIdentityAndCaption(t *testing.T) {
 	msg := &tg.Message{
 		PeerID:  &tg.PeerChannel{ChannelID: 77},
@@ -23,6 +23,7 @@ type TaskElement struct {
 	Storage         storage.Storage
 	Path            string
 	File            tfile.TGFile
+	Overwrite       bool // 覆盖已存在的文件, 不依赖任务级别的覆盖设置
 	localPath       string
 	stream          bool
 	sourceGroupKey  string
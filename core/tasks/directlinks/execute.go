@@ -15,7 +15,9 @@ import (
 	"github.com/krau/SaveAny-Bot/common/utils/ioutil"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
 	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -29,7 +31,7 @@ func (t *Task) Execute(ctx context.Context) error {
 	eg, gctx := errgroup.WithContext(ctx)
 	eg.SetLimit(config.C().Workers)
 	fetchedTotalBytes := atomic.Int64{}
-	for _, file := range t.files {
+	for i, file := range t.files {
 		eg.Go(func() error {
 			req, err := http.NewRequestWithContext(ctx, http.MethodHead, file.URL, nil)
 			if err != nil {
@@ -58,6 +60,7 @@ func (t *Task) Execute(ctx context.Context) error {
 			if file.Name == "" {
 				return fmt.Errorf("failed to determine filename for %s: Content-Disposition header is empty and URL does not contain a valid filename", file.URL)
 			}
+			t.renderName(ctx, file, i+1)
 
 			return nil
 		})
@@ -71,6 +74,13 @@ func (t *Task) Execute(ctx context.Context) error {
 		return err
 	}
 	t.totalBytes = fetchedTotalBytes.Load()
+	if t.NameTmpl != nil {
+		// 模板可能为多个文件渲染出相同的文件名
+		names := fnametmpl.UniqueNames{}
+		for i, file := range t.files {
+			file.Name = names.Make(file.Name, i+1)
+		}
+	}
 	// start downloading
 	eg, gctx = errgroup.WithContext(ctx)
 	eg.SetLimit(config.C().Workers)
@@ -113,9 +123,29 @@ func (t *Task) Execute(ctx context.Context) error {
 	return err
 }
 
-func (t *Task) processLink(ctx context.Context, file *File) error {
+// renderName 使用文件名模板重命名文件, 渲染失败时保留原文件名
+func (t *Task) renderName(ctx context.Context, file *File, index int) {
+	if t.NameTmpl == nil {
+		return
+	}
+	data := fnametmpl.NewFileData(file.Name, file.Size, index)
+	data.SetURL(file.URL)
+	name, err := t.NameTmpl.Execute(data)
+	if err != nil {
+		log.FromContext(ctx).Warnf("Failed to render filename template for %s: %v", file.URL, err)
+		return
+	}
+	file.Name = name
+}
+
+func (t *Task) processLink(ctx context.Context, file *File) (err error) {
+	ctx, span := tracing.Start(ctx, "file",
+		tracing.FileNameKey.String(file.Name),
+		tracing.FileSizeKey.Int64(file.Size),
+	)
+	defer func() { tracing.End(span, err) }()
 	logger := log.FromContext(ctx)
-	err := retry.Retry(func() error {
+	err = retry.Retry(func() error {
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, file.URL, nil)
 		if err != nil {
 			return fmt.Errorf("failed to create GET request for %s: %w", file.URL, err)
@@ -10,6 +10,7 @@ import (
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/core"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
 	"github.com/krau/SaveAny-Bot/storage"
 )
 
@@ -36,6 +37,8 @@ type Task struct {
 	Storage  storage.Storage
 	StorPath string
 	Progress ProgressTracker
+	// 文件名模板, 为 nil 时使用 Content-Disposition 或链接中的文件名
+	NameTmpl *fnametmpl.Template
 
 	client          *http.Client // [TODO] parallel download
 	stream          bool
@@ -15,8 +15,11 @@ import (
 	"github.com/krau/SaveAny-Bot/common/utils/ioutil"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
 	"github.com/krau/SaveAny-Bot/pkg/parser"
+	"github.com/krau/SaveAny-Bot/pkg/sidecar"
 	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -26,9 +29,10 @@ func (t *Task) Execute(ctx context.Context) error {
 	if t.progress != nil {
 		t.progress.OnStart(ctx, t)
 	}
+	resources := t.renderNames(ctx)
 	eg, gctx := errgroup.WithContext(ctx)
 	eg.SetLimit(config.C().Workers)
-	for _, resource := range t.item.Resources {
+	for _, resource := range resources {
 		eg.Go(func() error {
 			resourceID := resource.ID()
 			t.processingMu.Lock()
@@ -53,6 +57,7 @@ func (t *Task) Execute(ctx context.Context) error {
 				return fmt.Errorf("failed to process resource %s: %w", resource.URL, err)
 			}
 			t.downloaded.Add(1)
+			t.writeSidecar(gctx, resource)
 			return nil
 		})
 	}
@@ -68,9 +73,45 @@ func (t *Task) Execute(ctx context.Context) error {
 	return err
 }
 
-func (t *Task) processResource(ctx context.Context, resource parser.Resource) error {
+// renderNames 返回使用文件名模板重命名后的资源, 渲染失败时保留原文件名, 重名时添加序号.
+// item 可能被其他任务共用, 不修改原资源
+func (t *Task) renderNames(ctx context.Context) []parser.Resource {
+	item := t.item
+	if t.NameTmpl == nil {
+		return item.Resources
+	}
+	resources := make([]parser.Resource, len(item.Resources))
+	copy(resources, item.Resources)
+	names := fnametmpl.UniqueNames{}
+	for i := range resources {
+		res := &resources[i]
+		data := fnametmpl.NewFileData(res.Filename, res.Size, i+1)
+		data.SetURL(item.URL)
+		data.Site = item.Site
+		data.Title = fnametmpl.Sanitize(item.Title)
+		data.Author = fnametmpl.Sanitize(item.Author)
+		data.Tags = make([]string, 0, len(item.Tags))
+		for _, tag := range item.Tags {
+			data.Tags = append(data.Tags, fnametmpl.Sanitize(tag))
+		}
+		name, err := t.NameTmpl.Execute(data)
+		if err != nil {
+			log.FromContext(ctx).Warnf("Failed to render filename template for %s: %v", res.URL, err)
+			name = res.Filename
+		}
+		res.Filename = names.Make(name, i+1)
+	}
+	return resources
+}
+
+func (t *Task) processResource(ctx context.Context, resource parser.Resource) (err error) {
+	ctx, span := tracing.Start(ctx, "file",
+		tracing.FileNameKey.String(resource.Filename),
+		tracing.FileSizeKey.Int64(resource.Size),
+	)
+	defer func() { tracing.End(span, err) }()
 	logger := log.FromContext(ctx)
-	err := retry.Retry(func() error {
+	err = retry.Retry(func() error {
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, resource.URL, nil)
 		if err != nil {
 			return err
@@ -144,3 +185,10 @@ func (t *Task) processResource(ctx context.Context, resource parser.Resource) er
 	}
 	return err
 }
+
+func (t *Task) writeSidecar(ctx context.Context, resource parser.Resource) {
+	sc := config.C().Sidecar
+	sidecar.Save(ctx, sidecar.Options{Enable: sc.Enable, Format: sc.Format}, t.Stor, path.Join(t.StorPath, resource.Filename), func() *sidecar.Metadata {
+		return sidecar.FromParsedItem(t.item, resource)
+	})
+}
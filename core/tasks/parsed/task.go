@@ -11,6 +11,7 @@ import (
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/core"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
 	"github.com/krau/SaveAny-Bot/pkg/parser"
 	"github.com/krau/SaveAny-Bot/storage"
 )
@@ -26,6 +27,8 @@ type Task struct {
 	httpClient *http.Client // [TODO] btorrent support?
 	progress   ProgressTracker
 	stream     bool
+	// 文件名模板, 为 nil 时使用解析器给出的文件名
+	NameTmpl *fnametmpl.Template
 
 	totalResources  int64
 	downloaded      atomic.Int64 // downloaded resources count
@@ -6,12 +6,15 @@ import (
 	"io"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/charmbracelet/log"
 	"github.com/duke-git/lancet/v2/retry"
 	"github.com/krau/SaveAny-Bot/common/utils/fsutil"
 	"github.com/krau/SaveAny-Bot/config"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
 	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -21,11 +24,12 @@ func (t *Task) Execute(ctx context.Context) error {
 	if t.progress != nil {
 		t.progress.OnStart(ctx, t)
 	}
+	names := t.picNames(ctx)
 	eg, gctx := errgroup.WithContext(ctx)
 	eg.SetLimit(config.C().Workers)
 	for i, pic := range t.Pics {
 		eg.Go(func() error {
-			err := t.processPic(gctx, pic, i)
+			err := t.processPic(gctx, pic, i, names[i])
 			if err != nil {
 				logger.Errorf("Error processing picture %s: %v", pic, err)
 				return fmt.Errorf("failed to process picture %s: %w", pic, err)
@@ -55,21 +59,22 @@ func (t *Task) Execute(ctx context.Context) error {
 	return err
 }
 
-func (t *Task) processPic(ctx context.Context, picUrl string, index int) error {
+func (t *Task) processPic(ctx context.Context, picUrl string, index int, filename string) (err error) {
+	ctx, span := tracing.Start(ctx, "file", tracing.URLKey.String(picUrl))
+	defer func() { tracing.End(span, err) }()
 	retryOpts := []retry.Option{
 		retry.Context(ctx),
 		retry.RetryTimes(uint(config.C().Retry)),
 	}
-	err := retry.Retry(func() error {
+	err = retry.Retry(func() error {
 		body, err := t.client.Download(ctx, picUrl)
 		if err != nil {
 			return fmt.Errorf("failed to download picture %s: %w", picUrl, err)
 		}
 		defer body.Close()
-		filename := fmt.Sprintf("%d%s", index+1, path.Ext(picUrl))
 		if t.cannotStream {
 			cacheFile, err := fsutil.CreateFile(filepath.Join(config.C().Temp.BasePath,
-				fmt.Sprintf("tph_%s_%s", t.TaskID(), filename),
+				fmt.Sprintf("tph_%s_%d%s", t.TaskID(), index+1, path.Ext(picUrl)),
 			))
 			if err != nil {
 				return fmt.Errorf("failed to create cache file for picture %s: %w", filename, err)
@@ -103,3 +108,30 @@ func (t *Task) processPic(ctx context.Context, picUrl string, index int) error {
 	}, retryOpts...)
 	return err
 }
+
+// picNames 返回图片的文件名, 默认为图片序号, 设置了文件名模板时使用模板渲染, 重名时添加序号
+func (t *Task) picNames(ctx context.Context) []string {
+	filenames := make([]string, len(t.Pics))
+	names := fnametmpl.UniqueNames{}
+	for i, picUrl := range t.Pics {
+		filenames[i] = names.Make(t.picName(ctx, picUrl, i), i+1)
+	}
+	return filenames
+}
+
+func (t *Task) picName(ctx context.Context, picUrl string, index int) string {
+	filename := fmt.Sprintf("%d%s", index+1, path.Ext(picUrl))
+	if t.NameTmpl == nil {
+		return filename
+	}
+	data := fnametmpl.NewFileData(path.Base(picUrl), 0, index+1)
+	data.SetURL("https://telegra.ph/" + strings.TrimPrefix(t.PhPath, "/"))
+	data.Site = "telegraph"
+	data.Title = fnametmpl.Sanitize(t.PageTitle)
+	name, err := t.NameTmpl.Execute(data)
+	if err != nil {
+		log.FromContext(ctx).Warnf("Failed to render filename template for %s: %v", picUrl, err)
+		return filename
+	}
+	return name
+}
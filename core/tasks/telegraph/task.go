@@ -7,6 +7,7 @@ import (
 
 	"github.com/krau/SaveAny-Bot/core"
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
 	"github.com/krau/SaveAny-Bot/pkg/telegraph"
 	"github.com/krau/SaveAny-Bot/storage"
 )
@@ -22,6 +23,10 @@ type Task struct {
 	StorPath string
 	client   *telegraph.Client
 	progress ProgressTracker
+	// 页面标题, 用于文件名模板
+	PageTitle string
+	// 文件名模板, 为 nil 时以图片序号命名
+	NameTmpl *fnametmpl.Template
 
 	cannotStream bool
 	totalpics    int
@@ -12,8 +12,10 @@ import (
 	"github.com/krau/SaveAny-Bot/common/tdler"
 	"github.com/krau/SaveAny-Bot/common/utils/fsutil"
 	"github.com/krau/SaveAny-Bot/common/utils/ioutil"
+	"github.com/krau/SaveAny-Bot/common/utils/tgutil"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+	"github.com/krau/SaveAny-Bot/pkg/sidecar"
 	"github.com/krau/SaveAny-Bot/pkg/storagetypes"
 	tfilepkg "github.com/krau/SaveAny-Bot/pkg/tfile"
 	"github.com/krau/SaveAny-Bot/storage"
@@ -45,7 +47,7 @@ func (t *Task) Execute(ctx context.Context) error {
 			t.Progress.OnDone(ctx, t, err)
 		}
 	}()
-	_, err = tdler.NewDownloader(t.File).Parallel(ctx, wrAt)
+	err = tdler.Download(ctx, t.File, wrAt)
 	if err != nil {
 		return fmt.Errorf("failed to download file: %w", err)
 	}
@@ -97,9 +99,17 @@ func (t *Task) Execute(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to save file after retries: %w", err)
 	}
+	writeSidecar(ctx, t)
 	return nil
 }
 
+func writeSidecar(ctx context.Context, t *Task) {
+	sc := config.C().Sidecar
+	sidecar.Save(ctx, sidecar.Options{Enable: sc.Enable, Format: sc.Format}, t.Storage, t.Path, func() *sidecar.Metadata {
+		return tgutil.SidecarFromFile(t.File, t.Path)
+	})
+}
+
 func sourceCaption(file tfilepkg.TGFile) (string, bool) {
 	messageFile, ok := file.(tfilepkg.TGFileMessage)
 	if !ok || messageFile.Message() == nil {
@@ -23,7 +23,7 @@ func executeStream(ctx context.Context, task *Task) error {
 	errg.Go(func() error {
 		defer pw.Close()
 		logger.Info("Starting file download in stream mode")
-		_, err := tdler.NewDownloader(task.File).Stream(uploadCtx, wr)
+		err := tdler.Stream(uploadCtx, task.File, wr)
 		if err != nil {
 			logger.Errorf("Failed to download file: %v", err)
 			pw.CloseWithError(err)
@@ -40,5 +40,6 @@ func executeStream(ctx context.Context, task *Task) error {
 		return err
 	}
 	logger.Info("File downloaded successfully in stream mode")
+	writeSidecar(ctx, task)
 	return nil
 }
@@ -0,0 +1,68 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/krau/SaveAny-Bot/pkg/storagetypes"
+	"github.com/krau/SaveAny-Bot/storage"
+)
+
+// NewSendElements 创建将存储中的文件发送到 target 的任务元素.
+// filePath 为目录时发送其中的所有文件 (不递归), 这些文件共享同一个 SourceGroupKey,
+// 以便 Telegram 存储将其作为相册批量发送.
+func NewSendElements(ctx context.Context, source storage.Storage, filePath string, target storage.Storage) ([]TaskElement, error) {
+	if _, ok := source.(storage.StorageReadable); !ok {
+		return nil, fmt.Errorf("storage %s does not support reading", source.Name())
+	}
+	filePath = path.Clean("/" + filePath)
+	listable, ok := source.(storage.StorageListable)
+	if !ok {
+		if filePath == "/" {
+			return nil, fmt.Errorf("storage %s does not support listing", source.Name())
+		}
+		// 无法列举的存储只能按文件处理, 大小在打开文件时获得
+		file := storagetypes.FileInfo{Name: path.Base(filePath), Path: filePath}
+		return []TaskElement{*NewTaskElement(source, file, target, "")}, nil
+	}
+
+	if filePath != "/" {
+		siblings, err := listable.ListFiles(ctx, path.Dir(filePath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		var found *storagetypes.FileInfo
+		for i := range siblings {
+			if siblings[i].Name == path.Base(filePath) {
+				found = &siblings[i]
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("%s: %w", filePath, os.ErrNotExist)
+		}
+		if !found.IsDir {
+			return []TaskElement{*NewTaskElement(source, *found, target, "")}, nil
+		}
+	}
+
+	files, err := listable.ListFiles(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	elems := make([]TaskElement, 0, len(files))
+	for _, file := range files {
+		if file.IsDir {
+			continue
+		}
+		elem := NewTaskElement(source, file, target, "")
+		elem.SourceGroupKey = filePath
+		elems = append(elems, *elem)
+	}
+	if len(elems) == 0 {
+		return nil, fmt.Errorf("no files found in %s", filePath)
+	}
+	return elems, nil
+}
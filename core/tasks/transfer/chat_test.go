@@ -0,0 +1,129 @@
+package transfer_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	storconfig "github.com/krau/SaveAny-Bot/config/storage"
+	"github.com/krau/SaveAny-Bot/core/tasks/transfer"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+	"github.com/krau/SaveAny-Bot/pkg/storagetypes"
+	"github.com/krau/SaveAny-Bot/storage"
+)
+
+// memSource is a listable storage holding files in a flat map of path to content.
+type memSource map[string]string
+
+func (memSource) Init(context.Context, storconfig.StorageConfig) error { return nil }
+func (memSource) Type() storenum.StorageType                           { return storenum.Local }
+func (memSource) Name() string                                         { return "mem-source" }
+func (memSource) Save(context.Context, io.Reader, string) error        { return nil }
+func (memSource) Exists(context.Context, string) bool                  { return false }
+
+func (m memSource) ListFiles(_ context.Context, dirPath string) ([]storagetypes.FileInfo, error) {
+	seen := make(map[string]bool)
+	files := make([]storagetypes.FileInfo, 0)
+	for p, content := range m {
+		rel, ok := strings.CutPrefix(p, strings.TrimSuffix(dirPath, "/")+"/")
+		if !ok {
+			continue
+		}
+		name, _, nested := strings.Cut(rel, "/")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		files = append(files, storagetypes.FileInfo{
+			Name:  name,
+			Path:  path.Join(dirPath, name),
+			Size:  int64(len(content)),
+			IsDir: nested,
+		})
+	}
+	return files, nil
+}
+
+func (m memSource) OpenFile(_ context.Context, filePath string) (io.ReadCloser, int64, error) {
+	content, ok := m[filePath]
+	if !ok {
+		return nil, 0, os.ErrNotExist
+	}
+	return io.NopCloser(strings.NewReader(content)), int64(len(content)), nil
+}
+
+// batchTarget records the batches it is asked to save.
+type batchTarget struct {
+	voidTarget
+	batches [][]storagetypes.BatchItem
+}
+
+func (b *batchTarget) SaveBatch(_ context.Context, items []storagetypes.BatchItem) error {
+	b.batches = append(b.batches, items)
+	return nil
+}
+
+var (
+	_ storage.StorageListable   = memSource{}
+	_ storage.StorageReadable   = memSource{}
+	_ storage.StorageBatchSaver = (*batchTarget)(nil)
+)
+
+func TestNewSendElements(t *testing.T) {
+	source := memSource{
+		"/album/a.jpg":        "a",
+		"/album/b.jpg":        "bb",
+		"/album/nested/c.jpg": "c",
+		"/single.txt":         "single",
+	}
+
+	elems, err := transfer.NewSendElements(t.Context(), source, "single.txt", voidTarget{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elems) != 1 || elems[0].SourcePath != "/single.txt" || elems[0].SourceGroupKey != "" {
+		t.Fatalf("unexpected elements for a file: %+v", elems)
+	}
+
+	elems, err = transfer.NewSendElements(t.Context(), source, "/album/", voidTarget{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elems) != 2 {
+		t.Fatalf("expected 2 files without the nested folder, got %d", len(elems))
+	}
+	for _, elem := range elems {
+		if elem.SourceGroupKey != "/album" {
+			t.Fatalf("expected group key /album, got %q", elem.SourceGroupKey)
+		}
+	}
+
+	if _, err := transfer.NewSendElements(t.Context(), source, "/missing.txt", voidTarget{}); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestExecuteSavesGroupsAsBatch(t *testing.T) {
+	initConfig(t)
+	source := memSource{"/album/a.jpg": "a", "/album/b.jpg": "bb"}
+	target := &batchTarget{}
+	elems, err := transfer.NewSendElements(t.Context(), source, "/album", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := transfer.NewTransferTask("id", t.Context(), elems, nil, false)
+	if err := task.Execute(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if len(target.batches) != 1 || len(target.batches[0]) != 2 {
+		t.Fatalf("expected one batch of 2 items, got %+v", target.batches)
+	}
+	if task.Uploaded() != 3 {
+		t.Fatalf("expected 3 uploaded bytes, got %d", task.Uploaded())
+	}
+}
@@ -12,7 +12,9 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/krau/SaveAny-Bot/config"
 	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+	"github.com/krau/SaveAny-Bot/pkg/storagetypes"
 	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
 	"github.com/krau/SaveAny-Bot/storage"
 	"golang.org/x/sync/errgroup"
 )
@@ -25,25 +27,95 @@ func (t *Task) Execute(ctx context.Context) error {
 		t.Progress.OnStart(ctx, t)
 	}
 
-	workers := config.C().Workers
-	eg, gctx := errgroup.WithContext(ctx)
-	eg.SetLimit(workers)
-
-	for _, elem := range t.elems {
-		eg.Go(func() error {
+	singles, groups := t.executionGroups()
+	err := t.processElements(ctx, singles)
+	if err == nil {
+		for _, group := range groups {
+			err = t.processBatch(ctx, group)
+			if err == nil {
+				continue
+			}
+			if !t.IgnoreErrors || errors.Is(err, context.Canceled) {
+				break
+			}
 			t.processingMu.Lock()
-			if t.processing[elem.ID] != nil {
-				t.processingMu.Unlock()
-				return fmt.Errorf("element with ID %s is already being processed", elem.ID)
+			for _, elem := range group.elems {
+				t.failed[elem.ID] = err
 			}
-			t.processing[elem.ID] = &elem
 			t.processingMu.Unlock()
+			logger.Errorf("Failed to process file group %s: %v", group.elems[0].SourceGroupKey, err)
+			err = nil
+		}
+	}
+	if err != nil {
+		logger.Errorf("Error during transfer processing: %v", err)
+	} else {
+		logger.Info("Transfer task completed successfully")
+	}
 
-			defer func() {
-				t.processingMu.Lock()
-				delete(t.processing, elem.ID)
-				t.processingMu.Unlock()
-			}()
+	if t.Progress != nil {
+		t.Progress.OnDone(ctx, t, err)
+	}
+	return err
+}
+
+type executionGroup struct {
+	elems      []TaskElement
+	batchSaver storage.StorageBatchSaver
+}
+
+// executionGroups 将带有相同 SourceGroupKey 且目标存储支持批量保存的相邻元素分为一组,
+// 其余元素单独处理
+func (t *Task) executionGroups() ([]TaskElement, []executionGroup) {
+	singles := make([]TaskElement, 0, len(t.elems))
+	groups := make([]executionGroup, 0)
+	for i := 0; i < len(t.elems); {
+		elem := t.elems[i]
+		batchSaver, ok := elem.TargetStorage.(storage.StorageBatchSaver)
+		if !ok || elem.SourceGroupKey == "" {
+			singles = append(singles, elem)
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(t.elems) &&
+			t.elems[end].TargetStorage == elem.TargetStorage &&
+			t.elems[end].SourceGroupKey == elem.SourceGroupKey {
+			end++
+		}
+		groups = append(groups, executionGroup{elems: t.elems[i:end], batchSaver: batchSaver})
+		i = end
+	}
+	return singles, groups
+}
+
+func (t *Task) markProcessing(elem *TaskElement) error {
+	t.processingMu.Lock()
+	defer t.processingMu.Unlock()
+	if t.processing[elem.ID] != nil {
+		return fmt.Errorf("element with ID %s is already being processed", elem.ID)
+	}
+	t.processing[elem.ID] = elem
+	return nil
+}
+
+func (t *Task) unmarkProcessing(id string) {
+	t.processingMu.Lock()
+	delete(t.processing, id)
+	t.processingMu.Unlock()
+}
+
+func (t *Task) processElements(ctx context.Context, elems []TaskElement) error {
+	logger := log.FromContext(ctx)
+	eg, gctx := errgroup.WithContext(ctx)
+	eg.SetLimit(config.C().Workers)
+
+	for _, elem := range elems {
+		eg.Go(func() error {
+			if err := t.markProcessing(&elem); err != nil {
+				return err
+			}
+			defer t.unmarkProcessing(elem.ID)
 
 			err := t.processElement(gctx, elem)
 			if err != nil && (!t.IgnoreErrors || errors.Is(err, context.Canceled)) {
@@ -58,21 +130,112 @@ func (t *Task) Execute(ctx context.Context) error {
 			return nil
 		})
 	}
+	return eg.Wait()
+}
+
+// processBatch 将一组文件下载到临时文件后通过 StorageBatchSaver 一次保存,
+// 使 Telegram 等存储可以将其作为相册发送
+func (t *Task) processBatch(ctx context.Context, group executionGroup) error {
+	files := make([]*os.File, len(group.elems))
+	defer func() {
+		for _, file := range files {
+			if file != nil {
+				file.Close()
+				os.Remove(file.Name())
+			}
+		}
+	}()
 
-	err := eg.Wait()
+	eg, gctx := errgroup.WithContext(ctx)
+	eg.SetLimit(config.C().Workers)
+	for i := range group.elems {
+		elem := &group.elems[i]
+		eg.Go(func() error {
+			if err := t.markProcessing(elem); err != nil {
+				return err
+			}
+			defer t.unmarkProcessing(elem.ID)
+			file, err := t.openToTemp(gctx, *elem)
+			if err != nil {
+				return fmt.Errorf("failed to download %s: %w", elem.FileInfo.Name, err)
+			}
+			files[i] = file
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	items := make([]storagetypes.BatchItem, 0, len(group.elems))
+	var size int64
+	for i, elem := range group.elems {
+		stat, err := files[i].Stat()
+		if err != nil {
+			return fmt.Errorf("failed to stat temp file: %w", err)
+		}
+		size += stat.Size()
+		items = append(items, storagetypes.BatchItem{
+			Reader:         files[i],
+			StoragePath:    path.Join(elem.TargetPath, elem.FileInfo.Name),
+			Size:           stat.Size(),
+			SourceGroupKey: elem.SourceGroupKey,
+		})
+	}
+	if err := group.batchSaver.SaveBatch(ctx, items); err != nil {
+		return fmt.Errorf("failed to save batch: %w", err)
+	}
+	t.addUploaded(ctx, size)
+	return nil
+}
+
+// openToTemp 将源文件下载到可 Seek 的临时文件
+func (t *Task) openToTemp(ctx context.Context, elem TaskElement) (tempFile *os.File, err error) {
+	ctx, span := tracing.Start(ctx, "file",
+		tracing.FilePathKey.String(elem.SourcePath),
+		tracing.FileSizeKey.Int64(elem.FileInfo.Size),
+	)
+	defer func() { tracing.End(span, err) }()
+	readableStorage, ok := elem.SourceStorage.(storage.StorageReadable)
+	if !ok {
+		return nil, fmt.Errorf("source storage %s does not support reading", elem.SourceStorage.Name())
+	}
+	reader, _, err := readableStorage.OpenFile(ctx, elem.SourcePath)
 	if err != nil {
-		logger.Errorf("Error during transfer processing: %v", err)
-	} else {
-		logger.Info("Transfer task completed successfully")
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer reader.Close()
+	tempFile, err = t.downloadToTemp(reader, elem.FileInfo.Name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tempFile.Seek(0, io.SeekStart); err != nil {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+		return nil, fmt.Errorf("failed to seek temp file: %w", err)
 	}
+	return tempFile, nil
+}
 
+func (t *Task) addUploaded(ctx context.Context, size int64) {
+	t.uploaded.Add(size)
 	if t.Progress != nil {
-		t.Progress.OnDone(ctx, t, err)
+		t.Progress.OnProgress(ctx, t)
 	}
-	return err
+	taskevent.Emit(ctx, taskevent.Event{
+		TaskID:          t.ID,
+		Phase:           taskevent.PhaseProgress,
+		TotalBytes:      t.totalSize,
+		DownloadedBytes: t.uploaded.Load(),
+	})
 }
 
-func (t *Task) processElement(ctx context.Context, elem TaskElement) error {
+func (t *Task) processElement(ctx context.Context, elem TaskElement) (err error) {
+	ctx, span := tracing.Start(ctx, "file",
+		tracing.FilePathKey.String(elem.SourcePath),
+		tracing.FileSizeKey.Int64(elem.FileInfo.Size),
+	)
+	defer func() { tracing.End(span, err) }()
 	logger := log.FromContext(ctx).WithPrefix(fmt.Sprintf("file[%s]", elem.FileInfo.Name))
 
 	// Check whether the source storage supports reading
@@ -117,16 +280,7 @@ func (t *Task) processElement(ctx context.Context, elem TaskElement) error {
 		}
 	}
 
-	t.uploaded.Add(size)
-	if t.Progress != nil {
-		t.Progress.OnProgress(ctx, t)
-	}
-	taskevent.Emit(ctx, taskevent.Event{
-		TaskID:          t.ID,
-		Phase:           taskevent.PhaseProgress,
-		TotalBytes:      t.totalSize,
-		DownloadedBytes: t.uploaded.Load(),
-	})
+	t.addUploaded(ctx, size)
 
 	logger.Info("File uploaded successfully")
 	return nil
@@ -22,6 +22,10 @@ type TaskElement struct {
 	FileInfo      storagetypes.FileInfo
 	TargetStorage storage.Storage
 	TargetPath    string
+
+	// SourceGroupKey 非空时, 相邻且 key 相同的元素会通过 StorageBatchSaver 一起保存,
+	// 例如作为 Telegram 相册发送
+	SourceGroupKey string
 }
 
 type Task struct {
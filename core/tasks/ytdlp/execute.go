@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
 	ytdlp "github.com/lrstanley/go-ytdlp"
 
 	"github.com/krau/SaveAny-Bot/config"
@@ -47,6 +49,16 @@ func (t *Task) Execute(ctx context.Context) error {
 		return err
 	}
 
+	var infoFiles map[string]string
+	if t.NameTmpl != nil {
+		var media []string
+		media, infoFiles = splitInfoFiles(downloadedFiles)
+		// 只为文件名模板写入的元数据文件不转存
+		if t.writesInfoJSON() {
+			downloadedFiles = media
+		}
+	}
+
 	if len(downloadedFiles) == 0 {
 		err := errors.New("no files were downloaded")
 		logger.Error(err.Error())
@@ -58,8 +70,14 @@ func (t *Task) Execute(ctx context.Context) error {
 
 	// Transfer downloaded files to storage
 	logger.Infof("Transferring %d file(s) to storage %s", len(downloadedFiles), t.Storage.Name())
-	for _, filePath := range downloadedFiles {
-		if err := t.transferFile(ctx, filePath); err != nil {
+	names := fnametmpl.UniqueNames{}
+	for i, filePath := range downloadedFiles {
+		infoPath := infoFiles[strings.TrimSuffix(filePath, filepath.Ext(filePath))]
+		name := t.renderName(ctx, filePath, infoPath, i+1)
+		if t.NameTmpl != nil {
+			name = names.Make(name, i+1)
+		}
+		if err := t.transferFile(ctx, filePath, name); err != nil {
 			logger.Errorf("File transfer failed: %v", err)
 			if t.Progress != nil {
 				t.Progress.OnDone(ctx, t, err)
@@ -90,6 +108,10 @@ func (t *Task) downloadFiles(ctx context.Context, tempDir string) ([]string, err
 	if len(t.Flags) == 0 {
 		cmd = applyFormatConfig(cmd, config.C().Ytdlp)
 	}
+	// 文件名模板需要视频的元数据
+	if t.writesInfoJSON() {
+		cmd = cmd.WriteInfoJSON()
+	}
 	// Note: If custom flags are provided, users have full control over format/quality
 	// The output path is always set above to ensure downloads go to the correct directory
 
@@ -138,7 +160,9 @@ func (t *Task) downloadFiles(ctx context.Context, tempDir string) ([]string, err
 }
 
 // transferFile transfers a single file to storage
-func (t *Task) transferFile(ctx context.Context, filePath string) error {
+func (t *Task) transferFile(ctx context.Context, filePath, fileName string) (err error) {
+	ctx, span := tracing.Start(ctx, "file", tracing.FileNameKey.String(fileName))
+	defer func() { tracing.End(span, err) }()
 	logger := log.FromContext(ctx)
 
 	// Check if file exists
@@ -150,6 +174,7 @@ func (t *Task) transferFile(ctx context.Context, filePath string) error {
 		}
 		return fmt.Errorf("failed to stat file %s: %w", filePath, err)
 	}
+	span.SetAttributes(tracing.FileSizeKey.Int64(fileInfo.Size()))
 
 	// Open file
 	f, err := os.Open(filePath)
@@ -162,9 +187,6 @@ func (t *Task) transferFile(ctx context.Context, filePath string) error {
 	ctx = context.WithValue(ctx, ctxkey.ContentLength, fileInfo.Size())
 
 	// Save to storage
-	fileName := filepath.Base(filePath)
-	// Remove special characters from filename if needed
-	fileName = sanitizeFilename(fileName)
 	destPath := filepath.Join(t.StorPath, fileName)
 
 	logger.Infof("Transferring file %s to %s:%s", fileName, t.Storage.Name(), destPath)
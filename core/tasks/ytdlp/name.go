@@ -0,0 +1,105 @@
+package ytdlp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+)
+
+const infoJSONSuffix = ".info.json"
+
+// videoInfo is the part of the .info.json written by yt-dlp used for filename
+// templates.
+type videoInfo struct {
+	Title      string   `json:"title"`
+	Uploader   string   `json:"uploader"`
+	UploadDate string   `json:"upload_date"` // YYYYMMDD
+	WebpageURL string   `json:"webpage_url"`
+	Extractor  string   `json:"extractor_key"`
+	Tags       []string `json:"tags"`
+}
+
+// writesInfoJSON reports whether the info json files are written for the
+// filename template, and not requested by the user.
+func (t *Task) writesInfoJSON() bool {
+	return t.NameTmpl != nil && !slices.Contains(t.Flags, "--write-info-json")
+}
+
+// splitInfoFiles separates the media files from the .info.json files written by
+// yt-dlp, which are keyed by the media file name without extension.
+func splitInfoFiles(files []string) ([]string, map[string]string) {
+	media := make([]string, 0, len(files))
+	infos := make(map[string]string)
+	for _, f := range files {
+		if base, ok := strings.CutSuffix(f, infoJSONSuffix); ok {
+			infos[base] = f
+			continue
+		}
+		media = append(media, f)
+	}
+	return media, infos
+}
+
+func readVideoInfo(path string) (*videoInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info := &videoInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// renderName 使用文件名模板为下载的文件命名, 渲染失败时返回原文件名
+func (t *Task) renderName(ctx context.Context, filePath, infoPath string, index int) string {
+	fileName := sanitizeFilename(filepath.Base(filePath))
+	if t.NameTmpl == nil {
+		return fileName
+	}
+	logger := log.FromContext(ctx)
+	var size int64
+	if stat, err := os.Stat(filePath); err == nil {
+		size = stat.Size()
+	}
+	data := fnametmpl.NewFileData(fileName, size, index)
+	if infoPath != "" {
+		info, err := readVideoInfo(infoPath)
+		if err != nil {
+			logger.Warnf("Failed to read yt-dlp info of %s: %v", fileName, err)
+		} else {
+			data.Title = fnametmpl.Sanitize(info.Title)
+			data.Uploader = fnametmpl.Sanitize(info.Uploader)
+			data.Author = data.Uploader
+			data.Site = strings.ToLower(info.Extractor)
+			data.UploadDate = info.UploadDate
+			if date, err := time.Parse("20060102", info.UploadDate); err == nil {
+				data.Time = date
+			}
+			for _, tag := range info.Tags {
+				data.Tags = append(data.Tags, fnametmpl.Sanitize(tag))
+			}
+			if info.WebpageURL != "" {
+				data.SetURL(info.WebpageURL)
+			}
+		}
+	}
+	if data.URL == "" && len(t.URLs) == 1 {
+		data.SetURL(t.URLs[0])
+	}
+	name, err := t.NameTmpl.Execute(data)
+	if err != nil {
+		logger.Warnf("Failed to render filename template for %s: %v", fileName, err)
+		return fileName
+	}
+	return name
+}
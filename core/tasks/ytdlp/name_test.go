@@ -0,0 +1,55 @@
+package ytdlp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+)
+
+func TestSplitInfoFiles(t *testing.T) {
+	media, infos := splitInfoFiles([]string{
+		"/tmp/a/Video One.mp4",
+		"/tmp/a/Video One.info.json",
+		"/tmp/a/Playlist.info.json",
+	})
+	if len(media) != 1 || media[0] != "/tmp/a/Video One.mp4" {
+		t.Fatalf("unexpected media files: %v", media)
+	}
+	if infos["/tmp/a/Video One"] != "/tmp/a/Video One.info.json" {
+		t.Fatalf("unexpected info files: %v", infos)
+	}
+}
+
+func TestRenderName(t *testing.T) {
+	dir := t.TempDir()
+	video := filepath.Join(dir, "Video: One.mp4")
+	info := filepath.Join(dir, "Video: One.info.json")
+	if err := os.WriteFile(video, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(info, []byte(`{"title":"Video: One","uploader":"Some/One","upload_date":"20240501","webpage_url":"https://www.youtube.com/watch?v=x","extractor_key":"Youtube"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := fnametmpl.Parse(`{{.UploadDate}}_{{.Uploader}}_{{.Title}}_{{.Host}}_{{.Date "2006"}}{{.Ext}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := NewTask("id", context.Background(), []string{"https://youtu.be/x"}, nil, &MockStorage{}, "/", nil)
+
+	if got := task.renderName(context.Background(), video, info, 1); got != "Video_ One.mp4" {
+		t.Errorf("without template: got %q", got)
+	}
+	task.NameTmpl = tmpl
+	want := "20240501_Some_One_Video_ One_www.youtube.com_2024.mp4"
+	if got := task.renderName(context.Background(), video, info, 1); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// 没有元数据时使用任务的链接
+	want = "___youtu.be_" + fnametmpl.NewFileData("", 0, 0).Date("2006") + ".mp4"
+	if got := task.renderName(context.Background(), video, "", 1); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func CreateAPIKey(ctx context.Context, key *APIKey) error {
+	return db.WithContext(ctx).Create(key).Error
+}
+
+func GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	var key APIKey
+	err := db.WithContext(ctx).Where("hash = ?", hash).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func GetAPIKeysByUserID(ctx context.Context, userID int64) ([]APIKey, error) {
+	var keys []APIKey
+	err := db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&keys).Error
+	return keys, err
+}
+
+// DeleteAPIKey 删除用户的密钥, 密钥不存在或不属于该用户时返回 gorm.ErrRecordNotFound
+func DeleteAPIKey(ctx context.Context, userID int64, id uint) error {
+	res := db.WithContext(ctx).Unscoped().Where("id = ? AND user_id = ?", id, userID).Delete(&APIKey{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func TouchAPIKey(ctx context.Context, id uint, at time.Time) error {
+	return db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
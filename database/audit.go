@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+func CreateAuditLog(ctx context.Context, l *AuditLog) error {
+	return db.WithContext(ctx).Create(l).Error
+}
+
+// AuditLogFilter 筛选审计记录, 零值表示不筛选
+type AuditLogFilter struct {
+	ActorID *int64
+	// Action 为完整的操作或其前缀, 如 rule 匹配 rule.create 和 rule.delete
+	Action   string
+	Source   string
+	Since    time.Time
+	BeforeID uint // 用于翻页, 只返回 ID 小于该值的记录
+	Limit    int
+}
+
+// ListAuditLogs 按时间倒序返回审计记录
+func ListAuditLogs(ctx context.Context, filter AuditLogFilter) ([]AuditLog, error) {
+	query := db.WithContext(ctx).Order("id DESC")
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		action := strings.TrimSuffix(filter.Action, ".")
+		query = query.Where("action = ? OR action LIKE ?", action, action+".%")
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var logs []AuditLog
+	err := query.Find(&logs).Error
+	return logs, err
+}
+
+// DeleteAuditLogsBefore 删除早于 t 的审计记录
+func DeleteAuditLogsBefore(ctx context.Context, t time.Time) error {
+	return db.WithContext(ctx).Unscoped().Where("created_at < ?", t).Delete(&AuditLog{}).Error
+}
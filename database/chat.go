@@ -1,6 +1,10 @@
 package database
 
-import "context"
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
 
 func (user *User) WatchChat(ctx context.Context, chat WatchChat) error {
 	if len(user.WatchChats) == 0 {
@@ -37,3 +41,57 @@ func GetWatchChatsByChatID(ctx context.Context, chatID int64) ([]*WatchChat, err
 	}
 	return watchChats, nil
 }
+
+func (user *User) GetWatchChat(ctx context.Context, chatID int64) (*WatchChat, error) {
+	var watchChat WatchChat
+	err := db.WithContext(ctx).Where("chat_id = ? AND user_id = ?", chatID, user.ID).First(&watchChat).Error
+	if err != nil {
+		return nil, err
+	}
+	return &watchChat, nil
+}
+
+func GetWatchChatByID(ctx context.Context, id uint) (*WatchChat, error) {
+	var watchChat WatchChat
+	err := db.WithContext(ctx).First(&watchChat, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &watchChat, nil
+}
+
+// UpdateWatchChatSettings saves the filter and save settings of a watched chat,
+// leaving the backfill checkpoint untouched.
+func UpdateWatchChatSettings(ctx context.Context, chat *WatchChat) error {
+	return db.WithContext(ctx).Model(chat).Select("filter", "storage_name", "dir_path", "filename_strategy").Updates(chat).Error
+}
+
+// UpdateWatchChatBackfill saves the backfill checkpoint of a watched chat. The
+// checkpoint is cleared once next passes until. It returns
+// gorm.ErrRecordNotFound when the chat is no longer watched.
+func UpdateWatchChatBackfill(ctx context.Context, id uint, next, until int) error {
+	if next > until {
+		next, until = 0, 0
+	}
+	res := db.WithContext(ctx).Model(&WatchChat{}).Where("id = ?", id).Updates(map[string]any{
+		"backfill_next":  next,
+		"backfill_until": until,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetBackfillingWatchChats returns the watched chats with an unfinished backfill.
+func GetBackfillingWatchChats(ctx context.Context) ([]*WatchChat, error) {
+	var watchChats []*WatchChat
+	err := db.WithContext(ctx).Where("backfill_until > 0").Find(&watchChats).Error
+	if err != nil {
+		return nil, err
+	}
+	return watchChats, nil
+}
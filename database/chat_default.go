@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// SetChatDefault creates or replaces the default storage and directory of the
+// user for files from a chat.
+func SetChatDefault(ctx context.Context, userID uint, chatID int64, storageName, dirPath string) error {
+	var cd ChatDefault
+	err := db.WithContext(ctx).Where("user_id = ? AND chat_id = ?", userID, chatID).First(&cd).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	cd.UserID = userID
+	cd.ChatID = chatID
+	cd.StorageName = storageName
+	cd.DirPath = dirPath
+	return db.WithContext(ctx).Save(&cd).Error
+}
+
+func DeleteChatDefault(ctx context.Context, userID uint, chatID int64) error {
+	res := db.WithContext(ctx).Unscoped().Where("user_id = ? AND chat_id = ?", userID, chatID).Delete(&ChatDefault{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ChatDefaultFor returns the first chat default of the user that matches one
+// of the chats, in order. The user must be loaded with its chat defaults.
+func (user *User) ChatDefaultFor(chatIDs ...int64) *ChatDefault {
+	for _, id := range chatIDs {
+		if id == 0 {
+			continue
+		}
+		for i := range user.ChatDefaults {
+			if user.ChatDefaults[i].ChatID == id {
+				return &user.ChatDefaults[i]
+			}
+		}
+	}
+	return nil
+}
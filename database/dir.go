@@ -1,14 +1,21 @@
 package database
 
-import "context"
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
 
 func CreateDirForUser(ctx context.Context, userID uint, storageName, path string) error {
-	dir := Dir{
+	return CreateDir(ctx, &Dir{
 		UserID:      userID,
 		StorageName: storageName,
 		Path:        path,
-	}
-	return db.WithContext(ctx).Create(&dir).Error
+	})
+}
+
+func CreateDir(ctx context.Context, dir *Dir) error {
+	return db.WithContext(ctx).Create(dir).Error
 }
 
 func GetDirByID(ctx context.Context, id uint) (*Dir, error) {
@@ -55,3 +62,18 @@ func DeleteDirForUser(ctx context.Context, userID uint, storageName, path string
 func DeleteDirByID(ctx context.Context, id uint) error {
 	return db.WithContext(ctx).Unscoped().Delete(&Dir{}, id).Error
 }
+
+// DeleteUserDir 删除用户的一个文件夹, 该文件夹为默认文件夹时一并清除.
+// 文件夹不属于该用户时返回 gorm.ErrRecordNotFound
+func DeleteUserDir(ctx context.Context, userID, dirID uint) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Where("id = ? AND user_id = ?", dirID, userID).Delete(&Dir{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&User{}).Where("id = ? AND default_dir = ?", userID, dirID).Update("default_dir", 0).Error
+	})
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -14,6 +16,7 @@ type User struct {
 	ApplyRule        bool
 	Rules            []Rule
 	WatchChats       []WatchChat
+	ChatDefaults     []ChatDefault
 	FilenameStrategy string
 	FilenameTemplate string
 	ConflictStrategy string
@@ -24,6 +27,22 @@ type WatchChat struct {
 	UserID uint // User's database ID (not chat ID)
 	ChatID int64
 	Filter string
+	// 监听的保存位置和文件名策略, 为空时使用聊天或用户的默认设置
+	StorageName      string
+	DirPath          string
+	FilenameStrategy string
+	// 历史回填的检查点: 下一条要处理的消息 ID 和最后一条消息 ID (含), 没有进行中的回填时为 0
+	BackfillNext  int
+	BackfillUntil int
+}
+
+// ChatDefault 为来自某个聊天的文件设置默认存储和目录, 在静默模式和监听中优先于用户的默认存储
+type ChatDefault struct {
+	gorm.Model
+	UserID      uint  `gorm:"uniqueIndex:idx_chat_default_user_chat"`
+	ChatID      int64 `gorm:"uniqueIndex:idx_chat_default_user_chat"` // 不含 -100 前缀
+	StorageName string
+	DirPath     string
 }
 
 type Dir struct {
@@ -40,4 +59,53 @@ type Rule struct {
 	Data        string
 	StorageName string
 	DirPath     string
+	Priority    int // 数值越大越先匹配, 相同时后创建的先匹配
+	// 匹配后执行的动作, 零值表示不执行, 见 rule.Actions
+	Rename       string
+	Skip         bool
+	Conflict     string
+	PostProcess  string
+	NotifyChatID int64
+}
+
+// APIKey 是用户通过 /apikey 创建的 API 密钥, 只保存密钥的哈希
+type APIKey struct {
+	gorm.Model
+	UserID     int64 `gorm:"index;not null"` // 创建者的 telegram user id, 存储权限跟随该用户
+	Name       string
+	Hash       string `gorm:"uniqueIndex;not null"`
+	Hint       string // 密钥的开头部分, 用于在列表中辨认
+	Scopes     string // 逗号分隔, 见 apikey.Scope
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// WebhookDelivery 是一次 Webhook 投递的记录, 用于查看和重新投递失败的 Webhook
+type WebhookDelivery struct {
+	gorm.Model
+	TaskID  string `gorm:"index"`
+	Event   string
+	URL     string
+	UserID  int64 // 创建任务的用户, 用于检查 API 调用者的权限, 0 表示只有 admin 可以访问
+	Payload string
+	// 不保存密钥, 重新投递时全局 Webhook 的密钥从配置中查找, 任务的 Webhook 的密钥从内存中查找
+	Global bool
+	Signed bool
+	// 最后一次请求的结果, StatusCode 为 0 表示请求失败
+	StatusCode   int
+	Error        string
+	Attempts     int
+	Success      bool
+	RedeliveryOf uint // 重新投递的原记录 ID
+}
+
+// AuditLog 是一条用户操作的审计记录, 见 pkg/audit
+type AuditLog struct {
+	gorm.Model
+	ActorID int64  `gorm:"index"` // 操作者的 telegram user id, 配置中的 API token 和系统操作为 0
+	Source  string // bot, api 或 system
+	KeyID   uint   // 调用 API 使用的密钥
+	Action  string `gorm:"index"`
+	Target  string
+	Details string // JSON
 }
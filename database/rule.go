@@ -1,6 +1,10 @@
 package database
 
-import "context"
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
 
 func CreateRule(ctx context.Context, rule *Rule) error {
 	return db.WithContext(ctx).Create(rule).Error
@@ -10,6 +14,47 @@ func DeleteRule(ctx context.Context, ruleID uint) error {
 	return db.WithContext(ctx).Unscoped().Delete(&Rule{}, ruleID).Error
 }
 
+func UpdateRulePriority(ctx context.Context, userID, ruleID uint, priority int) error {
+	result := db.WithContext(ctx).Model(&Rule{}).
+		Where("id = ? AND user_id = ?", ruleID, userID).
+		Update("priority", priority)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateRule 保存规则的条件, 目标和动作. 规则不属于 rule.UserID 时返回 gorm.ErrRecordNotFound
+func UpdateRule(ctx context.Context, rule *Rule) error {
+	result := db.WithContext(ctx).Model(rule).
+		Where("user_id = ?", rule.UserID).
+		Select("type", "data", "storage_name", "dir_path", "priority",
+			"rename", "skip", "conflict", "post_process", "notify_chat_id").
+		Updates(rule)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteUserRule 删除用户的一条规则, 规则不属于该用户时返回 gorm.ErrRecordNotFound
+func DeleteUserRule(ctx context.Context, userID, ruleID uint) error {
+	result := db.WithContext(ctx).Unscoped().Where("id = ? AND user_id = ?", ruleID, userID).Delete(&Rule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 func UpdateUserApplyRule(ctx context.Context, chatID int64, applyRule bool) error {
 	return db.WithContext(ctx).Model(&User{}).Where("chat_id = ?", chatID).Update("apply_rule", applyRule).Error
 }
@@ -22,3 +67,68 @@ func GetRulesByUserChatID(ctx context.Context, chatID int64) ([]Rule, error) {
 	}
 	return rules, nil
 }
+
+// ImportUserSettings 在事务中导入用户的规则, 目录和文件名设置.
+// replace 为 true 时先删除用户现有的规则和目录, 否则跳过已存在的相同规则和目录.
+// 返回实际新增的规则和目录数量
+func ImportUserSettings(ctx context.Context, user *User, rules []Rule, dirs []Dir, replace bool) (int, int, error) {
+	addedRules, addedDirs := 0, 0
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if replace {
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&Rule{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&Dir{}).Error; err != nil {
+				return err
+			}
+		}
+		for _, r := range rules {
+			r.UserID = user.ID
+			if !replace {
+				var count int64
+				if err := tx.Model(&Rule{}).
+					Where("user_id = ? AND type = ? AND data = ? AND storage_name = ? AND dir_path = ?",
+						user.ID, r.Type, r.Data, r.StorageName, r.DirPath).
+					Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					continue
+				}
+			}
+			if err := tx.Create(&r).Error; err != nil {
+				return err
+			}
+			addedRules++
+		}
+		for _, d := range dirs {
+			d.UserID = user.ID
+			if !replace {
+				var count int64
+				if err := tx.Model(&Dir{}).
+					Where("user_id = ? AND storage_name = ? AND path = ?", user.ID, d.StorageName, d.Path).
+					Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					continue
+				}
+			}
+			if err := tx.Create(&d).Error; err != nil {
+				return err
+			}
+			addedDirs++
+		}
+		settings := map[string]any{
+			"apply_rule":        user.ApplyRule,
+			"filename_strategy": user.FilenameStrategy,
+			"filename_template": user.FilenameTemplate,
+		}
+		if replace {
+			// 默认目录随旧目录一起被删除
+			settings["default_dir"] = 0
+		}
+		return tx.Model(&User{}).Where("id = ?", user.ID).Updates(settings).Error
+	})
+	return addedRules, addedDirs, err
+}
@@ -36,6 +36,14 @@ func UpdateUser(ctx context.Context, user *User) error {
 	return db.WithContext(ctx).Save(user).Error
 }
 
+// UpdateUserSettings 保存用户的设置, 不修改文件夹, 规则等关联数据
+func UpdateUserSettings(ctx context.Context, user *User) error {
+	return db.WithContext(ctx).Model(user).
+		Select("silent", "default_storage", "default_dir", "apply_rule",
+			"filename_strategy", "filename_template", "conflict_strategy").
+		Updates(user).Error
+}
+
 func DeleteUser(ctx context.Context, user *User) error {
 	return db.WithContext(ctx).
 		Unscoped().
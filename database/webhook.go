@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// dropWebhookDeliverySecrets 删除旧版本保存在投递记录中的密钥
+func dropWebhookDeliverySecrets() error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&WebhookDelivery{}, "secret") {
+		return nil
+	}
+	if err := db.Model(&WebhookDelivery{}).Where("secret <> ''").Update("signed", true).Error; err != nil {
+		return fmt.Errorf("failed to mark signed deliveries: %w", err)
+	}
+	return migrator.DropColumn(&WebhookDelivery{}, "secret")
+}
+
+func CreateWebhookDelivery(ctx context.Context, d *WebhookDelivery) error {
+	return db.WithContext(ctx).Create(d).Error
+}
+
+func UpdateWebhookDelivery(ctx context.Context, d *WebhookDelivery) error {
+	return db.WithContext(ctx).Save(d).Error
+}
+
+func GetWebhookDelivery(ctx context.Context, id uint) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := db.WithContext(ctx).First(&d, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// WebhookDeliveryFilter 筛选投递记录, 零值表示不筛选
+type WebhookDeliveryFilter struct {
+	TaskID     string
+	FailedOnly bool
+	Limit      int
+}
+
+// ListWebhookDeliveries 按时间倒序返回投递记录
+func ListWebhookDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]WebhookDelivery, error) {
+	query := db.WithContext(ctx).Order("id DESC")
+	if filter.TaskID != "" {
+		query = query.Where("task_id = ?", filter.TaskID)
+	}
+	if filter.FailedOnly {
+		query = query.Where("success = ?", false)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var deliveries []WebhookDelivery
+	err := query.Find(&deliveries).Error
+	return deliveries, err
+}
+
+// DeleteWebhookDeliveriesBefore 删除早于 t 的投递记录
+func DeleteWebhookDeliveriesBefore(ctx context.Context, t time.Time) error {
+	return db.WithContext(ctx).Unscoped().Where("created_at < ?", t).Delete(&WebhookDelivery{}).Error
+}
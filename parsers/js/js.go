@@ -37,6 +37,11 @@ type jsParserResp struct {
 
 const canHandleTimeout = 10 * time.Second
 
+// Name returns the plugin name.
+func (p *jsParser) Name() string {
+	return p.meta.Name
+}
+
 func (p *jsParser) CanHandle(url string) bool {
 	respCh := make(chan jsParserResp, 1)
 	timer := time.NewTimer(canHandleTimeout)
@@ -30,6 +30,10 @@ const (
 	kemonoApiBase = "https://kemono.cr/api/v1"
 )
 
+func (k *KemonoParser) Name() string {
+	return "kemono"
+}
+
 func (k *KemonoParser) CanHandle(text string) bool {
 	text = strings.TrimPrefix(text, "https://")
 	text = strings.TrimPrefix(text, "http://")
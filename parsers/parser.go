@@ -8,7 +8,9 @@ import (
 	"github.com/krau/SaveAny-Bot/parsers/native/kemono"
 	"github.com/krau/SaveAny-Bot/parsers/native/twitter"
 	"github.com/krau/SaveAny-Bot/parsers/parsers"
+	"github.com/krau/SaveAny-Bot/pkg/metrics"
 	"github.com/krau/SaveAny-Bot/pkg/parser"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
 )
 
 func init() {
@@ -28,7 +30,7 @@ func ParseWithContext(ctx context.Context, url string) (*parser.Item, error) {
 			if !pser.CanHandle(url) {
 				continue
 			}
-			item, err := pser.Parse(ctx, url)
+			item, err := Parse(ctx, pser, url)
 			if err != nil {
 				errCh <- err
 				return
@@ -49,6 +51,25 @@ func ParseWithContext(ctx context.Context, url string) (*parser.Item, error) {
 	}
 }
 
+// Parse parses the URL with pser, recording the result in the parser metrics
+// and a tracing span.
+func Parse(ctx context.Context, pser parser.Parser, url string) (*parser.Item, error) {
+	name := Name(pser)
+	ctx, span := tracing.Start(ctx, "parser.parse", tracing.ParserKey.String(name), tracing.URLKey.String(url))
+	item, err := pser.Parse(ctx, url)
+	tracing.End(span, err)
+	metrics.ObserveParse(name, err)
+	return item, err
+}
+
+// Name returns the name of a parser, or its type name when it has none.
+func Name(pser parser.Parser) string {
+	if named, ok := pser.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return fmt.Sprintf("%T", pser)
+}
+
 // CanHandle checks if any registered parser can handle the given URL and returns the parser if found.
 func CanHandle(url string) (bool, parser.Parser) {
 	for _, pser := range parsers.Get() {
@@ -0,0 +1,554 @@
+// Package apiclient is a typed client for the SaveAny-Bot HTTP API.
+// The API is described by the OpenAPI document served at /api/v1/openapi.json.
+package apiclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidURL = errors.New("apiclient: invalid URL")
+	// ErrStreamClosed is returned by WatchTask when the stream ends before the task
+	ErrStreamClosed = errors.New("apiclient: event stream closed")
+)
+
+// Error is returned when the API responds with an error status
+type Error struct {
+	StatusCode int
+	// Code is the machine readable error, e.g. task_not_found
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("apiclient: %d %s", e.StatusCode, e.Code)
+	}
+	return fmt.Sprintf("apiclient: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Client represents a SaveAny-Bot API client
+type Client struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewClient creates a new API client
+// baseURL: the API server address (e.g., "http://localhost:8080")
+// token: the API token from the config, or an API key created with /apikey
+func NewClient(baseURL, token string) (*Client, error) {
+	return NewClientWithHTTPClient(baseURL, token, nil)
+}
+
+// NewClientWithHTTPClient creates a new API client with custom HTTP client
+func NewClientWithHTTPClient(baseURL, token string, httpClient *http.Client) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  httpClient,
+	}, nil
+}
+
+// newRequest creates an authorized request. body is encoded as JSON unless it is a []byte
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body any) (*http.Request, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("apiclient: failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("apiclient: failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// do sends the request and returns the response, or an *Error for error statuses
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("apiclient: failed to send request: %w", err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	var body ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+		apiErr.Code = body.Error
+		apiErr.Message = body.Message
+	}
+	return nil, apiErr
+}
+
+// call sends a request and decodes the JSON response into result
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body, result any) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("apiclient: failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// Health checks that the server is up and the token is valid
+func (c *Client) Health(ctx context.Context) error {
+	return c.call(ctx, http.MethodGet, "/health", nil, nil, nil)
+}
+
+// CreateTask creates a task
+func (c *Client) CreateTask(ctx context.Context, req *CreateTaskRequest) (*CreateTaskResponse, error) {
+	var resp CreateTaskResponse
+	if err := c.call(ctx, http.MethodPost, "/api/v1/tasks", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateTasks creates many tasks at once. Failed tasks are reported in
+// the results instead of failing the call; batchKey, if set, makes retries of
+// the whole batch idempotent.
+func (c *Client) CreateTasks(ctx context.Context, reqs []CreateTaskRequest, batchKey string) (*BatchCreateTaskResponse, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/v1/tasks:batch", nil, &BatchCreateTaskRequest{Tasks: reqs})
+	if err != nil {
+		return nil, err
+	}
+	if batchKey != "" {
+		req.Header.Set("Idempotency-Key", batchKey)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var result BatchCreateTaskResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("apiclient: failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// ListTasks lists the tasks the caller can access
+func (c *Client) ListTasks(ctx context.Context) ([]TaskInfoResponse, error) {
+	var resp TasksListResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/tasks", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Tasks, nil
+}
+
+// GetTask gets a task
+func (c *Client) GetTask(ctx context.Context, taskID string) (*TaskInfoResponse, error) {
+	var resp TaskInfoResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/tasks/"+url.PathEscape(taskID), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CancelTask cancels a task
+func (c *Client) CancelTask(ctx context.Context, taskID string) error {
+	return c.call(ctx, http.MethodDelete, "/api/v1/tasks/"+url.PathEscape(taskID), nil, nil, nil)
+}
+
+// WatchTask calls fn for every event of a task until the task ends,
+// fn returns an error or ctx is done. The first event is the current status.
+func (c *Client) WatchTask(ctx context.Context, taskID string, fn func(TaskEvent) error) error {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/tasks/"+url.PathEscape(taskID)+"/events", nil, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if rest, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(rest, " "))
+			continue
+		}
+		// Comments (pings) and event names are ignored, the event name is also in the data
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		var ev TaskEvent
+		if err := json.Unmarshal([]byte(data.String()), &ev); err != nil {
+			return fmt.Errorf("apiclient: failed to decode event: %w", err)
+		}
+		data.Reset()
+		if err := fn(ev); err != nil {
+			return err
+		}
+		if ev.Event == TaskEventDone || ev.Event == TaskEventError || isTerminal(ev.Status) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("apiclient: failed to read events: %w", err)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return ErrStreamClosed
+}
+
+func isTerminal(status TaskStatus) bool {
+	return status == TaskStatusCompleted || status == TaskStatusFailed || status == TaskStatusCancelled
+}
+
+// TaskTypes lists the supported task types
+func (c *Client) TaskTypes(ctx context.Context) ([]TaskType, error) {
+	var resp TaskTypesResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/task-types", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Types, nil
+}
+
+// ListStorages lists the storages the caller can use
+func (c *Client) ListStorages(ctx context.Context) ([]StorageInfo, error) {
+	var resp StoragesResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/storages", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Storages, nil
+}
+
+// SendFile sends a file of a storage to a Telegram chat
+func (c *Client) SendFile(ctx context.Context, storage string, req *SendFileRequest) (*CreateTaskResponse, error) {
+	var resp CreateTaskResponse
+	if err := c.call(ctx, http.MethodPost, "/api/v1/storages/"+url.PathEscape(storage)+"/send", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListFiles lists a directory of a storage, dir defaults to the root
+func (c *Client) ListFiles(ctx context.Context, storage, dir string) ([]FileEntry, error) {
+	query := url.Values{}
+	if dir != "" {
+		query.Set("path", dir)
+	}
+	var resp FilesResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/storages/"+url.PathEscape(storage)+"/files", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Files, nil
+}
+
+// OpenFile downloads a file of a storage. rangeHeader, e.g. "bytes=100-",
+// is sent as the Range header when set; the server ignores it for storages
+// that cannot seek, which is reported by a 200 instead of a 206 status.
+// The caller must close the response body.
+func (c *Client) OpenFile(ctx context.Context, storage, filePath, rangeHeader string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/storages/"+url.PathEscape(storage)+"/file", url.Values{"path": {filePath}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+	return c.do(req)
+}
+
+// DeleteFile deletes a file or directory of a storage
+func (c *Client) DeleteFile(ctx context.Context, storage, filePath string) error {
+	return c.call(ctx, http.MethodDelete, "/api/v1/storages/"+url.PathEscape(storage)+"/file", url.Values{"path": {filePath}}, nil, nil)
+}
+
+// MoveFile moves or renames a file within a storage
+func (c *Client) MoveFile(ctx context.Context, storage, filePath, destination string) error {
+	return c.call(ctx, http.MethodPost, "/api/v1/storages/"+url.PathEscape(storage)+"/file:move", nil,
+		&MoveFileRequest{Path: filePath, Destination: destination}, nil)
+}
+
+// WebhookDeliveryFilter filters webhook deliveries, zero values are ignored
+type WebhookDeliveryFilter struct {
+	TaskID     string
+	FailedOnly bool
+	Limit      int
+}
+
+// ListWebhookDeliveries lists webhook deliveries, newest first
+func (c *Client) ListWebhookDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]WebhookDeliveryResponse, error) {
+	query := url.Values{}
+	if filter.TaskID != "" {
+		query.Set("task_id", filter.TaskID)
+	}
+	if filter.FailedOnly {
+		query.Set("failed", "true")
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	var resp WebhookDeliveriesResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/webhooks/deliveries", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Deliveries, nil
+}
+
+// AuditLogFilter filters audit log entries, zero values are ignored
+type AuditLogFilter struct {
+	// ActorID only returns actions of this Telegram user, 0 is the API token of the config
+	ActorID  *int64
+	Action   string
+	Source   string
+	Since    time.Time
+	BeforeID uint
+	Limit    int
+}
+
+// ListAuditLogs lists audit log entries, newest first. Requires the admin scope
+func (c *Client) ListAuditLogs(ctx context.Context, filter AuditLogFilter) ([]AuditLogResponse, error) {
+	query := url.Values{}
+	if filter.ActorID != nil {
+		query.Set("actor", strconv.FormatInt(*filter.ActorID, 10))
+	}
+	if filter.Action != "" {
+		query.Set("action", filter.Action)
+	}
+	if filter.Source != "" {
+		query.Set("source", filter.Source)
+	}
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.Format(time.RFC3339))
+	}
+	if filter.BeforeID > 0 {
+		query.Set("before", strconv.FormatUint(uint64(filter.BeforeID), 10))
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	var resp AuditLogsResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/audit", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Logs, nil
+}
+
+// RedeliverWebhook sends the payload of a delivery again and returns the new delivery
+func (c *Client) RedeliverWebhook(ctx context.Context, deliveryID uint) (*WebhookDeliveryResponse, error) {
+	var resp WebhookDeliveryResponse
+	path := "/api/v1/webhooks/deliveries/" + strconv.FormatUint(uint64(deliveryID), 10) + "/redeliver"
+	if err := c.call(ctx, http.MethodPost, path, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ExportRules exports the rules of a user, format is "json" or "toml"
+func (c *Client) ExportRules(ctx context.Context, userID int64, format string) ([]byte, error) {
+	query := url.Values{}
+	if format != "" {
+		query.Set("format", format)
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, userPath(userID)+"/rules", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("apiclient: failed to read response: %w", err)
+	}
+	return data, nil
+}
+
+// ImportRules imports a JSON or TOML rules document for a user,
+// merging with the existing rules unless replace is set
+func (c *Client) ImportRules(ctx context.Context, userID int64, doc []byte, replace bool) (*ImportRulesResponse, error) {
+	query := url.Values{}
+	if replace {
+		query.Set("mode", "replace")
+	}
+	var resp ImportRulesResponse
+	if err := c.call(ctx, http.MethodPut, userPath(userID)+"/rules", query, doc, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func userPath(userID int64) string {
+	return "/api/v1/users/" + strconv.FormatInt(userID, 10)
+}
+
+// ListUsers lists all users, it needs the admin scope
+func (c *Client) ListUsers(ctx context.Context) ([]UserResponse, error) {
+	var resp UsersResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/users", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Users, nil
+}
+
+// GetUser returns the settings, dirs, rules and watched chats of a user
+func (c *Client) GetUser(ctx context.Context, userID int64) (*UserResponse, error) {
+	var resp UserResponse
+	if err := c.call(ctx, http.MethodGet, userPath(userID), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateUser changes the settings set in req and returns the updated user
+func (c *Client) UpdateUser(ctx context.Context, userID int64, req *UpdateUserRequest) (*UserResponse, error) {
+	var resp UserResponse
+	if err := c.call(ctx, http.MethodPatch, userPath(userID), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListDirs lists the dirs of a user
+func (c *Client) ListDirs(ctx context.Context, userID int64) ([]DirResponse, error) {
+	var resp DirsResponse
+	if err := c.call(ctx, http.MethodGet, userPath(userID)+"/dirs", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Dirs, nil
+}
+
+// CreateDir adds a dir for a user
+func (c *Client) CreateDir(ctx context.Context, userID int64, req *DirRequest) (*DirResponse, error) {
+	var resp DirResponse
+	if err := c.call(ctx, http.MethodPost, userPath(userID)+"/dirs", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteDir deletes a dir of a user
+func (c *Client) DeleteDir(ctx context.Context, userID int64, dirID uint) error {
+	return c.call(ctx, http.MethodDelete, userPath(userID)+"/dirs/"+strconv.FormatUint(uint64(dirID), 10), nil, nil, nil)
+}
+
+// CreateRule adds a rule for a user
+func (c *Client) CreateRule(ctx context.Context, userID int64, req *RuleRequest) (*RuleResponse, error) {
+	var resp RuleResponse
+	if err := c.call(ctx, http.MethodPost, userPath(userID)+"/rules", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetRule returns a rule of a user
+func (c *Client) GetRule(ctx context.Context, userID int64, ruleID uint) (*RuleResponse, error) {
+	var resp RuleResponse
+	if err := c.call(ctx, http.MethodGet, userPath(userID)+"/rules/"+strconv.FormatUint(uint64(ruleID), 10), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateRule replaces a rule of a user
+func (c *Client) UpdateRule(ctx context.Context, userID int64, ruleID uint, req *RuleRequest) (*RuleResponse, error) {
+	var resp RuleResponse
+	if err := c.call(ctx, http.MethodPut, userPath(userID)+"/rules/"+strconv.FormatUint(uint64(ruleID), 10), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteRule deletes a rule of a user
+func (c *Client) DeleteRule(ctx context.Context, userID int64, ruleID uint) error {
+	return c.call(ctx, http.MethodDelete, userPath(userID)+"/rules/"+strconv.FormatUint(uint64(ruleID), 10), nil, nil, nil)
+}
+
+// ListWatches lists the chats watched by a user
+func (c *Client) ListWatches(ctx context.Context, userID int64) ([]WatchResponse, error) {
+	var resp WatchesResponse
+	if err := c.call(ctx, http.MethodGet, userPath(userID)+"/watches", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Watches, nil
+}
+
+// Watch starts watching a chat for a user
+func (c *Client) Watch(ctx context.Context, userID int64, req *WatchRequest) (*WatchResponse, error) {
+	var resp WatchResponse
+	if err := c.call(ctx, http.MethodPost, userPath(userID)+"/watches", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateWatch changes the settings set in req of a watched chat
+func (c *Client) UpdateWatch(ctx context.Context, userID, chatID int64, req *UpdateWatchRequest) (*WatchResponse, error) {
+	var resp WatchResponse
+	if err := c.call(ctx, http.MethodPatch, userPath(userID)+"/watches/"+strconv.FormatInt(chatID, 10), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Unwatch stops watching a chat for a user
+func (c *Client) Unwatch(ctx context.Context, userID, chatID int64) error {
+	return c.call(ctx, http.MethodDelete, userPath(userID)+"/watches/"+strconv.FormatInt(chatID, 10), nil, nil, nil)
+}
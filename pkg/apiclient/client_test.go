@@ -0,0 +1,117 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewClient(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:8080", "://bad"} {
+		if _, err := NewClient(baseURL, "token"); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("NewClient(%q) error = %v, want ErrInvalidURL", baseURL, err)
+		}
+	}
+	if _, err := NewClient("http://localhost:8080/", "token"); err != nil {
+		t.Errorf("NewClient() error = %v", err)
+	}
+}
+
+func TestClient_CreateTask(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/tasks" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var req struct {
+			Type   TaskType          `json:"type"`
+			Params DirectLinksParams `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Type != TaskTypeDirectlinks || len(req.Params.URLs) != 1 {
+			t.Errorf("unexpected body %+v", req)
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(CreateTaskResponse{TaskID: "t1", Type: req.Type, Status: TaskStatusQueued})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, "secret")
+	resp, err := client.CreateTask(context.Background(), &CreateTaskRequest{
+		Type:    TaskTypeDirectlinks,
+		Storage: "local",
+		Params:  DirectLinksParams{URLs: []string{"https://example.com/a.zip"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	if resp.TaskID != "t1" || resp.Status != TaskStatusQueued {
+		t.Errorf("CreateTask() = %+v", resp)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "task_not_found", Message: "task not found: x"})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, "secret")
+	_, err := client.GetTask(context.Background(), "x")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("GetTask() error = %v, want *Error", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Code != "task_not_found" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+}
+
+func TestClient_WatchTask(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/tasks/t1/events" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []TaskEvent{
+			{Event: TaskEventStatus, TaskID: "t1", Status: TaskStatusRunning},
+			{Event: TaskEventProgress, TaskID: "t1", Status: TaskStatusRunning, Progress: &TaskProgress{Percent: 50}},
+			{Event: TaskEventDone, TaskID: "t1", Status: TaskStatusCompleted},
+			{Event: TaskEventStatus, TaskID: "t1", Status: TaskStatusCompleted},
+		}
+		for i, ev := range events {
+			data, _ := json.Marshal(ev)
+			if i == 1 {
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Event, data)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, "secret")
+	var got []TaskEvent
+	err := client.WatchTask(context.Background(), "t1", func(ev TaskEvent) error {
+		got = append(got, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WatchTask() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d events, want 3 (stop after done)", len(got))
+	}
+	if got[1].Progress == nil || got[1].Progress.Percent != 50 {
+		t.Errorf("unexpected progress event %+v", got[1])
+	}
+}
@@ -0,0 +1,410 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// The types in this file mirror the JSON types of the api package,
+// so that services can use the client without importing the bot.
+
+// TaskStatus is the status of a task
+type TaskStatus string
+
+const (
+	TaskStatusQueued    TaskStatus = "queued"
+	TaskStatusRunning   TaskStatus = "running"
+	TaskStatusCompleted TaskStatus = "completed"
+	TaskStatusFailed    TaskStatus = "failed"
+	TaskStatusCancelled TaskStatus = "cancelled"
+)
+
+// TaskType is the type of a task
+type TaskType string
+
+const (
+	TaskTypeDirectlinks TaskType = "directlinks"
+	TaskTypeYtdlp       TaskType = "ytdlp"
+	TaskTypeAria2       TaskType = "aria2"
+	TaskTypeParseditem  TaskType = "parseditem"
+	TaskTypeTgfiles     TaskType = "tgfiles"
+	TaskTypeTphpics     TaskType = "tphpics"
+	TaskTypeTransfer    TaskType = "transfer"
+)
+
+// Task event names
+const (
+	TaskEventStatus   = "status"
+	TaskEventStart    = "start"
+	TaskEventProgress = "progress"
+	TaskEventFile     = "file"
+	TaskEventDone     = "done"
+	TaskEventError    = "error"
+)
+
+// CreateTaskRequest creates a task. Params must match Type,
+// e.g. DirectLinksParams for TaskTypeDirectlinks.
+type CreateTaskRequest struct {
+	Type          TaskType `json:"type"`
+	Storage       string   `json:"storage"`
+	Path          string   `json:"path"`
+	Webhook       string   `json:"webhook,omitempty"`
+	WebhookSecret string   `json:"webhook_secret,omitempty"`
+	WebhookEvents []string `json:"webhook_events,omitempty"`
+	// IdempotencyKey makes retries return the original task instead of creating a new one
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	Params         any    `json:"params"`
+}
+
+// BatchCreateTaskRequest creates many tasks at once
+type BatchCreateTaskRequest struct {
+	Tasks []CreateTaskRequest `json:"tasks"`
+}
+
+// BatchTaskResult is the result of one task of a batch
+type BatchTaskResult struct {
+	Index int `json:"index"`
+	// Status is the HTTP status the task would get if created on its own
+	Status   int                 `json:"status"`
+	Task     *CreateTaskResponse `json:"task,omitempty"`
+	Replayed bool                `json:"replayed,omitempty"`
+	Error    *ErrorResponse      `json:"error,omitempty"`
+}
+
+// BatchCreateTaskResponse lists the results of a batch in request order
+type BatchCreateTaskResponse struct {
+	Results   []BatchTaskResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+}
+
+// CreateTaskResponse is returned when a task is created
+type CreateTaskResponse struct {
+	TaskID    string     `json:"task_id"`
+	Type      TaskType   `json:"type"`
+	Status    TaskStatus `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TaskProgress is the progress of a task
+type TaskProgress struct {
+	TotalBytes      int64   `json:"total_bytes,omitempty"`
+	DownloadedBytes int64   `json:"downloaded_bytes,omitempty"`
+	TotalFiles      int     `json:"total_files,omitempty"`
+	DownloadedFiles int     `json:"downloaded_files,omitempty"`
+	Percent         float64 `json:"percent,omitempty"`
+	SpeedMBPS       float64 `json:"speed_mbps,omitempty"`
+	ETASeconds      float64 `json:"eta_seconds,omitempty"`
+}
+
+// TaskInfoResponse describes a task
+type TaskInfoResponse struct {
+	TaskID    string        `json:"task_id"`
+	Type      TaskType      `json:"type"`
+	Status    TaskStatus    `json:"status"`
+	Title     string        `json:"title"`
+	Progress  *TaskProgress `json:"progress,omitempty"`
+	Storage   string        `json:"storage"`
+	Path      string        `json:"path"`
+	Error     string        `json:"error,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// TaskEvent is pushed by the task event stream
+type TaskEvent struct {
+	Event    string        `json:"event"`
+	TaskID   string        `json:"task_id"`
+	Status   TaskStatus    `json:"status"`
+	Progress *TaskProgress `json:"progress,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Time     time.Time     `json:"time"`
+}
+
+// TasksListResponse lists tasks
+type TasksListResponse struct {
+	Tasks []TaskInfoResponse `json:"tasks"`
+	Total int                `json:"total"`
+}
+
+// StoragesResponse lists storages
+type StoragesResponse struct {
+	Storages []StorageInfo `json:"storages"`
+}
+
+// StorageInfo describes a storage
+type StorageInfo struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TaskTypesResponse lists the supported task types
+type TaskTypesResponse struct {
+	Types []TaskType `json:"types"`
+}
+
+// MessageResponse carries a message only
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// HealthResponse is returned by the health check
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// ErrorResponse is the body of failed requests
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message,omitempty"`
+}
+
+// FileEntry is a file or directory of a storage
+type FileEntry struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	IsDir   bool      `json:"is_dir"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// FilesResponse lists a directory, directories first
+type FilesResponse struct {
+	Storage string      `json:"storage"`
+	Path    string      `json:"path"`
+	Files   []FileEntry `json:"files"`
+	Total   int         `json:"total"`
+}
+
+// MoveFileRequest moves or renames a file within a storage
+type MoveFileRequest struct {
+	Path        string `json:"path"`
+	Destination string `json:"destination"`
+}
+
+// UserResponse is a user with its settings, dirs, rules and watched chats
+type UserResponse struct {
+	ChatID           int64           `json:"chat_id"`
+	Silent           bool            `json:"silent"`
+	DefaultStorage   string          `json:"default_storage"`
+	DefaultDir       uint            `json:"default_dir"` // ID of the default dir, 0 for the storage root
+	ApplyRule        bool            `json:"apply_rule"`
+	FilenameStrategy string          `json:"filename_strategy"`
+	FilenameTemplate string          `json:"filename_template"`
+	ConflictStrategy string          `json:"conflict_strategy"`
+	Dirs             []DirResponse   `json:"dirs"`
+	Rules            []RuleResponse  `json:"rules"`
+	Watches          []WatchResponse `json:"watches"`
+}
+
+// UsersResponse lists users
+type UsersResponse struct {
+	Users []UserResponse `json:"users"`
+	Total int            `json:"total"`
+}
+
+// UpdateUserRequest changes the given settings of a user, empty strings restore the defaults
+type UpdateUserRequest struct {
+	Silent           *bool   `json:"silent,omitempty"`
+	DefaultStorage   *string `json:"default_storage,omitempty"`
+	DefaultDir       *uint   `json:"default_dir,omitempty"` // must be a dir of the default storage
+	ApplyRule        *bool   `json:"apply_rule,omitempty"`
+	FilenameStrategy *string `json:"filename_strategy,omitempty"`
+	FilenameTemplate *string `json:"filename_template,omitempty"`
+	ConflictStrategy *string `json:"conflict_strategy,omitempty"`
+}
+
+// DirRequest adds a dir
+type DirRequest struct {
+	Storage string `json:"storage"`
+	Path    string `json:"path"`
+}
+
+// DirResponse is a dir of a user
+type DirResponse struct {
+	ID      uint   `json:"id"`
+	Storage string `json:"storage"`
+	Path    string `json:"path"`
+}
+
+// DirsResponse lists dirs
+type DirsResponse struct {
+	Dirs  []DirResponse `json:"dirs"`
+	Total int           `json:"total"`
+}
+
+// RuleRequest creates or replaces a rule, the fields are those of rules documents
+type RuleRequest struct {
+	Type        string `json:"type"`
+	Data        string `json:"data"`
+	Storage     string `json:"storage"`
+	Dir         string `json:"dir"`
+	Priority    int    `json:"priority,omitempty"`
+	Rename      string `json:"rename,omitempty"`
+	Skip        bool   `json:"skip,omitempty"`
+	Conflict    string `json:"conflict,omitempty"`
+	PostProcess string `json:"post,omitempty"`
+	NotifyChat  int64  `json:"notify,omitempty"`
+}
+
+// RuleResponse is a rule of a user
+type RuleResponse struct {
+	ID          uint   `json:"id"`
+	Type        string `json:"type"`
+	Data        string `json:"data"`
+	Storage     string `json:"storage"`
+	Dir         string `json:"dir"`
+	Priority    int    `json:"priority"`
+	Rename      string `json:"rename,omitempty"`
+	Skip        bool   `json:"skip,omitempty"`
+	Conflict    string `json:"conflict,omitempty"`
+	PostProcess string `json:"post,omitempty"`
+	NotifyChat  int64  `json:"notify,omitempty"`
+}
+
+// WatchRequest watches a chat. Omitted settings use the defaults of the chat or the user
+type WatchRequest struct {
+	ChatID           int64  `json:"chat_id"`
+	Filter           string `json:"filter,omitempty"` // msgre:<regexp> or expr:<expression>
+	Storage          string `json:"storage,omitempty"`
+	Dir              string `json:"dir,omitempty"`
+	FilenameStrategy string `json:"filename_strategy,omitempty"`
+}
+
+// UpdateWatchRequest changes the given settings of a watched chat, empty strings restore the defaults
+type UpdateWatchRequest struct {
+	Filter           *string `json:"filter,omitempty"`
+	Storage          *string `json:"storage,omitempty"`
+	Dir              *string `json:"dir,omitempty"`
+	FilenameStrategy *string `json:"filename_strategy,omitempty"`
+}
+
+// WatchResponse is a watched chat
+type WatchResponse struct {
+	ChatID           int64  `json:"chat_id"`
+	Filter           string `json:"filter,omitempty"`
+	Storage          string `json:"storage,omitempty"`
+	Dir              string `json:"dir,omitempty"`
+	FilenameStrategy string `json:"filename_strategy,omitempty"`
+	// BackfillNext and BackfillUntil are the next and last message IDs of a running backfill
+	BackfillNext  int `json:"backfill_next,omitempty"`
+	BackfillUntil int `json:"backfill_until,omitempty"`
+}
+
+// WatchesResponse lists watched chats
+type WatchesResponse struct {
+	Watches []WatchResponse `json:"watches"`
+	Total   int             `json:"total"`
+}
+
+// WebhookPayload is the body of webhook requests
+type WebhookPayload struct {
+	Event       string        `json:"event"`
+	TaskID      string        `json:"task_id"`
+	Type        string        `json:"type"`
+	Title       string        `json:"title,omitempty"`
+	Status      TaskStatus    `json:"status"`
+	Storage     string        `json:"storage"`
+	Path        string        `json:"path"`
+	Progress    *TaskProgress `json:"progress,omitempty"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Timestamp   time.Time     `json:"timestamp"`
+}
+
+// WebhookDeliveryResponse is a logged webhook delivery
+type WebhookDeliveryResponse struct {
+	ID           uint            `json:"id"`
+	TaskID       string          `json:"task_id"`
+	Event        string          `json:"event"`
+	URL          string          `json:"url"`
+	Signed       bool            `json:"signed"`
+	StatusCode   int             `json:"status_code"`
+	Error        string          `json:"error,omitempty"`
+	Attempts     int             `json:"attempts"`
+	Success      bool            `json:"success"`
+	RedeliveryOf uint            `json:"redelivery_of,omitempty"`
+	Payload      json.RawMessage `json:"payload"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// WebhookDeliveriesResponse lists webhook deliveries
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	Total      int                       `json:"total"`
+}
+
+// AuditLogResponse is a recorded user action
+type AuditLogResponse struct {
+	ID      uint            `json:"id"`
+	Time    time.Time       `json:"time"`
+	ActorID int64           `json:"actor_id"`
+	Source  string          `json:"source"`
+	KeyID   uint            `json:"key_id,omitempty"`
+	Action  string          `json:"action"`
+	Target  string          `json:"target,omitempty"`
+	Details json.RawMessage `json:"details,omitempty"`
+}
+
+// AuditLogsResponse lists audit log entries
+type AuditLogsResponse struct {
+	Logs  []AuditLogResponse `json:"logs"`
+	Total int                `json:"total"`
+}
+
+// ImportRulesResponse is returned when rules are imported
+type ImportRulesResponse struct {
+	Mode  string `json:"mode"`
+	Rules int    `json:"rules"`
+	Dirs  int    `json:"dirs"`
+}
+
+// DirectLinksParams are the params of directlinks tasks
+type DirectLinksParams struct {
+	URLs []string `json:"urls"`
+}
+
+// YTDLPParams are the params of ytdlp tasks
+type YTDLPParams struct {
+	URLs  []string `json:"urls"`
+	Flags []string `json:"flags,omitempty"`
+}
+
+// Aria2Params are the params of aria2 tasks
+type Aria2Params struct {
+	URLs    []string          `json:"urls"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// ParsedParams are the params of parseditem tasks
+type ParsedParams struct {
+	URL string `json:"url"`
+}
+
+// TransferParams are the params of transfer tasks
+type TransferParams struct {
+	SourceStorage string `json:"source_storage"`
+	SourcePath    string `json:"source_path"`
+	TargetStorage string `json:"target_storage"`
+	TargetPath    string `json:"target_path"`
+}
+
+// TGFilesParams are the params of tgfiles tasks
+type TGFilesParams struct {
+	MessageLinks []string `json:"message_links"`
+}
+
+// TPHPicsParams are the params of tphpics tasks
+type TPHPicsParams struct {
+	TelegraphURL string `json:"telegraph_url"`
+}
+
+// SendFileRequest sends a stored file to a Telegram chat
+type SendFileRequest struct {
+	Path          string   `json:"path"`
+	ChatID        int64    `json:"chat_id"`
+	Webhook       string   `json:"webhook,omitempty"`
+	WebhookSecret string   `json:"webhook_secret,omitempty"`
+	WebhookEvents []string `json:"webhook_events,omitempty"`
+}
@@ -0,0 +1,126 @@
+// Package apikey generates and checks the named API keys that users create
+// with /apikey. Only the SHA-256 hash of a key is stored; the key itself is
+// shown once when it is created.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prefix marks the tokens issued by the bot, to tell them apart from the
+// token in the config file.
+const Prefix = "sab_"
+
+type Scope string
+
+const (
+	ScopeTasksRead    Scope = "tasks:read"
+	ScopeTasksWrite   Scope = "tasks:write"
+	ScopeStoragesRead Scope = "storages:read"
+	// ScopeStoragesWrite allows deleting and moving files. It is not granted by default.
+	ScopeStoragesWrite Scope = "storages:write"
+	// ScopeAdmin grants every other scope and access to the settings of all users.
+	ScopeAdmin Scope = "admin"
+)
+
+// Scopes returns all known scopes.
+func Scopes() []Scope {
+	return []Scope{ScopeTasksRead, ScopeTasksWrite, ScopeStoragesRead, ScopeStoragesWrite, ScopeAdmin}
+}
+
+// DefaultScopes are granted when a key is created without scopes.
+func DefaultScopes() []Scope {
+	return []Scope{ScopeTasksRead, ScopeTasksWrite, ScopeStoragesRead}
+}
+
+// ParseScopes parses a comma separated list of scopes, dropping duplicates.
+func ParseScopes(s string) ([]Scope, error) {
+	var scopes []Scope
+	for part := range strings.SplitSeq(s, ",") {
+		scope := Scope(strings.ToLower(strings.TrimSpace(part)))
+		if scope == "" {
+			continue
+		}
+		if !slices.Contains(Scopes(), scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("no scope given")
+	}
+	return scopes, nil
+}
+
+// FormatScopes joins the scopes with commas, the inverse of ParseScopes.
+func FormatScopes(scopes []Scope) string {
+	parts := make([]string, len(scopes))
+	for i, s := range scopes {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, ",")
+}
+
+// HasScope reports whether the granted scopes allow want. Admin allows everything.
+func HasScope(granted []Scope, want Scope) bool {
+	return slices.Contains(granted, want) || slices.Contains(granted, ScopeAdmin)
+}
+
+// Generate returns a new random token.
+func Generate() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash returns the hex encoded SHA-256 of the token, which is what gets stored.
+// Tokens are random, so a plain hash is enough to make lookups by hash safe.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Hint returns the start of a token, to recognize a key in lists without storing it.
+func Hint(token string) string {
+	n := min(len(token), len(Prefix)+4)
+	return token[:n] + "…"
+}
+
+// ParseExpiry parses a key lifetime such as 30d, 12h or 90m, or an expiry
+// date in YYYY-MM-DD format, and returns the expiry time.
+func ParseExpiry(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return time.Time{}, fmt.Errorf("invalid expiry %q", s)
+		}
+		return now.AddDate(0, 0, n), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("invalid expiry %q", s)
+		}
+		return now.Add(d), nil
+	}
+	date, err := time.ParseInLocation(time.DateOnly, s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry %q", s)
+	}
+	if !date.After(now) {
+		return time.Time{}, fmt.Errorf("expiry %q is in the past", s)
+	}
+	return date, nil
+}
@@ -0,0 +1,105 @@
+package apikey
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []Scope
+		wantErr bool
+	}{
+		{in: "tasks:read", want: []Scope{ScopeTasksRead}},
+		{in: " tasks:read, TASKS:WRITE ,tasks:read", want: []Scope{ScopeTasksRead, ScopeTasksWrite}},
+		{in: "admin", want: []Scope{ScopeAdmin}},
+		{in: "tasks:delete", wantErr: true},
+		{in: " , ", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseScopes(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseScopes(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseScopes(%q): %v", tt.in, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseScopes(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		if again, _ := ParseScopes(FormatScopes(got)); !slices.Equal(again, got) {
+			t.Errorf("FormatScopes(%v) does not round trip: %v", got, again)
+		}
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	granted := []Scope{ScopeTasksRead}
+	if !HasScope(granted, ScopeTasksRead) {
+		t.Error("granted scope not allowed")
+	}
+	if HasScope(granted, ScopeTasksWrite) {
+		t.Error("missing scope allowed")
+	}
+	for _, s := range Scopes() {
+		if !HasScope([]Scope{ScopeAdmin}, s) {
+			t.Errorf("admin does not allow %s", s)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	a, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Generate()
+	if a == b {
+		t.Error("tokens are not random")
+	}
+	if !strings.HasPrefix(a, Prefix) {
+		t.Errorf("token %q has no prefix", a)
+	}
+	if Hash(a) == Hash(b) || Hash(a) != Hash(a) || len(Hash(a)) != 64 {
+		t.Errorf("unexpected hash %q", Hash(a))
+	}
+	if hint := Hint(a); !strings.HasPrefix(a, strings.TrimSuffix(hint, "…")) || len(hint) >= len(a) {
+		t.Errorf("Hint(%q) = %q", a, hint)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "30d", want: now.AddDate(0, 0, 30)},
+		{in: "12h", want: now.Add(12 * time.Hour)},
+		{in: "2025-07-01", want: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{in: "2025-01-01", wantErr: true},
+		{in: "0d", wantErr: true},
+		{in: "-1h", wantErr: true},
+		{in: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseExpiry(tt.in, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseExpiry(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseExpiry(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
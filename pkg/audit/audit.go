@@ -0,0 +1,202 @@
+// Package audit records who did what: bot commands and callbacks, task
+// creation and cancellation, API calls and changes to rules, dirs, storages
+// and settings. Entries are passed to the configured store and optionally
+// appended to a JSON Lines file. Until Setup is called Record does nothing.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Sources of the actions.
+const (
+	SourceBot = "bot"
+	SourceAPI = "api"
+	// SourceSystem is used for actions without an actor in the context, e.g.
+	// tasks created by watched chats.
+	SourceSystem = "system"
+)
+
+// Actions recorded by the bot and the API.
+const (
+	ActionCommand    = "command"
+	ActionCallback   = "callback"
+	ActionAPIRequest = "api.request"
+	ActionTaskCreate = "task.create"
+	ActionTaskCancel = "task.cancel"
+	ActionRuleCreate = "rule.create"
+	ActionRuleUpdate = "rule.update"
+	ActionRuleDelete = "rule.delete"
+	ActionRuleImport = "rule.import"
+	ActionDirCreate  = "dir.create"
+	ActionDirDelete  = "dir.delete"
+	// ActionWatchCreate, ActionWatchUpdate and ActionWatchDelete change watched chats.
+	ActionWatchCreate = "watch.create"
+	ActionWatchUpdate = "watch.update"
+	ActionWatchDelete = "watch.delete"
+	// ActionStorageDefault changes the default storage of a user or a chat.
+	ActionStorageDefault = "storage.default"
+	ActionStorageDelete  = "storage.delete"
+	ActionStorageMove    = "storage.move"
+	ActionSettingsUpdate = "settings.update"
+)
+
+// Actor is the user performing the actions of a context.
+type Actor struct {
+	// UserID is the telegram user id, 0 for the API token of the config.
+	UserID int64
+	Source string
+	// KeyID is the API key used by the caller.
+	KeyID uint
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose actions are recorded as done by actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor of ctx, or the system actor if there is none.
+func ActorFrom(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{Source: SourceSystem}
+}
+
+// Entry is a recorded action, also the format of the JSON Lines file.
+type Entry struct {
+	Time    time.Time      `json:"time"`
+	ActorID int64          `json:"actor_id"`
+	Source  string         `json:"source"`
+	KeyID   uint           `json:"key_id,omitempty"`
+	Action  string         `json:"action"`
+	Target  string         `json:"target,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Options configures where entries are kept.
+type Options struct {
+	// Store saves an entry, e.g. to the database. Nil skips it.
+	Store func(ctx context.Context, e *Entry) error
+	// File is a JSON Lines file entries are appended to, in addition to the
+	// store. Empty disables it.
+	File string
+	// Prune deletes stored entries created before the given time.
+	Prune func(ctx context.Context, before time.Time) error
+	// Retention is how long stored entries are kept, 0 keeps them forever.
+	// Needs Prune. The file is never truncated.
+	Retention time.Duration
+}
+
+var (
+	enabled atomic.Bool
+	store   func(ctx context.Context, e *Entry) error
+	fileMu  sync.Mutex
+	file    *os.File
+)
+
+// Setup enables recording. Expired entries are deleted until ctx is done.
+func Setup(ctx context.Context, opts Options) error {
+	if opts.File != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.File), 0755); err != nil {
+			return fmt.Errorf("failed to create audit log directory: %w", err)
+		}
+		f, err := os.OpenFile(opts.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log file: %w", err)
+		}
+		fileMu.Lock()
+		file = f
+		fileMu.Unlock()
+	}
+	store = opts.Store
+	if opts.Retention > 0 && opts.Prune != nil {
+		go cleanupLoop(ctx, opts.Prune, opts.Retention)
+	}
+	enabled.Store(true)
+	return nil
+}
+
+// Close stops recording and closes the JSON Lines file.
+func Close() error {
+	enabled.Store(false)
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	return err
+}
+
+// Record records an action of the actor of ctx. Failures are only logged,
+// they never fail the action itself.
+func Record(ctx context.Context, action, target string, details map[string]any) {
+	if !enabled.Load() {
+		return
+	}
+	actor := ActorFrom(ctx)
+	e := Entry{
+		Time:    time.Now(),
+		ActorID: actor.UserID,
+		Source:  actor.Source,
+		KeyID:   actor.KeyID,
+		Action:  action,
+		Target:  target,
+		Details: details,
+	}
+	if err := write(context.WithoutCancel(ctx), e); err != nil {
+		log.FromContext(ctx).Errorf("Failed to record audit log %s: %s", action, err)
+	}
+}
+
+func write(ctx context.Context, e Entry) error {
+	var storeErr error
+	if store != nil {
+		// 存储失败时仍写入文件
+		storeErr = store(ctx, &e)
+	}
+	return errors.Join(storeErr, appendLine(e))
+}
+
+func appendLine(e Entry) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	if file == nil {
+		return nil
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %w", err)
+	}
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+func cleanupLoop(ctx context.Context, prune func(context.Context, time.Time) error, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if err := prune(ctx, time.Now().Add(-retention)); err != nil && ctx.Err() == nil {
+			log.FromContext(ctx).Errorf("Failed to delete expired audit logs: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	var saved []*Entry
+	saveEntry := func(ctx context.Context, e *Entry) error {
+		saved = append(saved, e)
+		if e.Action == ActionTaskCancel {
+			return errors.New("database is locked")
+		}
+		return nil
+	}
+
+	Record(context.Background(), ActionCommand, "/start", nil)
+	if len(saved) != 0 {
+		t.Fatal("recorded before Setup")
+	}
+
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	if err := Setup(context.Background(), Options{Store: saveEntry, File: path}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithActor(context.Background(), Actor{UserID: 42, Source: SourceAPI, KeyID: 7})
+	Record(ctx, ActionRuleCreate, "3", map[string]any{"storage": "local"})
+	// 存储失败时仍写入文件
+	Record(context.Background(), ActionTaskCancel, "task-1", nil)
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	Record(ctx, ActionCommand, "/start", nil)
+
+	if len(saved) != 2 {
+		t.Fatalf("saved %d entries, want 2", len(saved))
+	}
+	if e := saved[0]; e.ActorID != 42 || e.Source != SourceAPI || e.KeyID != 7 || e.Target != "3" ||
+		e.Details["storage"] != "local" || e.Time.IsZero() {
+		t.Errorf("saved = %+v", e)
+	}
+	if e := saved[1]; e.ActorID != 0 || e.Source != SourceSystem || e.Details != nil {
+		t.Errorf("saved = %+v, want a system entry without details", e)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid line %q: %s", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("file has %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.Action != ActionRuleCreate || e.ActorID != 42 || e.Details["storage"] != "local" ||
+		!e.Time.Equal(saved[0].Time) {
+		t.Errorf("entry = %+v", e)
+	}
+	if e := entries[1]; e.Action != ActionTaskCancel || e.Source != SourceSystem {
+		t.Errorf("entry = %+v", e)
+	}
+}
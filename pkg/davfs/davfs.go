@@ -0,0 +1,274 @@
+// Package davfs adapts listable and readable storages to a read-only
+// webdav.FileSystem, with every storage mounted as a top-level folder.
+package davfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/pkg/storagetypes"
+	"golang.org/x/net/webdav"
+)
+
+// Storage is the subset of a storage needed to browse and read it.
+type Storage interface {
+	ListFiles(ctx context.Context, dirPath string) ([]storagetypes.FileInfo, error)
+	OpenFile(ctx context.Context, filePath string) (io.ReadCloser, int64, error)
+}
+
+// FS is a read-only webdav.FileSystem over a set of named storages.
+type FS struct {
+	storages map[string]Storage
+}
+
+var _ webdav.FileSystem = (*FS)(nil)
+
+func New(storages map[string]Storage) *FS {
+	return &FS{storages: storages}
+}
+
+// resolve splits a webdav path into the storage it belongs to and the path
+// inside that storage. An empty storage name means the root folder.
+func (f *FS) resolve(name string) (string, Storage, string, error) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return "", nil, "", nil
+	}
+	storName, rest, _ := strings.Cut(name, "/")
+	stor, ok := f.storages[storName]
+	if !ok {
+		return "", nil, "", os.ErrNotExist
+	}
+	return storName, stor, "/" + rest, nil
+}
+
+func (f *FS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return os.ErrPermission
+}
+
+func (f *FS) RemoveAll(ctx context.Context, name string) error {
+	return os.ErrPermission
+}
+
+func (f *FS) Rename(ctx context.Context, oldName, newName string) error {
+	return os.ErrPermission
+}
+
+func (f *FS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	storName, stor, p, err := f.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if stor == nil {
+		return dirInfo("/"), nil
+	}
+	if p == "/" {
+		return dirInfo(storName), nil
+	}
+	entries, err := stor.ListFiles(ctx, path.Dir(p))
+	if err != nil {
+		return nil, os.ErrNotExist
+	}
+	base := path.Base(p)
+	for _, e := range entries {
+		if e.Name == base {
+			return fileInfo{e}, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func (f *FS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, os.ErrPermission
+	}
+	info, err := f.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	_, stor, p, _ := f.resolve(name)
+	if info.IsDir() {
+		return &dir{ctx: ctx, fs: f, stor: stor, path: p, info: info}, nil
+	}
+	return &file{ctx: ctx, stor: stor, path: p, info: info}, nil
+}
+
+// dir lists its entries on the first Readdir call.
+type dir struct {
+	ctx     context.Context
+	fs      *FS
+	stor    Storage
+	path    string
+	info    os.FileInfo
+	entries []fs.FileInfo
+	loaded  bool
+	pos     int
+}
+
+func (d *dir) load() error {
+	if d.loaded {
+		return nil
+	}
+	if d.stor == nil {
+		names := make([]string, 0, len(d.fs.storages))
+		for name := range d.fs.storages {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			d.entries = append(d.entries, dirInfo(name))
+		}
+	} else {
+		files, err := d.stor.ListFiles(d.ctx, d.path)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			d.entries = append(d.entries, fileInfo{file})
+		}
+	}
+	d.loaded = true
+	return nil
+}
+
+func (d *dir) Readdir(count int) ([]fs.FileInfo, error) {
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	rest := d.entries[d.pos:]
+	if count <= 0 {
+		d.pos = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	rest = rest[:min(count, len(rest))]
+	d.pos += len(rest)
+	return rest, nil
+}
+
+func (d *dir) Stat() (fs.FileInfo, error)                   { return d.info, nil }
+func (d *dir) Read(p []byte) (int, error)                   { return 0, fs.ErrInvalid }
+func (d *dir) Write(p []byte) (int, error)                  { return 0, os.ErrPermission }
+func (d *dir) Seek(offset int64, whence int) (int64, error) { return 0, fs.ErrInvalid }
+func (d *dir) Close() error                                 { return nil }
+
+// file opens the storage reader lazily, so PROPFIND and HEAD requests never
+// touch the file content. Seeking on backends without a seekable reader is
+// emulated by discarding bytes or reopening the file.
+type file struct {
+	ctx      context.Context
+	stor     Storage
+	path     string
+	info     os.FileInfo
+	rc       io.ReadCloser
+	rcOffset int64
+	offset   int64
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.offset >= f.info.Size() {
+		return 0, io.EOF
+	}
+	if err := f.sync(); err != nil {
+		return 0, err
+	}
+	n, err := f.rc.Read(p)
+	f.offset += int64(n)
+	f.rcOffset += int64(n)
+	return n, err
+}
+
+// sync moves the underlying reader to the current offset.
+func (f *file) sync() error {
+	if f.rc != nil && f.rcOffset == f.offset {
+		return nil
+	}
+	if f.rc != nil && f.skip() == nil {
+		return nil
+	}
+	if f.rc != nil {
+		f.rc.Close()
+		f.rc = nil
+	}
+	rc, _, err := f.stor.OpenFile(f.ctx, f.path)
+	if err != nil {
+		return err
+	}
+	f.rc, f.rcOffset = rc, 0
+	return f.skip()
+}
+
+func (f *file) skip() error {
+	if seeker, ok := f.rc.(io.Seeker); ok {
+		if _, err := seeker.Seek(f.offset, io.SeekStart); err != nil {
+			return err
+		}
+		f.rcOffset = f.offset
+		return nil
+	}
+	if f.offset < f.rcOffset {
+		return fs.ErrInvalid
+	}
+	n, err := io.CopyN(io.Discard, f.rc, f.offset-f.rcOffset)
+	f.rcOffset += n
+	return err
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = f.offset + offset
+	case io.SeekEnd:
+		abs = f.info.Size() + offset
+	default:
+		return 0, fs.ErrInvalid
+	}
+	if abs < 0 {
+		return 0, fs.ErrInvalid
+	}
+	f.offset = abs
+	return abs, nil
+}
+
+func (f *file) Close() error {
+	if f.rc == nil {
+		return nil
+	}
+	return f.rc.Close()
+}
+
+func (f *file) Stat() (fs.FileInfo, error)               { return f.info, nil }
+func (f *file) Readdir(count int) ([]fs.FileInfo, error) { return nil, fs.ErrInvalid }
+func (f *file) Write(p []byte) (int, error)              { return 0, os.ErrPermission }
+
+type fileInfo struct {
+	storagetypes.FileInfo
+}
+
+func (i fileInfo) Name() string       { return i.FileInfo.Name }
+func (i fileInfo) Size() int64        { return i.FileInfo.Size }
+func (i fileInfo) ModTime() time.Time { return i.FileInfo.ModTime }
+func (i fileInfo) IsDir() bool        { return i.FileInfo.IsDir }
+func (i fileInfo) Sys() any           { return nil }
+
+func (i fileInfo) Mode() fs.FileMode {
+	if i.FileInfo.IsDir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+func dirInfo(name string) fileInfo {
+	return fileInfo{storagetypes.FileInfo{Name: name, IsDir: true}}
+}
@@ -0,0 +1,114 @@
+package davfs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/pkg/storagetypes"
+	"golang.org/x/net/webdav"
+)
+
+// memStorage serves files from a map of path to content. Its readers are not
+// seekable, like most remote backends.
+type memStorage map[string]string
+
+func (m memStorage) ListFiles(_ context.Context, dirPath string) ([]storagetypes.FileInfo, error) {
+	var files []storagetypes.FileInfo
+	seen := make(map[string]bool)
+	for p, content := range m {
+		rel, ok := strings.CutPrefix(p, strings.TrimSuffix(dirPath, "/")+"/")
+		if !ok {
+			continue
+		}
+		name, _, isDir := strings.Cut(rel, "/")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		info := storagetypes.FileInfo{Name: name, Path: path.Join(dirPath, name), IsDir: isDir, ModTime: time.Unix(0, 0)}
+		if !isDir {
+			info.Size = int64(len(content))
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+func (m memStorage) OpenFile(_ context.Context, filePath string) (io.ReadCloser, int64, error) {
+	content, ok := m[filePath]
+	if !ok {
+		return nil, 0, os.ErrNotExist
+	}
+	return io.NopCloser(strings.NewReader(content)), int64(len(content)), nil
+}
+
+func newTestServer() *httptest.Server {
+	fs := New(map[string]Storage{
+		"local": memStorage{"/videos/a.txt": "0123456789", "/b.txt": "hello"},
+		"other": memStorage{},
+	})
+	return httptest.NewServer(&webdav.Handler{FileSystem: fs, LockSystem: webdav.NewMemLS()})
+}
+
+func TestPropfindRoot(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	req, _ := http.NewRequest("PROPFIND", srv.URL+"/", nil)
+	req.Header.Set("Depth", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	for _, want := range []string{"/local/", "/other/"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("root listing missing %s:\n%s", want, body)
+		}
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/local/videos/a.txt", nil)
+	req.Header.Set("Range", "bytes=3-5")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "345" {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	for _, method := range []string{http.MethodPut, http.MethodDelete, "MKCOL"} {
+		req, _ := http.NewRequest(method, srv.URL+"/local/new.txt", strings.NewReader("x"))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 400 {
+			t.Errorf("%s should be rejected, got %d", method, resp.StatusCode)
+		}
+	}
+	if _, err := New(nil).Stat(context.Background(), "/missing/x"); !os.IsNotExist(err) {
+		t.Errorf("expected not exist, got %v", err)
+	}
+}
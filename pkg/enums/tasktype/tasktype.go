@@ -1,6 +1,6 @@
 package tasktype
 
-// ENUM(tgfiles,tphpics,parseditem,directlinks,aria2,ytdlp,transfer)
+// ENUM(tgfiles,tphpics,parseditem,directlinks,aria2,ytdlp,transfer,backfill)
 //
 //go:generate go-enum --values --names --flag --nocase
 type TaskType string
@@ -0,0 +1,101 @@
+package fnametmpl
+
+import (
+	"net/url"
+	"time"
+)
+
+// Data is the data of a template. Fields that do not apply to a file are
+// empty.
+type Data struct {
+	// 旧版本模板的变量, 均为字符串
+	MsgID    string
+	MsgTags  string
+	MsgGen   string
+	MsgDate  string
+	MsgRaw   string
+	OrigName string
+	ChatID   string
+
+	// Telegram 消息
+	ChatTitle      string
+	ChatUsername   string
+	SenderName     string
+	SenderUsername string
+	// 转发来源的名称
+	ForwardFrom string
+	// 在相册中的序号, 从 1 开始, 不是相册时为 0
+	AlbumIndex int
+	// photo, video, audio, voice, document, animation 或 sticker
+	MediaType string
+
+	// 文件
+	Name string // 不含扩展名的原始文件名
+	Ext  string // 含 "." 的扩展名
+	Size int64
+	Time time.Time
+	// 文件在任务中的序号, 从 1 开始
+	Index int
+
+	// 链接
+	URL  string
+	Host string
+
+	// 解析器的 Item, yt-dlp 的元数据或 Telegraph 页面
+	Site   string
+	Author string
+	Title  string
+	Tags   []string
+	// yt-dlp
+	Uploader   string
+	UploadDate string // YYYYMMDD
+}
+
+// NewFileData returns the data of a file of a non-Telegram task, dated now.
+func NewFileData(name string, size int64, index int) *Data {
+	d := &Data{Time: time.Now(), Index: index}
+	d.SetFile(name, size)
+	return d
+}
+
+// Date formats the time of the message or task, "2006-01-02" by default:
+// {{.Date "2006-01"}}.
+func (d *Data) Date(layout ...string) string {
+	if d.Time.IsZero() {
+		return ""
+	}
+	l := "2006-01-02"
+	if len(layout) > 0 && layout[0] != "" {
+		l = layout[0]
+	}
+	return d.Time.Format(l)
+}
+
+// SetFile fills the file name fields from a file name.
+func (d *Data) SetFile(name string, size int64) {
+	d.Name, d.Ext = splitExt(name)
+	d.Size = size
+	if d.OrigName == "" {
+		d.OrigName = name
+	}
+}
+
+// SetURL fills the URL and Host fields.
+func (d *Data) SetURL(rawURL string) {
+	d.URL = rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		d.Host = u.Hostname()
+	}
+}
+
+func splitExt(name string) (string, string) {
+	for i := len(name) - 1; i > 0; i-- {
+		switch name[i] {
+		case '.':
+			return name[:i], name[i:]
+		case '/':
+			return name, ""
+		}
+	}
+	return name, ""
+}
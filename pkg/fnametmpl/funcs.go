@@ -0,0 +1,124 @@
+package fnametmpl
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Funcs returns the functions available in templates. Functions take the
+// piped value as their last argument, e.g. {{.MsgRaw | truncate 20}}.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"date":         formatDate,
+		"now":          time.Now,
+		"lower":        strings.ToLower,
+		"upper":        strings.ToUpper,
+		"trim":         strings.TrimSpace,
+		"slugify":      Slugify,
+		"truncate":     truncate,
+		"replace":      replace,
+		"regexReplace": regexReplace,
+		"pad":          pad,
+		"default":      defaultValue,
+		"join":         join,
+		"sanitize":     Sanitize,
+	}
+}
+
+// formatDate formats a time with a Go layout, e.g. {{date "2006-01" .Time}}.
+func formatDate(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// Slugify converts s to lower case words joined by "-". Letters of any
+// script are kept.
+func Slugify(s string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "-")
+}
+
+// truncate cuts s to at most n characters.
+func truncate(n int, s string) string {
+	if n < 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func replace(old, new, s string) string {
+	return strings.ReplaceAll(s, old, new)
+}
+
+func regexReplace(pattern, repl, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(s, repl), nil
+}
+
+// pad left pads a number or string with zeros to the width.
+func pad(width int, v any) string {
+	s := fmt.Sprint(v)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	if n := width - len(s); n > 0 {
+		s = strings.Repeat("0", n) + s
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// defaultValue returns def when v is empty, e.g. {{.ChatTitle | default "unknown"}}.
+func defaultValue(def string, v any) string {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	if rv.IsZero() || (rv.Kind() == reflect.Slice && rv.Len() == 0) {
+		return def
+	}
+	return fmt.Sprint(v)
+}
+
+func join(sep string, list []string) string {
+	return strings.Join(list, sep)
+}
+
+// Sanitize replaces characters that are not allowed in file names on common
+// file systems with "_".
+func Sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,228 @@
+// Package fnametmpl renders user defined filename and directory templates.
+//
+// Templates use text/template syntax with the Data fields and the functions
+// in Funcs. The lower case variables of the first template version, such as
+// {{.msgid}}, are still accepted.
+package fnametmpl
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Template is a parsed filename or directory template.
+type Template struct {
+	t *template.Template
+}
+
+// 旧版本模板使用的变量名
+var legacyFields = map[string]string{
+	"msgid":    "MsgID",
+	"msgtags":  "MsgTags",
+	"msggen":   "MsgGen",
+	"msgdate":  "MsgDate",
+	"msgraw":   "MsgRaw",
+	"origname": "OrigName",
+	"chatid":   "ChatID",
+}
+
+// Parse parses a template.
+func Parse(text string) (*Template, error) {
+	t, err := template.New("filename").Funcs(Funcs()).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if t.Tree != nil {
+		renameLegacy(t.Tree.Root)
+	}
+	return &Template{t: t}, nil
+}
+
+// Execute renders the template. Surrounding spaces are trimmed and an empty
+// result is an error.
+func (t *Template) Execute(data *Data) (string, error) {
+	if data == nil {
+		data = &Data{}
+	}
+	var sb strings.Builder
+	if err := t.t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	out := strings.TrimSpace(sb.String())
+	if out == "" {
+		return "", fmt.Errorf("template rendered an empty string")
+	}
+	return out, nil
+}
+
+// Render parses and executes a filename template.
+func Render(text string, data *Data) (string, error) {
+	t, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+	return t.Execute(data)
+}
+
+// IsTemplate reports whether s contains template actions.
+func IsTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+// RenderDir renders a directory template. Paths without template actions are
+// returned unchanged. Each element of the result is cleaned so that a
+// variable can not escape the directory, e.g. with "..".
+func RenderDir(dir string, data *Data) (string, error) {
+	if !IsTemplate(dir) {
+		return dir, nil
+	}
+	t, err := Parse(dir)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := t.t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	parts := strings.Split(sb.String(), "/")
+	cleaned := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" || p == "." || p == ".." {
+			continue
+		}
+		cleaned = append(cleaned, p)
+	}
+	out := path.Join(cleaned...)
+	if strings.HasPrefix(dir, "/") {
+		out = "/" + out
+	}
+	return out, nil
+}
+
+// StaticPrefix returns the part of a directory template before the first
+// action, used when the template fails to render.
+func StaticPrefix(dir string) string {
+	i := strings.Index(dir, "{{")
+	if i < 0 {
+		return dir
+	}
+	prefix := dir[:i]
+	if j := strings.LastIndex(prefix, "/"); j >= 0 {
+		return prefix[:j]
+	}
+	return ""
+}
+
+// Telegram 消息的变量, 其他文件的这些变量为空
+var messageFields = map[string]bool{
+	"MsgID":          true,
+	"MsgTags":        true,
+	"MsgGen":         true,
+	"MsgDate":        true,
+	"MsgRaw":         true,
+	"ChatID":         true,
+	"ChatTitle":      true,
+	"ChatUsername":   true,
+	"SenderName":     true,
+	"SenderUsername": true,
+	"ForwardFrom":    true,
+	"AlbumIndex":     true,
+	"MediaType":      true,
+}
+
+// UsesMessage reports whether the template uses a variable of Telegram
+// messages. Such templates render the same name for every file that is not
+// from Telegram.
+func (t *Template) UsesMessage() bool {
+	uses := false
+	if t.t.Tree != nil {
+		walkFields(t.t.Tree.Root, func(n *parse.FieldNode) {
+			if len(n.Ident) > 0 && messageFields[n.Ident[0]] {
+				uses = true
+			}
+		})
+	}
+	return uses
+}
+
+// renameLegacy 将旧版本的小写变量名替换为 Data 的字段名
+func renameLegacy(node parse.Node) {
+	walkFields(node, func(n *parse.FieldNode) {
+		if len(n.Ident) > 0 {
+			if name, ok := legacyFields[n.Ident[0]]; ok {
+				n.Ident[0] = name
+			}
+		}
+	})
+}
+
+// walkFields 对模板中的每个变量调用 fn
+func walkFields(node parse.Node, fn func(*parse.FieldNode)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkFields(c, fn)
+		}
+	case *parse.ActionNode:
+		walkFields(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			walkFields(c, fn)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			walkFields(a, fn)
+		}
+	case *parse.FieldNode:
+		fn(n)
+	case *parse.ChainNode:
+		walkFields(n.Node, fn)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkFields(n.Pipe, fn)
+	}
+}
+
+func walkBranch(b *parse.BranchNode, fn func(*parse.FieldNode)) {
+	walkFields(b.Pipe, fn)
+	walkFields(b.List, fn)
+	walkFields(b.ElseList, fn)
+}
+
+// UniqueNames makes the names of the files of a task unique, so that files
+// do not overwrite each other when a template renders the same name for them.
+// It is not safe for concurrent use.
+type UniqueNames map[string]struct{}
+
+// Make returns name, or appends the index of the file to it when the name was
+// already used: "title.jpg", "title_2.jpg".
+func (u UniqueNames) Make(name string, index int) string {
+	unique := name
+	if _, used := u[unique]; used {
+		base, ext := splitExt(name)
+		unique = fmt.Sprintf("%s_%d%s", base, index, ext)
+		for i := 2; ; i++ {
+			if _, used := u[unique]; !used {
+				break
+			}
+			unique = fmt.Sprintf("%s_%d_%d%s", base, index, i, ext)
+		}
+	}
+	u[unique] = struct{}{}
+	return unique
+}
@@ -0,0 +1,145 @@
+package fnametmpl
+
+import (
+	"testing"
+	"time"
+)
+
+func testData() *Data {
+	d := &Data{
+		MsgID:      "42",
+		MsgDate:    "2024-05-01_12-00-00",
+		ChatID:     "1234",
+		ChatTitle:  "My Channel",
+		MsgRaw:     "A very long caption about Go templates",
+		AlbumIndex: 3,
+		MediaType:  "video",
+		Time:       time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Tags:       []string{"go", "tmpl"},
+	}
+	d.SetFile("Clip.Final.mp4", 1024)
+	d.SetURL("https://cdn.example.com:8443/v/clip.mp4?x=1")
+	d.Index = 2
+	return d
+}
+
+func TestRender(t *testing.T) {
+	cases := map[string]string{
+		`{{.msgid}}_{{.origname}}`:                   "42_Clip.Final.mp4",
+		`{{if .msgid}}{{.msgid}}{{end}}-{{.chatid}}`: "42-1234",
+		`{{.Name}}{{.Ext}}`:                          "Clip.Final.mp4",
+		`{{.Date}}`:                                  "2024-05-01",
+		`{{.Date "2006-01"}}`:                        "2024-05",
+		`{{date "20060102" .Time}}`:                  "20240501",
+		`{{.ChatTitle | slugify}}`:                   "my-channel",
+		`{{.MsgRaw | truncate 6}}`:                   "A very",
+		`{{.ChatTitle | lower}}`:                     "my channel",
+		`{{.MediaType | upper}}`:                     "VIDEO",
+		`{{.AlbumIndex | pad 3}}`:                    "003",
+		`{{.Name | regexReplace "\\." "_"}}{{.Ext}}`: "Clip_Final.mp4",
+		`{{.ChatTitle | replace " " "_"}}`:           "My_Channel",
+		`{{.SenderName | default "unknown"}}`:        "unknown",
+		`{{.Tags | join "+"}}`:                       "go+tmpl",
+		`{{"a/b:c" | sanitize}}`:                     "a_b_c",
+		`  {{.msgid}}  `:                             "42",
+		`{{.Host}}_{{.Index | pad 2}}{{.Ext}}`:       "cdn.example.com_02.mp4",
+	}
+	for tmpl, want := range cases {
+		got, err := Render(tmpl, testData())
+		if err != nil {
+			t.Errorf("%s: %v", tmpl, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s = %q, want %q", tmpl, got, want)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	for _, tmpl := range []string{
+		`{{.msgid`,
+		`{{.Unknown}}`,
+		`{{.SenderName}}`,
+		`{{.Name | regexReplace "(" ""}}`,
+	} {
+		if _, err := Render(tmpl, testData()); err == nil {
+			t.Errorf("%s: expected error", tmpl)
+		}
+	}
+}
+
+func TestRenderDir(t *testing.T) {
+	cases := map[string]string{
+		"/videos":                                "/videos",
+		`/tg/{{.ChatTitle}}/{{.Date "2006-01"}}`: "/tg/My Channel/2024-05",
+		`tg/{{.SenderName}}/{{.MediaType}}`:      "tg/video",
+		`/tg/{{"../.."}}/x`:                      "/tg/x",
+	}
+	for dir, want := range cases {
+		got, err := RenderDir(dir, testData())
+		if err != nil {
+			t.Errorf("%s: %v", dir, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s = %q, want %q", dir, got, want)
+		}
+	}
+	if got := StaticPrefix(`/tg/chats/{{.ChatTitle}}`); got != "/tg/chats" {
+		t.Errorf("StaticPrefix = %q", got)
+	}
+}
+
+func TestUsesMessage(t *testing.T) {
+	cases := map[string]bool{
+		`{{.msgid}}`:                           true,
+		`{{if .ChatTitle}}{{.Name}}{{end}}`:    true,
+		`{{.SenderName | default .Title}}`:     true,
+		`{{.Title}}{{.Ext}}`:                   false,
+		`{{.origname}}`:                        false,
+		`{{.Date "2006"}}_{{.Index | pad 2}}`:  false,
+		`{{range .Tags}}{{.}}{{end}}{{.Ext}}`:  false,
+		`{{with .Host}}{{.}}_{{end}}{{.Name}}`: false,
+	}
+	for tmpl, want := range cases {
+		parsed, err := Parse(tmpl)
+		if err != nil {
+			t.Fatalf("%s: %v", tmpl, err)
+		}
+		if got := parsed.UsesMessage(); got != want {
+			t.Errorf("%s: UsesMessage = %v, want %v", tmpl, got, want)
+		}
+	}
+}
+
+func TestUniqueNames(t *testing.T) {
+	names := UniqueNames{}
+	got := []string{
+		names.Make("title.jpg", 1),
+		names.Make("title.jpg", 2),
+		names.Make("title_3.jpg", 3),
+		names.Make("title.jpg", 3),
+		names.Make("README", 5),
+		names.Make("README", 6),
+	}
+	want := []string{"title.jpg", "title_2.jpg", "title_3.jpg", "title_3_2.jpg", "README", "README_6"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("name %d = %q, want %q", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Hello, World!": "hello-world",
+		"  --Go--  ":    "go",
+		"视频 合集 2024":    "视频-合集-2024",
+	}
+	for in, want := range cases {
+		if got := Slugify(in); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
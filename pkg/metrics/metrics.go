@@ -0,0 +1,136 @@
+// Package metrics exposes the Prometheus collectors of the bot. Producers
+// record observations through the helpers in this package and Handler serves
+// them in the Prometheus text format. All helpers are safe for concurrent use.
+package metrics
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "saveany"
+
+// Directions of the storage byte counters.
+const (
+	DirectionUpload   = "upload"
+	DirectionDownload = "download"
+)
+
+// Results of parsers and uploads.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	queueLength atomic.Pointer[func() int]
+
+	tasksRunning = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tasks_running",
+		Help:      "Number of tasks being executed, by task type.",
+	}, []string{"type"})
+	tasksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_total",
+		Help:      "Finished tasks by task type, storage and status (completed, failed or cancelled).",
+	}, []string{"type", "storage", "status"})
+	storageBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_bytes_total",
+		Help:      "Bytes uploaded to and downloaded from storages.",
+	}, []string{"storage", "direction"})
+	uploadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_upload_duration_seconds",
+		Help:      "Time spent saving files to storages, by storage and result.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 16),
+	}, []string{"storage", "result"})
+	floodWaits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_flood_wait_total",
+		Help:      "FLOOD_WAIT errors returned by Telegram.",
+	})
+	floodWaitSeconds = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_flood_wait_seconds_total",
+		Help:      "Seconds Telegram asked to wait in FLOOD_WAIT errors.",
+	})
+	parserResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parser_results_total",
+		Help:      "Parse attempts by parser and result (success or failure).",
+	}, []string{"parser", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queue_length",
+			Help:      "Number of tasks waiting in the queue.",
+		}, func() float64 {
+			if f := queueLength.Load(); f != nil {
+				return float64((*f)())
+			}
+			return 0
+		}),
+		tasksRunning,
+		tasksTotal,
+		storageBytes,
+		uploadDuration,
+		floodWaits,
+		floodWaitSeconds,
+		parserResults,
+	)
+}
+
+// Handler serves all metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// SetQueueLength sets the function reporting the queue length when scraped.
+func SetQueueLength(f func() int) {
+	queueLength.Store(&f)
+}
+
+// AddStorageBytes records n bytes moved to or from a storage in the given direction.
+func AddStorageBytes(storage, direction string, n int64) {
+	if n > 0 {
+		storageBytes.WithLabelValues(storage, direction).Add(float64(n))
+	}
+}
+
+// ObserveUpload records a finished save to a storage.
+func ObserveUpload(storage string, n int64, d time.Duration, err error) {
+	AddStorageBytes(storage, DirectionUpload, n)
+	uploadDuration.WithLabelValues(storage, result(err)).Observe(d.Seconds())
+}
+
+// ObserveFloodWait records a FLOOD_WAIT error asking to wait for d.
+func ObserveFloodWait(d time.Duration) {
+	floodWaits.Inc()
+	floodWaitSeconds.Add(d.Seconds())
+}
+
+// ObserveParse records the result of a parser.
+func ObserveParse(parser string, err error) {
+	parserResults.WithLabelValues(parser, result(err)).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
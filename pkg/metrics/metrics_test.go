@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTaskSink(t *testing.T) {
+	tests := []struct {
+		taskType string
+		err      error
+		status   string
+	}{
+		{taskType: "test_ok", status: StatusCompleted},
+		{taskType: "test_fail", err: errors.New("boom"), status: StatusFailed},
+		{taskType: "test_cancel", err: fmt.Errorf("wrapped: %w", context.Canceled), status: StatusCancelled},
+	}
+	for _, tt := range tests {
+		sink := TaskSink("id-"+tt.taskType, tt.taskType, "local")
+		sink.Emit(taskevent.Event{TaskID: "id-" + tt.taskType, Phase: taskevent.PhaseStart})
+		if got := testutil.ToFloat64(tasksRunning.WithLabelValues(tt.taskType)); got != 1 {
+			t.Fatalf("%s: running = %v, want 1", tt.taskType, got)
+		}
+		// 子任务的事件不影响父任务
+		sink.Emit(taskevent.Event{TaskID: "child", Phase: taskevent.PhaseDone})
+		sink.Emit(taskevent.Event{TaskID: "id-" + tt.taskType, Phase: taskevent.PhaseDone, Err: tt.err})
+		sink.Emit(taskevent.Event{TaskID: "id-" + tt.taskType, Phase: taskevent.PhaseDone, Err: tt.err})
+		if got := testutil.ToFloat64(tasksRunning.WithLabelValues(tt.taskType)); got != 0 {
+			t.Fatalf("%s: running = %v, want 0", tt.taskType, got)
+		}
+		if got := testutil.ToFloat64(tasksTotal.WithLabelValues(tt.taskType, "local", tt.status)); got != 1 {
+			t.Fatalf("%s: %s total = %v, want 1", tt.taskType, tt.status, got)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	SetQueueLength(func() int { return 3 })
+	ObserveParse("test_parser", nil)
+	ObserveParse("test_parser", errors.New("boom"))
+	ObserveUpload("test_storage", 1024, time.Second, nil)
+	AddStorageBytes("test_storage", DirectionDownload, 0)
+	ObserveFloodWait(2 * time.Second)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"saveany_queue_length 3",
+		`saveany_parser_results_total{parser="test_parser",result="success"} 1`,
+		`saveany_parser_results_total{parser="test_parser",result="failure"} 1`,
+		`saveany_storage_bytes_total{direction="upload",storage="test_storage"} 1024`,
+		`saveany_storage_upload_duration_seconds_count{result="success",storage="test_storage"} 1`,
+		"saveany_telegram_flood_wait_total 1",
+		"saveany_telegram_flood_wait_seconds_total 2",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+	if strings.Contains(body, `direction="download",storage="test_storage"`) {
+		t.Error("zero byte download should not create a series")
+	}
+}
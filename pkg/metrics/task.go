@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/krau/SaveAny-Bot/pkg/taskevent"
+)
+
+// Task statuses of the tasks_total counter.
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+type taskSink struct {
+	taskID   string
+	taskType string
+	storage  string
+	started  atomic.Bool
+	done     atomic.Bool
+}
+
+// TaskSink returns a Sink recording the running and finished tasks. Sub tasks
+// inherit the sinks of their parent, so events of other tasks are ignored.
+func TaskSink(taskID, taskType, storage string) taskevent.Sink {
+	return &taskSink{taskID: taskID, taskType: taskType, storage: storage}
+}
+
+func (s *taskSink) Emit(e taskevent.Event) {
+	if e.TaskID != s.taskID {
+		return
+	}
+	switch e.Phase {
+	case taskevent.PhaseStart:
+		if s.started.CompareAndSwap(false, true) {
+			tasksRunning.WithLabelValues(s.taskType).Inc()
+		}
+	case taskevent.PhaseDone:
+		if !s.done.CompareAndSwap(false, true) {
+			return
+		}
+		if s.started.Load() {
+			tasksRunning.WithLabelValues(s.taskType).Dec()
+		}
+		tasksTotal.WithLabelValues(s.taskType, s.storage, taskStatus(e.Err)).Inc()
+	}
+}
+
+func taskStatus(err error) string {
+	switch {
+	case err == nil:
+		return StatusCompleted
+	case errors.Is(err, context.Canceled):
+		return StatusCancelled
+	default:
+		return StatusFailed
+	}
+}
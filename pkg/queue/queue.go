@@ -2,6 +2,7 @@ package queue
 
 import (
 	"container/list"
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -56,13 +57,28 @@ var ErrQueueClosed = errors.New("queue is closed and empty")
 // Get retrieves and removes the next non-cancelled task from the queue, adding it to the running tasks.
 // Blocks until a task is available or the queue is closed.
 func (tq *TaskQueue[T]) Get() (*Task[T], error) {
+	return tq.GetContext(context.Background())
+}
+
+// GetContext is like Get, but stops waiting and returns ctx.Err() once ctx is done.
+func (tq *TaskQueue[T]) GetContext(ctx context.Context) (*Task[T], error) {
+	stop := context.AfterFunc(ctx, func() {
+		tq.mu.Lock()
+		defer tq.mu.Unlock()
+		tq.cond.Broadcast()
+	})
+	defer stop()
+
 	tq.mu.Lock()
 	defer tq.mu.Unlock()
 
 	for {
-		for tq.tasks.Len() == 0 && !tq.closed {
+		for tq.tasks.Len() == 0 && !tq.closed && ctx.Err() == nil {
 			tq.cond.Wait()
 		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 
 		for tq.tasks.Len() > 0 {
 			element := tq.tasks.Front()
@@ -116,6 +132,19 @@ func (tq *TaskQueue[T]) ActiveLength() int {
 	return count
 }
 
+// Pending reports whether a task is waiting in the queue or running.
+// Cancelled tasks still in the queue are not pending, they will never run.
+func (tq *TaskQueue[T]) Pending(taskID string) bool {
+	tq.mu.RLock()
+	defer tq.mu.RUnlock()
+
+	if _, running := tq.runningTaskMap[taskID]; running {
+		return true
+	}
+	task, exists := tq.taskMap[taskID]
+	return exists && !task.Cancelled()
+}
+
 // RunningTasks returns the currently running tasks' info.
 func (tq *TaskQueue[T]) RunningTasks() []TaskInfo {
 	tq.mu.RLock()
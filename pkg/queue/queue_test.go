@@ -79,6 +79,32 @@ func TestCloseBehavior(t *testing.T) {
 	<-done
 }
 
+func TestGetContextCanceled(t *testing.T) {
+	q := queue.NewTaskQueue[int]()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := q.GetContext(ctx)
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("GetContext() kept waiting after the context was canceled")
+	}
+	// 取消后加入的任务仍由 Get 取得
+	if err := q.Add(newTask("after")); err != nil {
+		t.Fatalf("unexpected error on Add: %v", err)
+	}
+	if task, err := q.Get(); err != nil || task.ID != "after" {
+		t.Fatalf("expected task 'after', got %v, %v", task, err)
+	}
+}
+
 // Regression: Get() must not deadlock when every queued task was cancelled
 // before a worker picked it up (previously recursed while holding the mutex).
 func TestGetAfterAllCancelled(t *testing.T) {
@@ -182,3 +208,24 @@ func TestConcurrencySafety(t *testing.T) {
 	})
 	wg.Wait()
 }
+
+func TestPending(t *testing.T) {
+	q := queue.NewTaskQueue[int]()
+	q.Add(newTask("running"))
+	q.Add(newTask("cancelled"))
+	q.Add(newTask("queued"))
+	q.CancelTask("cancelled")
+	if _, err := q.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if !q.Pending("running") || !q.Pending("queued") {
+		t.Fatal("expected running and queued tasks to be pending")
+	}
+	if q.Pending("cancelled") {
+		t.Fatal("expected cancelled task not to be pending")
+	}
+	q.Done("running")
+	if q.Pending("running") || q.Pending("unknown") {
+		t.Fatal("expected finished and unknown tasks not to be pending")
+	}
+}
@@ -0,0 +1,105 @@
+package rule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Actions are applied to the files matched by a rule, in addition to routing
+// them to the storage and directory of the rule. Zero values are no-ops.
+type Actions struct {
+	// Rename is a filename template, see the filename template docs.
+	Rename string
+	// Skip drops matched files instead of saving them.
+	Skip bool
+	// Conflict overrides the conflict strategy of the user.
+	Conflict string
+	// PostProcess names a command from the [hook.postprocess] config,
+	// executed for every saved file.
+	PostProcess string
+	// NotifyChat receives a message after the files are saved.
+	NotifyChat int64
+}
+
+const (
+	actionRename   = "rename"
+	actionSkip     = "skip"
+	actionConflict = "conflict"
+	actionPost     = "post"
+	actionNotify   = "notify"
+)
+
+func (a Actions) IsZero() bool {
+	return a == Actions{}
+}
+
+// String returns the actions in the form accepted by ParseActions.
+func (a Actions) String() string {
+	parts := make([]string, 0, 5)
+	if a.Skip {
+		parts = append(parts, actionSkip)
+	}
+	if a.Rename != "" {
+		parts = append(parts, actionRename+"="+quoteValue(a.Rename))
+	}
+	if a.Conflict != "" {
+		parts = append(parts, actionConflict+"="+a.Conflict)
+	}
+	if a.PostProcess != "" {
+		parts = append(parts, actionPost+"="+quoteValue(a.PostProcess))
+	}
+	if a.NotifyChat != 0 {
+		parts = append(parts, actionNotify+"="+strconv.FormatInt(a.NotifyChat, 10))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseActions parses actions given as separate arguments, e.g.
+//
+//	skip
+//	rename={{.msgid}}_{{.origname}}
+//	conflict=overwrite
+//	post=transcode
+//	notify=-1001234567890
+//
+// Values are not checked against the config; callers validate conflict
+// strategies and post-processor names.
+func ParseActions(args []string) (Actions, error) {
+	var a Actions
+	for _, arg := range args {
+		key, value, hasValue := strings.Cut(arg, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == actionSkip && !hasValue {
+			a.Skip = true
+			continue
+		}
+		if !hasValue || value == "" {
+			return Actions{}, fmt.Errorf("invalid action %q, expected <action>=<value>", arg)
+		}
+		switch key {
+		case actionRename:
+			a.Rename = value
+		case actionConflict:
+			a.Conflict = strings.ToLower(value)
+		case actionPost:
+			a.PostProcess = value
+		case actionNotify:
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || id == 0 {
+				return Actions{}, fmt.Errorf("invalid chat ID %q", value)
+			}
+			a.NotifyChat = id
+		case actionSkip:
+			skip, err := strconv.ParseBool(value)
+			if err != nil {
+				return Actions{}, fmt.Errorf("invalid boolean %q", value)
+			}
+			a.Skip = skip
+		default:
+			return Actions{}, fmt.Errorf("unknown action %q, available: %s", key,
+				strings.Join([]string{actionRename, actionSkip, actionConflict, actionPost, actionNotify}, ", "))
+		}
+	}
+	return a, nil
+}
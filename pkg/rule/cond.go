@@ -0,0 +1,427 @@
+package rule
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// condition is a single "<field><op><value>" test, e.g. size>10MB.
+type condition struct {
+	field string
+	op    string
+	value string
+	match func(in *Input) bool
+}
+
+func (c *condition) Match(in *Input) bool {
+	return c.match(in)
+}
+
+func (c *condition) String() string {
+	return c.field + c.op + quoteValue(c.value)
+}
+
+func quoteValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\r\n()\"\\") {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return `"` + v + `"`
+}
+
+var (
+	eqOps      = []string{":", "="}
+	orderedOps = []string{":", "=", ">", ">=", "<", "<="}
+)
+
+type fieldDef struct {
+	ops   []string
+	build func(op, value string) (func(in *Input) bool, error)
+}
+
+var fields = map[string]fieldDef{
+	"size":    {orderedOps, buildSize},
+	"mime":    {eqOps, buildMIME},
+	"ext":     {eqOps, buildExt},
+	"name":    {eqOps, buildRegex(func(in *Input) string { return in.FileName })},
+	"caption": {eqOps, buildRegex(func(in *Input) string { return in.Text })},
+	"chat": {eqOps, buildPeer(func(in *Input) (int64, string) {
+		return in.ChatID, in.ChatUsername
+	})},
+	"sender": {eqOps, buildPeer(func(in *Input) (int64, string) {
+		return in.SenderID, in.SenderUsername
+	})},
+	"forward": {eqOps, buildForward},
+	"tag":     {eqOps, buildTag},
+	"media":   {eqOps, buildMedia},
+	"date":    {orderedOps, buildDate},
+	"album":   {eqOps, buildAlbum},
+	"url":     {eqOps, buildRegex(func(in *Input) string { return in.URL })},
+	"host":    {eqOps, buildHost},
+	"path": {eqOps, buildRegex(func(in *Input) string {
+		if u, err := url.Parse(in.URL); err == nil {
+			return u.Path
+		}
+		return ""
+	})},
+	"site":   {eqOps, buildFold(func(in *Input) string { return in.Site })},
+	"author": {eqOps, buildFold(func(in *Input) string { return in.Author })},
+}
+
+// Fields returns the names of the supported condition fields.
+func Fields() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func newCondition(field, op, value string) (*condition, error) {
+	field = strings.ToLower(field)
+	def, ok := fields[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q, available: %s", field, strings.Join(Fields(), ", "))
+	}
+	if !slices.Contains(def.ops, op) {
+		return nil, fmt.Errorf("operator %q is not supported by field %q", op, field)
+	}
+	match, err := def.build(op, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s condition: %w", field, err)
+	}
+	return &condition{field: field, op: op, value: value, match: match}, nil
+}
+
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func compare(op string, c int) bool {
+	switch op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	default:
+		return c == 0
+	}
+}
+
+// ParseSize parses sizes such as 1024, 10KB, 1.5G or 2GiB. Units are binary.
+func ParseSize(s string) (int64, error) {
+	num := strings.ToUpper(strings.TrimSpace(s))
+	num = strings.TrimSuffix(num, "B")
+	num = strings.TrimSuffix(num, "I")
+	mult := 1.0
+	if n := len(num); n > 0 {
+		if i := strings.IndexByte("KMGT", num[n-1]); i >= 0 {
+			mult = float64(int64(1) << (10 * (i + 1)))
+			num = num[:n-1]
+		}
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(f * mult), nil
+}
+
+func buildSize(op, value string) (func(in *Input) bool, error) {
+	if op == ":" {
+		if from, to, ok := strings.Cut(value, "-"); ok {
+			lo, err := ParseSize(from)
+			if err != nil {
+				return nil, err
+			}
+			hi, err := ParseSize(to)
+			if err != nil {
+				return nil, err
+			}
+			if lo > hi {
+				return nil, fmt.Errorf("empty size range %q", value)
+			}
+			return func(in *Input) bool {
+				return in.FileSize >= lo && in.FileSize <= hi
+			}, nil
+		}
+	}
+	size, err := ParseSize(value)
+	if err != nil {
+		return nil, err
+	}
+	return func(in *Input) bool {
+		if in.FileSize < 0 {
+			return false
+		}
+		return compare(op, cmp.Compare(in.FileSize, size))
+	}, nil
+}
+
+func buildMIME(_, value string) (func(in *Input) bool, error) {
+	patterns := splitList(strings.ToLower(value))
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q", p)
+		}
+	}
+	return func(in *Input) bool {
+		mime := strings.ToLower(in.MIMEType)
+		if mime == "" {
+			return false
+		}
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, mime); ok {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func buildExt(_, value string) (func(in *Input) bool, error) {
+	exts := splitList(strings.ToLower(value))
+	if len(exts) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	for i := range exts {
+		exts[i] = strings.TrimPrefix(exts[i], ".")
+	}
+	return func(in *Input) bool {
+		ext := strings.ToLower(strings.TrimPrefix(path.Ext(in.FileName), "."))
+		return ext != "" && slices.Contains(exts, ext)
+	}, nil
+}
+
+func buildRegex(get func(in *Input) string) func(op, value string) (func(in *Input) bool, error) {
+	return func(_, value string) (func(in *Input) bool, error) {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(in *Input) bool {
+			return re.MatchString(get(in))
+		}, nil
+	}
+}
+
+// NormalizeChatID strips the -100 channel prefix and the sign of chat IDs, so
+// that IDs copied from bots, links and the API compare equal.
+func NormalizeChatID(id int64) int64 {
+	if id < -1000000000000 {
+		return -id - 1000000000000
+	}
+	if id < 0 {
+		return -id
+	}
+	return id
+}
+
+type peerPattern struct {
+	id       int64
+	username string
+}
+
+func parsePeerPatterns(value string) ([]peerPattern, error) {
+	items := splitList(value)
+	if len(items) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	patterns := make([]peerPattern, 0, len(items))
+	for _, item := range items {
+		if id, err := strconv.ParseInt(item, 10, 64); err == nil {
+			patterns = append(patterns, peerPattern{id: NormalizeChatID(id)})
+			continue
+		}
+		patterns = append(patterns, peerPattern{username: strings.ToLower(strings.TrimPrefix(item, "@"))})
+	}
+	return patterns, nil
+}
+
+func matchPeer(patterns []peerPattern, id int64, username string) bool {
+	username = strings.ToLower(strings.TrimPrefix(username, "@"))
+	for _, p := range patterns {
+		if p.username != "" && p.username == username {
+			return true
+		}
+		if p.id != 0 && id != 0 && p.id == NormalizeChatID(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func buildPeer(get func(in *Input) (int64, string)) func(op, value string) (func(in *Input) bool, error) {
+	return func(_, value string) (func(in *Input) bool, error) {
+		patterns, err := parsePeerPatterns(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(in *Input) bool {
+			id, username := get(in)
+			return matchPeer(patterns, id, username)
+		}, nil
+	}
+}
+
+// buildForward matches the origin of forwarded messages. "*" matches any
+// forwarded message; other values match the origin ID, username or the name
+// shown for hidden senders.
+func buildForward(_, value string) (func(in *Input) bool, error) {
+	if value == "*" {
+		return func(in *Input) bool { return in.Forwarded }, nil
+	}
+	patterns, err := parsePeerPatterns(value)
+	if err != nil {
+		return nil, err
+	}
+	return func(in *Input) bool {
+		if !in.Forwarded {
+			return false
+		}
+		if matchPeer(patterns, in.ForwardFromID, in.ForwardFromUsername) {
+			return true
+		}
+		name := strings.ToLower(in.ForwardFromName)
+		return name != "" && slices.ContainsFunc(patterns, func(p peerPattern) bool { return p.username == name })
+	}, nil
+}
+
+func buildTag(_, value string) (func(in *Input) bool, error) {
+	tags := splitList(strings.ToLower(value))
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	for i := range tags {
+		tags[i] = strings.TrimPrefix(tags[i], "#")
+	}
+	return func(in *Input) bool {
+		for _, tag := range in.Tags {
+			if slices.Contains(tags, strings.ToLower(strings.TrimPrefix(tag, "#"))) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func buildMedia(_, value string) (func(in *Input) bool, error) {
+	items := splitList(strings.ToLower(value))
+	if len(items) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	types := make([]MediaType, 0, len(items))
+	for _, item := range items {
+		if !slices.Contains(MediaTypes(), MediaType(item)) {
+			return nil, fmt.Errorf("unknown media type %q", item)
+		}
+		types = append(types, MediaType(item))
+	}
+	return func(in *Input) bool {
+		return slices.Contains(types, in.MediaType)
+	}, nil
+}
+
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+func parseDate(s string) (time.Time, bool, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, layout == "2006-01-02", nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date %q, use YYYY-MM-DD or RFC 3339", s)
+}
+
+// buildDate compares the message date. With ":" or "=" a date without a time
+// matches the whole day.
+func buildDate(op, value string) (func(in *Input) bool, error) {
+	t, dayOnly, err := parseDate(value)
+	if err != nil {
+		return nil, err
+	}
+	return func(in *Input) bool {
+		if in.Date.IsZero() {
+			return false
+		}
+		if dayOnly && (op == ":" || op == "=") {
+			y1, m1, d1 := in.Date.In(time.Local).Date()
+			y2, m2, d2 := t.Date()
+			return y1 == y2 && m1 == m2 && d1 == d2
+		}
+		return compare(op, in.Date.Compare(t))
+	}, nil
+}
+
+func buildAlbum(_, value string) (func(in *Input) bool, error) {
+	want, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid boolean %q", value)
+	}
+	return func(in *Input) bool {
+		return in.IsAlbum == want
+	}, nil
+}
+
+// buildHost matches the host of the URL. A domain also matches its subdomains,
+// so host:example.com matches www.example.com.
+func buildHost(_, value string) (func(in *Input) bool, error) {
+	hosts := splitList(strings.ToLower(value))
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return func(in *Input) bool {
+		u, err := url.Parse(in.URL)
+		if err != nil {
+			return false
+		}
+		host := strings.ToLower(u.Hostname())
+		if host == "" {
+			return false
+		}
+		for _, h := range hosts {
+			if host == h || strings.HasSuffix(host, "."+h) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// buildFold matches one of the comma separated values, ignoring case.
+func buildFold(get func(in *Input) string) func(op, value string) (func(in *Input) bool, error) {
+	return func(_, value string) (func(in *Input) bool, error) {
+		items := splitList(value)
+		if len(items) == 0 {
+			return nil, fmt.Errorf("empty value")
+		}
+		return func(in *Input) bool {
+			v := get(in)
+			return v != "" && slices.ContainsFunc(items, func(item string) bool {
+				return strings.EqualFold(item, v)
+			})
+		}, nil
+	}
+}
@@ -6,6 +6,7 @@ const (
 	FileNameRegex RuleType = "FILENAME-REGEX"
 	MessageRegex  RuleType = "MESSAGE-REGEX"
 	IsAlbum       RuleType = "IS-ALBUM"
+	Expression    RuleType = "EXPR"
 )
 
 func (r RuleType) String() string {
@@ -13,5 +14,5 @@ func (r RuleType) String() string {
 }
 
 func Values() []RuleType {
-	return []RuleType{FileNameRegex, MessageRegex, IsAlbum}
+	return []RuleType{FileNameRegex, MessageRegex, IsAlbum, Expression}
 }
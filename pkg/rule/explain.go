@@ -0,0 +1,58 @@
+package rule
+
+import "strings"
+
+// Reason is a condition that decided the result of an expression.
+type Reason struct {
+	Cond    string
+	Matched bool
+}
+
+func (r Reason) String() string {
+	if r.Matched {
+		return "✓ " + r.Cond
+	}
+	return "✗ " + r.Cond
+}
+
+// Explain evaluates the expression like Match and returns the conditions that
+// decided the result: every condition of a matching "and", the first failing
+// one otherwise, and the reverse for "or".
+func Explain(e Expr, in *Input) (bool, []Reason) {
+	switch e := e.(type) {
+	case andExpr:
+		var reasons []Reason
+		for _, sub := range e {
+			ok, rs := Explain(sub, in)
+			if !ok {
+				return false, rs
+			}
+			reasons = append(reasons, rs...)
+		}
+		return true, reasons
+	case orExpr:
+		var reasons []Reason
+		for _, sub := range e {
+			ok, rs := Explain(sub, in)
+			if ok {
+				return true, rs
+			}
+			reasons = append(reasons, rs...)
+		}
+		return false, reasons
+	case notExpr:
+		ok, rs := Explain(e.expr, in)
+		return !ok, rs
+	}
+	ok := e.Match(in)
+	return ok, []Reason{{Cond: e.String(), Matched: ok}}
+}
+
+// FormatReasons joins the reasons for display.
+func FormatReasons(reasons []Reason) string {
+	parts := make([]string, len(reasons))
+	for i, r := range reasons {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ", ")
+}
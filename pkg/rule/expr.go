@@ -0,0 +1,103 @@
+package rule
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Expr is a compiled rule expression.
+type Expr interface {
+	Match(in *Input) bool
+	// String returns the expression in a canonical form that Parse accepts.
+	String() string
+}
+
+type andExpr []Expr
+
+func (e andExpr) Match(in *Input) bool {
+	for _, sub := range e {
+		if !sub.Match(in) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e andExpr) String() string {
+	parts := make([]string, len(e))
+	for i, sub := range e {
+		parts[i] = sub.String()
+		if _, ok := sub.(orExpr); ok {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, " and ")
+}
+
+type orExpr []Expr
+
+func (e orExpr) Match(in *Input) bool {
+	for _, sub := range e {
+		if sub.Match(in) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e orExpr) String() string {
+	parts := make([]string, len(e))
+	for i, sub := range e {
+		parts[i] = sub.String()
+	}
+	return strings.Join(parts, " or ")
+}
+
+type notExpr struct {
+	expr Expr
+}
+
+func (e notExpr) Match(in *Input) bool {
+	return !e.expr.Match(in)
+}
+
+func (e notExpr) String() string {
+	switch e.expr.(type) {
+	case andExpr, orExpr:
+		return "not (" + e.expr.String() + ")"
+	}
+	return "not " + e.expr.String()
+}
+
+// Rule is a compiled rule ready to be evaluated.
+type Rule struct {
+	ID          uint
+	Priority    int
+	StorageName string
+	DirPath     string
+	Expr        Expr
+	Actions     Actions
+}
+
+// Sort orders rules for evaluation: higher priority first, rules with the same
+// priority newest (highest ID) first. Rules without priorities thus keep the
+// old behaviour, where the last created matching rule won.
+func Sort(rules []Rule) {
+	slices.SortStableFunc(rules, func(a, b Rule) int {
+		if a.Priority != b.Priority {
+			return cmp.Compare(b.Priority, a.Priority)
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+}
+
+// First returns the first of the sorted rules that matches the input.
+func First(rules []Rule, in *Input) (*Rule, bool) {
+	for i := range rules {
+		if rules[i].Expr.Match(in) {
+			return &rules[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,284 @@
+package rule
+
+import (
+	"testing"
+	"time"
+)
+
+func testInput() *Input {
+	return &Input{
+		FileName:        "Movie.2024.MKV",
+		FileSize:        300 << 20,
+		MIMEType:        "video/x-matroska",
+		MediaType:       MediaVideo,
+		ChatID:          -1001234567890,
+		ChatUsername:    "MovieChannel",
+		SenderID:        42,
+		Forwarded:       true,
+		ForwardFromID:   777,
+		ForwardFromName: "Hidden User",
+		Text:            "New release #movie #HD",
+		Tags:            []string{"movie", "HD"},
+		Date:            time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local),
+		IsAlbum:         false,
+	}
+}
+
+func TestParseAndMatch(t *testing.T) {
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{`size>100MB`, true},
+		{`size<=100MB`, false},
+		{`size:100MB-1GB`, true},
+		{`mime:video/*`, true},
+		{`mime:image/*,audio/*`, false},
+		{`ext:mp4,mkv`, true},
+		{`ext:.MKV`, true},
+		{`name:"^Movie\\.\\d{4}"`, true},
+		{`caption:release`, true},
+		{`chat:1234567890`, true},
+		{`chat:-1001234567890`, true},
+		{`chat:@moviechannel`, true},
+		{`chat:@other`, false},
+		{`sender:42`, true},
+		{`forward:*`, true},
+		{`forward:777`, true},
+		{`forward:"hidden user"`, true},
+		{`tag:#hd`, true},
+		{`tag:music`, false},
+		{`media:photo,video`, true},
+		{`media:voice`, false},
+		{`date:2024-05-01`, true},
+		{`date>=2024-06-01`, false},
+		{`date<2024-06-01T00:00:00Z`, true},
+		{`album:false`, true},
+		{`ext:mkv and size>1GB`, false},
+		{`ext:mkv && size>1GB || tag:movie`, true},
+		{`not media:photo and !album:true`, true},
+		{`(ext:mp4 or ext:mkv) and not (chat:@other or sender:1)`, true},
+		{`ext:mkv size>1MB`, true},
+		{`NOT ext:mkv OR media:photo`, false},
+	}
+	in := testInput()
+	for _, tc := range cases {
+		expr, err := Parse(tc.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tc.expr, err)
+			continue
+		}
+		if got := expr.Match(in); got != tc.want {
+			t.Errorf("%q matched %v, want %v", tc.expr, got, tc.want)
+		}
+		// The canonical form must parse to an equivalent expression.
+		again, err := Parse(expr.String())
+		if err != nil {
+			t.Errorf("Parse(%q) of canonical form failed: %v", expr.String(), err)
+			continue
+		}
+		if again.Match(in) != tc.want || again.String() != expr.String() {
+			t.Errorf("canonical form %q is not stable", expr.String())
+		}
+	}
+}
+
+func TestURLAndItemFields(t *testing.T) {
+	in := &Input{
+		FileName: "clip.mp4",
+		FileSize: -1,
+		URL:      "https://www.example.com/videos/clip.mp4?x=1",
+		Site:     "Twitter",
+		Author:   "SomeArtist",
+		Tags:     []string{"art"},
+	}
+	for _, tc := range []struct {
+		expr string
+		want bool
+	}{
+		{`host:example.com`, true},
+		{`host:www.example.com,other.org`, true},
+		{`host:ample.com`, false},
+		{`path:^/videos/`, true},
+		{`path:x=1`, false},
+		{`url:x=1`, true},
+		{`site:twitter,pixiv`, true},
+		{`site:pixiv`, false},
+		{`author:someartist`, true},
+		{`tag:art and ext:mp4`, true},
+	} {
+		expr, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tc.expr, err)
+		}
+		if got := expr.Match(in); got != tc.want {
+			t.Errorf("%q matched %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestUnknownAttributesDoNotMatch(t *testing.T) {
+	in := &Input{FileSize: -1}
+	for _, s := range []string{`size<1GB`, `mime:*`, `ext:mp4`, `chat:1`, `forward:*`, `date<2100-01-01`, `host:example.com`, `site:twitter`} {
+		expr, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", s, err)
+		}
+		if expr.Match(in) {
+			t.Errorf("%q matched an empty input", s)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		``,
+		`ext`,
+		`foo:bar`,
+		`size:big`,
+		`size:2GB-1GB`,
+		`ext:mp4 and`,
+		`(ext:mp4`,
+		`ext:mp4)`,
+		`name:"(unclosed`,
+		`name:"[a-"`,
+		`media:movie`,
+		`album:maybe`,
+		`date:yesterday`,
+		`ext>mp4`,
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestCompileLegacyTypes(t *testing.T) {
+	in := testInput()
+	for _, tc := range []struct {
+		typ, data string
+		want      bool
+	}{
+		{"FILENAME-REGEX", `(?i)\.mkv$`, true},
+		{"MESSAGE-REGEX", `#movie`, true},
+		{"IS-ALBUM", "true", false},
+		{"IS-ALBUM", "not a bool", true},
+		{"EXPR", "ext:mkv", true},
+	} {
+		expr, err := Compile(tc.typ, tc.data)
+		if err != nil {
+			t.Fatalf("Compile(%s, %q) failed: %v", tc.typ, tc.data, err)
+		}
+		if got := expr.Match(in); got != tc.want {
+			t.Errorf("Compile(%s, %q) matched %v, want %v", tc.typ, tc.data, got, tc.want)
+		}
+	}
+	if _, err := Compile("UNKNOWN", "x"); err == nil {
+		t.Error("Compile of an unknown type succeeded")
+	}
+}
+
+func TestFirstMatchByPriority(t *testing.T) {
+	always, _ := Parse("size>=0")
+	never, _ := Parse("size<0")
+	rules := []Rule{
+		{ID: 1, StorageName: "first-created", Expr: always},
+		{ID: 2, Priority: 10, StorageName: "never", Expr: never},
+		{ID: 3, Priority: 5, StorageName: "high", Expr: always},
+		{ID: 4, Priority: 5, StorageName: "high-later", Expr: always},
+	}
+	Sort(rules)
+	matched, ok := First(rules, testInput())
+	if !ok || matched.StorageName != "high-later" {
+		t.Fatalf("expected rule high-later to match first, got %+v", matched)
+	}
+	if _, ok := First(rules[:1], &Input{FileSize: -1}); ok {
+		t.Fatal("expected no match for an unknown size")
+	}
+}
+
+func TestFirstMatchKeepsLastCreatedWithoutPriority(t *testing.T) {
+	always, _ := Parse("size>=0")
+	// 未设置优先级时与旧版一致: 最后创建的匹配规则生效
+	rules := []Rule{
+		{ID: 1, StorageName: "old", Expr: always},
+		{ID: 2, StorageName: "new", Expr: always},
+	}
+	Sort(rules)
+	matched, ok := First(rules, testInput())
+	if !ok || matched.StorageName != "new" {
+		t.Fatalf("expected the last created rule to match, got %+v", matched)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	cases := []struct {
+		expr    string
+		want    bool
+		reasons string
+	}{
+		{`ext:mkv and size>100MB`, true, "✓ ext:mkv, ✓ size>100MB"},
+		{`ext:mkv and size>1GB and tag:movie`, false, "✗ size>1GB"},
+		{`ext:mp4 or tag:movie`, true, "✓ tag:movie"},
+		{`ext:mp4 or media:photo`, false, "✗ ext:mp4, ✗ media:photo"},
+		{`not ext:mkv`, false, "✓ ext:mkv"},
+	}
+	for _, c := range cases {
+		expr, err := Parse(c.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", c.expr, err)
+		}
+		got, reasons := Explain(expr, testInput())
+		if got != c.want || got != expr.Match(testInput()) {
+			t.Errorf("%q: got %v, want %v", c.expr, got, c.want)
+		}
+		if s := FormatReasons(reasons); s != c.reasons {
+			t.Errorf("%q: reasons %q, want %q", c.expr, s, c.reasons)
+		}
+	}
+}
+
+func TestParseActions(t *testing.T) {
+	a, err := ParseActions([]string{"skip", "rename={{.msgid}} {{.origname}}", "conflict=Overwrite", "post=transcode", "notify=-1001234567890"})
+	if err != nil {
+		t.Fatalf("ParseActions failed: %v", err)
+	}
+	want := Actions{
+		Rename:      "{{.msgid}} {{.origname}}",
+		Skip:        true,
+		Conflict:    "overwrite",
+		PostProcess: "transcode",
+		NotifyChat:  -1001234567890,
+	}
+	if a != want {
+		t.Fatalf("ParseActions = %+v, want %+v", a, want)
+	}
+	if got := a.String(); got != `skip rename="{{.msgid}} {{.origname}}" conflict=overwrite post=transcode notify=-1001234567890` {
+		t.Errorf("String() = %s", got)
+	}
+	for _, args := range [][]string{{"rename"}, {"notify=abc"}, {"color=red"}, {"skip=maybe"}} {
+		if _, err := ParseActions(args); err == nil {
+			t.Errorf("ParseActions(%q) succeeded, want error", args)
+		}
+	}
+	if a, _ := ParseActions(nil); !a.IsZero() {
+		t.Error("ParseActions(nil) returned actions")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{
+		"1024":  1024,
+		"10B":   10,
+		"1KB":   1024,
+		"1.5k":  1536,
+		"2MiB":  2 << 20,
+		"1 GB":  1 << 30,
+		"0.5TB": 1 << 39,
+	} {
+		got, err := ParseSize(s)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package rule
+
+import "time"
+
+type MediaType string
+
+const (
+	MediaPhoto     MediaType = "photo"
+	MediaVideo     MediaType = "video"
+	MediaAudio     MediaType = "audio"
+	MediaVoice     MediaType = "voice"
+	MediaSticker   MediaType = "sticker"
+	MediaAnimation MediaType = "animation"
+	MediaDocument  MediaType = "document"
+)
+
+func MediaTypes() []MediaType {
+	return []MediaType{MediaPhoto, MediaVideo, MediaAudio, MediaVoice, MediaSticker, MediaAnimation, MediaDocument}
+}
+
+// Input holds the attributes of a file and its source message that conditions
+// are evaluated against. Zero values mean the attribute is unknown, and
+// conditions on unknown attributes do not match.
+type Input struct {
+	FileName string
+	// FileSize is negative when the size is unknown.
+	FileSize  int64
+	MIMEType  string
+	MediaType MediaType
+
+	ChatID         int64
+	ChatUsername   string
+	SenderID       int64
+	SenderUsername string
+
+	Forwarded           bool
+	ForwardFromID       int64
+	ForwardFromUsername string
+	ForwardFromName     string
+
+	// Text is the message text or media caption.
+	Text    string
+	Tags    []string
+	Date    time.Time
+	IsAlbum bool
+
+	// URL is the source link of downloads that do not come from Telegram.
+	URL string
+	// Site and Author describe items returned by parsers.
+	Site   string
+	Author string
+}
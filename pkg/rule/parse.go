@@ -0,0 +1,293 @@
+package rule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse compiles a rule expression such as
+//
+//	(ext:mp4,mkv or mime:video/*) and size>100MB and not chat:@somechannel
+//
+// Conditions are written as <field><op><value>, where op is one of ":", "=",
+// ">", ">=", "<" or "<=". Values containing spaces or parentheses must be
+// quoted with double quotes. Conditions are combined with and/&&, or/|| and
+// not/!, grouped with parentheses; adjacent conditions are joined with and.
+func Parse(s string) (Expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok, ok := p.peek(); ok {
+		return nil, tok.errorf("unexpected %q", tok.text)
+	}
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokLParen tokenKind = iota
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokCond
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	pos   int
+	field string
+	op    string
+	value string
+}
+
+func (t token) errorf(format string, args ...any) error {
+	return fmt.Errorf("position %d: %s", t.pos+1, fmt.Sprintf(format, args...))
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isFieldChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isOpChar(c byte) bool {
+	return c == ':' || c == '=' || c == '>' || c == '<'
+}
+
+func tokenize(s string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == '!':
+			tokens = append(tokens, token{kind: tokNot, text: "!", pos: i})
+			i++
+		case strings.HasPrefix(s[i:], "&&"):
+			tokens = append(tokens, token{kind: tokAnd, text: "&&", pos: i})
+			i += 2
+		case strings.HasPrefix(s[i:], "||"):
+			tokens = append(tokens, token{kind: tokOr, text: "||", pos: i})
+			i += 2
+		default:
+			tok, next, err := scanWord(s, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i = next
+		}
+	}
+	return tokens, nil
+}
+
+// scanWord scans a keyword or a condition starting at i.
+func scanWord(s string, i int) (token, int, error) {
+	j := i
+	for j < len(s) && isFieldChar(s[j]) {
+		j++
+	}
+	word := s[i:j]
+	if j == len(s) || !isOpChar(s[j]) {
+		switch strings.ToLower(word) {
+		case "and":
+			return token{kind: tokAnd, text: word, pos: i}, j, nil
+		case "or":
+			return token{kind: tokOr, text: word, pos: i}, j, nil
+		case "not":
+			return token{kind: tokNot, text: word, pos: i}, j, nil
+		}
+		end := j
+		for end < len(s) && !isSpace(s[end]) && s[end] != '(' && s[end] != ')' {
+			end++
+		}
+		return token{}, 0, token{pos: i}.errorf("expected <field><op><value>, got %q", s[i:end])
+	}
+	if word == "" {
+		return token{}, 0, token{pos: i}.errorf("missing field name")
+	}
+
+	opStart := j
+	j++
+	if j < len(s) && s[j] == '=' && (s[opStart] == '>' || s[opStart] == '<') {
+		j++
+	}
+	op := s[opStart:j]
+
+	var value strings.Builder
+	if j < len(s) && s[j] == '"' {
+		j++
+		closed := false
+		for j < len(s) {
+			if s[j] == '\\' && j+1 < len(s) {
+				value.WriteByte(s[j+1])
+				j += 2
+				continue
+			}
+			if s[j] == '"' {
+				closed = true
+				j++
+				break
+			}
+			value.WriteByte(s[j])
+			j++
+		}
+		if !closed {
+			return token{}, 0, token{pos: i}.errorf("unterminated quoted value")
+		}
+	} else {
+		for j < len(s) && !isSpace(s[j]) && s[j] != '(' && s[j] != ')' {
+			value.WriteByte(s[j])
+			j++
+		}
+		if value.Len() == 0 {
+			return token{}, 0, token{pos: i}.errorf("missing value for %q", word)
+		}
+	}
+	return token{
+		kind:  tokCond,
+		text:  s[i:j],
+		pos:   i,
+		field: word,
+		op:    op,
+		value: value.String(),
+	}, j, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokOr {
+			break
+		}
+		p.pos++
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, next)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return orExpr(exprs), nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokOr || tok.kind == tokRParen {
+			break
+		}
+		if tok.kind == tokAnd {
+			p.pos++
+		}
+		next, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, next)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return andExpr(exprs), nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	switch tok.kind {
+	case tokNot:
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr: expr}, nil
+	case tokLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing, ok := p.peek()
+		if !ok || closing.kind != tokRParen {
+			return nil, tok.errorf("unclosed parenthesis")
+		}
+		p.pos++
+		return expr, nil
+	case tokCond:
+		cond, err := newCondition(tok.field, tok.op, tok.value)
+		if err != nil {
+			return nil, tok.errorf("%s", err)
+		}
+		return cond, nil
+	default:
+		return nil, tok.errorf("unexpected %q", tok.text)
+	}
+}
+
+// Compile compiles the data of a stored rule. The legacy rule types are
+// single conditions of the expression language.
+func Compile(ruleType, data string) (Expr, error) {
+	switch RuleType(strings.ToUpper(ruleType)) {
+	case FileNameRegex:
+		return newCondition("name", ":", data)
+	case MessageRegex:
+		return newCondition("caption", ":", data)
+	case IsAlbum:
+		// 旧版本中无法解析的值视为 false
+		matchAlbum, _ := strconv.ParseBool(data)
+		return newCondition("album", ":", strconv.FormatBool(matchAlbum))
+	case Expression:
+		return Parse(data)
+	default:
+		return nil, fmt.Errorf("unknown rule type: %s", ruleType)
+	}
+}
@@ -0,0 +1,161 @@
+// Package ruleset encodes the rules, directories and filename settings of a
+// user as a portable document, to back up or share them between users and
+// instances.
+package ruleset
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/krau/SaveAny-Bot/pkg/enums/fnamest"
+	"github.com/krau/SaveAny-Bot/pkg/fnametmpl"
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+	"github.com/pelletier/go-toml/v2"
+)
+
+const Version = 1
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+)
+
+// ParseFormat parses a format name, defaulting to JSON when empty.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", string(FormatJSON):
+		return FormatJSON, nil
+	case string(FormatTOML):
+		return FormatTOML, nil
+	}
+	return "", fmt.Errorf("unknown format %q, available: json, toml", s)
+}
+
+// Document is the exported settings of a user. Settings left out of a
+// document are kept when it is imported.
+type Document struct {
+	Version          int    `json:"version" toml:"version"`
+	ApplyRule        *bool  `json:"apply_rule,omitempty" toml:"apply_rule,omitempty"`
+	FilenameStrategy string `json:"filename_strategy,omitempty" toml:"filename_strategy,omitempty"`
+	FilenameTemplate string `json:"filename_template,omitempty" toml:"filename_template,omitempty"`
+	Dirs             []Dir  `json:"dirs" toml:"dirs"`
+	Rules            []Rule `json:"rules" toml:"rules"`
+}
+
+type Dir struct {
+	Storage string `json:"storage" toml:"storage"`
+	Path    string `json:"path" toml:"path"`
+}
+
+type Rule struct {
+	Type     string `json:"type" toml:"type"`
+	Data     string `json:"data" toml:"data"`
+	Storage  string `json:"storage" toml:"storage"`
+	Dir      string `json:"dir" toml:"dir"`
+	Priority int    `json:"priority,omitempty" toml:"priority,omitempty"`
+
+	Rename      string `json:"rename,omitempty" toml:"rename,omitempty"`
+	Skip        bool   `json:"skip,omitempty" toml:"skip,omitempty"`
+	Conflict    string `json:"conflict,omitempty" toml:"conflict,omitempty"`
+	PostProcess string `json:"post,omitempty" toml:"post,omitempty"`
+	NotifyChat  int64  `json:"notify,omitempty" toml:"notify,omitempty"`
+}
+
+func (r Rule) Actions() rule.Actions {
+	return rule.Actions{
+		Rename:      r.Rename,
+		Skip:        r.Skip,
+		Conflict:    r.Conflict,
+		PostProcess: r.PostProcess,
+		NotifyChat:  r.NotifyChat,
+	}
+}
+
+// Encode encodes the document in the given format.
+func Encode(doc *Document, format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(doc, "", "  ")
+	case FormatTOML:
+		return toml.Marshal(doc)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// Decode decodes a JSON or TOML document, detecting the format from the
+// content.
+func Decode(data []byte) (*Document, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	doc := &Document{}
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(doc)
+	} else {
+		err = toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	if doc.Version > Version {
+		return nil, fmt.Errorf("unsupported document version %d", doc.Version)
+	}
+	return doc, nil
+}
+
+// Validate checks every rule and dir of the document. hasStorage reports
+// whether the importing user can use a storage; checkActions validates the
+// actions of a rule against the config. Both may be nil.
+func (d *Document) Validate(hasStorage func(name string) bool, checkActions func(rule.Actions) error) error {
+	var errs []error
+	if d.FilenameStrategy != "" {
+		if _, err := fnamest.ParseFnameST(d.FilenameStrategy); err != nil {
+			errs = append(errs, fmt.Errorf("filename_strategy: %w", err))
+		}
+	}
+	if d.FilenameTemplate != "" {
+		if _, err := fnametmpl.Parse(d.FilenameTemplate); err != nil {
+			errs = append(errs, fmt.Errorf("filename_template: %w", err))
+		}
+	}
+	for i, dir := range d.Dirs {
+		if dir.Storage == "" || dir.Path == "" {
+			errs = append(errs, fmt.Errorf("dir %d: storage and path are required", i+1))
+			continue
+		}
+		if hasStorage != nil && !hasStorage(dir.Storage) {
+			errs = append(errs, fmt.Errorf("dir %d: storage %q not found", i+1, dir.Storage))
+		}
+	}
+	for i, r := range d.Rules {
+		if err := r.Validate(hasStorage, checkActions); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks a single rule the same way as Document.Validate.
+func (r Rule) Validate(hasStorage func(name string) bool, checkActions func(rule.Actions) error) error {
+	if r.Storage == "" || r.Dir == "" {
+		return errors.New("storage and dir are required")
+	}
+	if _, err := rule.Compile(r.Type, r.Data); err != nil {
+		return err
+	}
+	if r.Storage != rule.RuleStorNameChosen && hasStorage != nil && !hasStorage(r.Storage) {
+		return fmt.Errorf("storage %q not found", r.Storage)
+	}
+	if checkActions != nil {
+		if err := checkActions(r.Actions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package ruleset
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/krau/SaveAny-Bot/pkg/rule"
+)
+
+func testDocument() *Document {
+	applyRule := true
+	return &Document{
+		Version:          Version,
+		ApplyRule:        &applyRule,
+		FilenameStrategy: "template",
+		FilenameTemplate: "{{.msgid}}_{{.origname}}",
+		Dirs: []Dir{
+			{Storage: "local", Path: "/videos"},
+		},
+		Rules: []Rule{
+			{Type: "FILENAME-REGEX", Data: `\.mp4$`, Storage: "local", Dir: "/videos", Priority: 5, Conflict: "rename"},
+			{Type: "EXPR", Data: `ext:pdf and size>1MB`, Storage: "CHOSEN", Dir: "/docs", Skip: true, NotifyChat: -100123},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := testDocument()
+	for _, format := range []Format{FormatJSON, FormatTOML} {
+		data, err := Encode(want, format)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", format, err)
+		}
+		got, err := Decode(data)
+		if err != nil {
+			t.Fatalf("%s: decode: %v\n%s", format, err, data)
+		}
+		if got.Version != Version || got.ApplyRule == nil || *got.ApplyRule != *want.ApplyRule ||
+			got.FilenameStrategy != want.FilenameStrategy || got.FilenameTemplate != want.FilenameTemplate {
+			t.Errorf("%s: settings mismatch: %+v", format, got)
+		}
+		if len(got.Dirs) != 1 || got.Dirs[0] != want.Dirs[0] {
+			t.Errorf("%s: dirs mismatch: %+v", format, got.Dirs)
+		}
+		if len(got.Rules) != len(want.Rules) {
+			t.Fatalf("%s: got %d rules, want %d", format, len(got.Rules), len(want.Rules))
+		}
+		for i := range want.Rules {
+			if got.Rules[i] != want.Rules[i] {
+				t.Errorf("%s: rule %d = %+v, want %+v", format, i, got.Rules[i], want.Rules[i])
+			}
+		}
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	cases := map[string]string{
+		"unknown field":  `{"version": 1, "rulez": []}`,
+		"newer version":  `version = 99`,
+		"invalid syntax": `rules = [`,
+	}
+	for name, data := range cases {
+		if _, err := Decode([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	hasStorage := func(name string) bool { return name == "local" }
+	noPost := func(a rule.Actions) error {
+		if a.PostProcess != "" {
+			return errNoPost
+		}
+		return nil
+	}
+	doc := testDocument()
+	if err := doc.Validate(hasStorage, noPost); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc.FilenameStrategy = "nope"
+	doc.FilenameTemplate = "{{.msgid"
+	doc.Dirs = append(doc.Dirs, Dir{Storage: "remote", Path: "/a"})
+	doc.Rules = append(doc.Rules,
+		Rule{Type: "FILENAME-REGEX", Data: "(", Storage: "local", Dir: "/"},
+		Rule{Type: "EXPR", Data: "ext:mp4", Storage: "remote", Dir: "/"},
+		Rule{Type: "EXPR", Data: "ext:mp4", Storage: "local", Dir: "/", PostProcess: "x"},
+		Rule{Type: "UNKNOWN", Data: "x", Storage: "local", Dir: "/"},
+	)
+	err := doc.Validate(hasStorage, noPost)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"filename_strategy", "filename_template", "dir 2", "rule 3", "rule 4", "rule 5", "rule 6"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+var errNoPost = errors.New("post-processor not configured")
@@ -0,0 +1,30 @@
+package sidecar
+
+import (
+	"maps"
+
+	"github.com/krau/SaveAny-Bot/pkg/parser"
+)
+
+// FromParsedItem builds the sidecar metadata of one resource of a parsed item.
+func FromParsedItem(item *parser.Item, resource parser.Resource) *Metadata {
+	if item == nil {
+		return nil
+	}
+	meta := &Metadata{
+		FileName:  resource.Filename,
+		FileSize:  resource.Size,
+		Title:     item.Title,
+		SourceURL: item.URL,
+		Site:      item.Site,
+		Author:    item.Author,
+		Caption:   item.Description,
+		Tags:      item.Tags,
+	}
+	if len(item.Extra) > 0 || len(resource.Extra) > 0 {
+		meta.Extra = make(map[string]any, len(item.Extra)+len(resource.Extra))
+		maps.Copy(meta.Extra, item.Extra)
+		maps.Copy(meta.Extra, resource.Extra)
+	}
+	return meta
+}
@@ -0,0 +1,170 @@
+// Package sidecar describes the metadata written next to a saved file, so that
+// captions, tags and source information survive outside of Telegram.
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/pkg/enums/ctxkey"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatNFO  Format = "nfo"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(s))) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatNFO:
+		return FormatNFO, nil
+	default:
+		return "", fmt.Errorf("unsupported sidecar format: %s", s)
+	}
+}
+
+// Entity is a formatting entity of the source message text.
+type Entity struct {
+	Type   string `json:"type"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	URL    string `json:"url,omitempty"`
+}
+
+// Metadata is the information preserved in a sidecar file. Telegram sources
+// fill the chat/message fields, parsed items fill the site/author fields.
+type Metadata struct {
+	FileName  string         `json:"file_name,omitempty"`
+	FileSize  int64          `json:"file_size,omitempty"`
+	Title     string         `json:"title,omitempty"`
+	SourceURL string         `json:"source_url,omitempty"`
+	Site      string         `json:"site,omitempty"`
+	Author    string         `json:"author,omitempty"`
+	ChatID    int64          `json:"chat_id,omitempty"`
+	MessageID int            `json:"message_id,omitempty"`
+	Caption   string         `json:"caption,omitempty"`
+	Entities  []Entity       `json:"entities,omitempty"`
+	Tags      []string       `json:"tags,omitempty"`
+	Date      time.Time      `json:"date,omitzero"`
+	Extra     map[string]any `json:"extra,omitempty"`
+}
+
+// nfoDocument is a Kodi/Jellyfin compatible movie nfo.
+type nfoDocument struct {
+	XMLName   xml.Name      `xml:"movie"`
+	Title     string        `xml:"title,omitempty"`
+	Plot      string        `xml:"plot,omitempty"`
+	Studio    string        `xml:"studio,omitempty"`
+	Credits   string        `xml:"credits,omitempty"`
+	Premiered string        `xml:"premiered,omitempty"`
+	DateAdded string        `xml:"dateadded,omitempty"`
+	Tags      []string      `xml:"tag,omitempty"`
+	UniqueIDs []nfoUniqueID `xml:"uniqueid,omitempty"`
+}
+
+type nfoUniqueID struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Encode renders the metadata in the given format.
+func (m *Metadata) Encode(format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(m, "", "  ")
+	case FormatNFO:
+		doc := nfoDocument{
+			Title:   m.Title,
+			Plot:    m.Caption,
+			Studio:  m.Site,
+			Credits: m.Author,
+			Tags:    m.Tags,
+		}
+		if doc.Title == "" {
+			doc.Title = strings.TrimSuffix(m.FileName, path.Ext(m.FileName))
+		}
+		if !m.Date.IsZero() {
+			doc.Premiered = m.Date.Format(time.DateOnly)
+			doc.DateAdded = m.Date.Format(time.DateTime)
+		}
+		if m.SourceURL != "" {
+			doc.UniqueIDs = append(doc.UniqueIDs, nfoUniqueID{Type: "url", Value: m.SourceURL})
+		}
+		if m.ChatID != 0 && m.MessageID != 0 {
+			doc.UniqueIDs = append(doc.UniqueIDs, nfoUniqueID{
+				Type:  "telegram",
+				Value: strconv.FormatInt(m.ChatID, 10) + "/" + strconv.Itoa(m.MessageID),
+			})
+		}
+		out, err := xml.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(xml.Header), out...), nil
+	default:
+		return nil, fmt.Errorf("unsupported sidecar format: %s", format)
+	}
+}
+
+// PathFor returns the sidecar path of filePath. Nfo files replace the file
+// extension as media centers expect, json files are appended to the full name
+// so files differing only by extension do not share a sidecar.
+func PathFor(filePath string, format Format) string {
+	if format == FormatNFO {
+		return strings.TrimSuffix(filePath, path.Ext(filePath)) + ".nfo"
+	}
+	return filePath + "." + string(format)
+}
+
+// Saver is the subset of storage.Storage needed to write a sidecar.
+type Saver interface {
+	Save(ctx context.Context, reader io.Reader, storagePath string) error
+}
+
+// Write encodes meta and saves it next to filePath through saver.
+func Write(ctx context.Context, saver Saver, format string, filePath string, meta *Metadata) error {
+	if meta == nil {
+		return nil
+	}
+	f, err := ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	data, err := meta.Encode(f)
+	if err != nil {
+		return fmt.Errorf("failed to encode sidecar: %w", err)
+	}
+	ctx = context.WithValue(ctx, ctxkey.ContentLength, int64(len(data)))
+	return saver.Save(ctx, bytes.NewReader(data), PathFor(filePath, f))
+}
+
+// Options controls whether and how Save writes sidecars.
+type Options struct {
+	Enable bool
+	Format string
+}
+
+// Save writes the metadata returned by meta next to filePath when opts enables
+// sidecars. meta is only called then. Failures are logged, a sidecar never
+// fails the saved file.
+func Save(ctx context.Context, opts Options, saver Saver, filePath string, meta func() *Metadata) {
+	if !opts.Enable {
+		return
+	}
+	if err := Write(ctx, saver, opts.Format, filePath, meta()); err != nil {
+		log.FromContext(ctx).Warnf("Failed to write sidecar for %s: %v", filePath, err)
+	}
+}
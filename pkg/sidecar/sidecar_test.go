@@ -0,0 +1,112 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krau/SaveAny-Bot/pkg/parser"
+)
+
+func TestPathFor(t *testing.T) {
+	cases := []struct {
+		path   string
+		format Format
+		want   string
+	}{
+		{"dir/video.mp4", FormatJSON, "dir/video.mp4.json"},
+		{"dir/video.mp4", FormatNFO, "dir/video.nfo"},
+		{"noext", FormatNFO, "noext.nfo"},
+	}
+	for _, c := range cases {
+		if got := PathFor(c.path, c.format); got != c.want {
+			t.Errorf("PathFor(%q, %q) = %q, want %q", c.path, c.format, got, c.want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat(""); err != nil || f != FormatJSON {
+		t.Fatalf("empty format should default to json, got %q %v", f, err)
+	}
+	if f, err := ParseFormat("NFO"); err != nil || f != FormatNFO {
+		t.Fatalf("format should be case-insensitive, got %q %v", f, err)
+	}
+	if _, err := ParseFormat("yaml"); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
+
+func TestEncodeNFO(t *testing.T) {
+	meta := &Metadata{
+		FileName:  "clip.mp4",
+		Caption:   "hello <world>",
+		Tags:      []string{"a", "b"},
+		ChatID:    100,
+		MessageID: 7,
+		Date:      time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+	}
+	out, err := meta.Encode(FormatNFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(out)
+	for _, want := range []string{
+		"<movie>",
+		"<title>clip</title>",
+		"<plot>hello &lt;world&gt;</plot>",
+		"<tag>a</tag>",
+		"<premiered>2024-05-06</premiered>",
+		`<uniqueid type="telegram">100/7</uniqueid>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("nfo output missing %q:\n%s", want, s)
+		}
+	}
+}
+
+type memSaver struct {
+	path string
+	data []byte
+}
+
+func (m *memSaver) Save(_ context.Context, r io.Reader, storagePath string) error {
+	m.path = storagePath
+	var buf bytes.Buffer
+	_, err := io.Copy(&buf, r)
+	m.data = buf.Bytes()
+	return err
+}
+
+func TestWriteParsedItem(t *testing.T) {
+	item := &parser.Item{
+		Site:   "example",
+		URL:    "https://example.com/post/1",
+		Title:  "Post",
+		Author: "alice",
+		Tags:   []string{"x"},
+		Extra:  map[string]any{"id": "1"},
+	}
+	res := parser.Resource{Filename: "1.jpg", Size: 3, Extra: map[string]any{"index": 0}}
+	saver := &memSaver{}
+	if err := Write(context.Background(), saver, "json", "dir/1.jpg", FromParsedItem(item, res)); err != nil {
+		t.Fatal(err)
+	}
+	if saver.path != "dir/1.jpg.json" {
+		t.Fatalf("unexpected sidecar path %q", saver.path)
+	}
+	var got Metadata
+	if err := json.Unmarshal(saver.data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Author != "alice" || got.SourceURL != item.URL || got.FileName != "1.jpg" {
+		t.Fatalf("unexpected metadata: %+v", got)
+	}
+	if got.Extra["id"] != "1" || got.Extra["index"] != float64(0) {
+		t.Fatalf("extra not merged: %+v", got.Extra)
+	}
+}
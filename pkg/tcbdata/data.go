@@ -5,6 +5,7 @@ import (
 
 	"github.com/krau/SaveAny-Bot/pkg/enums/tasktype"
 	"github.com/krau/SaveAny-Bot/pkg/parser"
+	"github.com/krau/SaveAny-Bot/pkg/storagetypes"
 	"github.com/krau/SaveAny-Bot/pkg/telegraph"
 	"github.com/krau/SaveAny-Bot/pkg/tfile"
 )
@@ -14,6 +15,7 @@ const (
 	TypeSetDefault = "setdefault"
 	TypeConfig     = "config"
 	TypeCancel     = "cancel"
+	TypeBrowse     = "ls"
 )
 
 const (
@@ -84,3 +86,17 @@ type SetDefaultStorage struct {
 	StorageName string
 	DirID       uint
 }
+
+// Browse is the state of a /ls file browser message.
+type Browse struct {
+	StorageName string
+	Path        string
+	Page        int
+	Files       []storagetypes.FileInfo // sorted, directories first
+}
+
+// BrowseRename is a file waiting for the user to reply with its new name.
+type BrowseRename struct {
+	StorageName string
+	Path        string
+}
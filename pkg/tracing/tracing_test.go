@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+func TestStartEnd(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	ctx, parent := Start(context.Background(), "task", TaskIDKey.String("t1"))
+	_, child := Start(ctx, "file")
+	End(child, errors.New("boom"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	file, task := spans[0], spans[1]
+	if file.Parent().SpanID() != task.SpanContext().SpanID() {
+		t.Error("file span is not a child of the task span")
+	}
+	if file.Status().Code != codes.Error || file.Status().Description != "boom" || len(file.Events()) != 1 {
+		t.Errorf("error not recorded: status=%v events=%d", file.Status(), len(file.Events()))
+	}
+	if task.Status().Code != codes.Unset {
+		t.Errorf("task status = %v, want unset", task.Status())
+	}
+	if attrs := task.Attributes(); len(attrs) != 1 || attrs[0] != TaskIDKey.String("t1") {
+		t.Errorf("task attributes = %v", attrs)
+	}
+}
+
+func TestStartServer(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, span := StartServer(context.Background(), header, "GET")
+	span.End()
+
+	got := recorder.Ended()[0]
+	if got.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the one from traceparent", got.SpanContext().TraceID())
+	}
+	if got.Parent().SpanID().String() != "00f067aa0ba902b7" || !got.Parent().IsRemote() {
+		t.Errorf("parent = %v, want the remote span from traceparent", got.Parent())
+	}
+}
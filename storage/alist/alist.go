@@ -416,3 +416,70 @@ func (a *Alist) OpenFile(ctx context.Context, filePath string) (io.ReadCloser, i
 	a.logger.Debugf("Opened file %s, size: %d bytes", filePath, getResp.Data.Size)
 	return downloadResp.Body, getResp.Data.Size, nil
 }
+
+// fsAction posts a json body to an Alist fs endpoint that returns no data
+func (a *Alist) fsAction(ctx context.Context, endpoint string, body any) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+endpoint, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", a.authHeader())
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+
+	var actionResp fsActionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&actionResp); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if actionResp.Code != http.StatusOK {
+		return fmt.Errorf("%s: %d, %s", endpoint, actionResp.Code, actionResp.Message)
+	}
+	return nil
+}
+
+// Delete implements StorageDeletable interface
+func (a *Alist) Delete(ctx context.Context, filePath string) error {
+	a.logger.Infof("Deleting %s", filePath)
+	return a.fsAction(ctx, "/api/fs/remove", map[string]any{
+		"dir":   path.Dir(filePath),
+		"names": []string{path.Base(filePath)},
+	})
+}
+
+// Move implements StorageMovable interface
+func (a *Alist) Move(ctx context.Context, srcPath, dstPath string) error {
+	a.logger.Infof("Moving %s to %s", srcPath, dstPath)
+	srcDir, dstDir := path.Dir(srcPath), path.Dir(dstPath)
+	name := path.Base(srcPath)
+	if srcDir != dstDir {
+		if err := a.fsAction(ctx, "/api/fs/mkdir", map[string]any{"path": dstDir}); err != nil {
+			return err
+		}
+		if err := a.fsAction(ctx, "/api/fs/move", map[string]any{
+			"src_dir": srcDir,
+			"dst_dir": dstDir,
+			"names":   []string{name},
+		}); err != nil {
+			return err
+		}
+	}
+	if newName := path.Base(dstPath); newName != name {
+		return a.fsAction(ctx, "/api/fs/rename", map[string]any{
+			"path": path.Join(dstDir, name),
+			"name": newName,
+		})
+	}
+	return nil
+}
@@ -89,3 +89,8 @@ type fsListResponse struct {
 		Provider string `json:"provider"`
 	} `json:"data"`
 }
+
+type fsActionResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,340 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/krau/SaveAny-Bot/pkg/metrics"
+	"github.com/krau/SaveAny-Bot/pkg/storagetypes"
+	"github.com/krau/SaveAny-Bot/pkg/tracing"
+)
+
+// storageCaps 是存储实现的可选接口集合, 包装后的存储必须保留完全相同的集合,
+// 否则调用方的类型断言结果会改变
+type storageCaps uint
+
+const (
+	capCannotStream storageCaps = 1 << iota
+	capProgressSaver
+	capBatchSaver
+	capBatchProgressSaver
+	capListable
+	capReadable
+	capDeletable
+	capMovable
+
+	// 可选接口按组包装, 每组由一个小的包装类型实现, 存储要么实现组中的全部接口, 要么都不实现
+	capFiles = capListable | capReadable | capDeletable | capMovable
+	capBatch = capProgressSaver | capBatchSaver | capBatchProgressSaver
+)
+
+func capabilities(s Storage) storageCaps {
+	var caps storageCaps
+	if _, ok := s.(StorageCannotStream); ok {
+		caps |= capCannotStream
+	}
+	if _, ok := s.(StorageProgressSaver); ok {
+		caps |= capProgressSaver
+	}
+	if _, ok := s.(StorageBatchSaver); ok {
+		caps |= capBatchSaver
+	}
+	if _, ok := s.(StorageBatchProgressSaver); ok {
+		caps |= capBatchProgressSaver
+	}
+	if _, ok := s.(StorageListable); ok {
+		caps |= capListable
+	}
+	if _, ok := s.(StorageReadable); ok {
+		caps |= capReadable
+	}
+	if _, ok := s.(StorageDeletable); ok {
+		caps |= capDeletable
+	}
+	if _, ok := s.(StorageMovable); ok {
+		caps |= capMovable
+	}
+	return caps
+}
+
+// instrumentedConstructors 由各组的包装组合出每种组合的包装类型, 覆盖 capFiles, capBatch 和 capCannotStream 的全部组合
+var instrumentedConstructors = map[storageCaps]func(m *instrumentedStorage) Storage{
+	0: func(m *instrumentedStorage) Storage { return m },
+	capFiles: func(m *instrumentedStorage) Storage {
+		return &instrumentedFileStorage{m, filesOf(m)}
+	},
+	capBatch: func(m *instrumentedStorage) Storage {
+		return &instrumentedBatchStorage{m, batchOf(m)}
+	},
+	capCannotStream: func(m *instrumentedStorage) Storage {
+		return &instrumentedStreamlessStorage{m, cannotStreamOf(m)}
+	},
+	capFiles | capBatch: func(m *instrumentedStorage) Storage {
+		return &instrumentedFileBatchStorage{m, filesOf(m), batchOf(m)}
+	},
+	capFiles | capCannotStream: func(m *instrumentedStorage) Storage {
+		return &instrumentedStreamlessFileStorage{m, filesOf(m), cannotStreamOf(m)}
+	},
+	capBatch | capCannotStream: func(m *instrumentedStorage) Storage {
+		return &instrumentedStreamlessBatchStorage{m, batchOf(m), cannotStreamOf(m)}
+	},
+	capFiles | capBatch | capCannotStream: func(m *instrumentedStorage) Storage {
+		return &instrumentedStreamlessFileBatchStorage{m, filesOf(m), batchOf(m), cannotStreamOf(m)}
+	},
+}
+
+func filesOf(m *instrumentedStorage) instrumentedFiles {
+	return instrumentedFiles{m.Storage.(fileStorage), m.Name()}
+}
+
+func batchOf(m *instrumentedStorage) instrumentedBatch {
+	return instrumentedBatch{m.Storage.(batchStorage), m.Name()}
+}
+
+func cannotStreamOf(m *instrumentedStorage) cannotStream {
+	return cannotStream{m.Storage.(StorageCannotStream)}
+}
+
+// instrument 包装存储以记录上传和读取的字节数, 上传耗时和保存的 span.
+// 只实现了组中部分接口的存储不做包装, 以免改变存储的能力, TestInstrumentKeepsCapabilities 会对这样的已注册存储报错
+func instrument(s Storage) Storage {
+	construct, ok := instrumentedConstructors[capabilities(s)]
+	if !ok {
+		log.Warnf("Storage %s has no instrumented wrapper for its capabilities (%b), metrics and traces are disabled for it", s.Name(), capabilities(s))
+		return s
+	}
+	return construct(&instrumentedStorage{Storage: s})
+}
+
+type instrumentedStorage struct {
+	Storage
+}
+
+func (m *instrumentedStorage) Save(ctx context.Context, reader io.Reader, storagePath string) error {
+	ctx, done := startSave(ctx, m.Name(), "storage.save", storagePath)
+	reader, uploaded := countUpload(reader)
+	err := m.Storage.Save(ctx, reader, storagePath)
+	done(uploaded(), err)
+	return err
+}
+
+// startSave 开始一次保存的 span, 返回的函数结束 span 并记录上传的字节数和耗时
+func startSave(ctx context.Context, name, op, storagePath string) (context.Context, func(n int64, err error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, op, tracing.StorageKey.String(name), tracing.FilePathKey.String(storagePath))
+	return ctx, func(n int64, err error) {
+		span.SetAttributes(tracing.FileSizeKey.Int64(n))
+		tracing.End(span, err)
+		metrics.ObserveUpload(name, n, time.Since(start), err)
+	}
+}
+
+// countUpload 返回传给存储的 reader 和读取的字节数.
+// 可 Seek 的 reader 通过偏移量计算, 避免包装后丢失 *os.File 等具体类型
+func countUpload(r io.Reader) (io.Reader, func() int64) {
+	if seeker, ok := r.(io.Seeker); ok {
+		if start, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			return r, func() int64 {
+				end, err := seeker.Seek(0, io.SeekCurrent)
+				if err != nil || end < start {
+					return 0
+				}
+				return end - start
+			}
+		}
+	}
+	cr := &countingReader{Reader: r}
+	return cr, cr.n.Load
+}
+
+type countingReader struct {
+	io.Reader
+	n atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+type fileStorage interface {
+	StorageListable
+	StorageReadable
+	StorageDeletable
+	StorageMovable
+}
+
+type instrumentedFiles struct {
+	files fileStorage
+	name  string
+}
+
+func (m instrumentedFiles) ListFiles(ctx context.Context, dirPath string) ([]storagetypes.FileInfo, error) {
+	return m.files.ListFiles(ctx, dirPath)
+}
+
+func (m instrumentedFiles) OpenFile(ctx context.Context, filePath string) (io.ReadCloser, int64, error) {
+	rc, size, err := m.files.OpenFile(ctx, filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	// 保留 Seek 能力, 调用方依赖它处理 Range 请求
+	if rsc, ok := rc.(readSeekCloser); ok {
+		return &downloadReadSeekCloser{readSeekCloser: rsc, name: m.name}, size, nil
+	}
+	return &downloadReadCloser{ReadCloser: rc, name: m.name}, size, nil
+}
+
+func (m instrumentedFiles) Delete(ctx context.Context, filePath string) error {
+	return m.files.Delete(ctx, filePath)
+}
+
+func (m instrumentedFiles) Move(ctx context.Context, srcPath, dstPath string) error {
+	return m.files.Move(ctx, srcPath, dstPath)
+}
+
+type readSeekCloser interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+type downloadReadCloser struct {
+	io.ReadCloser
+	name string
+}
+
+func (r *downloadReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	metrics.AddStorageBytes(r.name, metrics.DirectionDownload, int64(n))
+	return n, err
+}
+
+type downloadReadSeekCloser struct {
+	readSeekCloser
+	name string
+}
+
+func (r *downloadReadSeekCloser) Read(p []byte) (int, error) {
+	n, err := r.readSeekCloser.Read(p)
+	metrics.AddStorageBytes(r.name, metrics.DirectionDownload, int64(n))
+	return n, err
+}
+
+type batchStorage interface {
+	StorageProgressSaver
+	StorageBatchProgressSaver
+}
+
+type instrumentedBatch struct {
+	batch batchStorage
+	name  string
+}
+
+func (m instrumentedBatch) SaveWithProgress(
+	ctx context.Context,
+	reader io.Reader,
+	storagePath string,
+	onProgress func(uploaded, total int64),
+) error {
+	ctx, done := startSave(ctx, m.name, "storage.save", storagePath)
+	var uploaded atomic.Int64
+	err := m.batch.SaveWithProgress(ctx, reader, storagePath, func(n, total int64) {
+		uploaded.Store(n)
+		if onProgress != nil {
+			onProgress(n, total)
+		}
+	})
+	done(uploaded.Load(), err)
+	return err
+}
+
+func (m instrumentedBatch) SaveBatch(ctx context.Context, items []storagetypes.BatchItem) error {
+	ctx, done := startSave(ctx, m.name, "storage.save_batch", batchPath(items))
+	err := m.batch.SaveBatch(ctx, items)
+	done(batchSize(items, err), err)
+	return err
+}
+
+func (m instrumentedBatch) SaveBatchWithProgress(
+	ctx context.Context,
+	items []storagetypes.BatchItem,
+	onProgress func(index int, uploaded, total int64),
+) error {
+	ctx, done := startSave(ctx, m.name, "storage.save_batch", batchPath(items))
+	err := m.batch.SaveBatchWithProgress(ctx, items, onProgress)
+	done(batchSize(items, err), err)
+	return err
+}
+
+// batchPath 返回批量中第一个文件的路径, 用作 span 的属性
+func batchPath(items []storagetypes.BatchItem) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return items[0].StoragePath
+}
+
+// batchSize 返回成功保存的批量文件总大小, 失败时无法得知已上传的部分
+func batchSize(items []storagetypes.BatchItem, err error) int64 {
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, item := range items {
+		total += item.Size
+	}
+	return total
+}
+
+// cannotStream 只转发 CannotStream, 直接嵌入 StorageCannotStream 会与 instrumentedStorage 的方法冲突
+type cannotStream struct {
+	s StorageCannotStream
+}
+
+func (c cannotStream) CannotStream() string {
+	return c.s.CannotStream()
+}
+
+type instrumentedFileStorage struct {
+	*instrumentedStorage
+	instrumentedFiles
+}
+
+type instrumentedBatchStorage struct {
+	*instrumentedStorage
+	instrumentedBatch
+}
+
+type instrumentedStreamlessStorage struct {
+	*instrumentedStorage
+	cannotStream
+}
+
+type instrumentedFileBatchStorage struct {
+	*instrumentedStorage
+	instrumentedFiles
+	instrumentedBatch
+}
+
+type instrumentedStreamlessFileStorage struct {
+	*instrumentedStorage
+	instrumentedFiles
+	cannotStream
+}
+
+type instrumentedStreamlessBatchStorage struct {
+	*instrumentedStorage
+	instrumentedBatch
+	cannotStream
+}
+
+type instrumentedStreamlessFileBatchStorage struct {
+	*instrumentedStorage
+	instrumentedFiles
+	instrumentedBatch
+	cannotStream
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	storcfg "github.com/krau/SaveAny-Bot/config/storage"
+	storenum "github.com/krau/SaveAny-Bot/pkg/enums/storage"
+	"github.com/krau/SaveAny-Bot/pkg/storagetypes"
+)
+
+func TestInstrumentKeepsCapabilities(t *testing.T) {
+	for typ, constructor := range storageConstructors {
+		s := constructor()
+		wrapped := instrument(s)
+		if wrapped == s {
+			t.Errorf("%s: storage is not instrumented, add its capabilities to instrumentedConstructors", typ)
+			continue
+		}
+		if got, want := capabilities(wrapped), capabilities(s); got != want {
+			t.Errorf("%s: capabilities = %b, want %b", typ, got, want)
+		}
+	}
+}
+
+// fullStorage 实现了全部可选接口
+type fullStorage struct{}
+
+func (fullStorage) Init(context.Context, storcfg.StorageConfig) error { return nil }
+func (fullStorage) Type() storenum.StorageType                        { return storenum.Local }
+func (fullStorage) Name() string                                      { return "full" }
+func (fullStorage) Save(context.Context, io.Reader, string) error     { return nil }
+func (fullStorage) Exists(context.Context, string) bool               { return false }
+func (fullStorage) CannotStream() string                              { return "" }
+func (fullStorage) SaveWithProgress(context.Context, io.Reader, string, func(int64, int64)) error {
+	return nil
+}
+func (fullStorage) SaveBatch(context.Context, []storagetypes.BatchItem) error { return nil }
+func (fullStorage) SaveBatchWithProgress(context.Context, []storagetypes.BatchItem, func(int, int64, int64)) error {
+	return nil
+}
+func (fullStorage) ListFiles(context.Context, string) ([]storagetypes.FileInfo, error) {
+	return nil, nil
+}
+func (fullStorage) OpenFile(context.Context, string) (io.ReadCloser, int64, error) {
+	return nil, 0, nil
+}
+func (fullStorage) Delete(context.Context, string) error       { return nil }
+func (fullStorage) Move(context.Context, string, string) error { return nil }
+
+func TestInstrumentedConstructorsCoverEveryGroupCombination(t *testing.T) {
+	groups := []storageCaps{capFiles, capBatch, capCannotStream}
+	for mask := range 1 << len(groups) {
+		var want storageCaps
+		for i, group := range groups {
+			if mask&(1<<i) != 0 {
+				want |= group
+			}
+		}
+		construct, ok := instrumentedConstructors[want]
+		if !ok {
+			t.Errorf("no instrumented wrapper for capabilities %b", want)
+			continue
+		}
+		if got := capabilities(construct(&instrumentedStorage{Storage: fullStorage{}})); got != want {
+			t.Errorf("wrapper for %b has capabilities %b", want, got)
+		}
+	}
+}
+
+func TestCountUpload(t *testing.T) {
+	seekable := bytes.NewReader([]byte("0123456789"))
+	seekable.Seek(2, io.SeekStart)
+	r, uploaded := countUpload(seekable)
+	if r != io.Reader(seekable) {
+		t.Fatal("seekable reader should be passed through")
+	}
+	io.CopyN(io.Discard, r, 5)
+	if got := uploaded(); got != 5 {
+		t.Fatalf("seekable uploaded = %d, want 5", got)
+	}
+
+	// MultiReader 不可 Seek
+	r, uploaded = countUpload(io.MultiReader(strings.NewReader("0123456789")))
+	io.Copy(io.Discard, r)
+	if got := uploaded(); got != 10 {
+		t.Fatalf("uploaded = %d, want 10", got)
+	}
+}
@@ -145,3 +145,53 @@ func (l *Local) OpenFile(ctx context.Context, filePath string) (io.ReadCloser, i
 
 	return file, stat.Size(), nil
 }
+
+// resolvePath 将存储路径拼接到 base_path, 并拒绝指向 base_path 之外的路径
+func (l *Local) resolvePath(storagePath string) (string, error) {
+	base, err := filepath.Abs(l.config.BasePath)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(l.JoinStoragePath(storagePath))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(base, absPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("local: invalid storage path: %s", storagePath)
+	}
+	return absPath, nil
+}
+
+// Delete implements StorageDeletable interface
+func (l *Local) Delete(ctx context.Context, filePath string) error {
+	absPath, err := l.resolvePath(filePath)
+	if err != nil {
+		return err
+	}
+	if !fileutil.IsExist(absPath) {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+	l.logger.Infof("Deleting %s", absPath)
+	return os.RemoveAll(absPath)
+}
+
+// Move implements StorageMovable interface
+func (l *Local) Move(ctx context.Context, srcPath, dstPath string) error {
+	src, err := l.resolvePath(srcPath)
+	if err != nil {
+		return err
+	}
+	dst, err := l.resolvePath(dstPath)
+	if err != nil {
+		return err
+	}
+	if fileutil.IsExist(dst) {
+		return fmt.Errorf("target already exists: %s", dstPath)
+	}
+	if err := fileutil.CreateDir(filepath.Dir(dst)); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	l.logger.Infof("Moving %s to %s", src, dst)
+	return os.Rename(src, dst)
+}
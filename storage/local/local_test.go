@@ -0,0 +1,57 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	config "github.com/krau/SaveAny-Bot/config/storage"
+)
+
+func newTestLocal(t *testing.T) (*Local, string) {
+	t.Helper()
+	base := t.TempDir()
+	l := new(Local)
+	if err := l.Init(context.Background(), &config.LocalStorageConfig{
+		BaseConfig: config.BaseConfig{Name: "test"},
+		BasePath:   base,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return l, base
+}
+
+func TestMoveAndDelete(t *testing.T) {
+	l, base := newTestLocal(t)
+	ctx := context.Background()
+	if err := os.WriteFile(filepath.Join(base, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Move(ctx, "a.txt", "sub/b.txt"); err != nil {
+		t.Fatalf("move failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "sub", "b.txt")); err != nil {
+		t.Fatalf("moved file not found: %v", err)
+	}
+	if err := l.Delete(ctx, "sub/b.txt"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "sub", "b.txt")); !os.IsNotExist(err) {
+		t.Fatalf("file should be deleted: %v", err)
+	}
+}
+
+func TestRejectPathsOutsideBase(t *testing.T) {
+	l, _ := newTestLocal(t)
+	ctx := context.Background()
+	for _, p := range []string{"", "/", "..", "../x", "sub/../../x"} {
+		if err := l.Delete(ctx, p); err == nil {
+			t.Errorf("Delete(%q) should fail", p)
+		}
+	}
+	if err := l.Move(ctx, "a.txt", "../a.txt"); err == nil {
+		t.Error("Move outside of base path should fail")
+	}
+}
@@ -8,4 +8,6 @@ var (
 	ErrFailedToSaveFile  = errors.New("rclone: failed to save file")
 	ErrFailedToListFiles = errors.New("rclone: failed to list files")
 	ErrFailedToOpenFile  = errors.New("rclone: failed to open file")
+	ErrFailedToDelete    = errors.New("rclone: failed to delete")
+	ErrFailedToMove      = errors.New("rclone: failed to move")
 )
@@ -286,3 +286,42 @@ func (r *rcloneCatReader) Close() error {
 	}
 	return nil
 }
+
+// Delete implements storage.StorageDeletable
+func (r *Rclone) Delete(ctx context.Context, filePath string) error {
+	r.logger.Infof("Deleting %s", filePath)
+
+	// deletefile 只能删除文件, 目录使用 purge
+	args := append(r.buildBaseArgs(), "deletefile", r.getRemotePath(filePath))
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "rclone", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err == nil {
+		return nil
+	}
+
+	stderr.Reset()
+	args = append(r.buildBaseArgs(), "purge", r.getRemotePath(filePath))
+	cmd = exec.CommandContext(ctx, "rclone", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		r.logger.Errorf("Failed to delete %s: %v, stderr: %s", filePath, err, stderr.String())
+		return fmt.Errorf("%w: %s", ErrFailedToDelete, stderr.String())
+	}
+	return nil
+}
+
+// Move implements storage.StorageMovable
+func (r *Rclone) Move(ctx context.Context, srcPath, dstPath string) error {
+	r.logger.Infof("Moving %s to %s", srcPath, dstPath)
+
+	args := append(r.buildBaseArgs(), "moveto", r.getRemotePath(srcPath), r.getRemotePath(dstPath))
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "rclone", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		r.logger.Errorf("Failed to move %s: %v, stderr: %s", srcPath, err, stderr.String())
+		return fmt.Errorf("%w: %s", ErrFailedToMove, stderr.String())
+	}
+	return nil
+}
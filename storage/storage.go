@@ -75,6 +75,18 @@ type StorageReadable interface {
 	OpenFile(ctx context.Context, filePath string) (io.ReadCloser, int64, error)
 }
 
+// StorageDeletable 表示支持删除文件或目录的存储
+type StorageDeletable interface {
+	Storage
+	Delete(ctx context.Context, filePath string) error
+}
+
+// StorageMovable 表示支持移动或重命名文件的存储
+type StorageMovable interface {
+	Storage
+	Move(ctx context.Context, srcPath, dstPath string) error
+}
+
 var _ StorageProgressSaver = (*telegram.Telegram)(nil)
 var _ StorageBatchProgressSaver = (*telegram.Telegram)(nil)
 
@@ -87,6 +99,15 @@ var _ StorageReadable = (*rclone.Rclone)(nil)
 var _ StorageListable = (*webdav.Webdav)(nil)
 var _ StorageReadable = (*webdav.Webdav)(nil)
 
+var _ StorageDeletable = (*alist.Alist)(nil)
+var _ StorageMovable = (*alist.Alist)(nil)
+var _ StorageDeletable = (*local.Local)(nil)
+var _ StorageMovable = (*local.Local)(nil)
+var _ StorageDeletable = (*rclone.Rclone)(nil)
+var _ StorageMovable = (*rclone.Rclone)(nil)
+var _ StorageDeletable = (*webdav.Webdav)(nil)
+var _ StorageMovable = (*webdav.Webdav)(nil)
+
 type StorageConstructor func() Storage
 
 var storageConstructors = map[storenum.StorageType]StorageConstructor{
@@ -111,5 +132,17 @@ func NewStorage(ctx context.Context, cfg storcfg.StorageConfig) (Storage, error)
 		return nil, fmt.Errorf("failed to initialize storage %s: %w", cfg.GetName(), err)
 	}
 
-	return storage, nil
+	return instrument(storage), nil
+}
+
+// NewTelegramChatStorage 创建一个发送到指定聊天的临时 Telegram 存储, 用于将文件发回给用户
+func NewTelegramChatStorage(ctx context.Context, chatID int64) (Storage, error) {
+	return NewStorage(ctx, &storcfg.TelegramStorageConfig{
+		BaseConfig: storcfg.BaseConfig{
+			Name:   "Telegram",
+			Type:   string(storenum.Telegram),
+			Enable: true,
+		},
+		ChatID: chatID,
+	})
 }
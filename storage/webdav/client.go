@@ -27,6 +27,8 @@ const (
 	WebdavMethodPropfind WebdavMethod = "PROPFIND"
 	WebdavMethodPut      WebdavMethod = "PUT"
 	WebdavMethodGet      WebdavMethod = "GET"
+	WebdavMethodDelete   WebdavMethod = "DELETE"
+	WebdavMethodMove     WebdavMethod = "MOVE"
 )
 
 // WebDAV XML structures for PROPFIND response
@@ -211,6 +213,65 @@ func (c *Client) ListDir(ctx context.Context, dirPath string) ([]Response, error
 	return results, nil
 }
 
+// fileURL returns the escaped URL of a remote path
+func (c *Client) fileURL(remotePath string) (string, error) {
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = path.Join(u.Path, strings.Trim(remotePath, "/"))
+	return u.String(), nil
+}
+
+// Delete removes a file or a directory recursively
+func (c *Client) Delete(ctx context.Context, remotePath string) error {
+	u, err := c.fileURL(remotePath)
+	if err != nil {
+		return err
+	}
+	resp, err := c.doRequest(ctx, WebdavMethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return fmt.Errorf("DELETE: %s", resp.Status)
+}
+
+// Move moves a file or directory to dstPath, failing if dstPath exists
+func (c *Client) Move(ctx context.Context, srcPath, dstPath string) error {
+	src, err := c.fileURL(srcPath)
+	if err != nil {
+		return err
+	}
+	dst, err := c.fileURL(dstPath)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, string(WebdavMethodMove), src, nil)
+	if err != nil {
+		return err
+	}
+	if c.Username != "" && c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	req.Header.Set("Destination", dst)
+	req.Header.Set("Overwrite", "F")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return fmt.Errorf("MOVE: %s", resp.Status)
+}
+
 // ReadFile downloads a file and returns a ReadCloser
 func (c *Client) ReadFile(ctx context.Context, filePath string) (io.ReadCloser, int64, error) {
 	filePath = strings.Trim(filePath, "/")
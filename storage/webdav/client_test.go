@@ -132,3 +132,28 @@ func TestWriteFile(t *testing.T) {
 		})
 	}
 }
+
+func TestMoveAndDelete(t *testing.T) {
+	server, tempDir := setupWebDAVServer(t)
+	defer os.RemoveAll(tempDir)
+	defer server.Close()
+
+	client := NewClient(server.URL, "", "", nil)
+	ctx := context.Background()
+
+	if err := client.WriteFile(ctx, "old 名.txt", strings.NewReader("data")); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if err := client.Move(ctx, "old 名.txt", "new 名.txt"); err != nil {
+		t.Fatalf("移动文件失败: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "new 名.txt")); err != nil {
+		t.Fatalf("移动后的文件不存在: %v", err)
+	}
+	if err := client.Delete(ctx, "new 名.txt"); err != nil {
+		t.Fatalf("删除文件失败: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "new 名.txt")); !os.IsNotExist(err) {
+		t.Fatalf("文件应已被删除: %v", err)
+	}
+}
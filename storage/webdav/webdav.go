@@ -157,3 +157,25 @@ func (w *Webdav) OpenFile(ctx context.Context, filePath string) (io.ReadCloser,
 	w.logger.Debugf("Opened file %s (size: %d bytes)", filePath, size)
 	return reader, size, nil
 }
+
+// Delete implements storage.StorageDeletable
+func (w *Webdav) Delete(ctx context.Context, filePath string) error {
+	w.logger.Infof("Deleting %s", filePath)
+	if err := w.client.Delete(ctx, path.Join(w.config.BasePath, filePath)); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// Move implements storage.StorageMovable
+func (w *Webdav) Move(ctx context.Context, srcPath, dstPath string) error {
+	w.logger.Infof("Moving %s to %s", srcPath, dstPath)
+	dst := path.Join(w.config.BasePath, dstPath)
+	if err := w.client.MkDir(ctx, path.Dir(dst)); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := w.client.Move(ctx, path.Join(w.config.BasePath, srcPath), dst); err != nil {
+		return fmt.Errorf("failed to move %s: %w", srcPath, err)
+	}
+	return nil
+}